@@ -0,0 +1,291 @@
+package avro_file
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"github.com/hamba/avro"
+	"github.com/hamba/avro/ocf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// Compression is the codec used to compress each block of an avro object container file
+type Compression int
+
+const (
+	_            Compression = iota
+	Deflate                  // Deflate is supported by every avro implementation, this is the default
+	Snappy                   // Snappy is faster than Deflate with a lower compression ratio
+	Uncompressed             // Uncompressed stores the raw blocks
+)
+
+const (
+	deflateCompressionStr      = "deflate"
+	snappyCompressionStr       = "snappy"
+	uncompressedCompressionStr = "uncompressed"
+)
+
+var compressions = map[Compression]string{
+	Deflate:      deflateCompressionStr,
+	Snappy:       snappyCompressionStr,
+	Uncompressed: uncompressedCompressionStr,
+}
+
+var codecs = map[Compression]ocf.CodecName{
+	Deflate:      ocf.Deflate,
+	Snappy:       ocf.Snappy,
+	Uncompressed: ocf.Null,
+}
+
+func (c Compression) String() string {
+	return compressions[c]
+}
+
+// Magic are the first bytes of every avro object container file
+var Magic = []byte{'O', 'b', 'j', 1}
+
+// Header metadata keys, see: https://avro.apache.org/docs/current/spec.html#Object+Container+Files
+const (
+	schemaKey = "avro.schema"
+	codecKey  = "avro.codec"
+)
+
+// DefaultBlockLength is the number of rows written in each block
+const DefaultBlockLength = 1000
+
+// Options control how the avro files are written
+type Options struct {
+	// Compression codec for the blocks, defaults to Deflate
+	Compression Compression
+	// BlockLength is the number of rows buffered before a block is written, defaults to DefaultBlockLength
+	BlockLength int
+}
+
+// DefaultOptions are deflate compressed files with the default block length
+func DefaultOptions() Options {
+	return Options{
+		Compression: Deflate,
+		BlockLength: DefaultBlockLength,
+	}
+}
+
+// WithDefaults fills in any missing options with their default value
+func (o Options) WithDefaults() Options {
+	defaults := DefaultOptions()
+	if o.Compression == 0 {
+		o.Compression = defaults.Compression
+	}
+	if o.BlockLength <= 0 {
+		o.BlockLength = defaults.BlockLength
+	}
+	return o
+}
+
+// Encoder writes the rows of an object container file in compressed blocks, each followed by the file's sync marker.
+type Encoder struct {
+	writer      *avro.Writer
+	block       *bytes.Buffer
+	encoder     *avro.Encoder
+	codec       ocf.Codec
+	sync        [16]byte
+	blockLength int
+	count       int
+}
+
+// NewEncoder writes the file header, including the schema, and returns an encoder for the rows.
+// The encoder must be closed to flush the last block.
+//
+// Errors:
+// - If the compression is unknown an error with GRPC status InvalidArgument will be returned
+//
+func NewEncoder(output io.Writer, schema avro.Schema, options Options) (*Encoder, error) {
+	options = options.WithDefaults()
+	codecName, ok := codecs[options.Compression]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown compression")
+	}
+
+	header := ocf.Header{
+		Meta: map[string][]byte{
+			schemaKey: []byte(schema.String()),
+			codecKey:  []byte(codecName),
+		},
+	}
+	copy(header.Magic[:], Magic)
+	_, err := rand.Read(header.Sync[:])
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// The header is written straight away, so a file without any rows is still valid
+	writer := avro.NewWriter(output, 512)
+	writer.WriteVal(ocf.HeaderSchema, header)
+	err = writer.Flush()
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	block := bytes.NewBuffer([]byte{})
+	return &Encoder{
+		writer:      writer,
+		block:       block,
+		encoder:     avro.NewEncoderForSchema(schema, block),
+		codec:       newCodec(options.Compression),
+		sync:        header.Sync,
+		blockLength: options.BlockLength,
+	}, nil
+}
+
+// Encode appends a row to the current block, and writes the block once it's full
+func (e *Encoder) Encode(value interface{}) error {
+	err := e.encoder.Encode(value)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	e.count++
+	if e.count < e.blockLength {
+		return nil
+	}
+	return e.flush()
+}
+
+// Close writes the last block, this does not close the underlying io.Writer
+func (e *Encoder) Close() error {
+	return e.flush()
+}
+
+func (e *Encoder) flush() error {
+	if e.count == 0 {
+		return nil
+	}
+	data := e.codec.Encode(e.block.Bytes())
+	e.writer.WriteLong(int64(e.count))
+	e.writer.WriteLong(int64(len(data)))
+	e.writer.Write(data)
+	e.writer.Write(e.sync[:])
+	e.count = 0
+	e.block.Reset()
+
+	err := e.writer.Flush()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func newCodec(compression Compression) ocf.Codec {
+	switch compression {
+	case Deflate:
+		return &ocf.DeflateCodec{}
+	case Snappy:
+		return &ocf.SnappyCodec{}
+	}
+	return &ocf.NullCodec{}
+}
+
+// IsContainer checks whether the input starts with the object container file magic bytes, without consuming them.
+// Anything else, including an empty input, is assumed to be a stream of raw datums.
+func IsContainer(input *bufio.Reader) (bool, error) {
+	header, err := input.Peek(len(Magic))
+	if nil != err && err == io.EOF {
+		return false, nil
+	}
+	if nil != err {
+		return false, status.Error(codes.Internal, err.Error())
+	}
+	return bytes.Equal(header, Magic), nil
+}
+
+// Decoder reads the rows of an object container file.
+//
+// Each file embeds the schema it was written with, and the rows are resolved to the reader's schema:
+// 1. Fields that only exist in the writer's schema are skipped
+// 2. Fields that only exist in the reader's schema are set to their default value
+// 3. Numbers are promoted to wider types, ex: int to long or float to double
+// 4. Unions and non-unions are resolved to the matching branch
+//
+type Decoder struct {
+	decoder *ocf.Decoder
+	reader  avro.Schema
+	writer  avro.Schema
+	resolve bool
+}
+
+// NewDecoder reads the file header and checks the writer's schema can be resolved to the reader's schema
+//
+// Errors:
+// - If the header is invalid an error with GRPC status DataLoss will be returned
+// - If the schemas are not compatible an error with GRPC status FailedPrecondition will be returned
+//
+func NewDecoder(input io.Reader, reader avro.Schema) (*Decoder, error) {
+	decoder, err := ocf.NewDecoder(input)
+	if nil != err {
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+	writer, err := avro.Parse(string(decoder.Metadata()[schemaKey]))
+	if nil != err {
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+
+	resolve := reader.Fingerprint() != writer.Fingerprint()
+	if resolve {
+		err = avro.NewSchemaCompatibility().Compatible(reader, writer)
+		if nil != err {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+
+	return &Decoder{
+		decoder: decoder,
+		reader:  reader,
+		writer:  writer,
+		resolve: resolve,
+	}, nil
+}
+
+// WriterSchema is the schema the file was written with
+func (d *Decoder) WriterSchema() avro.Schema {
+	return d.writer
+}
+
+// Decode reads the next row into the value, returning io.EOF once there are no more rows
+func (d *Decoder) Decode(value interface{}) error {
+	if !d.decoder.HasNext() {
+		err := d.decoder.Error()
+		if nil != err {
+			return status.Error(codes.DataLoss, err.Error())
+		}
+		return io.EOF
+	}
+
+	// The schemas match, so no resolution is needed
+	if !d.resolve {
+		err := d.decoder.Decode(value)
+		if nil != err {
+			return status.Error(codes.Internal, err.Error())
+		}
+		return nil
+	}
+
+	// Read the row with the writer's schema, then re-encode it with the reader's schema
+	var row interface{}
+	err := d.decoder.Decode(&row)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	resolved, err := Resolve(d.reader, d.writer, row)
+	if nil != err {
+		return err
+	}
+	data, err := avro.Marshal(d.reader, resolved)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	err = avro.Unmarshal(d.reader, data, value)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package avro_file
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/hamba/avro"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// The original schema, before any fields were added
+var writerSchema = avro.MustParse(`{
+	"type": "record",
+	"name": "row",
+	"fields": [
+		{"name": "time",  "type": "long"},
+		{"name": "price", "type": "float"},
+		{"name": "note",  "type": ["null", "string"]}
+	]
+}`)
+
+// The current schema, with a wider price, a removed note, and new fields
+var readerSchema = avro.MustParse(`{
+	"type": "record",
+	"name": "row",
+	"fields": [
+		{"name": "time",     "type": "long"},
+		{"name": "price",    "type": "double"},
+		{"name": "exchange", "type": "string", "default": "NYSE"},
+		{"name": "size",     "type": ["null", "long"], "default": null}
+	]
+}`)
+
+type writerRow struct {
+	Time  int64   `avro:"time"`
+	Price float32 `avro:"price"`
+	Note  *string `avro:"note"`
+}
+
+type readerRow struct {
+	Time     int64   `avro:"time"`
+	Price    float64 `avro:"price"`
+	Exchange string  `avro:"exchange"`
+	Size     *int64  `avro:"size"`
+}
+
+func TestCompression(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, Deflate.String(), deflateCompressionStr)
+		require.Equal(t, Snappy.String(), snappyCompressionStr)
+		require.Equal(t, Uncompressed.String(), uncompressedCompressionStr)
+	})
+}
+
+func TestOptions(t *testing.T) {
+	output := Options{}.WithDefaults()
+	require.Equal(t, output, DefaultOptions())
+
+	output = Options{Compression: Snappy, BlockLength: 2}.WithDefaults()
+	require.Equal(t, output, Options{Compression: Snappy, BlockLength: 2})
+
+	_, err := NewEncoder(ioutil.Discard, writerSchema, Options{Compression: Compression(100)})
+	require.Error(t, err)
+}
+
+func TestDecoder(t *testing.T) {
+	note := "hello"
+	rows := []writerRow{
+		{Time: 1, Price: 1.5, Note: &note},
+		{Time: 2, Price: 2.5},
+		{Time: 3, Price: 3.5},
+	}
+
+	write := func(t *testing.T, options Options) []byte {
+		buff := bytes.NewBuffer([]byte{})
+		encoder, err := NewEncoder(buff, writerSchema, options)
+		require.NoError(t, err)
+		for _, row := range rows {
+			require.NoError(t, encoder.Encode(row))
+		}
+		require.NoError(t, encoder.Close())
+		return buff.Bytes()
+	}
+
+	for _, compression := range []Compression{Deflate, Snappy, Uncompressed} {
+		compression := compression
+		t.Run(compression.String(), func(t *testing.T) {
+			// Small blocks so there are several sync markers
+			data := write(t, Options{Compression: compression, BlockLength: 2})
+			require.Equal(t, data[:4], Magic)
+
+			decoder, err := NewDecoder(bytes.NewReader(data), writerSchema)
+			require.NoError(t, err)
+			require.Equal(t, decoder.WriterSchema().Fingerprint(), writerSchema.Fingerprint())
+			for _, row := range rows {
+				output := writerRow{}
+				require.NoError(t, decoder.Decode(&output))
+				require.Equal(t, output, row)
+			}
+			require.Equal(t, decoder.Decode(&writerRow{}), io.EOF)
+		})
+	}
+
+	t.Run("Schema evolution", func(t *testing.T) {
+		data := write(t, DefaultOptions())
+
+		decoder, err := NewDecoder(bytes.NewReader(data), readerSchema)
+		require.NoError(t, err)
+		for _, row := range rows {
+			output := readerRow{}
+			require.NoError(t, decoder.Decode(&output))
+			require.Equal(t, output.Time, row.Time)
+			require.Equal(t, output.Price, float64(row.Price))
+			require.Equal(t, output.Exchange, "NYSE")
+			require.Nil(t, output.Size)
+		}
+		require.Equal(t, decoder.Decode(&readerRow{}), io.EOF)
+	})
+
+	t.Run("Incompatible schema", func(t *testing.T) {
+		data := write(t, DefaultOptions())
+
+		// A new field without a default can't be read from older files
+		schema := avro.MustParse(`{
+			"type": "record",
+			"name": "row",
+			"fields": [
+				{"name": "time",   "type": "long"},
+				{"name": "symbol", "type": "string"}
+			]
+		}`)
+		_, err := NewDecoder(bytes.NewReader(data), schema)
+		require.Error(t, err)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+	})
+
+	t.Run("Corrupt file", func(t *testing.T) {
+		data := write(t, DefaultOptions())
+		data[len(data)-1]++
+
+		decoder, err := NewDecoder(bytes.NewReader(data), writerSchema)
+		require.NoError(t, err)
+		for {
+			err = decoder.Decode(&writerRow{})
+			if nil != err {
+				break
+			}
+		}
+		require.NotEqual(t, err, io.EOF)
+	})
+}
+
+func TestIsContainer(t *testing.T) {
+	buff := bytes.NewBuffer([]byte{})
+	encoder, err := NewEncoder(buff, writerSchema, DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, encoder.Close())
+
+	tests := map[string]struct {
+		input []byte
+		ok    bool
+	}{
+		"Container": {buff.Bytes(), true},
+		"Raw":       {[]byte{2, 0, 0, 0, 0, 0}, false},
+		"Short":     {[]byte{'O'}, false},
+		"Empty":     {nil, false},
+	}
+	for key, arg := range tests {
+		arg := arg
+		t.Run(key, func(t *testing.T) {
+			input := bufio.NewReader(bytes.NewReader(arg.input))
+			ok, err := IsContainer(input)
+			require.NoError(t, err)
+			require.Equal(t, ok, arg.ok)
+
+			// Nothing is consumed
+			data, err := ioutil.ReadAll(input)
+			require.NoError(t, err)
+			require.Equal(t, len(data), len(arg.input))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package avro_file
+
+import (
+	"github.com/hamba/avro"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Resolve converts a generic value read with the writer's schema into a generic value of the reader's schema,
+// following the avro specification's schema resolution rules.
+//
+// Generic values are the same as those returned by decoding into an `interface{}`:
+// records and maps are `map[string]interface{}`, arrays are `[]interface{}`,
+// and non-null unions are a single entry map of the branch's name to it's value.
+//
+// Errors:
+// - If the value can't be resolved an error with GRPC status FailedPrecondition will be returned
+//
+func Resolve(reader, writer avro.Schema, value interface{}) (interface{}, error) {
+	reader = dereference(reader)
+	writer = dereference(writer)
+
+	// The writer's union branch is known from the value
+	if writer.Type() == avro.Union {
+		if nil == value {
+			return Resolve(reader, avro.NewPrimitiveSchema(avro.Null, nil), nil)
+		}
+		name, inner, err := unionBranch(value)
+		if nil != err {
+			return nil, err
+		}
+		for _, branch := range writer.(*avro.UnionSchema).Types() {
+			if typeName(branch) == name {
+				return Resolve(reader, branch, inner)
+			}
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown union branch: %s", name)
+	}
+
+	// The first reader branch that matches the writer is used
+	if reader.Type() == avro.Union {
+		compatibility := avro.NewSchemaCompatibility()
+		for _, branch := range reader.(*avro.UnionSchema).Types() {
+			if nil != compatibility.Compatible(branch, writer) {
+				continue
+			}
+			resolved, err := Resolve(branch, writer, value)
+			if nil != err {
+				return nil, err
+			}
+			if branch.Type() == avro.Null {
+				return nil, nil
+			}
+			return map[string]interface{}{typeName(branch): resolved}, nil
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "no union branch matches: %s", writer.Type())
+	}
+
+	switch reader.Type() {
+	case avro.Record:
+		return resolveRecord(reader.(*avro.RecordSchema), writer, value)
+	case avro.Array:
+		readerItems := reader.(*avro.ArraySchema).Items()
+		writerItems, ok := writer.(*avro.ArraySchema)
+		values, isArray := value.([]interface{})
+		if !ok || !isArray {
+			return nil, mismatch(reader, writer)
+		}
+		output := make([]interface{}, 0, len(values))
+		for _, item := range values {
+			resolved, err := Resolve(readerItems, writerItems.Items(), item)
+			if nil != err {
+				return nil, err
+			}
+			output = append(output, resolved)
+		}
+		return output, nil
+	case avro.Map:
+		readerValues := reader.(*avro.MapSchema).Values()
+		writerValues, ok := writer.(*avro.MapSchema)
+		values, isMap := value.(map[string]interface{})
+		if !ok || !isMap {
+			return nil, mismatch(reader, writer)
+		}
+		output := make(map[string]interface{}, len(values))
+		for key, item := range values {
+			resolved, err := Resolve(readerValues, writerValues.Values(), item)
+			if nil != err {
+				return nil, err
+			}
+			output[key] = resolved
+		}
+		return output, nil
+	case avro.Enum:
+		symbol, ok := value.(string)
+		if !ok || writer.Type() != avro.Enum {
+			return nil, mismatch(reader, writer)
+		}
+		for _, known := range reader.(*avro.EnumSchema).Symbols() {
+			if known == symbol {
+				return symbol, nil
+			}
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown enum symbol: %s", symbol)
+	}
+	return promote(reader, writer, value)
+}
+
+func resolveRecord(reader *avro.RecordSchema, writer avro.Schema, value interface{}) (interface{}, error) {
+	writerRecord, ok := writer.(*avro.RecordSchema)
+	values, isMap := value.(map[string]interface{})
+	if !ok || !isMap {
+		return nil, mismatch(reader, writer)
+	}
+
+	writerFields := make(map[string]*avro.Field, len(writerRecord.Fields()))
+	for _, field := range writerRecord.Fields() {
+		writerFields[field.Name()] = field
+	}
+
+	// Any field that is only in the writer's schema is dropped
+	output := make(map[string]interface{}, len(reader.Fields()))
+	for _, field := range reader.Fields() {
+		writerField, ok := writerFields[field.Name()]
+		if ok {
+			resolved, err := Resolve(field.Type(), writerField.Type(), values[field.Name()])
+			if nil != err {
+				return nil, err
+			}
+			output[field.Name()] = resolved
+			continue
+		}
+		if !field.HasDefault() {
+			return nil, status.Errorf(codes.FailedPrecondition, "missing field without a default: %s", field.Name())
+		}
+		output[field.Name()] = defaultValue(field.Type(), field.Default())
+	}
+	return output, nil
+}
+
+// promote converts primitive values to a wider type, see: https://avro.apache.org/docs/current/spec.html#Schema+Resolution
+func promote(reader, writer avro.Schema, value interface{}) (interface{}, error) {
+	if reader.Type() == writer.Type() {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case int:
+		switch reader.Type() {
+		case avro.Long:
+			return int64(v), nil
+		case avro.Float:
+			return float32(v), nil
+		case avro.Double:
+			return float64(v), nil
+		}
+	case int64:
+		switch reader.Type() {
+		case avro.Float:
+			return float32(v), nil
+		case avro.Double:
+			return float64(v), nil
+		}
+	case float32:
+		if reader.Type() == avro.Double {
+			return float64(v), nil
+		}
+	case string:
+		if reader.Type() == avro.Bytes {
+			return []byte(v), nil
+		}
+	case []byte:
+		if reader.Type() == avro.String {
+			return string(v), nil
+		}
+	}
+	return nil, mismatch(reader, writer)
+}
+
+// defaultValue converts a parsed default into a generic value, the default of a union is always it's first branch
+func defaultValue(schema avro.Schema, value interface{}) interface{} {
+	schema = dereference(schema)
+	switch schema.Type() {
+	case avro.Union:
+		branch := schema.(*avro.UnionSchema).Types()[0]
+		if branch.Type() == avro.Null {
+			return nil
+		}
+		return map[string]interface{}{typeName(branch): defaultValue(branch, value)}
+	case avro.Record:
+		values, _ := value.(map[string]interface{})
+		output := make(map[string]interface{}, len(values))
+		for _, field := range schema.(*avro.RecordSchema).Fields() {
+			output[field.Name()] = defaultValue(field.Type(), values[field.Name()])
+		}
+		return output
+	case avro.Array:
+		values, _ := value.([]interface{})
+		output := make([]interface{}, 0, len(values))
+		for _, item := range values {
+			output = append(output, defaultValue(schema.(*avro.ArraySchema).Items(), item))
+		}
+		return output
+	case avro.Map:
+		values, _ := value.(map[string]interface{})
+		output := make(map[string]interface{}, len(values))
+		for key, item := range values {
+			output[key] = defaultValue(schema.(*avro.MapSchema).Values(), item)
+		}
+		return output
+	}
+	return value
+}
+
+// unionBranch splits a generic union value into it's branch name and value
+func unionBranch(value interface{}) (string, interface{}, error) {
+	branch, ok := value.(map[string]interface{})
+	if !ok || len(branch) != 1 {
+		return "", nil, status.Error(codes.FailedPrecondition, "invalid union value")
+	}
+	for name, inner := range branch {
+		return name, inner, nil
+	}
+	return "", nil, status.Error(codes.FailedPrecondition, "invalid union value")
+}
+
+// typeName is the name used for a union branch, named types use their full name and everything else the type
+func typeName(schema avro.Schema) string {
+	schema = dereference(schema)
+	if named, ok := schema.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	name := string(schema.Type())
+	if logical, ok := schema.(avro.LogicalTypeSchema); ok && nil != logical.Logical() {
+		name += "." + string(logical.Logical().Type())
+	}
+	return name
+}
+
+func dereference(schema avro.Schema) avro.Schema {
+	if reference, ok := schema.(*avro.RefSchema); ok {
+		return reference.Schema()
+	}
+	return schema
+}
+
+func mismatch(reader, writer avro.Schema) error {
+	return status.Errorf(codes.FailedPrecondition, "reader schema %s is not compatible with writer schema %s", reader.Type(), writer.Type())
+}
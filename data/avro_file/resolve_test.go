@@ -0,0 +1,64 @@
+package avro_file
+
+import (
+	"github.com/hamba/avro"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	type args struct {
+		reader string
+		writer string
+		value  interface{}
+		output interface{}
+		ok     bool
+	}
+	tests := map[string]args{
+		"Same type":         {`"long"`, `"long"`, int64(1), int64(1), true},
+		"Int to long":       {`"long"`, `"int"`, 1, int64(1), true},
+		"Int to double":     {`"double"`, `"int"`, 1, 1.0, true},
+		"Long to float":     {`"float"`, `"long"`, int64(1), float32(1), true},
+		"Float to double":   {`"double"`, `"float"`, float32(1.5), 1.5, true},
+		"String to bytes":   {`"bytes"`, `"string"`, "a", []byte("a"), true},
+		"Bytes to string":   {`"string"`, `"bytes"`, []byte("a"), "a", true},
+		"Long to int":       {`"int"`, `"long"`, int64(1), nil, false},
+		"Null to union":     {`["null", "long"]`, `"null"`, nil, nil, true},
+		"Value to union":    {`["null", "long"]`, `"int"`, 1, map[string]interface{}{"long": int64(1)}, true},
+		"Union to value":    {`"double"`, `["null", "int"]`, map[string]interface{}{"int": 1}, 1.0, true},
+		"Union null":        {`["null", "double"]`, `["null", "int"]`, nil, nil, true},
+		"Union to union":    {`["null", "double"]`, `["null", "int"]`, map[string]interface{}{"int": 1}, map[string]interface{}{"double": 1.0}, true},
+		"Missing branch":    {`"double"`, `["null", "int"]`, map[string]interface{}{"string": "a"}, nil, false},
+		"Array":             {`{"type": "array", "items": "long"}`, `{"type": "array", "items": "int"}`, []interface{}{1, 2}, []interface{}{int64(1), int64(2)}, true},
+		"Map":               {`{"type": "map", "values": "long"}`, `{"type": "map", "values": "int"}`, map[string]interface{}{"a": 1}, map[string]interface{}{"a": int64(1)}, true},
+		"Enum":              {`{"type": "enum", "name": "e", "symbols": ["A", "B"]}`, `{"type": "enum", "name": "e", "symbols": ["A"]}`, "A", "A", true},
+		"Removed enum":      {`{"type": "enum", "name": "e", "symbols": ["A"]}`, `{"type": "enum", "name": "e", "symbols": ["A", "B"]}`, "B", nil, false},
+		"Record with field": {`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "long"}]}`, `{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}]}`, map[string]interface{}{"a": 1, "b": 2}, map[string]interface{}{"a": int64(1)}, true},
+		"Record defaults": {
+			`{"type": "record", "name": "r", "fields": [{"name": "a", "type": ["long", "null"], "default": 5}, {"name": "b", "type": {"type": "array", "items": "int"}, "default": [1]}]}`,
+			`{"type": "record", "name": "r", "fields": []}`,
+			map[string]interface{}{},
+			map[string]interface{}{"a": map[string]interface{}{"long": int64(5)}, "b": []interface{}{1}},
+			true,
+		},
+		"Record missing default": {`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "long"}]}`, `{"type": "record", "name": "r", "fields": []}`, map[string]interface{}{}, nil, false},
+	}
+	for key, arg := range tests {
+		arg := arg
+		t.Run(key, func(t *testing.T) {
+			reader := avro.MustParse(arg.reader)
+			writer := avro.MustParse(arg.writer)
+			output, err := Resolve(reader, writer, arg.value)
+			if !arg.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, output, arg.output)
+
+			// The output is always valid for the reader's schema
+			_, err = avro.Marshal(reader, output)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Compression is the codec used to compress a whole file
+type Compression int
+
+const (
+	_            Compression = iota
+	Uncompressed             // Uncompressed files are read and written as-is
+	Gzip                     // Gzip is supported almost everywhere, ex: `gzip` and `zcat`
+	Zstd                     // Zstd compresses and decompresses much faster than Gzip, with a similar ratio
+	Snappy                   // Snappy uses the framed stream format, it's very fast with a lower ratio
+	LZ4                      // LZ4 uses the frame format, it decompresses the fastest with a lower ratio
+)
+
+const (
+	uncompressedCompressionStr = "uncompressed"
+	gzipCompressionStr         = "gzip"
+	zstdCompressionStr         = "zstd"
+	snappyCompressionStr       = "snappy"
+	lz4CompressionStr          = "lz4"
+)
+
+var compressions = map[Compression]string{
+	Uncompressed: uncompressedCompressionStr,
+	Gzip:         gzipCompressionStr,
+	Zstd:         zstdCompressionStr,
+	Snappy:       snappyCompressionStr,
+	LZ4:          lz4CompressionStr,
+}
+
+func (c Compression) String() string {
+	return compressions[c]
+}
+
+// Extensions maps the file extensions of each compressed format to it's compression
+var Extensions = map[string]Compression{
+	".gz":     Gzip,
+	".gzip":   Gzip,
+	".zst":    Zstd,
+	".zstd":   Zstd,
+	".sz":     Snappy,
+	".snappy": Snappy,
+	".lz4":    LZ4,
+}
+
+// extensions are the preferred file extension of each compression
+var extensions = map[Compression]string{
+	Gzip:   ".gz",
+	Zstd:   ".zst",
+	Snappy: ".sz",
+	LZ4:    ".lz4",
+}
+
+// Extension is the preferred file extension of the compression, Uncompressed files don't have an extension
+func (c Compression) Extension() string {
+	return extensions[c]
+}
+
+// Magic are the first bytes of each compressed format, used to detect compressed inputs
+var Magic = map[Compression][]byte{
+	Gzip:   {0x1f, 0x8b},
+	Zstd:   {0x28, 0xb5, 0x2f, 0xfd},
+	Snappy: {0xff, 0x06, 0x00, 0x00, 's', 'N', 'a', 'P', 'p', 'Y'},
+	LZ4:    {0x04, 0x22, 0x4d, 0x18},
+}
+
+// magicLength is the length of the longest magic bytes
+const magicLength = 10
+
+// FromPath returns the compression of a file from it's extension, and the path without the compression extension.
+//
+// Example: "AAPL.csv.gz" is Gzip, and the remaining path is "AAPL.csv"
+//
+func FromPath(path string) (Compression, string) {
+	extension := filepath.Ext(path)
+	compression, ok := Extensions[strings.ToLower(extension)]
+	if !ok {
+		return Uncompressed, path
+	}
+	return compression, strings.TrimSuffix(path, extension)
+}
+
+// NewReader decompresses the input as it's read.
+// Closing the reader releases any resources held by the decompressor, it does not close the input.
+//
+// Errors:
+// - If the compression is unknown an error with GRPC status InvalidArgument will be returned
+// - If the input is not valid an error with GRPC status DataLoss will be returned
+//
+func NewReader(input io.Reader, compression Compression) (io.ReadCloser, error) {
+	switch compression {
+	case Uncompressed:
+		return io.NopCloser(input), nil
+	case Gzip:
+		reader, err := gzip.NewReader(input)
+		if nil != err {
+			return nil, status.Error(codes.DataLoss, err.Error())
+		}
+		return reader, nil
+	case Zstd:
+		decoder, err := zstd.NewReader(input)
+		if nil != err {
+			return nil, status.Error(codes.DataLoss, err.Error())
+		}
+		return &zstdReader{decoder: decoder}, nil
+	case Snappy:
+		return io.NopCloser(snappy.NewReader(input)), nil
+	case LZ4:
+		return io.NopCloser(lz4.NewReader(input)), nil
+	}
+	return nil, status.Error(codes.InvalidArgument, "unknown compression")
+}
+
+// Detect checks the magic bytes at the start of the input, without consuming them.
+// An input that doesn't start with any known magic bytes, including an empty input, is Uncompressed.
+func Detect(input *bufio.Reader) (Compression, error) {
+	header, err := input.Peek(magicLength)
+	if nil != err && err != io.EOF {
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+	for _, compression := range []Compression{Gzip, Zstd, Snappy, LZ4} {
+		if bytes.HasPrefix(header, Magic[compression]) {
+			return compression, nil
+		}
+	}
+	return Uncompressed, nil
+}
+
+// Decompress detects the compression from the input's magic bytes and decompresses it as it's read.
+// Closing the reader releases any resources held by the decompressor, it does not close the input.
+//
+// Errors:
+// - See Detect and NewReader
+//
+func Decompress(input io.Reader) (io.ReadCloser, Compression, error) {
+	buffered := bufio.NewReader(input)
+	compression, err := Detect(buffered)
+	if nil != err {
+		return nil, 0, err
+	}
+	reader, err := NewReader(buffered, compression)
+	if nil != err {
+		return nil, 0, err
+	}
+	return reader, compression, nil
+}
+
+// NewWriter compresses everything written to the output.
+// The writer must be closed to flush the end of the compressed stream, it does not close the output.
+//
+// Errors:
+// - If the compression is unknown an error with GRPC status InvalidArgument will be returned
+//
+func NewWriter(output io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case Uncompressed:
+		return &nopWriteCloser{Writer: output}, nil
+	case Gzip:
+		return gzip.NewWriter(output), nil
+	case Zstd:
+		encoder, err := zstd.NewWriter(output)
+		if nil != err {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return encoder, nil
+	case Snappy:
+		return snappy.NewBufferedWriter(output), nil
+	case LZ4:
+		return lz4.NewWriter(output), nil
+	}
+	return nil, status.Error(codes.InvalidArgument, "unknown compression")
+}
+
+// zstdReader adapts the zstd.Decoder, who's Close method doesn't return an error
+type zstdReader struct {
+	decoder *zstd.Decoder
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	return z.decoder.Read(p)
+}
+
+func (z *zstdReader) Close() error {
+	z.decoder.Close()
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (n *nopWriteCloser) Close() error {
+	return nil
+}
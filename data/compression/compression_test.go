@@ -0,0 +1,114 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, Uncompressed.String(), uncompressedCompressionStr)
+		require.Equal(t, Gzip.String(), gzipCompressionStr)
+		require.Equal(t, Zstd.String(), zstdCompressionStr)
+		require.Equal(t, Snappy.String(), snappyCompressionStr)
+		require.Equal(t, LZ4.String(), lz4CompressionStr)
+	})
+}
+
+func TestExtension(t *testing.T) {
+	for _, compression := range []Compression{Gzip, Zstd, Snappy, LZ4} {
+		extension := compression.Extension()
+		require.Equal(t, Extensions[extension], compression)
+	}
+	require.Empty(t, Uncompressed.Extension())
+}
+
+func TestFromPath(t *testing.T) {
+	type args struct {
+		compression Compression
+		path        string
+	}
+	tests := map[string]args{
+		"AAPL.csv":         {Uncompressed, "AAPL.csv"},
+		"AAPL.csv.gz":      {Gzip, "AAPL.csv"},
+		"AAPL.CSV.GZ":      {Gzip, "AAPL.CSV"},
+		"AAPL.avro.gzip":   {Gzip, "AAPL.avro"},
+		"data/AAPL.pb.zst": {Zstd, "data/AAPL.pb"},
+		"AAPL.zstd":        {Zstd, "AAPL"},
+		"AAPL.csv.sz":      {Snappy, "AAPL.csv"},
+		"AAPL.csv.snappy":  {Snappy, "AAPL.csv"},
+		"AAPL.csv.lz4":     {LZ4, "AAPL.csv"},
+		"AAPL":             {Uncompressed, "AAPL"},
+	}
+	for path, arg := range tests {
+		arg := arg
+		t.Run(path, func(t *testing.T) {
+			compression, remaining := FromPath(path)
+			require.Equal(t, compression, arg.compression)
+			require.Equal(t, remaining, arg.path)
+		})
+	}
+}
+
+func TestReaderWriter(t *testing.T) {
+	input := strings.Repeat("time,open,high,low,close,volume,open_interest\n", 100)
+	for _, compression := range []Compression{Uncompressed, Gzip, Zstd, Snappy, LZ4} {
+		compression := compression
+		t.Run(compression.String(), func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			writer, err := NewWriter(buff, compression)
+			require.NoError(t, err)
+			_, err = writer.Write([]byte(input))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			if compression != Uncompressed {
+				require.Less(t, buff.Len(), len(input))
+			}
+
+			data := buff.Bytes()
+			reader, err := NewReader(bytes.NewReader(data), compression)
+			require.NoError(t, err)
+			output, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			require.Equal(t, string(output), input)
+
+			// The compression is detected from the magic bytes
+			detected, err := Detect(bufio.NewReader(bytes.NewReader(data)))
+			require.NoError(t, err)
+			require.Equal(t, detected, compression)
+
+			reader, detected, err = Decompress(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, detected, compression)
+			output, err = ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			require.Equal(t, string(output), input)
+		})
+	}
+
+	t.Run("Unknown compression", func(t *testing.T) {
+		_, err := NewReader(bytes.NewReader(nil), Compression(0))
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = NewWriter(bytes.NewBuffer(nil), Compression(0))
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Empty input", func(t *testing.T) {
+		detected, err := Detect(bufio.NewReader(bytes.NewReader(nil)))
+		require.NoError(t, err)
+		require.Equal(t, detected, Uncompressed)
+	})
+
+	t.Run("Invalid gzip", func(t *testing.T) {
+		_, err := NewReader(strings.NewReader("not gzip"), Gzip)
+		require.Equal(t, status.Code(err), codes.DataLoss)
+	})
+}
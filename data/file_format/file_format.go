@@ -0,0 +1,279 @@
+package file_format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"github.com/ta4g/ta4g/data/compression"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SniffLength is the number of bytes from the start of a file that are passed to each Sniff function
+const SniffLength = 512
+
+// Format describes a file format that can be found by it's name, file extension, or content
+type Format struct {
+	// Name is the unique name of the format, ex: "csv"
+	Name string
+	// Extensions are the file extensions used by the format, including the leading dot, ex: ".csv"
+	Extensions []string
+	// Sniff reports whether the start of a file looks like this format, this is optional.
+	// The header is at most SniffLength bytes, and may be shorter or empty for small files.
+	Sniff func(header []byte) bool
+}
+
+// Registry is the set of known formats, it's safe to use from multiple goroutines
+type Registry struct {
+	mutex   sync.RWMutex
+	formats []Format
+	names   map[string]int
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		formats: make([]Format, 0),
+		names:   make(map[string]int),
+	}
+}
+
+// Register adds a new format to the registry
+//
+// Errors:
+// - If the name is empty, or an extension doesn't start with a dot, an error with GRPC status InvalidArgument will be returned
+// - If a format with the same name is already registered an error with GRPC status AlreadyExists will be returned
+//
+func (r *Registry) Register(format Format) error {
+	if format.Name == "" {
+		return status.Error(codes.InvalidArgument, "format name is required")
+	}
+	extensions := make([]string, 0, len(format.Extensions))
+	for _, extension := range format.Extensions {
+		if !strings.HasPrefix(extension, ".") {
+			return status.Errorf(codes.InvalidArgument, "extension must start with a dot: %s", extension)
+		}
+		extensions = append(extensions, strings.ToLower(extension))
+	}
+	format.Extensions = extensions
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.names[format.Name]; ok {
+		return status.Errorf(codes.AlreadyExists, "format is already registered: %s", format.Name)
+	}
+	r.names[format.Name] = len(r.formats)
+	r.formats = append(r.formats, format)
+	return nil
+}
+
+// Lookup finds a format by it's name
+//
+// Errors:
+// - If there is no format with the name an error with GRPC status NotFound will be returned
+//
+func (r *Registry) Lookup(name string) (Format, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	index, ok := r.names[name]
+	if !ok {
+		return Format{}, status.Errorf(codes.NotFound, "unknown format: %s", name)
+	}
+	return r.formats[index], nil
+}
+
+// Formats lists every format in the order they were registered
+func (r *Registry) Formats() []Format {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	output := make([]Format, len(r.formats))
+	copy(output, r.formats)
+	return output
+}
+
+// Match finds the format of a file from it's path and the start of it's content.
+// Any compression extension should already be removed from the path, see compression.FromPath.
+//
+// The format is chosen by:
+// 1. If exactly one format uses the path's extension, that format is used without looking at the content
+// 2. Otherwise the header is sniffed by every format using the extension, or every format when the extension is unknown.
+//    Formats are sniffed in reverse registration order, so formats registered later, which are usually more specific, win.
+// 3. If nothing matches the header, the first format registered with the extension is used
+//
+// A nil header skips sniffing, this is used when writing a new file.
+//
+// Errors:
+// - If no format matches an error with GRPC status NotFound will be returned
+//
+func (r *Registry) Match(path string, header []byte) (Format, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	extension := strings.ToLower(filepath.Ext(path))
+	candidates := make([]Format, 0)
+	if extension != "" {
+		for _, format := range r.formats {
+			if hasExtension(format, extension) {
+				candidates = append(candidates, format)
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	sniffed := candidates
+	if len(sniffed) == 0 {
+		sniffed = r.formats
+	}
+	if nil != header {
+		for index := len(sniffed) - 1; index >= 0; index-- {
+			format := sniffed[index]
+			if nil != format.Sniff && format.Sniff(header) {
+				return format, nil
+			}
+		}
+	}
+
+	if len(candidates) > 0 {
+		return candidates[0], nil
+	}
+	return Format{}, status.Errorf(codes.NotFound, "unknown format: %s", path)
+}
+
+// Peek returns the first SniffLength bytes of the input without consuming them, an empty input has an empty header
+func Peek(input *bufio.Reader) ([]byte, error) {
+	header, err := input.Peek(SniffLength)
+	if nil != err && (err == io.EOF || err == bufio.ErrBufferFull) {
+		return header, nil
+	}
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return header, nil
+}
+
+// Open prepares an input for reading, by decompressing it according to the path's extension
+// and matching the format against the decompressed content.
+// When the path doesn't have a compression extension the compression is detected from the input's magic bytes.
+// The returned reader must be used instead of the input, and closed once it's no longer needed.
+//
+// Errors:
+// - If the input can't be decompressed an error with GRPC status DataLoss will be returned
+// - If no format matches an error with GRPC status NotFound will be returned
+//
+func (r *Registry) Open(path string, input io.Reader) (Format, io.ReadCloser, error) {
+	codec, path := compression.FromPath(path)
+	var decompressed io.ReadCloser
+	var err error
+	if codec == compression.Uncompressed {
+		decompressed, _, err = compression.Decompress(input)
+	} else {
+		decompressed, err = compression.NewReader(input, codec)
+	}
+	if nil != err {
+		return Format{}, nil, err
+	}
+
+	buffered := bufio.NewReaderSize(decompressed, SniffLength)
+	header, err := Peek(buffered)
+	if nil != err {
+		decompressed.Close()
+		return Format{}, nil, err
+	}
+	format, err := r.Match(path, header)
+	if nil != err {
+		decompressed.Close()
+		return Format{}, nil, err
+	}
+	return format, &bufferedReader{Reader: buffered, closer: decompressed}, nil
+}
+
+// Create prepares an output for writing, by compressing it according to the path's extension
+// and matching the format from the path alone.
+// The returned writer must be used instead of the output, and closed to flush the compressed stream.
+//
+// Errors:
+// - If no format matches an error with GRPC status NotFound will be returned
+//
+func (r *Registry) Create(path string, output io.Writer) (Format, io.WriteCloser, error) {
+	codec, path := compression.FromPath(path)
+	format, err := r.Match(path, nil)
+	if nil != err {
+		return Format{}, nil, err
+	}
+	compressed, err := compression.NewWriter(output, codec)
+	if nil != err {
+		return Format{}, nil, err
+	}
+	return format, compressed, nil
+}
+
+// SniffPrefix matches files that start with the magic bytes
+func SniffPrefix(magic []byte) func(header []byte) bool {
+	return func(header []byte) bool {
+		return bytes.HasPrefix(header, magic)
+	}
+}
+
+// SniffJSON matches text files that start with a JSON object, ex: new line delimited JSON
+func SniffJSON(header []byte) bool {
+	trimmed := bytes.TrimLeft(header, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{' && isText(header)
+}
+
+// SniffDelimited matches text files who's first line contains the delimiter, ex: a comma for CSV files
+func SniffDelimited(delimiter byte) func(header []byte) bool {
+	return func(header []byte) bool {
+		line := header
+		if index := bytes.IndexByte(header, '\n'); index >= 0 {
+			line = header[:index]
+		}
+		return bytes.IndexByte(line, delimiter) >= 0 && !SniffJSON(header) && isText(header)
+	}
+}
+
+// SniffLengthDelimited matches a stream of varint length prefixed protobuf messages, who's first field has the tag.
+// Plain protobuf files start with the tag instead of a length, so they never match.
+func SniffLengthDelimited(tag byte) func(header []byte) bool {
+	return func(header []byte) bool {
+		if len(header) == 0 || header[0] == tag {
+			return false
+		}
+		length, n := binary.Uvarint(header)
+		return n > 0 && length > 0 && len(header) > n && header[n] == tag
+	}
+}
+
+// isText checks the header doesn't contain any binary control characters
+func isText(header []byte) bool {
+	for _, b := range header {
+		if b < ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+func hasExtension(format Format, extension string) bool {
+	for _, known := range format.Extensions {
+		if known == extension {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedReader reads through the sniffing buffer and closes the decompressor
+type bufferedReader struct {
+	*bufio.Reader
+	closer io.Closer
+}
+
+func (b *bufferedReader) Close() error {
+	return b.closer.Close()
+}
@@ -0,0 +1,141 @@
+package file_format
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	registry := NewRegistry()
+	formats := []Format{
+		{Name: "csv", Extensions: []string{".csv"}, Sniff: SniffDelimited(',')},
+		{Name: "ndjson", Extensions: []string{".ndjson", ".JSONL"}, Sniff: SniffJSON},
+		{Name: "raw", Extensions: []string{".bin"}},
+		{Name: "magic", Extensions: []string{".bin"}, Sniff: SniffPrefix([]byte("MAGIC"))},
+	}
+	for _, format := range formats {
+		require.NoError(t, registry.Register(format))
+	}
+	return registry
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Register", func(t *testing.T) {
+		registry := newTestRegistry(t)
+
+		err := registry.Register(Format{Name: "csv"})
+		require.Equal(t, status.Code(err), codes.AlreadyExists)
+		err = registry.Register(Format{})
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = registry.Register(Format{Name: "tsv", Extensions: []string{"tsv"}})
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		formats := registry.Formats()
+		require.Len(t, formats, 4)
+		require.Equal(t, formats[0].Name, "csv")
+		require.Equal(t, formats[1].Extensions, []string{".ndjson", ".jsonl"})
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		format, err := registry.Lookup("ndjson")
+		require.NoError(t, err)
+		require.Equal(t, format.Name, "ndjson")
+
+		_, err = registry.Lookup("xml")
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		type args struct {
+			path   string
+			header []byte
+			name   string
+		}
+		tests := map[string]args{
+			"Extension":                        {"AAPL.csv", []byte("{}"), "csv"},
+			"Extension is case insensitive":    {"AAPL.JsonL", nil, "ndjson"},
+			"Ambiguous extension sniffs":       {"AAPL.bin", []byte("MAGIC..."), "magic"},
+			"Ambiguous extension falls back":   {"AAPL.bin", []byte("...."), "raw"},
+			"Ambiguous extension when writing": {"AAPL.bin", nil, "raw"},
+			"Unknown extension sniffs":         {"AAPL.txt", []byte("{\"time\": 1}\n"), "ndjson"},
+			"No extension sniffs":              {"", []byte("time,close\n1,2\n"), "csv"},
+		}
+		registry := newTestRegistry(t)
+		for key, arg := range tests {
+			arg := arg
+			t.Run(key, func(t *testing.T) {
+				format, err := registry.Match(arg.path, arg.header)
+				require.NoError(t, err)
+				require.Equal(t, format.Name, arg.name)
+			})
+		}
+
+		_, err := registry.Match("AAPL.txt", []byte{0, 1, 2})
+		require.Equal(t, status.Code(err), codes.NotFound)
+		_, err = registry.Match("AAPL.txt", nil)
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+
+	t.Run("Open and Create", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		input := "time,close\n1,2\n"
+
+		buff := bytes.NewBuffer([]byte{})
+		format, writer, err := registry.Create("AAPL.csv.zst", buff)
+		require.NoError(t, err)
+		require.Equal(t, format.Name, "csv")
+		_, err = writer.Write([]byte(input))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.NotEqual(t, buff.String(), input)
+
+		// The decompressed content is sniffed, the compression comes from the extension or the magic bytes
+		for _, path := range []string{".zst", ""} {
+			format, reader, err := registry.Open(path, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, format.Name, "csv")
+			output, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			require.Equal(t, string(output), input)
+		}
+
+		_, _, err = registry.Create("AAPL.txt", buff)
+		require.Equal(t, status.Code(err), codes.NotFound)
+		_, _, err = registry.Open("AAPL.csv.gz", strings.NewReader(input))
+		require.Equal(t, status.Code(err), codes.DataLoss)
+	})
+}
+
+func TestSniff(t *testing.T) {
+	csv := SniffDelimited(',')
+	require.True(t, csv([]byte("time,open\n1,2")))
+	require.True(t, csv([]byte("time,open")))
+	require.False(t, csv([]byte("time;open\n1,2")))
+	require.False(t, csv([]byte("{\"a\": 1, \"b\": 2}")))
+	require.False(t, csv([]byte("a,\x00b")))
+	require.False(t, csv([]byte{}))
+
+	require.True(t, SniffJSON([]byte("  \n{\"a\": 1}")))
+	require.False(t, SniffJSON([]byte("[1, 2]")))
+	require.False(t, SniffJSON([]byte{}))
+
+	magic := SniffPrefix([]byte("PAR1"))
+	require.True(t, magic([]byte("PAR1....")))
+	require.False(t, magic([]byte("PAR")))
+
+	delimited := SniffLengthDelimited(0x0a)
+	require.True(t, delimited([]byte{0x3a, 0x0a, 0x06}))
+	require.True(t, delimited([]byte{0xc8, 0x01, 0x0a}))
+	require.False(t, delimited([]byte{0x0a, 0x06, 0x08}))
+	require.False(t, delimited([]byte{0x3a, 0x12}))
+	require.False(t, delimited([]byte{0x00, 0x0a}))
+	require.False(t, delimited([]byte{0x80}))
+	require.False(t, delimited([]byte{}))
+}
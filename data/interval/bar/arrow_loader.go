@@ -0,0 +1,555 @@
+package bar
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+//
+// Arrow Loader
+//
+// Bars are stored as a single table with one column per field, using either:
+// 1. The Arrow IPC file format, also known as Feather (v2)
+// 2. The Arrow IPC stream format
+//
+// Both can be opened directly with pyarrow, ex: `pyarrow.feather.read_table(path)` or `pyarrow.ipc.open_stream(data)`.
+// The time is stored as a UTC timestamp in seconds, and the other columns match the StandardBar.
+//
+// The rows are written in record batches of DefaultArrowBatchSize, and read one record batch at a time.
+// Reading the columns with ReadColumns shares the record batch memory rather than copying it into bars.
+//
+
+// DefaultArrowBatchSize is the number of rows written in each record batch
+const DefaultArrowBatchSize = 4096
+
+// ColumnLoader reads and writes a whole table of bars in columnar form
+type ColumnLoader interface {
+	ReadColumns(ctx context.Context, input io.Reader) (*Columnar, error)
+	WriteColumns(ctx context.Context, output io.Writer, input *Columnar) error
+}
+
+// ArrowLoader reads and writes bars as either individual bars or as columns
+type ArrowLoader interface {
+	Loader
+	ColumnLoader
+}
+
+// Compile time type assertions
+var _ ArrowLoader = &arrowLoader{}
+
+var arrowSchema = arrow.NewSchema(
+	[]arrow.Field{
+		{Name: TimeColumn, Type: &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}},
+		{Name: OpenColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: HighColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: LowColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: CloseColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: VolumeColumn, Type: arrow.PrimitiveTypes.Float64},
+		{Name: OpenInterestColumn, Type: arrow.PrimitiveTypes.Int64},
+	},
+	nil,
+)
+
+// arrowTimeUnits are the size of each arrow time unit
+var arrowTimeUnits = map[arrow.TimeUnit]time.Duration{
+	arrow.Second:      time.Second,
+	arrow.Millisecond: time.Millisecond,
+	arrow.Microsecond: time.Microsecond,
+	arrow.Nanosecond:  time.Nanosecond,
+}
+
+type arrowLoader struct {
+	file      bool
+	batchSize int
+}
+
+// arrowRecords returns the next record batch in the input, or io.EOF once there are no more
+type arrowRecords func() (array.Record, error)
+
+// arrowRecordWriter is implemented by both the arrow file and stream writers
+type arrowRecordWriter interface {
+	Write(record array.Record) error
+	Close() error
+}
+
+type arrowReader struct {
+	records arrowRecords
+	batch   *Columnar
+	index   int
+}
+
+type arrowWriter struct {
+	logger    *zap.Logger
+	writer    arrowRecordWriter
+	batch     *Columnar
+	batchSize int
+}
+
+// arrowOffsetWriter tracks the offset within the output.
+// The arrow file writer only ever seeks to find its current offset, so any io.Writer can be used.
+type arrowOffsetWriter struct {
+	output io.Writer
+	offset int64
+}
+
+// NewArrowFileLoader creates a Loader for the Arrow IPC file format, also known as Feather (v2).
+//
+// Arrow files end with a footer, so the reader needs random access to the input.
+// When the input is not an io.ReaderAt and io.Seeker then it's read into memory first.
+//
+func NewArrowFileLoader() ArrowLoader {
+	return &arrowLoader{file: true, batchSize: DefaultArrowBatchSize}
+}
+
+// NewArrowStreamLoader creates a Loader for the Arrow IPC stream format.
+func NewArrowStreamLoader() ArrowLoader {
+	return &arrowLoader{file: false, batchSize: DefaultArrowBatchSize}
+}
+
+func (a arrowLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
+	columns, err := a.ReadColumns(ctx, input)
+	if nil != err {
+		return nil, err
+	}
+	return columns.Bars(), nil
+}
+
+func (a arrowLoader) Write(ctx context.Context, output io.Writer, input []Bar) error {
+	return a.WriteColumns(ctx, output, NewColumnar(input))
+}
+
+func (a arrowLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	records, err := a.openRecords(ctx, input)
+	if nil != err {
+		return nil, err
+	}
+	return &arrowReader{records: records}, nil
+}
+
+func (a arrowLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	logger := ctxzap.Extract(ctx)
+
+	writer, err := a.openWriter(ctx, output)
+	if nil != err {
+		return nil, err
+	}
+	return &arrowWriter{
+		logger:    logger,
+		writer:    writer,
+		batch:     newColumnar(a.batchSize),
+		batchSize: a.batchSize,
+	}, nil
+}
+
+// ReadColumns reads every record batch into a single columnar view.
+//
+// When the input has a single record batch with the standard column types then the columns share its memory,
+// otherwise the record batches are copied into a single set of columns.
+//
+// Errors:
+// - If the time column is missing, has nulls, or a column has an unsupported type an error with GRPC status InvalidArgument will be returned
+// - If the input is not valid arrow data an error with GRPC status Internal will be returned
+//
+func (a arrowLoader) ReadColumns(ctx context.Context, input io.Reader) (*Columnar, error) {
+	records, err := a.openRecords(ctx, input)
+	if nil != err {
+		return nil, err
+	}
+
+	batches := make([]*Columnar, 0)
+	rows := 0
+	for {
+		record, err := records()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return nil, err
+		}
+		columns, err := fromArrowRecord(record)
+		if nil != err {
+			return nil, err
+		}
+		batches = append(batches, columns)
+		rows += columns.Len()
+	}
+
+	if len(batches) == 1 {
+		return batches[0], nil
+	}
+	output := newColumnar(rows)
+	for _, columns := range batches {
+		output.Time = append(output.Time, columns.Time...)
+		output.Open = append(output.Open, columns.Open...)
+		output.High = append(output.High, columns.High...)
+		output.Low = append(output.Low, columns.Low...)
+		output.Close = append(output.Close, columns.Close...)
+		output.Volume = append(output.Volume, columns.Volume...)
+		output.OpenInterest = append(output.OpenInterest, columns.OpenInterest...)
+	}
+	return output, nil
+}
+
+// WriteColumns writes the columns in record batches, each record batch shares the memory of the columns.
+//
+// Errors:
+// - If the columns have different lengths an error with GRPC status InvalidArgument will be returned
+// - If the output can't be written an error with GRPC status Internal will be returned
+//
+func (a arrowLoader) WriteColumns(ctx context.Context, output io.Writer, input *Columnar) error {
+	logger := ctxzap.Extract(ctx)
+
+	err := input.Validate()
+	if nil != err {
+		return err
+	}
+	writer, err := a.openWriter(ctx, output)
+	if nil != err {
+		return err
+	}
+
+	for start := 0; start < input.Len(); start += a.batchSize {
+		end := start + a.batchSize
+		if end > input.Len() {
+			end = input.Len()
+		}
+		err = writeArrowRecord(writer, input.Slice(start, end))
+		if nil != err {
+			logger.Error("Failed to write record batch", zap.Error(err))
+			return err
+		}
+	}
+
+	err = writer.Close()
+	if nil != err {
+		logger.Error("Failed to close writer", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (a arrowLoader) openRecords(ctx context.Context, input io.Reader) (arrowRecords, error) {
+	logger := ctxzap.Extract(ctx)
+	empty := func() (array.Record, error) {
+		return nil, io.EOF
+	}
+
+	if !a.file {
+		// An empty input has no bars, rather than a missing schema
+		buffered := bufio.NewReader(input)
+		if _, err := buffered.Peek(1); nil != err && err == io.EOF {
+			return empty, nil
+		}
+		streamReader, err := ipc.NewReader(buffered, ipc.WithAllocator(memory.NewGoAllocator()))
+		if nil != err {
+			logger.Error("Failed to read schema", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return func() (array.Record, error) {
+			if streamReader.Next() {
+				return streamReader.Record(), nil
+			}
+			if nil != streamReader.Err() {
+				return nil, status.Error(codes.Internal, streamReader.Err().Error())
+			}
+			return nil, io.EOF
+		}, nil
+	}
+
+	file, size, err := newArrowFile(input)
+	if nil != err {
+		logger.Error("Failed to open file", zap.Error(err))
+		return nil, err
+	}
+	if size == 0 {
+		return empty, nil
+	}
+	fileReader, err := ipc.NewFileReader(file, ipc.WithAllocator(memory.NewGoAllocator()))
+	if nil != err {
+		logger.Error("Failed to read footer", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	index := 0
+	return func() (array.Record, error) {
+		if index >= fileReader.NumRecords() {
+			return nil, io.EOF
+		}
+		record, err := fileReader.Record(index)
+		if nil != err {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		index++
+		return record, nil
+	}, nil
+}
+
+func (a arrowLoader) openWriter(ctx context.Context, output io.Writer) (arrowRecordWriter, error) {
+	logger := ctxzap.Extract(ctx)
+
+	if !a.file {
+		return ipc.NewWriter(output, ipc.WithSchema(arrowSchema)), nil
+	}
+	fileWriter, err := ipc.NewFileWriter(&arrowOffsetWriter{output: output}, ipc.WithSchema(arrowSchema))
+	if nil != err {
+		logger.Error("Failed to create writer", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return fileWriter, nil
+}
+
+func (a *arrowReader) Next() (Bar, error) {
+	for nil == a.batch || a.index >= a.batch.Len() {
+		record, err := a.records()
+		if nil != err {
+			return nil, err
+		}
+		a.batch, err = fromArrowRecord(record)
+		if nil != err {
+			return nil, err
+		}
+		a.index = 0
+	}
+	output := a.batch.At(a.index)
+	a.index++
+	return output, nil
+}
+
+func (a *arrowWriter) WriteBar(bar Bar) error {
+	a.batch.Append(bar)
+	if a.batch.Len() < a.batchSize {
+		return nil
+	}
+	return a.flush()
+}
+
+func (a *arrowWriter) Close() error {
+	err := a.flush()
+	if nil != err {
+		return err
+	}
+	err = a.writer.Close()
+	if nil != err {
+		a.logger.Error("Failed to close writer", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// flush writes the buffered bars as a single record batch
+func (a *arrowWriter) flush() error {
+	if a.batch.Len() == 0 {
+		return nil
+	}
+	err := writeArrowRecord(a.writer, a.batch)
+	if nil != err {
+		a.logger.Error("Failed to write record batch", zap.Error(err))
+		return err
+	}
+	// The record batch has already been written, so it's safe to re-use the memory
+	a.batch = a.batch.Slice(0, 0)
+	return nil
+}
+
+func (a *arrowOffsetWriter) Write(p []byte) (int, error) {
+	n, err := a.output.Write(p)
+	a.offset += int64(n)
+	return n, err
+}
+
+func (a *arrowOffsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, status.Error(codes.Unimplemented, "only the current offset is supported")
+	}
+	return a.offset, nil
+}
+
+// newArrowFile returns a random access view of the input, and it's size
+func newArrowFile(input io.Reader) (ipc.ReadAtSeeker, int64, error) {
+	if file, ok := input.(ipc.ReadAtSeeker); ok {
+		size, err := file.Seek(0, io.SeekEnd)
+		if nil != err {
+			return nil, 0, status.Error(codes.Internal, err.Error())
+		}
+		return file, size, nil
+	}
+	data, err := ioutil.ReadAll(input)
+	if nil != err {
+		return nil, 0, status.Error(codes.Internal, err.Error())
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// writeArrowRecord writes the columns as a single record batch, without copying them
+func writeArrowRecord(writer arrowRecordWriter, input *Columnar) error {
+	rows := input.Len()
+	columns := []array.Interface{
+		array.NewTimestampData(newArrowData(arrowSchema.Field(0).Type, rows, arrow.Int64Traits.CastToBytes(input.Time))),
+		array.NewFloat64Data(newArrowData(arrowSchema.Field(1).Type, rows, arrow.Float64Traits.CastToBytes(input.Open))),
+		array.NewFloat64Data(newArrowData(arrowSchema.Field(2).Type, rows, arrow.Float64Traits.CastToBytes(input.High))),
+		array.NewFloat64Data(newArrowData(arrowSchema.Field(3).Type, rows, arrow.Float64Traits.CastToBytes(input.Low))),
+		array.NewFloat64Data(newArrowData(arrowSchema.Field(4).Type, rows, arrow.Float64Traits.CastToBytes(input.Close))),
+		array.NewFloat64Data(newArrowData(arrowSchema.Field(5).Type, rows, arrow.Float64Traits.CastToBytes(input.Volume))),
+		array.NewInt64Data(newArrowData(arrowSchema.Field(6).Type, rows, arrow.Int64Traits.CastToBytes(input.OpenInterest))),
+	}
+	record := array.NewRecord(arrowSchema, columns, int64(rows))
+	defer record.Release()
+	for _, column := range columns {
+		column.Release()
+	}
+
+	err := writer.Write(record)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// newArrowData wraps the values of a fixed width column that has no nulls
+func newArrowData(dataType arrow.DataType, rows int, values []byte) *array.Data {
+	return array.NewData(dataType, rows, []*memory.Buffer{nil, memory.NewBufferBytes(values)}, nil, 0, 0)
+}
+
+// fromArrowRecord maps the columns of the record batch by name.
+// The float columns are left as zero when they're missing, and the open interest as -1.
+func fromArrowRecord(record array.Record) (*Columnar, error) {
+	schema := record.Schema()
+	rows := int(record.NumRows())
+	column := func(name string) array.Interface {
+		indices := schema.FieldIndices(name)
+		if len(indices) == 0 {
+			return nil
+		}
+		return record.Column(indices[0])
+	}
+
+	timeColumn := column(TimeColumn)
+	if nil == timeColumn {
+		return nil, status.Error(codes.InvalidArgument, "missing time column")
+	}
+
+	var err error
+	output := &Columnar{}
+	output.Time, err = arrowTimes(timeColumn)
+	if nil != err {
+		return nil, err
+	}
+	floats := map[string]*[]float64{
+		OpenColumn:   &output.Open,
+		HighColumn:   &output.High,
+		LowColumn:    &output.Low,
+		CloseColumn:  &output.Close,
+		VolumeColumn: &output.Volume,
+	}
+	for name, values := range floats {
+		*values, err = arrowFloats(column(name), rows)
+		if nil != err {
+			return nil, err
+		}
+	}
+	output.OpenInterest, err = arrowInts(column(OpenInterestColumn), rows, -1)
+	if nil != err {
+		return nil, err
+	}
+	return output, nil
+}
+
+// arrowTimes converts the time column to unix seconds, which shares the column's memory when it's already in seconds
+func arrowTimes(values array.Interface) ([]int64, error) {
+	if values.NullN() != 0 {
+		return nil, status.Error(codes.InvalidArgument, "time column has nulls")
+	}
+	switch v := values.(type) {
+	case *array.Int64:
+		return v.Int64Values(), nil
+	case *array.Timestamp:
+		raw := arrow.TimestampTraits.CastToBytes(v.TimestampValues())
+		unit := arrowTimeUnits[v.DataType().(*arrow.TimestampType).Unit]
+		if unit == time.Second {
+			return arrow.Int64Traits.CastFromBytes(raw), nil
+		}
+		output := arrow.Int64Traits.CastFromBytes(raw)
+		seconds := make([]int64, len(output))
+		for index, value := range output {
+			seconds[index] = value / int64(time.Second/unit)
+		}
+		return seconds, nil
+	}
+	return nil, status.Errorf(codes.InvalidArgument, "unsupported time column type: %s", values.DataType())
+}
+
+// arrowFloats converts a numeric column to floats, which shares the column's memory when it's already float64.
+// Null values are returned as NaN.
+func arrowFloats(values array.Interface, rows int) ([]float64, error) {
+	if nil == values {
+		return make([]float64, rows), nil
+	}
+	if v, ok := values.(*array.Float64); ok && v.NullN() == 0 {
+		return v.Float64Values(), nil
+	}
+
+	output := make([]float64, values.Len())
+	for index := range output {
+		switch v := values.(type) {
+		case *array.Float64:
+			output[index] = v.Value(index)
+		case *array.Float32:
+			output[index] = float64(v.Value(index))
+		case *array.Int64:
+			output[index] = float64(v.Value(index))
+		case *array.Int32:
+			output[index] = float64(v.Value(index))
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported column type: %s", values.DataType())
+		}
+		if values.IsNull(index) {
+			output[index] = math.NaN()
+		}
+	}
+	return output, nil
+}
+
+// arrowInts converts a numeric column to ints, which shares the column's memory when it's already int64.
+// Missing and null values are returned as the fallback.
+func arrowInts(values array.Interface, rows int, fallback int64) ([]int64, error) {
+	if nil == values {
+		output := make([]int64, rows)
+		for index := range output {
+			output[index] = fallback
+		}
+		return output, nil
+	}
+	if v, ok := values.(*array.Int64); ok && v.NullN() == 0 {
+		return v.Int64Values(), nil
+	}
+
+	output := make([]int64, values.Len())
+	for index := range output {
+		switch v := values.(type) {
+		case *array.Int64:
+			output[index] = v.Value(index)
+		case *array.Int32:
+			output[index] = int64(v.Value(index))
+		case *array.Float64:
+			output[index] = int64(v.Value(index))
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported column type: %s", values.DataType())
+		}
+		if values.IsNull(index) {
+			output[index] = fallback
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,173 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"io"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestArrowLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := make([]Bar, 0)
+	for index := 0; index < 50; index++ {
+		bars = append(bars, NewFakeBar(now.Add(time.Duration(index)*time_series.Day)))
+	}
+
+	ctx := context.Background()
+
+	for name, file := range map[string]bool{"File": true, "Stream": false} {
+		file := file
+		t.Run(name, func(t *testing.T) {
+			// Small batches so we have to stream across several of them
+			loader := &arrowLoader{file: file, batchSize: 7}
+
+			buff := bytes.NewBuffer([]byte{})
+			writer, err := loader.NewWriter(ctx, buff)
+			require.NoError(t, err)
+			count, err := Copy(writer, &sliceReader{bars: bars})
+			require.NoError(t, err)
+			require.Equal(t, count, len(bars))
+			if file {
+				require.Equal(t, buff.Bytes()[:6], []byte("ARROW1"))
+			}
+
+			// A plain io.Reader is read into memory first
+			reader, err := loader.NewReader(ctx, ioutil.NopCloser(bytes.NewReader(buff.Bytes())))
+			require.NoError(t, err)
+			for _, b := range bars {
+				row, err := reader.Next()
+				require.NoError(t, err)
+				requireEqualBar(t, row, b)
+			}
+			_, err = reader.Next()
+			require.Equal(t, err, io.EOF)
+
+			// Several record batches are joined into one set of columns
+			columns, err := loader.ReadColumns(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.NoError(t, columns.Validate())
+			require.Equal(t, columns.Len(), len(bars))
+			for index, b := range bars {
+				requireEqualBar(t, columns.At(index), b)
+			}
+
+			// The slice API writes the same data
+			buff = bytes.NewBuffer([]byte{})
+			err = loader.Write(ctx, buff, bars)
+			require.NoError(t, err)
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+
+			// An empty input has no bars
+			reader, err = loader.NewReader(ctx, bytes.NewReader(nil))
+			require.NoError(t, err)
+			_, err = reader.Next()
+			require.Equal(t, err, io.EOF)
+
+			// No bars is still a valid table
+			buff = bytes.NewBuffer([]byte{})
+			err = loader.Write(ctx, buff, nil)
+			require.NoError(t, err)
+			columns, err = loader.ReadColumns(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, columns.Len(), 0)
+		})
+	}
+
+	t.Run("Columns share memory with a single record batch", func(t *testing.T) {
+		input := NewColumnar(bars)
+		buff := bytes.NewBuffer([]byte{})
+		err := NewArrowFileLoader().WriteColumns(ctx, buff, input)
+		require.NoError(t, err)
+
+		columns, err := NewArrowFileLoader().ReadColumns(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, columns, input)
+
+		// Mismatched columns are not written
+		input.Close = input.Close[1:]
+		err = NewArrowFileLoader().WriteColumns(ctx, bytes.NewBuffer([]byte{}), input)
+		require.Error(t, err)
+	})
+
+	t.Run("Tables written by other tools", func(t *testing.T) {
+		// Millisecond time, float32 prices with a null, and no open interest column
+		schema := arrow.NewSchema(
+			[]arrow.Field{
+				{Name: TimeColumn, Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+				{Name: CloseColumn, Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+			},
+			nil,
+		)
+		builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+		defer builder.Release()
+		builder.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{
+			arrow.Timestamp(now.Unix() * 1000),
+			arrow.Timestamp(now.Add(time_series.Day).Unix() * 1000),
+		}, nil)
+		builder.Field(1).(*array.Float32Builder).AppendValues([]float32{1.5, 0}, []bool{true, false})
+		record := builder.NewRecord()
+		defer record.Release()
+
+		buff := bytes.NewBuffer([]byte{})
+		writer := ipc.NewWriter(buff, ipc.WithSchema(schema))
+		require.NoError(t, writer.Write(record))
+		require.NoError(t, writer.Close())
+
+		columns, err := NewArrowStreamLoader().ReadColumns(ctx, buff)
+		require.NoError(t, err)
+		require.Equal(t, columns.Len(), 2)
+		require.Equal(t, columns.TimeAt(1).UTC().String(), now.Add(time_series.Day).String())
+		require.Equal(t, columns.Close[0], 1.5)
+		require.True(t, math.IsNaN(columns.Close[1]))
+		require.Equal(t, columns.Open, []float64{0, 0})
+		require.Equal(t, columns.OpenInterest, []int64{-1, -1})
+	})
+
+	t.Run("Missing time column", func(t *testing.T) {
+		schema := arrow.NewSchema([]arrow.Field{{Name: CloseColumn, Type: arrow.PrimitiveTypes.Float64}}, nil)
+		builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+		defer builder.Release()
+		builder.Field(0).(*array.Float64Builder).Append(1)
+		record := builder.NewRecord()
+		defer record.Release()
+
+		buff := bytes.NewBuffer([]byte{})
+		writer := ipc.NewWriter(buff, ipc.WithSchema(schema))
+		require.NoError(t, writer.Write(record))
+		require.NoError(t, writer.Close())
+
+		_, err := NewArrowStreamLoader().Read(ctx, buff)
+		require.Error(t, err)
+	})
+}
+
+// sliceReader streams bars from a slice
+type sliceReader struct {
+	bars []Bar
+}
+
+func (s *sliceReader) Next() (Bar, error) {
+	if len(s.bars) == 0 {
+		return nil, io.EOF
+	}
+	output := s.bars[0]
+	s.bars = s.bars[1:]
+	return output, nil
+}
@@ -0,0 +1,566 @@
+package bar
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//
+// Binary Loader
+//
+// A compact fixed-width format, so a file can be memory-mapped and any bar found by it's index without parsing the file.
+// Everything is little endian, the header is followed by one record per bar:
+//
+//   header (96 bytes):
+//     magic      [8]byte   "TA4GBARS"
+//     version    uint32    1
+//     precision  uint32    bytes per price, 8 for float64 or 4 for float32
+//     interval   int64     nanoseconds, 0 when unknown
+//     count      uint64    number of bars, or all ones when the stream ended before the count was known
+//     symbol     [64]byte  UTF-8, padded with zeros
+//
+//   record (16 + 5 * precision bytes):
+//     time           int64   unix seconds
+//     open, high, low, close, volume   float64 or float32
+//     open_interest  int64
+//
+// Float32 prices halve the size of the records, at the cost of rounding the prices to ~7 significant digits.
+//
+
+// BinaryMagic is the start of every binary bar file
+var BinaryMagic = []byte("TA4GBARS")
+
+const (
+	binaryVersion       = 1
+	binaryHeaderSize    = 96
+	binarySymbolSize    = 64
+	binaryCountOffset   = 24
+	binaryUnknownCount  = ^uint64(0)
+	binaryFixedFieldLen = 8
+)
+
+// BinaryPrecision is the size of the prices within each record
+type BinaryPrecision int
+
+const (
+	_ BinaryPrecision = iota
+	Float64Precision
+	Float32Precision
+)
+
+const (
+	float64PrecisionStr = "float64"
+	float32PrecisionStr = "float32"
+)
+
+var binaryPrecisions = map[BinaryPrecision]string{
+	Float64Precision: float64PrecisionStr,
+	Float32Precision: float32PrecisionStr,
+}
+
+var binaryPrecisionSizes = map[BinaryPrecision]int{
+	Float64Precision: 8,
+	Float32Precision: 4,
+}
+
+func (p BinaryPrecision) String() string {
+	return binaryPrecisions[p]
+}
+
+// Size is the number of bytes in each price, or 0 for an unknown precision
+func (p BinaryPrecision) Size() int {
+	return binaryPrecisionSizes[p]
+}
+
+// RecordSize is the number of bytes in each bar
+func (p BinaryPrecision) RecordSize() int {
+	return 2*binaryFixedFieldLen + 5*p.Size()
+}
+
+func binaryPrecisionFromSize(size uint32) (BinaryPrecision, bool) {
+	for precision, known := range binaryPrecisionSizes {
+		if uint32(known) == size {
+			return precision, true
+		}
+	}
+	return 0, false
+}
+
+// BinaryHeader describes the bars within a binary file
+type BinaryHeader struct {
+	// Symbol of the bars, at most 64 bytes
+	Symbol string
+	// Interval between each bar, ex: time_series.Day
+	Interval time.Duration
+	// Precision of the prices, defaults to Float64Precision
+	Precision BinaryPrecision
+	// Count is the number of bars, it's filled in when reading a file
+	Count int
+}
+
+// validate fills in the default precision, and checks the header can be written
+func (h BinaryHeader) validate() (BinaryHeader, error) {
+	if h.Precision == 0 {
+		h.Precision = Float64Precision
+	}
+	if h.Precision.Size() == 0 {
+		return h, status.Errorf(codes.InvalidArgument, "unknown precision %d", h.Precision)
+	}
+	if len(h.Symbol) > binarySymbolSize {
+		return h, status.Errorf(codes.InvalidArgument, "symbol is longer than %d bytes", binarySymbolSize)
+	}
+	if h.Interval < 0 {
+		return h, status.Error(codes.InvalidArgument, "interval must not be negative")
+	}
+	return h, nil
+}
+
+func (h BinaryHeader) marshal(count uint64) []byte {
+	data := make([]byte, binaryHeaderSize)
+	copy(data, BinaryMagic)
+	binary.LittleEndian.PutUint32(data[8:], binaryVersion)
+	binary.LittleEndian.PutUint32(data[12:], uint32(h.Precision.Size()))
+	binary.LittleEndian.PutUint64(data[16:], uint64(h.Interval))
+	binary.LittleEndian.PutUint64(data[binaryCountOffset:], count)
+	copy(data[32:], h.Symbol)
+	return data
+}
+
+// unmarshalBinaryHeader reads the header, and the raw count which may be binaryUnknownCount
+func unmarshalBinaryHeader(data []byte) (BinaryHeader, uint64, error) {
+	if len(data) < binaryHeaderSize || !bytes.HasPrefix(data, BinaryMagic) {
+		return BinaryHeader{}, 0, status.Error(codes.InvalidArgument, "not a binary bar file")
+	}
+	version := binary.LittleEndian.Uint32(data[8:])
+	if version != binaryVersion {
+		return BinaryHeader{}, 0, status.Errorf(codes.Unimplemented, "unsupported binary bar file version %d", version)
+	}
+	precision, ok := binaryPrecisionFromSize(binary.LittleEndian.Uint32(data[12:]))
+	if !ok {
+		return BinaryHeader{}, 0, status.Error(codes.DataLoss, "unknown precision")
+	}
+	return BinaryHeader{
+		Symbol:    string(bytes.TrimRight(data[32:32+binarySymbolSize], "\x00")),
+		Interval:  time.Duration(binary.LittleEndian.Uint64(data[16:])),
+		Precision: precision,
+	}, binary.LittleEndian.Uint64(data[binaryCountOffset:]), nil
+}
+
+func marshalBinaryRecord(data []byte, precision BinaryPrecision, b Bar) {
+	binary.LittleEndian.PutUint64(data, uint64(b.GetTime().Unix()))
+	offset := binaryFixedFieldLen
+	for _, value := range []float64{b.GetOpen(), b.GetHigh(), b.GetLow(), b.GetClose(), b.GetVolume()} {
+		if precision == Float32Precision {
+			binary.LittleEndian.PutUint32(data[offset:], math.Float32bits(float32(value)))
+		} else {
+			binary.LittleEndian.PutUint64(data[offset:], math.Float64bits(value))
+		}
+		offset += precision.Size()
+	}
+	binary.LittleEndian.PutUint64(data[offset:], uint64(b.GetOpenInterest()))
+}
+
+// Compile time type assertions
+var _ StreamLoader = &binaryLoader{}
+var _ Bar = &binaryBar{}
+
+type binaryLoader struct {
+	header BinaryHeader
+}
+
+// NewBinaryLoader creates a Loader for binary bar files.
+// The header is only used for writing, readers use the header within the file.
+func NewBinaryLoader(header BinaryHeader) Loader {
+	return NewLoader(&binaryLoader{header: header})
+}
+
+type binaryReader struct {
+	logger    *zap.Logger
+	reader    *bufio.Reader
+	header    BinaryHeader
+	remaining uint64
+	record    []byte
+}
+
+type binaryWriter struct {
+	logger *zap.Logger
+	output io.Writer
+	writer *bufio.Writer
+	header BinaryHeader
+	start  int64
+	count  uint64
+	record []byte
+}
+
+func (l binaryLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	logger := ctxzap.Extract(ctx)
+
+	reader := bufio.NewReader(input)
+	data := make([]byte, binaryHeaderSize)
+	_, err := io.ReadFull(reader, data)
+	if nil != err && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, "not a binary bar file")
+	}
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	header, count, err := unmarshalBinaryHeader(data)
+	if nil != err {
+		logger.Error("Failed to parse header", zap.Error(err))
+		return nil, err
+	}
+	return &binaryReader{
+		logger:    logger,
+		reader:    reader,
+		header:    header,
+		remaining: count,
+		record:    make([]byte, header.Precision.RecordSize()),
+	}, nil
+}
+
+func (l binaryLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	logger := ctxzap.Extract(ctx)
+
+	header, err := l.header.validate()
+	if nil != err {
+		logger.Error("Invalid header", zap.Error(err))
+		return nil, err
+	}
+
+	// Seekable outputs have their count filled in when they're closed
+	start := int64(-1)
+	if seeker, ok := output.(io.Seeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if nil == err {
+			start = offset
+		}
+	}
+
+	writer := bufio.NewWriter(output)
+	_, err = writer.Write(header.marshal(binaryUnknownCount))
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &binaryWriter{
+		logger: logger,
+		output: output,
+		writer: writer,
+		header: header,
+		start:  start,
+		record: make([]byte, header.Precision.RecordSize()),
+	}, nil
+}
+
+func (r *binaryReader) Next() (Bar, error) {
+	if r.remaining == 0 {
+		return nil, io.EOF
+	}
+	_, err := io.ReadFull(r.reader, r.record)
+	if nil != err && err == io.EOF && r.remaining == binaryUnknownCount {
+		return nil, io.EOF
+	}
+	if nil != err && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+		r.logger.Error("Failed to read row", zap.Error(err))
+		return nil, status.Error(codes.DataLoss, "binary bar file is truncated")
+	}
+	if nil != err {
+		r.logger.Error("Failed to read row", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if r.remaining != binaryUnknownCount {
+		r.remaining--
+	}
+	return binaryBar{record: r.record, precision: r.header.Precision}.Clone()
+}
+
+func (w *binaryWriter) WriteBar(bar Bar) error {
+	marshalBinaryRecord(w.record, w.header.Precision, bar)
+	_, err := w.writer.Write(w.record)
+	if nil != err {
+		w.logger.Error("Failed to write row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	w.count++
+	return nil
+}
+
+func (w *binaryWriter) Close() error {
+	err := w.writer.Flush()
+	if nil != err {
+		w.logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	if w.start < 0 {
+		return nil
+	}
+
+	// Go back and fill in the count, then return to the end of the output
+	seeker := w.output.(io.WriteSeeker)
+	count := make([]byte, 8)
+	binary.LittleEndian.PutUint64(count, w.count)
+	end, err := seeker.Seek(0, io.SeekCurrent)
+	if nil == err {
+		_, err = seeker.Seek(w.start+binaryCountOffset, io.SeekStart)
+	}
+	if nil == err {
+		_, err = seeker.Write(count)
+	}
+	if nil == err {
+		_, err = seeker.Seek(end, io.SeekStart)
+	}
+	if nil != err {
+		w.logger.Error("Failed to write count", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+//
+// Binary File
+//
+
+// BinaryFile is a read-only view of a binary bar file, with random access to every bar.
+// On Linux and macOS the file is memory-mapped, so opening a file is cheap no matter how large it is,
+// and the operating system shares the pages between every backtest reading the same file.
+//
+// The bars returned by At and Bars read directly from the file, so they must not be used after Close.
+// Clone a bar to keep it.
+type BinaryFile struct {
+	header  BinaryHeader
+	data    []byte
+	records []byte
+	closer  func() error
+}
+
+// OpenBinaryFile opens a binary bar file for random access
+//
+// Errors:
+// - If the file doesn't exist an error with GRPC status NotFound will be returned
+// - If the file isn't a binary bar file an error with GRPC status InvalidArgument will be returned
+// - If the file is truncated an error with GRPC status DataLoss will be returned
+//
+func OpenBinaryFile(path string) (*BinaryFile, error) {
+	file, err := os.Open(path)
+	if nil != err && os.IsNotExist(err) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer file.Close()
+
+	data, closer, err := mapFile(file)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	output, err := NewBinaryFile(data)
+	if nil != err {
+		_ = closer()
+		return nil, err
+	}
+	output.closer = closer
+	return output, nil
+}
+
+// NewBinaryFile creates a view of a binary bar file that is already in memory, the data is not copied
+//
+// Errors:
+// - If the data isn't a binary bar file an error with GRPC status InvalidArgument will be returned
+// - If the data is truncated an error with GRPC status DataLoss will be returned
+//
+func NewBinaryFile(data []byte) (*BinaryFile, error) {
+	header, count, err := unmarshalBinaryHeader(data)
+	if nil != err {
+		return nil, err
+	}
+
+	recordSize := uint64(header.Precision.RecordSize())
+	available := uint64(len(data)-binaryHeaderSize) / recordSize
+	if count == binaryUnknownCount {
+		if uint64(len(data)-binaryHeaderSize)%recordSize != 0 {
+			return nil, status.Error(codes.DataLoss, "binary bar file is truncated")
+		}
+		count = available
+	}
+	if count > available {
+		return nil, status.Error(codes.DataLoss, "binary bar file is truncated")
+	}
+
+	header.Count = int(count)
+	return &BinaryFile{
+		header:  header,
+		data:    data,
+		records: data[binaryHeaderSize : binaryHeaderSize+count*recordSize],
+		closer:  func() error { return nil },
+	}, nil
+}
+
+// Header of the file
+func (f *BinaryFile) Header() BinaryHeader {
+	return f.header
+}
+
+// Len is the number of bars
+func (f *BinaryFile) Len() int {
+	return f.header.Count
+}
+
+// At is the bar at the index, it panics if the index is out of range the same as a slice
+func (f *BinaryFile) At(index int) Bar {
+	size := f.header.Precision.RecordSize()
+	return binaryBar{record: f.records[index*size : (index+1)*size], precision: f.header.Precision}
+}
+
+// Bars is a view of every bar, only the slice is allocated and the bars still read directly from the file
+func (f *BinaryFile) Bars() []Bar {
+	output := make([]Bar, 0, f.Len())
+	for index := 0; index < f.Len(); index++ {
+		output = append(output, f.At(index))
+	}
+	return output
+}
+
+// Slice is a view of the bars in the range [start, end), nothing is copied
+func (f *BinaryFile) Slice(start, end int) *BinaryFile {
+	size := f.header.Precision.RecordSize()
+	header := f.header
+	header.Count = end - start
+	return &BinaryFile{
+		header:  header,
+		data:    f.data,
+		records: f.records[start*size : end*size],
+		closer:  func() error { return nil },
+	}
+}
+
+// NewReader streams the bars of the file, ex: to Copy them to another format
+func (f *BinaryFile) NewReader() Reader {
+	return &binaryFileReader{file: f}
+}
+
+// Close unmaps the file, slices of the file share the mapping and are closed along with it
+func (f *BinaryFile) Close() error {
+	closer := f.closer
+	f.closer = func() error { return nil }
+	err := closer()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+type binaryFileReader struct {
+	file  *BinaryFile
+	index int
+}
+
+func (r *binaryFileReader) Next() (Bar, error) {
+	if r.index >= r.file.Len() {
+		return nil, io.EOF
+	}
+	output := r.file.At(r.index)
+	r.index++
+	return output, nil
+}
+
+// WriteBinaryFile converts the bars from any Reader into a binary bar file, and returns the number of bars written.
+// The file is written to a temporary file first, so readers never see a partially written file.
+//
+// Example converting an avro file:
+//
+//   reader, err := NewAvroLoader().NewReader(ctx, input)
+//   ...
+//   count, err := WriteBinaryFile(ctx, "AAPL.bars", BinaryHeader{Symbol: "AAPL", Interval: time_series.Day}, reader)
+//
+func WriteBinaryFile(ctx context.Context, path string, header BinaryHeader, reader Reader) (int, error) {
+	logger := ctxzap.Extract(ctx)
+
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if nil != err {
+		logger.Error("Failed to create file", zap.Error(err))
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer, err := binaryLoader{header: header}.NewWriter(ctx, file)
+	if nil != err {
+		return 0, err
+	}
+	count, err := Copy(writer, reader)
+	if nil != err {
+		return count, err
+	}
+
+	err = file.Close()
+	if nil == err {
+		err = os.Rename(file.Name(), path)
+	}
+	if nil != err {
+		logger.Error("Failed to write file", zap.Error(err))
+		return count, status.Error(codes.Internal, err.Error())
+	}
+	return count, nil
+}
+
+// binaryBar reads the fields of a single record on demand
+type binaryBar struct {
+	record    []byte
+	precision BinaryPrecision
+}
+
+func (b binaryBar) price(index int) float64 {
+	offset := binaryFixedFieldLen + index*b.precision.Size()
+	if b.precision == Float32Precision {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b.record[offset:])))
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b.record[offset:]))
+}
+
+func (b binaryBar) GetTime() time.Time {
+	return time.Unix(int64(binary.LittleEndian.Uint64(b.record)), 0)
+}
+
+func (b binaryBar) GetOpen() float64 {
+	return b.price(0)
+}
+
+func (b binaryBar) GetHigh() float64 {
+	return b.price(1)
+}
+
+func (b binaryBar) GetLow() float64 {
+	return b.price(2)
+}
+
+func (b binaryBar) GetClose() float64 {
+	return b.price(3)
+}
+
+func (b binaryBar) GetVolume() float64 {
+	return b.price(4)
+}
+
+func (b binaryBar) GetOpenInterest() int64 {
+	return int64(binary.LittleEndian.Uint64(b.record[binaryFixedFieldLen+5*b.precision.Size():]))
+}
+
+// Clone copies the bar out of the file, so it's safe to use after the file is closed
+func (b binaryBar) Clone() (Bar, error) {
+	return copyToStandardBar(b), nil
+}
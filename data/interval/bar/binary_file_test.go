@@ -0,0 +1,209 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBinaryLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := []Bar{
+		NewFakeBar(now),
+		NewFakeBar(now.Add(time_series.Day)),
+		NewFakeBar(now.Add(2 * time_series.Day)),
+		New(now.Add(3*time_series.Day), math.NaN(), math.Inf(1), 0, 1.5, 0, -1),
+	}
+	ctx := context.Background()
+	header := BinaryHeader{Symbol: "AAPL", Interval: time_series.Day}
+
+	t.Run("Round trip", func(t *testing.T) {
+		loader := NewBinaryLoader(header)
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, bars)
+		require.NoError(t, err)
+		require.Equal(t, buff.Len(), binaryHeaderSize+len(bars)*Float64Precision.RecordSize())
+		require.True(t, bytes.HasPrefix(buff.Bytes(), BinaryMagic))
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output[:3] {
+			requireEqualBar(t, row, bars[index])
+		}
+		require.True(t, math.IsNaN(output[3].GetOpen()))
+		require.True(t, math.IsInf(output[3].GetHigh(), 1))
+		require.Equal(t, output[3].GetOpenInterest(), int64(-1))
+	})
+
+	t.Run("Float32 precision", func(t *testing.T) {
+		loader := NewBinaryLoader(BinaryHeader{Symbol: "AAPL", Precision: Float32Precision})
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, bars)
+		require.NoError(t, err)
+		require.Equal(t, buff.Len(), binaryHeaderSize+len(bars)*Float32Precision.RecordSize())
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output[:3] {
+			require.Equal(t, row.GetTime(), bars[index].GetTime())
+			require.InDelta(t, row.GetClose(), bars[index].GetClose(), 1e-6)
+			require.Equal(t, row.GetOpenInterest(), bars[index].GetOpenInterest())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		loader := NewBinaryLoader(header)
+		_, err := loader.Read(ctx, strings.NewReader(""))
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = loader.Read(ctx, strings.NewReader(strings.Repeat("x", binaryHeaderSize)))
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, bars)
+		require.NoError(t, err)
+		data := buff.Bytes()
+		_, err = loader.Read(ctx, bytes.NewReader(data[:len(data)-1]))
+		require.Equal(t, status.Code(err), codes.DataLoss)
+
+		err = NewBinaryLoader(BinaryHeader{Symbol: strings.Repeat("A", 65)}).Write(ctx, buff, bars)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = NewBinaryLoader(BinaryHeader{Precision: 7}).Write(ctx, buff, bars)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
+
+func TestBinaryFile(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := make([]Bar, 0)
+	for index := 0; index < 100; index++ {
+		bars = append(bars, NewFakeBar(now.Add(time.Duration(index)*time_series.Day)))
+	}
+	ctx := context.Background()
+	header := BinaryHeader{Symbol: "BTC/USD", Interval: time_series.Day}
+
+	t.Run("Convert and open", func(t *testing.T) {
+		// Start from an avro file, the same as an existing data set
+		buff := bytes.NewBuffer([]byte{})
+		err := NewAvroLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+		reader, err := NewAvroLoader().NewReader(ctx, buff)
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "BTC-USD.bars")
+		count, err := WriteBinaryFile(ctx, path, header, reader)
+		require.NoError(t, err)
+		require.Equal(t, count, len(bars))
+
+		// Files can seek, so the count is filled in
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, data[binaryCountOffset:binaryCountOffset+8], []byte{100, 0, 0, 0, 0, 0, 0, 0})
+
+		file, err := OpenBinaryFile(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		require.Equal(t, file.Header(), BinaryHeader{Symbol: "BTC/USD", Interval: time_series.Day, Precision: Float64Precision, Count: len(bars)})
+		require.Equal(t, file.Len(), len(bars))
+		requireEqualBar(t, file.At(42), bars[42])
+		requireEqualBar(t, file.At(99), bars[99])
+
+		view := file.Bars()
+		require.Len(t, view, len(bars))
+		for index, row := range view {
+			requireEqualBar(t, row, bars[index])
+		}
+
+		slice := file.Slice(10, 20)
+		require.Equal(t, slice.Len(), 10)
+		requireEqualBar(t, slice.At(0), bars[10])
+
+		streamed, err := ReadAll(slice.NewReader())
+		require.NoError(t, err)
+		require.Len(t, streamed, 10)
+		requireEqualBar(t, streamed[9], bars[19])
+
+		// Clones are copied out of the file
+		clone, err := file.At(5).Clone()
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		requireEqualBar(t, clone, bars[5])
+	})
+
+	t.Run("Unknown count", func(t *testing.T) {
+		// Streams that can't seek, ex: compressed files, can't go back and fill in the count
+		output := bytes.NewBuffer([]byte{})
+		err := NewBinaryLoader(header).Write(ctx, output, bars[:3])
+		require.NoError(t, err)
+		require.Equal(t, output.Bytes()[binaryCountOffset:binaryCountOffset+8], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+		file, err := NewBinaryFile(output.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, file.Len(), 3)
+
+		reader, err := NewBinaryLoader(header).NewReader(ctx, bytes.NewReader(output.Bytes()))
+		require.NoError(t, err)
+		streamed, err := ReadAll(reader)
+		require.NoError(t, err)
+		require.Len(t, streamed, 3)
+
+		_, err = NewBinaryFile(output.Bytes()[:output.Len()-1])
+		require.Equal(t, status.Code(err), codes.DataLoss)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := OpenBinaryFile(filepath.Join(dir, "missing.bars"))
+		require.Equal(t, status.Code(err), codes.NotFound)
+
+		path := filepath.Join(dir, "empty.bars")
+		require.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+		_, err = OpenBinaryFile(path)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		// The header says there are more bars than the file holds
+		buff := bytes.NewBuffer([]byte{})
+		err = NewBinaryLoader(header).Write(ctx, buff, bars[:2])
+		require.NoError(t, err)
+		validated, err := header.validate()
+		require.NoError(t, err)
+		data := validated.marshal(3)
+		data = append(data, buff.Bytes()[binaryHeaderSize:]...)
+		_, err = NewBinaryFile(data)
+		require.Equal(t, status.Code(err), codes.DataLoss)
+
+		// A failed conversion doesn't leave a file behind
+		path = filepath.Join(dir, "failed.bars")
+		_, err = WriteBinaryFile(ctx, path, header, &errorReader{})
+		require.Error(t, err)
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+}
+
+// errorReader fails part way through a stream
+type errorReader struct{}
+
+func (e *errorReader) Next() (Bar, error) {
+	return nil, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,137 @@
+package bar
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"time"
+)
+
+// Columnar stores bars as one contiguous slice per column, rather than one struct per bar.
+//
+// This is the layout used by Arrow and Parquet, so the columns can be shared with them without copying,
+// and it lets indicators walk a single column (ex: every close price) without an interface call per bar.
+//
+// Every column must have the same length, index `i` of each column is the i-th bar.
+//
+type Columnar struct {
+	// Time is the unix time of each bar in seconds, the same as StandardBar.UnixTime
+	Time         []int64
+	Open         []float64
+	High         []float64
+	Low          []float64
+	Close        []float64
+	Volume       []float64
+	OpenInterest []int64
+}
+
+// NewColumnar copies the bars into a new columnar view
+func NewColumnar(bars []Bar) *Columnar {
+	output := newColumnar(len(bars))
+	for _, b := range bars {
+		output.Append(b)
+	}
+	return output
+}
+
+func newColumnar(capacity int) *Columnar {
+	return &Columnar{
+		Time:         make([]int64, 0, capacity),
+		Open:         make([]float64, 0, capacity),
+		High:         make([]float64, 0, capacity),
+		Low:          make([]float64, 0, capacity),
+		Close:        make([]float64, 0, capacity),
+		Volume:       make([]float64, 0, capacity),
+		OpenInterest: make([]int64, 0, capacity),
+	}
+}
+
+// ReadColumnar reads every remaining bar from the stream into a new columnar view
+func ReadColumnar(reader Reader) (*Columnar, error) {
+	output := newColumnar(0)
+	for {
+		row, err := reader.Next()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return nil, err
+		}
+		output.Append(row)
+	}
+	return output, nil
+}
+
+// Len is the number of bars
+func (c *Columnar) Len() int {
+	return len(c.Time)
+}
+
+// Validate checks that every column has the same number of bars
+//
+// Errors:
+// - If the columns have different lengths an error with GRPC status InvalidArgument will be returned
+//
+func (c *Columnar) Validate() error {
+	size := len(c.Time)
+	for _, column := range [][]float64{c.Open, c.High, c.Low, c.Close, c.Volume} {
+		if len(column) != size {
+			return status.Error(codes.InvalidArgument, "columns have different lengths")
+		}
+	}
+	if len(c.OpenInterest) != size {
+		return status.Error(codes.InvalidArgument, "columns have different lengths")
+	}
+	return nil
+}
+
+// Append copies a single bar onto the end of each column
+func (c *Columnar) Append(b Bar) {
+	c.Time = append(c.Time, b.GetTime().Unix())
+	c.Open = append(c.Open, b.GetOpen())
+	c.High = append(c.High, b.GetHigh())
+	c.Low = append(c.Low, b.GetLow())
+	c.Close = append(c.Close, b.GetClose())
+	c.Volume = append(c.Volume, b.GetVolume())
+	c.OpenInterest = append(c.OpenInterest, b.GetOpenInterest())
+}
+
+// TimeAt is the time of the bar at the index
+func (c *Columnar) TimeAt(index int) time.Time {
+	return time.Unix(c.Time[index], 0)
+}
+
+// At copies the bar at the index out of the columns
+func (c *Columnar) At(index int) Bar {
+	return &StandardBar{
+		UnixTime:     c.Time[index],
+		Open:         c.Open[index],
+		High:         c.High[index],
+		Low:          c.Low[index],
+		Close:        c.Close[index],
+		Volume:       c.Volume[index],
+		OpenInterest: c.OpenInterest[index],
+	}
+}
+
+// Bars copies every bar out of the columns
+func (c *Columnar) Bars() []Bar {
+	output := make([]Bar, 0, c.Len())
+	for index := 0; index < c.Len(); index++ {
+		output = append(output, c.At(index))
+	}
+	return output
+}
+
+// Slice is a view of the bars in the range [start, end), the columns are shared and not copied
+func (c *Columnar) Slice(start, end int) *Columnar {
+	return &Columnar{
+		Time:         c.Time[start:end],
+		Open:         c.Open[start:end],
+		High:         c.High[start:end],
+		Low:          c.Low[start:end],
+		Close:        c.Close[start:end],
+		Volume:       c.Volume[start:end],
+		OpenInterest: c.OpenInterest[start:end],
+	}
+}
@@ -0,0 +1,70 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"testing"
+	"time"
+)
+
+func TestColumnar(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := []Bar{
+		NewFakeBar(now),
+		NewFakeBar(now.Add(time_series.Day)),
+		NewFakeBar(now.Add(2 * time_series.Day)),
+	}
+
+	t.Run("NewColumnar", func(t *testing.T) {
+		columns := NewColumnar(bars)
+		require.NoError(t, columns.Validate())
+		require.Equal(t, columns.Len(), len(bars))
+		for index, b := range bars {
+			require.Equal(t, columns.TimeAt(index).String(), b.GetTime().String())
+			require.Equal(t, columns.Close[index], b.GetClose())
+			requireEqualBar(t, columns.At(index), b)
+		}
+		require.Equal(t, columns.Bars(), bars)
+
+		empty := NewColumnar(nil)
+		require.NoError(t, empty.Validate())
+		require.Equal(t, empty.Len(), 0)
+	})
+
+	t.Run("Slice shares the columns", func(t *testing.T) {
+		columns := NewColumnar(bars)
+		slice := columns.Slice(1, 3)
+		require.Equal(t, slice.Len(), 2)
+		requireEqualBar(t, slice.At(0), bars[1])
+
+		slice.Close[0] = -1
+		require.Equal(t, columns.Close[1], -1.0)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		columns := NewColumnar(bars)
+		columns.Volume = columns.Volume[:1]
+		require.Error(t, columns.Validate())
+
+		columns = NewColumnar(bars)
+		columns.OpenInterest = nil
+		require.Error(t, columns.Validate())
+	})
+
+	t.Run("ReadColumnar", func(t *testing.T) {
+		ctx := context.Background()
+		buff := bytes.NewBuffer([]byte{})
+		err := NewCSVLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		reader, err := NewCSVLoader().NewReader(ctx, buff)
+		require.NoError(t, err)
+		columns, err := ReadColumnar(reader)
+		require.NoError(t, err)
+		require.Equal(t, columns, NewColumnar(bars))
+	})
+}
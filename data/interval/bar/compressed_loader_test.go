@@ -0,0 +1,100 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/compression"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCompressedLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := make([]Bar, 0)
+	for index := 0; index < 1000; index++ {
+		bars = append(bars, NewFakeBar(now.Add(time.Duration(index)*time_series.Day)))
+	}
+	ctx := context.Background()
+
+	codecs := []compression.Compression{
+		compression.Uncompressed,
+		compression.Gzip,
+		compression.Zstd,
+		compression.Snappy,
+		compression.LZ4,
+	}
+	for _, codec := range codecs {
+		codec := codec
+		t.Run(codec.String(), func(t *testing.T) {
+			plain := bytes.NewBuffer([]byte{})
+			err := NewCSVLoader().Write(ctx, plain, bars)
+			require.NoError(t, err)
+
+			loader := NewCompressedLoader(&csvLoader{}, codec)
+			buff := bytes.NewBuffer([]byte{})
+			err = loader.Write(ctx, buff, bars)
+			require.NoError(t, err)
+			if codec != compression.Uncompressed {
+				require.True(t, bytes.HasPrefix(buff.Bytes(), compression.Magic[codec]))
+				require.Less(t, buff.Len(), plain.Len())
+			}
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+
+			// Any compression can be read, no matter which one the loader writes
+			output, err = NewCompressedLoader(&csvLoader{}, compression.Gzip).Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+
+			// Streaming only decompresses the rows as they're needed
+			reader, err := NewCompressedLoader(&csvLoader{}, codec).NewReader(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			row, err := reader.Next()
+			require.NoError(t, err)
+			requireEqualBar(t, row, bars[0])
+		})
+	}
+
+	t.Run("Streaming between formats", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewCompressedLoader(&protoLoader{}, compression.Zstd).Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		reader, err := NewCompressedLoader(&protoLoader{}, compression.Zstd).NewReader(ctx, buff)
+		require.NoError(t, err)
+		output := bytes.NewBuffer([]byte{})
+		writer, err := NewCompressedLoader(&jsonNewLineLoader{}, compression.LZ4).NewWriter(ctx, output)
+		require.NoError(t, err)
+		count, err := Copy(writer, reader)
+		require.NoError(t, err)
+		require.Equal(t, count, len(bars))
+
+		_, err = reader.Next()
+		require.Equal(t, err, io.EOF)
+
+		again, err := NewCompressedLoader(&jsonNewLineLoader{}, compression.Uncompressed).Read(ctx, output)
+		require.NoError(t, err)
+		require.Len(t, again, len(bars))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		err := NewCompressedLoader(&csvLoader{}, compression.Compression(0)).Write(ctx, bytes.NewBuffer([]byte{}), bars)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		// Gzip magic bytes followed by garbage
+		_, err = NewCompressedLoader(&csvLoader{}, compression.Gzip).Read(ctx, bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+		require.Error(t, err)
+	})
+}
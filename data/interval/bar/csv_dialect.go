@@ -0,0 +1,799 @@
+package bar
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// CSV Dialect Loader
+//
+// Vendor exports all have their own take on CSV: column names, column order, how the time is written,
+// the delimiter, and even the decimal separator. A CSVDialect describes one of these layouts,
+// and there are presets for the common vendors (see: YahooCSVDialect, StooqCSVDialect, etc).
+//
+
+// CSVDateColumn is the key for a separate date column in CSVDialect.Columns,
+// when it's set the time column (if there is one) only has the time of day.
+const CSVDateColumn = "date"
+
+// TimeFormat is how the time of each bar is written
+type TimeFormat int
+
+const (
+	_               TimeFormat = iota
+	UnixSecondsTime            // UnixSecondsTime is the number of seconds since the epoch, ex: 1669852800
+	UnixMillisTime             // UnixMillisTime is the number of milliseconds since the epoch, ex: 1669852800000
+	ISO8601Time                // ISO8601Time is a date, or date and time, ex: 2022-12-01T09:30:00Z or 2022-12-01
+	LayoutTime                 // LayoutTime uses the CSVDialect's DateLayout and TimeLayouts
+)
+
+const (
+	unixSecondsTimeStr = "unix_seconds"
+	unixMillisTimeStr  = "unix_millis"
+	iso8601TimeStr     = "iso8601"
+	layoutTimeStr      = "layout"
+)
+
+var timeFormats = map[TimeFormat]string{
+	UnixSecondsTime: unixSecondsTimeStr,
+	UnixMillisTime:  unixMillisTimeStr,
+	ISO8601Time:     iso8601TimeStr,
+	LayoutTime:      layoutTimeStr,
+}
+
+func (t TimeFormat) String() string {
+	return timeFormats[t]
+}
+
+// iso8601Layouts are tried in order when reading ISO8601Time, the first is used for writing
+var iso8601Layouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// CSVDialect describes the layout of a CSV file of bars
+type CSVDialect struct {
+	// Delimiter between each field, defaults to ','
+	Delimiter rune
+	// Comment lines start with this character and are skipped, 0 means there are no comments
+	Comment rune
+	// DecimalSeparator of the numbers, defaults to '.'
+	DecimalSeparator rune
+	// Header is true when the first row has the column names.
+	// When reading the columns are found by name (ignoring case), so the order doesn't matter.
+	Header bool
+	// Fields are the names of every column in the order they are written.
+	// This is required when there is no header, otherwise it's only used for writing.
+	Fields []string
+	// Columns maps each StandardBar column (and CSVDateColumn) to the name of the column in the file.
+	// Any column that is not mapped is left empty when reading, with -1 for the open interest.
+	Columns map[string]string
+	// Null is a value that is treated as empty, ex: "null"
+	Null string
+	// TimeFormat of the time column
+	TimeFormat TimeFormat
+	// DateLayout is the Go time layout of the separate date column
+	DateLayout string
+	// TimeLayouts are the Go time layouts for the LayoutTime format, these are tried in order and the first is used to write.
+	// When there's a separate date column these are only the time of day.
+	TimeLayouts []string
+	// Location of times that don't have a time zone, defaults to UTC
+	Location *time.Location
+}
+
+// DefaultCSVDialect is the layout written by NewCSVLoader
+func DefaultCSVDialect() CSVDialect {
+	columns := make(map[string]string, len(Columns))
+	for _, column := range Columns {
+		columns[column] = column
+	}
+	return CSVDialect{
+		Header:     true,
+		Fields:     Columns,
+		Columns:    columns,
+		TimeFormat: UnixSecondsTime,
+	}
+}
+
+// YahooCSVDialect is the daily history download from Yahoo Finance:
+//   Date,Open,High,Low,Close,Adj Close,Volume
+//   2022-12-01,148.210007,149.130005,146.610001,148.309998,147.881622,71250400
+//
+func YahooCSVDialect() CSVDialect {
+	return CSVDialect{
+		Header: true,
+		Fields: []string{"Date", "Open", "High", "Low", "Close", "Adj Close", "Volume"},
+		Columns: map[string]string{
+			CSVDateColumn: "Date",
+			OpenColumn:    "Open",
+			HighColumn:    "High",
+			LowColumn:     "Low",
+			CloseColumn:   "Close",
+			VolumeColumn:  "Volume",
+		},
+		Null:       "null",
+		TimeFormat: LayoutTime,
+		DateLayout: "2006-01-02",
+	}
+}
+
+// StooqCSVDialect is the bulk (ASCII) download from Stooq, where the time is 000000 for daily bars:
+//   <TICKER>,<PER>,<DATE>,<TIME>,<OPEN>,<HIGH>,<LOW>,<CLOSE>,<VOL>,<OPENINT>
+//   AAPL.US,D,20221201,000000,148.21,149.13,146.61,148.31,71250400,0
+//
+func StooqCSVDialect() CSVDialect {
+	return CSVDialect{
+		Header: true,
+		Fields: []string{"<TICKER>", "<PER>", "<DATE>", "<TIME>", "<OPEN>", "<HIGH>", "<LOW>", "<CLOSE>", "<VOL>", "<OPENINT>"},
+		Columns: map[string]string{
+			CSVDateColumn:      "<DATE>",
+			TimeColumn:         "<TIME>",
+			OpenColumn:         "<OPEN>",
+			HighColumn:         "<HIGH>",
+			LowColumn:          "<LOW>",
+			CloseColumn:        "<CLOSE>",
+			VolumeColumn:       "<VOL>",
+			OpenInterestColumn: "<OPENINT>",
+		},
+		TimeFormat:  LayoutTime,
+		DateLayout:  "20060102",
+		TimeLayouts: []string{"150405"},
+	}
+}
+
+// MetaTrader4CSVDialect is the history center export from MetaTrader 4, which has no header:
+//   2022.12.01,09:30,1.04123,1.04200,1.04100,1.04150,1234
+//
+// The times are in the broker's server time zone, so the Location should be set to match.
+func MetaTrader4CSVDialect() CSVDialect {
+	return CSVDialect{
+		Header: false,
+		Fields: []string{"date", "time", "open", "high", "low", "close", "volume"},
+		Columns: map[string]string{
+			CSVDateColumn: "date",
+			TimeColumn:    "time",
+			OpenColumn:    "open",
+			HighColumn:    "high",
+			LowColumn:     "low",
+			CloseColumn:   "close",
+			VolumeColumn:  "volume",
+		},
+		TimeFormat:  LayoutTime,
+		DateLayout:  "2006.01.02",
+		TimeLayouts: []string{"15:04", "15:04:05"},
+	}
+}
+
+// MetaTrader5CSVDialect is the tab separated bars export from MetaTrader 5, daily bars have no <TIME> column:
+//   <DATE>	<TIME>	<OPEN>	<HIGH>	<LOW>	<CLOSE>	<TICKVOL>	<VOL>	<SPREAD>
+//   2022.12.01	09:30:00	1.04123	1.04200	1.04100	1.04150	1234	0	5
+//
+// The tick volume is used as the volume, since most forex brokers don't report a real volume.
+// The times are in the broker's server time zone, so the Location should be set to match.
+func MetaTrader5CSVDialect() CSVDialect {
+	return CSVDialect{
+		Delimiter: '\t',
+		Header:    true,
+		Fields:    []string{"<DATE>", "<TIME>", "<OPEN>", "<HIGH>", "<LOW>", "<CLOSE>", "<TICKVOL>", "<VOL>", "<SPREAD>"},
+		Columns: map[string]string{
+			CSVDateColumn: "<DATE>",
+			TimeColumn:    "<TIME>",
+			OpenColumn:    "<OPEN>",
+			HighColumn:    "<HIGH>",
+			LowColumn:     "<LOW>",
+			CloseColumn:   "<CLOSE>",
+			VolumeColumn:  "<TICKVOL>",
+		},
+		TimeFormat:  LayoutTime,
+		DateLayout:  "2006.01.02",
+		TimeLayouts: []string{"15:04:05", "15:04"},
+	}
+}
+
+// InteractiveBrokersCSVDialect is the historical bars from Interactive Brokers, daily bars only have the date:
+//   date,open,high,low,close,volume,average,barCount
+//   20221201  09:30:00,148.21,149.13,146.61,148.31,712504,147.9,3012
+//
+// The times are in the TWS login time zone, so the Location should be set to match.
+func InteractiveBrokersCSVDialect() CSVDialect {
+	return CSVDialect{
+		Header: true,
+		Fields: []string{"date", "open", "high", "low", "close", "volume", "average", "barCount"},
+		Columns: map[string]string{
+			TimeColumn:   "date",
+			OpenColumn:   "open",
+			HighColumn:   "high",
+			LowColumn:    "low",
+			CloseColumn:  "close",
+			VolumeColumn: "volume",
+		},
+		TimeFormat:  LayoutTime,
+		TimeLayouts: []string{"20060102  15:04:05", "20060102 15:04:05", "20060102"},
+	}
+}
+
+// BinanceCSVDialect is the klines download from Binance (data.binance.vision), which has no header:
+//   1669852800000,17165.53,17197.50,17105.00,17130.01,2121.24,1669856399999,36367513.39,71251,1015.33,17408361.19,0
+//
+func BinanceCSVDialect() CSVDialect {
+	return CSVDialect{
+		Header: false,
+		Fields: []string{
+			"open_time", "open", "high", "low", "close", "volume", "close_time",
+			"quote_volume", "count", "taker_buy_volume", "taker_buy_quote_volume", "ignore",
+		},
+		Columns: map[string]string{
+			TimeColumn:   "open_time",
+			OpenColumn:   "open",
+			HighColumn:   "high",
+			LowColumn:    "low",
+			CloseColumn:  "close",
+			VolumeColumn: "volume",
+		},
+		TimeFormat: UnixMillisTime,
+	}
+}
+
+// withDefaults fills in any missing options with their default value
+func (d CSVDialect) withDefaults() CSVDialect {
+	if d.Delimiter == 0 {
+		d.Delimiter = ','
+	}
+	if d.DecimalSeparator == 0 {
+		d.DecimalSeparator = '.'
+	}
+	if nil == d.Location {
+		d.Location = time.UTC
+	}
+	return d
+}
+
+// Validate checks the dialect is complete and consistent
+//
+// Errors:
+// - If the delimiter and decimal separator are the same an error with GRPC status InvalidArgument will be returned
+// - If there is no header and no fields an error with GRPC status InvalidArgument will be returned
+// - If a column is unknown, or isn't one of the fields, an error with GRPC status InvalidArgument will be returned
+// - If there is no time or date column an error with GRPC status InvalidArgument will be returned
+// - If the time format is unknown, or the layouts it needs are missing, an error with GRPC status InvalidArgument will be returned
+//
+func (d CSVDialect) Validate() error {
+	d = d.withDefaults()
+	if d.Delimiter == d.DecimalSeparator {
+		return status.Error(codes.InvalidArgument, "the delimiter and decimal separator must be different")
+	}
+	if !d.Header && len(d.Fields) == 0 {
+		return status.Error(codes.InvalidArgument, "fields are required when there is no header")
+	}
+	for column, name := range d.Columns {
+		if !isColumn(column) && column != CSVDateColumn {
+			return status.Errorf(codes.InvalidArgument, "unknown column: %s", column)
+		}
+		if len(d.Fields) > 0 && indexOf(d.Fields, name) < 0 {
+			return status.Errorf(codes.InvalidArgument, "column is not a field: %s", name)
+		}
+	}
+
+	_, hasTime := d.Columns[TimeColumn]
+	_, hasDate := d.Columns[CSVDateColumn]
+	if !hasTime && !hasDate {
+		return status.Error(codes.InvalidArgument, "missing time column")
+	}
+	if _, ok := timeFormats[d.TimeFormat]; !ok {
+		return status.Error(codes.InvalidArgument, "unknown time format")
+	}
+	if hasDate && (d.TimeFormat != LayoutTime || d.DateLayout == "") {
+		return status.Error(codes.InvalidArgument, "a date column needs a date layout")
+	}
+	if hasTime && d.TimeFormat == LayoutTime && len(d.TimeLayouts) == 0 {
+		return status.Error(codes.InvalidArgument, "missing time layouts")
+	}
+	return nil
+}
+
+// writeFields are the columns that are written, the Fields or just the mapped columns
+func (d CSVDialect) writeFields() []string {
+	if len(d.Fields) > 0 {
+		return d.Fields
+	}
+	output := make([]string, 0, len(d.Columns))
+	for _, column := range append([]string{CSVDateColumn}, Columns...) {
+		if name, ok := d.Columns[column]; ok {
+			output = append(output, name)
+		}
+	}
+	return output
+}
+
+// CSVParseError is a value that could not be parsed, with the line and column it's on
+type CSVParseError struct {
+	// Line number of the row, starting at 1
+	Line int
+	// Column number of the value within the row, starting at 1
+	Column int
+	// Field is the name of the column
+	Field string
+	// Value that could not be parsed
+	Value string
+	Err   error
+}
+
+func (c *CSVParseError) Error() string {
+	if c.Field == "" {
+		return fmt.Sprintf("line %d, column %d: %v", c.Line, c.Column, c.Err)
+	}
+	return fmt.Sprintf("line %d, column %d (%s): cannot parse %q: %v", c.Line, c.Column, c.Field, c.Value, c.Err)
+}
+
+func (c *CSVParseError) Unwrap() error {
+	return c.Err
+}
+
+// GRPCStatus lets status.Code() and status.FromError() treat the error as InvalidArgument
+func (c *CSVParseError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, c.Error())
+}
+
+// Compile time type assertions
+var _ StreamLoader = &csvDialectLoader{}
+
+type csvDialectLoader struct {
+	dialect CSVDialect
+}
+
+type csvDialectReader struct {
+	logger  *zap.Logger
+	dialect CSVDialect
+	lines   *csvLineReader
+	reader  *csv.Reader
+	// names of each column in the file
+	names []string
+	// indices of each StandardBar column (and CSVDateColumn) in the file
+	indices map[string]int
+	// first is true until the first row has been read
+	first bool
+}
+
+type csvDialectWriter struct {
+	logger  *zap.Logger
+	dialect CSVDialect
+	writer  *csv.Writer
+	fields  []string
+	// columns are the StandardBar column (or CSVDateColumn) for each field
+	columns []string
+}
+
+// csvLineReader hands the csv reader a single line at a time,
+// so the number of lines it has read is always the line the current row ends on.
+type csvLineReader struct {
+	input   *bufio.Reader
+	pending []byte
+	lines   int
+	partial bool
+}
+
+// NewCSVDialectLoader creates a Loader for CSV files with the given layout
+//
+// Errors:
+// - If the dialect is not valid an error with GRPC status InvalidArgument will be returned, see CSVDialect.Validate
+//
+func NewCSVDialectLoader(dialect CSVDialect) (Loader, error) {
+	err := dialect.Validate()
+	if nil != err {
+		return nil, err
+	}
+	return NewLoader(&csvDialectLoader{dialect: dialect.withDefaults()}), nil
+}
+
+func (c csvDialectLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	logger := ctxzap.Extract(ctx)
+
+	lines := &csvLineReader{input: bufio.NewReader(input)}
+	reader := csv.NewReader(lines)
+	reader.Comma = c.dialect.Delimiter
+	reader.Comment = c.dialect.Comment
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	output := &csvDialectReader{
+		logger:  logger,
+		dialect: c.dialect,
+		lines:   lines,
+		reader:  reader,
+		names:   c.dialect.Fields,
+		first:   true,
+	}
+
+	// The header is read right away, an empty input has no rows
+	if c.dialect.Header {
+		header, err := reader.Read()
+		if nil != err && err == io.EOF {
+			return &csvDialectReader{logger: logger}, nil
+		}
+		if nil != err {
+			logger.Error("Failed to read header", zap.Error(err))
+			return nil, output.wrapError(err)
+		}
+		output.names = make([]string, 0, len(header))
+		for index, name := range header {
+			if index == 0 {
+				// Strip the UTF-8 byte order mark that some tools add
+				name = strings.TrimPrefix(name, "\ufeff")
+			}
+			output.names = append(output.names, strings.TrimSpace(name))
+		}
+		output.first = false
+	}
+
+	output.indices = make(map[string]int, len(c.dialect.Columns))
+	for column, name := range c.dialect.Columns {
+		for index, field := range output.names {
+			if strings.EqualFold(field, name) {
+				output.indices[column] = index
+				break
+			}
+		}
+	}
+	_, hasTime := output.indices[TimeColumn]
+	_, hasDate := output.indices[CSVDateColumn]
+	if !hasTime && !hasDate {
+		return nil, status.Error(codes.InvalidArgument, "missing time column")
+	}
+	return output, nil
+}
+
+func (c csvDialectLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	logger := ctxzap.Extract(ctx)
+
+	fields := c.dialect.writeFields()
+	columns := make([]string, len(fields))
+	for column, name := range c.dialect.Columns {
+		columns[indexOf(fields, name)] = column
+	}
+
+	writer := csv.NewWriter(output)
+	writer.Comma = c.dialect.Delimiter
+	if c.dialect.Header {
+		err := writer.Write(fields)
+		if nil != err {
+			logger.Error("Failed to write header", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return &csvDialectWriter{
+		logger:  logger,
+		dialect: c.dialect,
+		writer:  writer,
+		fields:  fields,
+		columns: columns,
+	}, nil
+}
+
+func (c *csvDialectReader) Next() (Bar, error) {
+	if nil == c.reader {
+		return nil, io.EOF
+	}
+
+	for {
+		record, err := c.reader.Read()
+		if nil != err && err == io.EOF {
+			return nil, io.EOF
+		}
+		if nil != err {
+			c.logger.Error("Failed to read row", zap.Error(err))
+			return nil, c.wrapError(err)
+		}
+
+		// Headerless files sometimes have a header anyway
+		first := c.first
+		c.first = false
+		if first && c.isHeader(record) {
+			continue
+		}
+
+		output, err := c.parse(record)
+		if nil != err {
+			c.logger.Error("Failed to parse row", zap.Error(err))
+			return nil, err
+		}
+		return output, nil
+	}
+}
+
+// isHeader checks if the row is just the names of each field
+func (c *csvDialectReader) isHeader(record []string) bool {
+	for index, value := range record {
+		if index >= len(c.names) || !strings.EqualFold(strings.TrimSpace(value), c.names[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *csvDialectReader) parse(record []string) (Bar, error) {
+	output := &StandardBar{OpenInterest: -1}
+
+	unixTime, err := c.parseTime(record)
+	if nil != err {
+		return nil, err
+	}
+	output.UnixTime = unixTime
+
+	floats := map[string]*float64{
+		OpenColumn:   &output.Open,
+		HighColumn:   &output.High,
+		LowColumn:    &output.Low,
+		CloseColumn:  &output.Close,
+		VolumeColumn: &output.Volume,
+	}
+	for column, value := range floats {
+		text, index, ok := c.value(record, column)
+		if !ok {
+			continue
+		}
+		*value, err = strconv.ParseFloat(c.number(text), 64)
+		if nil != err {
+			return nil, c.parseError(index, text, err)
+		}
+	}
+
+	text, index, ok := c.value(record, OpenInterestColumn)
+	if ok {
+		// Some vendors write the open interest as a float
+		openInterest, err := strconv.ParseFloat(c.number(text), 64)
+		if nil != err {
+			return nil, c.parseError(index, text, err)
+		}
+		output.OpenInterest = int64(openInterest)
+	}
+	return output, nil
+}
+
+// parseTime reads the time, or date and time, as unix seconds
+func (c *csvDialectReader) parseTime(record []string) (int64, error) {
+	timeText, timeIndex, hasTime := c.value(record, TimeColumn)
+	dateText, dateIndex, hasDate := c.value(record, CSVDateColumn)
+	if !hasTime && !hasDate {
+		index := timeIndex
+		if _, ok := c.indices[TimeColumn]; !ok {
+			index = dateIndex
+		}
+		return 0, c.parseError(index, "", status.Error(codes.InvalidArgument, "missing time"))
+	}
+
+	switch c.dialect.TimeFormat {
+	case UnixSecondsTime, UnixMillisTime:
+		value, err := strconv.ParseInt(timeText, 10, 64)
+		if nil != err {
+			return 0, c.parseError(timeIndex, timeText, err)
+		}
+		if c.dialect.TimeFormat == UnixMillisTime {
+			value = value / 1000
+		}
+		return value, nil
+	case ISO8601Time:
+		value, err := parseTimeLayouts(timeText, iso8601Layouts, c.dialect.Location)
+		if nil != err {
+			return 0, c.parseError(timeIndex, timeText, err)
+		}
+		return value.Unix(), nil
+	}
+
+	// Only a date, or only a time
+	if !hasTime {
+		value, err := time.ParseInLocation(c.dialect.DateLayout, dateText, c.dialect.Location)
+		if nil != err {
+			return 0, c.parseError(dateIndex, dateText, err)
+		}
+		return value.Unix(), nil
+	}
+	if !hasDate {
+		value, err := parseTimeLayouts(timeText, c.dialect.TimeLayouts, c.dialect.Location)
+		if nil != err {
+			return 0, c.parseError(timeIndex, timeText, err)
+		}
+		return value.Unix(), nil
+	}
+
+	// A separate date and time, the date is checked on it's own so errors point at the right column
+	_, err := time.ParseInLocation(c.dialect.DateLayout, dateText, c.dialect.Location)
+	if nil != err {
+		return 0, c.parseError(dateIndex, dateText, err)
+	}
+	layouts := make([]string, 0, len(c.dialect.TimeLayouts))
+	for _, layout := range c.dialect.TimeLayouts {
+		layouts = append(layouts, c.dialect.DateLayout+" "+layout)
+	}
+	value, err := parseTimeLayouts(dateText+" "+timeText, layouts, c.dialect.Location)
+	if nil != err {
+		return 0, c.parseError(timeIndex, timeText, err)
+	}
+	return value.Unix(), nil
+}
+
+// value is the text of the column in the row, and it's index, or false when it's missing or empty
+func (c *csvDialectReader) value(record []string, column string) (string, int, bool) {
+	index, ok := c.indices[column]
+	if !ok || index >= len(record) {
+		return "", index, false
+	}
+	text := strings.TrimSpace(record[index])
+	if text == "" || (c.dialect.Null != "" && text == c.dialect.Null) {
+		return "", index, false
+	}
+	return text, index, true
+}
+
+// number converts the decimal separator to a '.'
+func (c *csvDialectReader) number(text string) string {
+	if c.dialect.DecimalSeparator == '.' {
+		return text
+	}
+	return strings.Replace(text, string(c.dialect.DecimalSeparator), ".", 1)
+}
+
+func (c *csvDialectReader) parseError(index int, value string, err error) error {
+	field := ""
+	if index < len(c.names) {
+		field = c.names[index]
+	}
+	return &CSVParseError{
+		Line:   c.lines.line(),
+		Column: index + 1,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+// wrapError converts the csv package's syntax errors to a CSVParseError
+func (c *csvDialectReader) wrapError(err error) error {
+	if parseError, ok := err.(*csv.ParseError); ok {
+		return &CSVParseError{
+			Line:   parseError.Line,
+			Column: parseError.Column,
+			Err:    parseError.Err,
+		}
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (c *csvDialectWriter) WriteBar(bar Bar) error {
+	row := make([]string, len(c.fields))
+	for index, column := range c.columns {
+		row[index] = c.format(bar, column)
+	}
+	err := c.writer.Write(row)
+	if nil != err {
+		c.logger.Error("Failed to write row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (c *csvDialectWriter) Close() error {
+	c.writer.Flush()
+	err := c.writer.Error()
+	if nil != err {
+		c.logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// format writes a single column of the bar, columns that are not mapped are left empty
+func (c *csvDialectWriter) format(bar Bar, column string) string {
+	t := bar.GetTime().In(c.dialect.Location)
+	switch column {
+	case CSVDateColumn:
+		return t.Format(c.dialect.DateLayout)
+	case TimeColumn:
+		switch c.dialect.TimeFormat {
+		case UnixSecondsTime:
+			return strconv.FormatInt(t.Unix(), 10)
+		case UnixMillisTime:
+			return strconv.FormatInt(t.Unix()*1000, 10)
+		case ISO8601Time:
+			return t.Format(iso8601Layouts[0])
+		}
+		return t.Format(c.dialect.TimeLayouts[0])
+	case OpenColumn:
+		return c.number(bar.GetOpen())
+	case HighColumn:
+		return c.number(bar.GetHigh())
+	case LowColumn:
+		return c.number(bar.GetLow())
+	case CloseColumn:
+		return c.number(bar.GetClose())
+	case VolumeColumn:
+		return c.number(bar.GetVolume())
+	case OpenInterestColumn:
+		return strconv.FormatInt(bar.GetOpenInterest(), 10)
+	}
+	return ""
+}
+
+// number writes the float with the dialect's decimal separator
+func (c *csvDialectWriter) number(value float64) string {
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if c.dialect.DecimalSeparator == '.' {
+		return text
+	}
+	return strings.Replace(text, ".", string(c.dialect.DecimalSeparator), 1)
+}
+
+func (c *csvLineReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(c.pending) == 0 {
+		line, err := c.input.ReadSlice('\n')
+		if nil != err && err == bufio.ErrBufferFull {
+			err = nil
+		}
+		if len(line) == 0 {
+			return 0, err
+		}
+		// The slice is only valid until the next read, which won't happen until it's been copied out
+		c.pending = line
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	if p[n-1] == '\n' {
+		c.lines++
+		c.partial = false
+	} else {
+		c.partial = true
+	}
+	return n, nil
+}
+
+// line is the line number of the last line that has been read
+func (c *csvLineReader) line() int {
+	if c.partial {
+		return c.lines + 1
+	}
+	return c.lines
+}
+
+// parseTimeLayouts tries each layout in order, returning the first error if none of them match
+func parseTimeLayouts(value string, layouts []string, location *time.Location) (time.Time, error) {
+	var firstErr error
+	for _, layout := range layouts {
+		output, err := time.ParseInLocation(layout, value, location)
+		if nil == err {
+			return output, nil
+		}
+		if nil == firstErr {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
+func indexOf(values []string, value string) int {
+	for index, item := range values {
+		if item == value {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,279 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, UnixSecondsTime.String(), unixSecondsTimeStr)
+		require.Equal(t, UnixMillisTime.String(), unixMillisTimeStr)
+		require.Equal(t, ISO8601Time.String(), iso8601TimeStr)
+		require.Equal(t, LayoutTime.String(), layoutTimeStr)
+	})
+}
+
+func TestCSVDialectLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("Presets", func(t *testing.T) {
+		type args struct {
+			dialect CSVDialect
+			input   string
+			bar     Bar
+		}
+		tests := map[string]args{
+			"Default": {
+				DefaultCSVDialect(),
+				"time,open,high,low,close,volume,open_interest\n1669852800,1.5,2,1,1.75,100,10\n",
+				New(now, 1.5, 2, 1, 1.75, 100, 10),
+			},
+			"Yahoo": {
+				YahooCSVDialect(),
+				"Date,Open,High,Low,Close,Adj Close,Volume\n2022-12-01,148.21,149.13,146.61,148.31,147.88,71250400\n",
+				New(now, 148.21, 149.13, 146.61, 148.31, 71250400, -1),
+			},
+			"Stooq": {
+				StooqCSVDialect(),
+				"<TICKER>,<PER>,<DATE>,<TIME>,<OPEN>,<HIGH>,<LOW>,<CLOSE>,<VOL>,<OPENINT>\nES.F,5,20221201,093000,4000.25,4010,3990.5,4005,12000,2500000\n",
+				New(now.Add(9*time.Hour+30*time.Minute), 4000.25, 4010, 3990.5, 4005, 12000, 2500000),
+			},
+			"MetaTrader 4": {
+				MetaTrader4CSVDialect(),
+				"2022.12.01,09:30,1.04123,1.042,1.041,1.0415,1234\n",
+				New(now.Add(9*time.Hour+30*time.Minute), 1.04123, 1.042, 1.041, 1.0415, 1234, -1),
+			},
+			"MetaTrader 5": {
+				MetaTrader5CSVDialect(),
+				"<DATE>\t<TIME>\t<OPEN>\t<HIGH>\t<LOW>\t<CLOSE>\t<TICKVOL>\t<VOL>\t<SPREAD>\n2022.12.01\t09:30:00\t1.04123\t1.042\t1.041\t1.0415\t1234\t0\t5\n",
+				New(now.Add(9*time.Hour+30*time.Minute), 1.04123, 1.042, 1.041, 1.0415, 1234, -1),
+			},
+			"MetaTrader 5 - daily": {
+				MetaTrader5CSVDialect(),
+				"<DATE>\t<OPEN>\t<HIGH>\t<LOW>\t<CLOSE>\t<TICKVOL>\t<VOL>\t<SPREAD>\n2022.12.01\t1.04123\t1.042\t1.041\t1.0415\t1234\t0\t5\n",
+				New(now, 1.04123, 1.042, 1.041, 1.0415, 1234, -1),
+			},
+			"Interactive Brokers": {
+				InteractiveBrokersCSVDialect(),
+				"date,open,high,low,close,volume,average,barCount\n20221201  09:30:00,148.21,149.13,146.61,148.31,712504,147.9,3012\n",
+				New(now.Add(9*time.Hour+30*time.Minute), 148.21, 149.13, 146.61, 148.31, 712504, -1),
+			},
+			"Interactive Brokers - daily": {
+				InteractiveBrokersCSVDialect(),
+				"date,open,high,low,close,volume,average,barCount\n20221201,148.21,149.13,146.61,148.31,712504,147.9,3012\n",
+				New(now, 148.21, 149.13, 146.61, 148.31, 712504, -1),
+			},
+			"Binance": {
+				BinanceCSVDialect(),
+				"1669852800000,17165.53,17197.5,17105,17130.01,2121.24,1669856399999,36367513.39,71251,1015.33,17408361.19,0\n",
+				New(now, 17165.53, 17197.5, 17105, 17130.01, 2121.24, -1),
+			},
+			"Binance - with a header": {
+				BinanceCSVDialect(),
+				"open_time,open,high,low,close,volume,close_time,quote_volume,count,taker_buy_volume,taker_buy_quote_volume,ignore\n" +
+					"1669852800000,17165.53,17197.5,17105,17130.01,2121.24,1669856399999,36367513.39,71251,1015.33,17408361.19,0\n",
+				New(now, 17165.53, 17197.5, 17105, 17130.01, 2121.24, -1),
+			},
+		}
+		for key, arg := range tests {
+			arg := arg
+			t.Run(key, func(t *testing.T) {
+				loader, err := NewCSVDialectLoader(arg.dialect)
+				require.NoError(t, err)
+
+				output, err := loader.Read(ctx, strings.NewReader(arg.input))
+				require.NoError(t, err)
+				require.Len(t, output, 1)
+				requireEqualBar(t, output[0], arg.bar)
+
+				// Writing and reading the bars gives back the same bars
+				buff := bytes.NewBuffer([]byte{})
+				err = loader.Write(ctx, buff, output)
+				require.NoError(t, err)
+				again, err := loader.Read(ctx, buff)
+				require.NoError(t, err)
+				require.Equal(t, again, output)
+			})
+		}
+	})
+
+	t.Run("Default dialect matches NewCSVLoader", func(t *testing.T) {
+		bars := []Bar{NewFakeBar(now), NewFakeBar(now.Add(time_series.Day))}
+		buff := bytes.NewBuffer([]byte{})
+		err := NewCSVLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		loader, err := NewCSVDialectLoader(DefaultCSVDialect())
+		require.NoError(t, err)
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			requireEqualBar(t, row, bars[index])
+		}
+	})
+
+	t.Run("European numbers", func(t *testing.T) {
+		dialect := CSVDialect{
+			Delimiter:        ';',
+			DecimalSeparator: ',',
+			Header:           true,
+			Columns: map[string]string{
+				TimeColumn:  "Zeit",
+				CloseColumn: "Schluss",
+			},
+			TimeFormat: ISO8601Time,
+			Location:   newYork,
+		}
+		loader, err := NewCSVDialectLoader(dialect)
+		require.NoError(t, err)
+
+		output, err := loader.Read(ctx, strings.NewReader("Zeit;Schluss\n2022-12-01 09:30:00;148,31\n"))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].GetTime().Unix(), time.Date(2022, 12, 1, 9, 30, 0, 0, newYork).Unix())
+		require.Equal(t, output[0].GetClose(), 148.31)
+		require.Equal(t, output[0].GetOpen(), 0.0)
+		require.Equal(t, output[0].GetOpenInterest(), int64(-1))
+
+		// Only the mapped columns are written
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, output)
+		require.NoError(t, err)
+		require.Equal(t, buff.String(), "Zeit;Schluss\n2022-12-01T09:30:00-05:00;148,31\n")
+	})
+
+	t.Run("Comments, blank lines, and nulls", func(t *testing.T) {
+		dialect := YahooCSVDialect()
+		dialect.Comment = '#'
+		loader, err := NewCSVDialectLoader(dialect)
+		require.NoError(t, err)
+
+		input := "# Exported from Yahoo\nDate,Open,High,Low,Close,Adj Close,Volume\n\n2022-12-01,null,null,null,null,null,null\n"
+		output, err := loader.Read(ctx, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].GetVolume(), 0.0)
+	})
+
+	t.Run("Empty input", func(t *testing.T) {
+		for _, dialect := range []CSVDialect{YahooCSVDialect(), BinanceCSVDialect()} {
+			loader, err := NewCSVDialectLoader(dialect)
+			require.NoError(t, err)
+			reader, err := loader.NewReader(ctx, strings.NewReader(""))
+			require.NoError(t, err)
+			_, err = reader.Next()
+			require.Equal(t, err, io.EOF)
+		}
+	})
+
+	t.Run("Parse errors", func(t *testing.T) {
+		type args struct {
+			dialect CSVDialect
+			input   string
+			line    int
+			column  int
+			field   string
+		}
+		tests := map[string]args{
+			"Bad number": {
+				YahooCSVDialect(),
+				"Date,Open,High,Low,Close,Adj Close,Volume\n2022-12-01,1,2,1,1.5,1.5,100\n2022-12-02,1,2,oops,1.5,1.5,100\n",
+				3, 4, "Low",
+			},
+			"Bad date after comments": {
+				CSVDialect{Comment: '#', Header: true, Columns: map[string]string{CSVDateColumn: "d", TimeColumn: "t"}, TimeFormat: LayoutTime, DateLayout: "2006-01-02", TimeLayouts: []string{"15:04"}},
+				"d,t\n# one\n# two\n2022-13-01,09:30\n",
+				4, 1, "d",
+			},
+			"Bad time": {
+				MetaTrader4CSVDialect(),
+				"2022.12.01,09:30,1,2,1,1.5,100\n\n2022.12.01,25:30,1,2,1,1.5,100\n",
+				3, 2, "time",
+			},
+			"Bad epoch": {
+				BinanceCSVDialect(),
+				"1669852800000,1,2,1,1.5,100\nabc,1,2,1,1.5,100",
+				2, 1, "open_time",
+			},
+			"Bad quote": {
+				DefaultCSVDialect(),
+				"time,open,high,low,close,volume,open_interest\n1669852800,1,2,1,1.5,100,1\n1669852800,\"1,2,1,1.5,100,1\n",
+				3, 0, "",
+			},
+		}
+		for key, arg := range tests {
+			arg := arg
+			t.Run(key, func(t *testing.T) {
+				loader, err := NewCSVDialectLoader(arg.dialect)
+				require.NoError(t, err)
+
+				_, err = loader.Read(ctx, strings.NewReader(arg.input))
+				require.Error(t, err)
+				require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+				var parseError *CSVParseError
+				require.True(t, errors.As(err, &parseError))
+				require.Equal(t, parseError.Line, arg.line)
+				if arg.column > 0 {
+					require.Equal(t, parseError.Column, arg.column)
+				}
+				require.Equal(t, parseError.Field, arg.field)
+			})
+		}
+	})
+
+	t.Run("Missing time column in the header", func(t *testing.T) {
+		loader, err := NewCSVDialectLoader(YahooCSVDialect())
+		require.NoError(t, err)
+		_, err = loader.Read(ctx, strings.NewReader("Open,Close\n1,2\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		columns := map[string]string{TimeColumn: "t"}
+		tests := map[string]struct {
+			dialect CSVDialect
+			ok      bool
+		}{
+			"OK":                      {CSVDialect{Header: true, Columns: columns, TimeFormat: UnixSecondsTime}, true},
+			"Same delimiter":          {CSVDialect{Header: true, Columns: columns, TimeFormat: UnixSecondsTime, DecimalSeparator: ','}, false},
+			"No header or fields":     {CSVDialect{Columns: columns, TimeFormat: UnixSecondsTime}, false},
+			"Unknown column":          {CSVDialect{Header: true, Columns: map[string]string{TimeColumn: "t", "vwap": "v"}, TimeFormat: UnixSecondsTime}, false},
+			"Column is not a field":   {CSVDialect{Fields: []string{"a"}, Columns: columns, TimeFormat: UnixSecondsTime}, false},
+			"No time column":          {CSVDialect{Header: true, Columns: map[string]string{CloseColumn: "c"}, TimeFormat: UnixSecondsTime}, false},
+			"No time format":          {CSVDialect{Header: true, Columns: columns}, false},
+			"Date without a layout":   {CSVDialect{Header: true, Columns: map[string]string{CSVDateColumn: "d"}, TimeFormat: LayoutTime}, false},
+			"Date with epoch":         {CSVDialect{Header: true, Columns: map[string]string{CSVDateColumn: "d"}, TimeFormat: UnixSecondsTime, DateLayout: "2006"}, false},
+			"Layout without a layout": {CSVDialect{Header: true, Columns: columns, TimeFormat: LayoutTime}, false},
+		}
+		for key, arg := range tests {
+			arg := arg
+			t.Run(key, func(t *testing.T) {
+				loader, err := NewCSVDialectLoader(arg.dialect)
+				if !arg.ok {
+					require.Error(t, err)
+					require.Nil(t, loader)
+				} else {
+					require.NoError(t, err)
+					require.NotNil(t, loader)
+				}
+			})
+		}
+	})
+}
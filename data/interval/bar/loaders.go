@@ -5,40 +5,56 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/csv"
 	"encoding/json"
 	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/hamba/avro"
 	"github.com/jszwec/csvutil"
+	"github.com/ta4g/ta4g/data/avro_file"
+	"github.com/ta4g/ta4g/data/proto_stream"
 	pb "github.com/ta4g/ta4g/gen/interval/bar"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"io"
-	"io/ioutil"
 )
 
 // Loader reads and writes the Bar data to the desired format.
 // There are several loaders to choose from, each of which are self-contained with their own schemas:
-// 1. CSV
-// 2. Avro
-// 3. Proto
+// 1. CSV, see also NewCSVDialectLoader for vendor specific files
+// 2. JSON New Line
+// 3. Avro
+// 4. Proto, see also NewDelimitedProtoLoader for length-delimited streams
+// 5. Parquet
+// 6. Arrow (file / Feather, and stream)
+// 7. Binary, fixed-width records that can be memory-mapped with OpenBinaryFile
+//
+// Every Loader is also a StreamLoader, so inputs that don't fit in memory can be processed one bar at a time.
+// NewAutoLoader picks the format from a file's path or content, see RegisterFormat to add new formats.
+// NewCompressedLoader adds gzip, zstd, snappy, or lz4 compression to any format.
 type Loader interface {
+	StreamLoader
 	Read(ctx context.Context, input io.Reader) ([]Bar, error)
 	Write(ctx context.Context, output io.Writer, input []Bar) error
 }
 
 // Compile time type assertions
-var _ Loader = &csvLoader{}
-var _ Loader = &jsonNewLineLoader{}
-var _ Loader = &avroLoader{}
-var _ Loader = &protoLoader{}
+var _ StreamLoader = &csvLoader{}
+var _ StreamLoader = &jsonNewLineLoader{}
+var _ StreamLoader = &avroLoader{}
+var _ StreamLoader = &protoLoader{}
+var _ StreamLoader = &delimitedProtoLoader{}
 
 type csvLoader struct{}
 type jsonNewLineLoader struct{}
-type avroLoader struct{}
+type avroLoader struct {
+	options avro_file.Options
+}
 type protoLoader struct{}
+type delimitedProtoLoader struct{}
 
 //go:embed schema.avro
 var schemaStr string
@@ -58,65 +74,92 @@ func init() {
 //
 
 func NewCSVLoader() Loader {
-	return &csvLoader{}
+	return NewLoader(&csvLoader{})
+}
+
+type csvReader struct {
+	logger  *zap.Logger
+	decoder *csvutil.Decoder
+}
+
+type csvWriter struct {
+	logger  *zap.Logger
+	writer  *csv.Writer
+	encoder *csvutil.Encoder
+	count   int
 }
 
-func (c csvLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
+func (c csvLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
 	logger := ctxzap.Extract(ctx)
 
-	// Pull in the CSV
-	data, err := ioutil.ReadAll(input)
-	if nil != err {
-		logger.Error("Failed to read all rows", zap.Error(err))
-		return nil, status.Error(codes.Internal, err.Error())
+	// The header is read right away, an empty input has no rows
+	decoder, err := csvutil.NewDecoder(csv.NewReader(input))
+	if nil != err && err == io.EOF {
+		return &csvReader{logger: logger}, nil
 	}
-
-	// Read the rows
-	var bars []StandardBar
-	err = csvutil.Unmarshal(data, &bars)
 	if nil != err {
-		logger.Error("Failed to unmarshal rows", zap.Error(err))
+		logger.Error("Failed to read header", zap.Error(err))
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	return &csvReader{logger: logger, decoder: decoder}, nil
+}
 
-	// Type conversion
-	output := make([]Bar, 0, len(bars))
-	for _, b := range bars {
-		newBar, err := b.Clone()
-		if nil != err {
-			logger.Error("Failed to clone bar", zap.Error(err))
-			return nil, err
-		}
-		output = append(output, newBar)
-	}
-	return output, nil
+func (c csvLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	writer := csv.NewWriter(output)
+	return &csvWriter{
+		logger:  ctxzap.Extract(ctx),
+		writer:  writer,
+		encoder: csvutil.NewEncoder(writer),
+	}, nil
 }
 
-func (c csvLoader) Write(ctx context.Context, output io.Writer, input []Bar) error {
-	logger := ctxzap.Extract(ctx)
+func (c *csvReader) Next() (Bar, error) {
+	if nil == c.decoder {
+		return nil, io.EOF
+	}
 
-	// Type conversion
-	bars := make([]StandardBar, 0, len(input))
-	for _, b := range input {
-		value, ok := b.(*StandardBar)
-		if !ok {
-			value = copyToStandardBar(b)
-		}
-		bars = append(bars, *value)
+	stdBar := &StandardBar{}
+	err := c.decoder.Decode(stdBar)
+	if nil != err && err == io.EOF {
+		return nil, io.EOF
 	}
+	if nil != err {
+		c.logger.Error("Failed to unmarshal row", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return stdBar, nil
+}
 
-	data, err := csvutil.Marshal(bars)
+func (c *csvWriter) WriteBar(bar Bar) error {
+	stdBar, ok := bar.(*StandardBar)
+	if !ok {
+		stdBar = copyToStandardBar(bar)
+	}
+	err := c.encoder.Encode(stdBar)
 	if nil != err {
-		logger.Error("Failed to marshal rows", zap.Error(err))
+		c.logger.Error("Failed to marshal row", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
+	c.count++
+	return nil
+}
 
-	_, err = io.Copy(output, bytes.NewReader(data))
+func (c *csvWriter) Close() error {
+	// Always write the header, even when there are no rows
+	if c.count == 0 {
+		err := c.encoder.EncodeHeader(StandardBar{})
+		if nil != err {
+			c.logger.Error("Failed to marshal header", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	c.writer.Flush()
+	err := c.writer.Error()
 	if nil != err {
-		logger.Error("Failed to write all rows", zap.Error(err))
+		c.logger.Error("Failed to write all rows", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
-
 	return nil
 }
 
@@ -125,66 +168,83 @@ func (c csvLoader) Write(ctx context.Context, output io.Writer, input []Bar) err
 //
 
 func NewJsonNewLineLoader() Loader {
-	return &jsonNewLineLoader{}
+	return NewLoader(&jsonNewLineLoader{})
 }
 
-func (j jsonNewLineLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
-	logger := ctxzap.Extract(ctx)
+type jsonNewLineReader struct {
+	logger *zap.Logger
+	reader *bufio.Reader
+}
 
-	// Pull in the CSV
-	reader := bufio.NewReader(input)
-	output := make([]Bar, 0)
+type jsonNewLineWriter struct {
+	logger *zap.Logger
+	writer *bufio.Writer
+}
+
+func (j jsonNewLineLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	return &jsonNewLineReader{
+		logger: ctxzap.Extract(ctx),
+		reader: bufio.NewReader(input),
+	}, nil
+}
+
+func (j jsonNewLineLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	return &jsonNewLineWriter{
+		logger: ctxzap.Extract(ctx),
+		writer: bufio.NewWriter(output),
+	}, nil
+}
+
+func (j *jsonNewLineReader) Next() (Bar, error) {
 	for {
-		// Read the rows line by line
-		data, err := reader.ReadBytes('\n')
-		if nil != err && err == io.EOF {
-			break
-		}
-		if nil != err {
-			logger.Error("Failed to read line", zap.Error(err))
+		// Read the rows line by line, the last line may not have a trailing new line
+		data, err := j.reader.ReadBytes('\n')
+		if nil != err && err != io.EOF {
+			j.logger.Error("Failed to read line", zap.Error(err))
 			return nil, status.Error(codes.Internal, err.Error())
 		}
-		if len(data) == 0 {
-			break
+		if len(bytes.TrimSpace(data)) == 0 {
+			if nil != err {
+				return nil, io.EOF
+			}
+			continue
 		}
 
-		// Now parse the JSON and add it to the output
+		// Now parse the JSON
 		bar := &StandardBar{}
 		err = json.Unmarshal(data, bar)
 		if nil != err {
-			logger.Error("Failed to unmarshal row", zap.Error(err))
+			j.logger.Error("Failed to unmarshal row", zap.Error(err))
 			return nil, status.Error(codes.Internal, err.Error())
 		}
-		output = append(output, bar)
+		return bar, nil
 	}
-	return output, nil
 }
 
-func (j jsonNewLineLoader) Write(ctx context.Context, writer io.Writer, bars []Bar) error {
-	logger := ctxzap.Extract(ctx)
-
-	for _, bar := range bars {
-		// Serialize as json
-		stdBar := copyToStandardBar(bar)
-		data, err := json.Marshal(stdBar)
-		if nil != err {
-			logger.Error("Failed to marshal row", zap.Error(err))
-			return status.Error(codes.Internal, err.Error())
-		}
+func (j *jsonNewLineWriter) WriteBar(bar Bar) error {
+	// Serialize as json
+	stdBar := copyToStandardBar(bar)
+	data, err := json.Marshal(stdBar)
+	if nil != err {
+		j.logger.Error("Failed to marshal row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
 
-		// Write the bar
-		_, err = writer.Write(data)
-		if nil != err {
-			logger.Error("Failed to write line", zap.Error(err))
-			return status.Error(codes.Internal, err.Error())
-		}
+	// Write the bar, followed by the delimiter
+	data = append(data, '\n')
+	_, err = j.writer.Write(data)
+	if nil != err {
+		j.logger.Error("Failed to write line", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
 
-		// Now write the delimiter
-		_, err = writer.Write([]byte("\n"))
-		if nil != err {
-			logger.Error("Failed to write line", zap.Error(err))
-			return status.Error(codes.Internal, err.Error())
-		}
+func (j *jsonNewLineWriter) Close() error {
+	err := j.writer.Flush()
+	if nil != err {
+		j.logger.Error("Failed to write line", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
 	}
 	return nil
 }
@@ -192,46 +252,114 @@ func (j jsonNewLineLoader) Write(ctx context.Context, writer io.Writer, bars []B
 //
 // Avro Loader
 //
+// Bars are written as an avro object container file, which embeds the schema it was written with,
+// so older files are still readable after new fields are added to `schema.avro` (as long as they have a default).
+//
+// Inputs without the container file header are read as a stream of raw datums with the current schema,
+// which is how bars were written before the container files.
+//
 
+// NewAvroLoader creates a Loader for deflate compressed avro files
 func NewAvroLoader() Loader {
-	return &avroLoader{}
+	return NewAvroFileLoader(avro_file.DefaultOptions())
+}
+
+// NewAvroFileLoader creates a Loader for avro files with the given compression and block length
+func NewAvroFileLoader(options avro_file.Options) Loader {
+	return NewLoader(&avroLoader{options: options.WithDefaults()})
+}
+
+// avroDecoder is implemented by both the container file and raw datum decoders
+type avroDecoder interface {
+	Decode(value interface{}) error
 }
 
-func (a avroLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
+type avroReader struct {
+	logger  *zap.Logger
+	decoder avroDecoder
+}
+
+type avroWriter struct {
+	logger  *zap.Logger
+	encoder *avro_file.Encoder
+}
+
+func (a avroLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
 	logger := ctxzap.Extract(ctx)
 
-	decoder := avro.NewDecoderForSchema(avroSchema, input)
+	buffered := bufio.NewReader(input)
+	container, err := avro_file.IsContainer(buffered)
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, err
+	}
+	if !container {
+		return &avroReader{
+			logger:  logger,
+			decoder: avro.NewDecoderForSchema(avroSchema, buffered),
+		}, nil
+	}
 
-	output := make([]Bar, 0)
-	for {
-		stdBar := &StandardBar{}
-		err := decoder.Decode(stdBar)
-		if nil != err && err == io.EOF {
-			break
-		}
-		if nil != err {
-			logger.Error("Failed to unmarshal row", zap.Error(err))
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		output = append(output, stdBar)
+	decoder, err := avro_file.NewDecoder(buffered, avroSchema)
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, err
 	}
-	return output, nil
+	return &avroReader{
+		logger:  logger,
+		decoder: decoder,
+	}, nil
 }
 
-func (a avroLoader) Write(ctx context.Context, output io.Writer, input []Bar) error {
+func (a avroLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
 	logger := ctxzap.Extract(ctx)
 
-	encoder := avro.NewEncoderForSchema(avroSchema, output)
-	for _, bar := range input {
-		stdBar, ok := bar.(*StandardBar)
-		if !ok {
-			stdBar = copyToStandardBar(bar)
-		}
-		err := encoder.Encode(stdBar)
-		if nil != err {
-			logger.Error("Failed to marshal row", zap.Error(err))
-			return status.Error(codes.Internal, err.Error())
+	encoder, err := avro_file.NewEncoder(output, avroSchema, a.options)
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return nil, err
+	}
+	return &avroWriter{
+		logger:  logger,
+		encoder: encoder,
+	}, nil
+}
+
+func (a *avroReader) Next() (Bar, error) {
+	stdBar := &StandardBar{}
+	err := a.decoder.Decode(stdBar)
+	if nil != err && err == io.EOF {
+		return nil, io.EOF
+	}
+	if nil != err {
+		a.logger.Error("Failed to unmarshal row", zap.Error(err))
+		if _, ok := status.FromError(err); !ok {
+			err = status.Error(codes.Internal, err.Error())
 		}
+		return nil, err
+	}
+	return stdBar, nil
+}
+
+func (a *avroWriter) WriteBar(bar Bar) error {
+	stdBar, ok := bar.(*StandardBar)
+	if !ok {
+		stdBar = copyToStandardBar(bar)
+	}
+	err := a.encoder.Encode(stdBar)
+	if nil != err {
+		a.logger.Error("Failed to marshal row", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *avroWriter) Close() error {
+	// Flush the last block
+	err := a.encoder.Close()
+	if nil != err {
+		a.logger.Error("Failed to write block", zap.Error(err))
+		return err
 	}
 	return nil
 }
@@ -239,73 +367,197 @@ func (a avroLoader) Write(ctx context.Context, output io.Writer, input []Bar) er
 //
 // Proto Loader
 //
+// The output is a `StandardBars` message, which is streamed one `StandardBar` at a time.
+// On the wire a repeated message field is just each message prefixed with it's tag and length,
+// so we never need to hold the whole `StandardBars` message in memory, and files can be appended to.
+//
+
+// protoBarsField is the field number of `StandardBars.bars`
+const protoBarsField = protowire.Number(1)
 
 func NewProtoLoader() Loader {
-	return &protoLoader{}
+	return NewLoader(&protoLoader{})
 }
 
-func (a protoLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
-	logger := ctxzap.Extract(ctx)
+type protoReader struct {
+	logger *zap.Logger
+	reader *bufio.Reader
+}
+
+type protoWriter struct {
+	logger *zap.Logger
+	writer *bufio.Writer
+}
 
-	data, err := ioutil.ReadAll(input)
+func (a protoLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	return &protoReader{
+		logger: ctxzap.Extract(ctx),
+		reader: bufio.NewReader(input),
+	}, nil
+}
+
+func (a protoLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	return &protoWriter{
+		logger: ctxzap.Extract(ctx),
+		writer: bufio.NewWriter(output),
+	}, nil
+}
+
+func (a *protoReader) Next() (Bar, error) {
+	for {
+		number, wireType, data, err := proto_stream.ReadField(a.reader)
+		if nil != err && err == io.EOF {
+			return nil, io.EOF
+		}
+		if nil != err {
+			a.logger.Error("Failed to read row", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		// Skip over anything that isn't a bar
+		if number != protoBarsField || wireType != protowire.BytesType {
+			continue
+		}
+
+		message := &pb.StandardBar{}
+		err = proto.Unmarshal(data, message)
+		if nil != err {
+			a.logger.Error("Failed to unmarshal row", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return fromProto(message), nil
+	}
+}
+
+func (a *protoWriter) WriteBar(bar Bar) error {
+	data, err := proto.Marshal(toProto(bar))
 	if nil != err {
-		logger.Error("Failed to read all rows", zap.Error(err))
-		return nil, status.Error(codes.Internal, err.Error())
+		a.logger.Error("Failed to marshal row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
 	}
 
-	messages := &pb.StandardBars{}
-	err = proto.Unmarshal(data, messages)
+	buff := protowire.AppendTag(nil, protoBarsField, protowire.BytesType)
+	buff = protowire.AppendBytes(buff, data)
+	_, err = a.writer.Write(buff)
 	if nil != err {
-		logger.Error("Failed to unmarshal rows", zap.Error(err))
-		return nil, status.Error(codes.Internal, err.Error())
+		a.logger.Error("Failed to write row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
 	}
+	return nil
+}
 
-	// Convert the bars
-	output := make([]Bar, 0)
-	for _, bar := range messages.Bars {
-		row := New(
-			bar.GetTime().AsTime(),
-			bar.GetOpen(),
-			bar.GetHigh(),
-			bar.GetLow(),
-			bar.GetClose(),
-			bar.GetVolume(),
-			bar.GetOpenInterest(),
-		)
-		output = append(output, row)
+func (a *protoWriter) Close() error {
+	err := a.writer.Flush()
+	if nil != err {
+		a.logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
 	}
-	return output, nil
+	return nil
 }
 
-func (a protoLoader) Write(ctx context.Context, output io.Writer, input []Bar) error {
-	logger := ctxzap.Extract(ctx)
+func fromProto(bar *pb.StandardBar) Bar {
+	return New(
+		bar.GetTime().AsTime(),
+		bar.GetOpen(),
+		bar.GetHigh(),
+		bar.GetLow(),
+		bar.GetClose(),
+		bar.GetVolume(),
+		bar.GetOpenInterest(),
+	)
+}
+
+func toProto(b Bar) *pb.StandardBar {
+	return &pb.StandardBar{
+		Time:         timestamppb.New(b.GetTime()),
+		Open:         b.GetOpen(),
+		High:         b.GetHigh(),
+		Low:          b.GetLow(),
+		Close:        b.GetClose(),
+		Volume:       b.GetVolume(),
+		OpenInterest: b.GetOpenInterest(),
+	}
+}
+
+//
+// Delimited Proto Loader
+//
+// The output is a stream of `StandardBar` messages, each one prefixed with it's length as a varint.
+// This is the same framing as `writeDelimitedTo` / `parseDelimitedFrom` in the Java, C++, and C# protobuf libraries,
+// so the files can be shared with services written in other languages.
+//
+
+// NewDelimitedProtoLoader creates a Loader for length-delimited `StandardBar` messages
+func NewDelimitedProtoLoader() Loader {
+	return NewLoader(&delimitedProtoLoader{})
+}
 
-	// Type conversion
-	bars := make([]*pb.StandardBar, 0, len(input))
-	for _, b := range input {
-		value := &pb.StandardBar{
-			Time:         timestamppb.New(b.GetTime()),
-			Open:         b.GetOpen(),
-			High:         b.GetHigh(),
-			Low:          b.GetLow(),
-			Close:        b.GetClose(),
-			Volume:       b.GetVolume(),
-			OpenInterest: b.GetOpenInterest(),
+type delimitedProtoReader struct {
+	logger *zap.Logger
+	reader *bufio.Reader
+}
+
+type delimitedProtoWriter struct {
+	logger *zap.Logger
+	writer *bufio.Writer
+}
+
+func (d delimitedProtoLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	return &delimitedProtoReader{
+		logger: ctxzap.Extract(ctx),
+		reader: bufio.NewReader(input),
+	}, nil
+}
+
+func (d delimitedProtoLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	return &delimitedProtoWriter{
+		logger: ctxzap.Extract(ctx),
+		writer: bufio.NewWriter(output),
+	}, nil
+}
+
+func (d *delimitedProtoReader) Next() (Bar, error) {
+	data, err := proto_stream.ReadDelimited(d.reader)
+	if nil != err && err == io.EOF {
+		return nil, io.EOF
+	}
+	if nil != err {
+		d.logger.Error("Failed to read row", zap.Error(err))
+		if _, ok := status.FromError(err); ok {
+			return nil, err
 		}
-		bars = append(bars, value)
+		return nil, status.Error(codes.DataLoss, err.Error())
 	}
 
-	data, err := proto.Marshal(&pb.StandardBars{Bars: bars})
+	message := &pb.StandardBar{}
+	err = proto.Unmarshal(data, message)
+	if nil != err {
+		d.logger.Error("Failed to unmarshal row", zap.Error(err))
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+	return fromProto(message), nil
+}
+
+func (d *delimitedProtoWriter) WriteBar(bar Bar) error {
+	data, err := proto.Marshal(toProto(bar))
 	if nil != err {
-		logger.Error("Failed to marshal rows", zap.Error(err))
+		d.logger.Error("Failed to marshal row", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
 
-	_, err = io.Copy(output, bytes.NewReader(data))
+	err = proto_stream.WriteDelimited(d.writer, data)
 	if nil != err {
-		logger.Error("Failed to write all rows", zap.Error(err))
+		d.logger.Error("Failed to write row", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
+	return nil
+}
 
+func (d *delimitedProtoWriter) Close() error {
+	err := d.writer.Flush()
+	if nil != err {
+		d.logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
 	return nil
 }
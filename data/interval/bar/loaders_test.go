@@ -3,8 +3,19 @@ package bar
 import (
 	"bytes"
 	"context"
+	"github.com/hamba/avro"
 	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/avro_file"
+	"github.com/ta4g/ta4g/data/proto_stream"
 	"github.com/ta4g/ta4g/data/time/time_series"
+	pb "github.com/ta4g/ta4g/gen/interval/bar"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -86,6 +97,24 @@ func TestJsonNewLineLoader(t *testing.T) {
 		require.Equal(t, row.GetVolume(), b.GetVolume())
 		require.Equal(t, row.GetOpenInterest(), b.GetOpenInterest())
 	}
+
+	t.Run("Special floats", func(t *testing.T) {
+		// A halted symbol, without any trades
+		input := []Bar{New(now, math.NaN(), math.Inf(1), math.Inf(-1), math.NaN(), 0, -1)}
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, input)
+		require.NoError(t, err)
+		require.Equal(t, buff.String(), `{"time":1669852800,"open":"NaN","high":"Infinity","low":"-Infinity","close":"NaN","volume":0,"open_interest":-1}`+"\n")
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.True(t, math.IsNaN(output[0].GetOpen()))
+		require.True(t, math.IsInf(output[0].GetHigh(), 1))
+		require.True(t, math.IsInf(output[0].GetLow(), -1))
+		require.True(t, math.IsNaN(output[0].GetClose()))
+		require.Equal(t, output[0].GetOpenInterest(), int64(-1))
+	})
 }
 
 func TestAvroLoader(t *testing.T) {
@@ -121,6 +150,85 @@ func TestAvroLoader(t *testing.T) {
 		require.Equal(t, row.GetVolume(), b.GetVolume())
 		require.Equal(t, row.GetOpenInterest(), b.GetOpenInterest())
 	}
+
+	t.Run("Container file", func(t *testing.T) {
+		for _, compression := range []avro_file.Compression{avro_file.Deflate, avro_file.Snappy, avro_file.Uncompressed} {
+			buff := bytes.NewBuffer([]byte{})
+			err := NewAvroFileLoader(avro_file.Options{Compression: compression, BlockLength: 2}).Write(ctx, buff, bars)
+			require.NoError(t, err)
+			require.Equal(t, buff.Bytes()[:4], avro_file.Magic)
+
+			// Any loader can read the file, the codec is in the header
+			output, err := NewAvroLoader().Read(ctx, buff)
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+		}
+
+		// No bars still has a header
+		buff := bytes.NewBuffer([]byte{})
+		err := NewAvroLoader().Write(ctx, buff, nil)
+		require.NoError(t, err)
+		require.Equal(t, buff.Bytes()[:4], avro_file.Magic)
+		output, err := NewAvroLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 0)
+	})
+
+	t.Run("Raw datums", func(t *testing.T) {
+		// Files written before the container format are still readable
+		buff := bytes.NewBuffer([]byte{})
+		encoder := avro.NewEncoderForSchema(avroSchema, buff)
+		for _, b := range bars {
+			require.NoError(t, encoder.Encode(b))
+		}
+		output, err := NewAvroLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			requireEqualBar(t, row, bars[index])
+		}
+	})
+
+	t.Run("Schema evolution", func(t *testing.T) {
+		// An older schema without the open interest, a float volume, and a field that has since been removed
+		schema := avro.MustParse(`{
+			"type": "record",
+			"name": "standard_bar",
+			"namespace": "ta4g.ta4g",
+			"fields": [
+				{"name": "time",   "type": "long"},
+				{"name": "open",   "type": "double"},
+				{"name": "high",   "type": "double"},
+				{"name": "low",    "type": "double"},
+				{"name": "close",  "type": "double"},
+				{"name": "volume", "type": "float"},
+				{"name": "source", "type": "string"}
+			]
+		}`)
+		type oldBar struct {
+			UnixTime int64   `avro:"time"`
+			Open     float64 `avro:"open"`
+			High     float64 `avro:"high"`
+			Low      float64 `avro:"low"`
+			Close    float64 `avro:"close"`
+			Volume   float32 `avro:"volume"`
+			Source   string  `avro:"source"`
+		}
+
+		buff := bytes.NewBuffer([]byte{})
+		encoder, err := avro_file.NewEncoder(buff, schema, avro_file.DefaultOptions())
+		require.NoError(t, err)
+		require.NoError(t, encoder.Encode(oldBar{UnixTime: now.Unix(), Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 100, Source: "test"}))
+		require.NoError(t, encoder.Close())
+
+		output, err := NewAvroLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		requireEqualBar(t, output[0], New(now, 1, 2, 0.5, 1.5, 100, -1))
+	})
 }
 
 func TestProtoLoader(t *testing.T) {
@@ -157,3 +265,101 @@ func TestProtoLoader(t *testing.T) {
 		require.Equal(t, row.GetOpenInterest(), b.GetOpenInterest())
 	}
 }
+
+func TestDelimitedProtoLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := []Bar{
+		NewFakeBar(now),
+		NewFakeBar(now.Add(time_series.Day)),
+		NewFakeBar(now.Add(2 * time_series.Day)),
+	}
+	ctx := context.Background()
+	loader := NewDelimitedProtoLoader()
+
+	t.Run("Round trip", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			requireEqualBar(t, row, bars[index])
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := loader.NewWriter(ctx, buff)
+		require.NoError(t, err)
+		for _, row := range bars {
+			require.NoError(t, writer.WriteBar(row))
+		}
+		require.NoError(t, writer.Close())
+
+		// Appending another stream is still a valid stream
+		err = loader.Write(ctx, buff, bars[:1])
+		require.NoError(t, err)
+
+		reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		for index := 0; index < len(bars)+1; index++ {
+			row, err := reader.Next()
+			require.NoError(t, err)
+			requireEqualBar(t, row, bars[index%len(bars)])
+		}
+		_, err = reader.Next()
+		require.Equal(t, err, io.EOF)
+	})
+
+	t.Run("Compatible with writeDelimitedTo", func(t *testing.T) {
+		// The same framing as `writeDelimitedTo`, a varint length followed by the message
+		data := make([]byte, 0)
+		for _, row := range bars {
+			message, err := proto.Marshal(&pb.StandardBar{
+				Time:         timestamppb.New(row.GetTime()),
+				Open:         row.GetOpen(),
+				High:         row.GetHigh(),
+				Low:          row.GetLow(),
+				Close:        row.GetClose(),
+				Volume:       row.GetVolume(),
+				OpenInterest: row.GetOpenInterest(),
+			})
+			require.NoError(t, err)
+			data = protowire.AppendVarint(data, uint64(len(message)))
+			data = append(data, message...)
+		}
+
+		output, err := loader.Read(ctx, bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			requireEqualBar(t, row, bars[index])
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		output, err := loader.Read(ctx, bytes.NewReader([]byte{}))
+		require.NoError(t, err)
+		require.Empty(t, output)
+
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, bars[:1])
+		require.NoError(t, err)
+		data := buff.Bytes()
+
+		// Truncated within the message, and within the length
+		_, err = loader.Read(ctx, bytes.NewReader(data[:len(data)-1]))
+		require.Equal(t, status.Code(err), codes.DataLoss)
+		_, err = loader.Read(ctx, bytes.NewReader([]byte{0x80}))
+		require.Equal(t, status.Code(err), codes.DataLoss)
+
+		// A corrupt length is never allocated
+		huge := protowire.AppendVarint(nil, proto_stream.MaxMessageSize+1)
+		_, err = loader.Read(ctx, bytes.NewReader(huge))
+		require.Equal(t, status.Code(err), codes.OutOfRange)
+	})
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package bar
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mapFile reads the whole file into memory on platforms without mmap support
+func mapFile(file *os.File) ([]byte, func() error, error) {
+	data, err := ioutil.ReadAll(file)
+	if nil != err {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
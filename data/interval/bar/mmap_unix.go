@@ -0,0 +1,26 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package bar
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the whole file into memory read-only, the pages are loaded by the operating system as they're read
+func mapFile(file *os.File) ([]byte, func() error, error) {
+	info, err := file.Stat()
+	if nil != err {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return []byte{}, func() error { return nil }, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if nil != err {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,305 @@
+package bar
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"time"
+)
+
+//
+// Parquet Loader
+//
+// Bars are stored as a flat table of columns, with the time stored as a millisecond timestamp
+// so other tools (Spark, Pandas, etc) read it as a time rather than a plain number.
+//
+// When reading the bars are streamed one batch of rows at a time, and only the projected columns are read.
+//
+
+// Compile time type assertion
+var _ StreamLoader = &parquetLoader{}
+
+// parquetBar is the parquet schema of a single bar
+type parquetBar struct {
+	Time         int64   `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Open         float64 `parquet:"name=open, type=DOUBLE"`
+	High         float64 `parquet:"name=high, type=DOUBLE"`
+	Low          float64 `parquet:"name=low, type=DOUBLE"`
+	Close        float64 `parquet:"name=close, type=DOUBLE"`
+	Volume       float64 `parquet:"name=volume, type=DOUBLE"`
+	OpenInterest int64   `parquet:"name=open_interest, type=INT64"`
+}
+
+type parquetLoader struct {
+	options parquet_file.Options
+	columns []string
+}
+
+type parquetReader struct {
+	logger    *zap.Logger
+	reader    *reader.ParquetReader
+	columns   []string
+	paths     map[string]string
+	timeUnit  time.Duration
+	remaining int64
+	batchSize int64
+	batch     []*StandardBar
+}
+
+type parquetWriter struct {
+	logger *zap.Logger
+	writer *writer.ParquetWriter
+}
+
+// NewParquetLoader creates a Loader for parquet files.
+//
+// The columns are an optional projection, when they are given only those columns are read from the file
+// and every other value in the bar is left empty (or -1 for the open interest).
+// The time column is always read.
+//
+func NewParquetLoader(options parquet_file.Options, columns ...string) Loader {
+	return NewLoader(&parquetLoader{
+		options: options.WithDefaults(),
+		columns: columns,
+	})
+}
+
+func (p parquetLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	logger := ctxzap.Extract(ctx)
+
+	// Work out which columns to read
+	columns := []string{TimeColumn}
+	for _, column := range p.columns {
+		if !isColumn(column) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown column: %s", column)
+		}
+		if column != TimeColumn {
+			columns = append(columns, column)
+		}
+	}
+	if len(p.columns) == 0 {
+		columns = Columns
+	}
+
+	file, err := parquet_file.NewFile(input)
+	if nil != err {
+		logger.Error("Failed to open file", zap.Error(err))
+		return nil, err
+	}
+	columnReader, err := reader.NewParquetColumnReader(file, 1)
+	if nil != err {
+		logger.Error("Failed to read footer", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// Map the columns to their paths within the file, anything missing is left empty
+	schemaHandler := columnReader.SchemaHandler
+	paths := make(map[string]string, len(columns))
+	for _, column := range columns {
+		path := common.PathToStr([]string{schemaHandler.GetRootExName(), column})
+		if _, err := schemaHandler.ConvertToInPathStr(path); nil == err {
+			paths[column] = path
+		}
+	}
+	if _, ok := paths[TimeColumn]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "missing time column")
+	}
+
+	// The time is read as seconds, millis, or micros depending on the schema
+	timeUnit, err := parquetTimeUnit(columnReader, paths[TimeColumn])
+	if nil != err {
+		logger.Error("Failed to read time column", zap.Error(err))
+		return nil, err
+	}
+
+	return &parquetReader{
+		logger:    logger,
+		reader:    columnReader,
+		columns:   columns,
+		paths:     paths,
+		timeUnit:  timeUnit,
+		remaining: columnReader.GetNumRows(),
+		batchSize: int64(p.options.BatchSize),
+	}, nil
+}
+
+func (p parquetLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	logger := ctxzap.Extract(ctx)
+
+	fileWriter, err := parquet_file.NewWriter(output, new(parquetBar), p.options)
+	if nil != err {
+		logger.Error("Failed to create writer", zap.Error(err))
+		return nil, err
+	}
+	return &parquetWriter{
+		logger: logger,
+		writer: fileWriter,
+	}, nil
+}
+
+func (p *parquetReader) Next() (Bar, error) {
+	if len(p.batch) == 0 {
+		err := p.readBatch()
+		if nil != err {
+			return nil, err
+		}
+	}
+	output := p.batch[0]
+	p.batch = p.batch[1:]
+	return output, nil
+}
+
+// readBatch reads the next batch of rows, one column at a time
+func (p *parquetReader) readBatch() error {
+	if p.remaining <= 0 {
+		return io.EOF
+	}
+	size := p.batchSize
+	if size > p.remaining {
+		size = p.remaining
+	}
+
+	batch := make([]*StandardBar, size)
+	for index := range batch {
+		batch[index] = &StandardBar{OpenInterest: -1}
+	}
+
+	for _, column := range p.columns {
+		path, ok := p.paths[column]
+		if !ok {
+			continue
+		}
+		values, _, _, err := p.reader.ReadColumnByPath(path, size)
+		if nil != err {
+			p.logger.Error("Failed to read column", zap.String("column", column), zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+		if int64(len(values)) != size {
+			p.logger.Error("Failed to read column", zap.String("column", column), zap.Int("rows", len(values)))
+			return status.Error(codes.DataLoss, "short column read")
+		}
+		for index, value := range values {
+			setParquetColumn(batch[index], column, value, p.timeUnit)
+		}
+	}
+
+	p.remaining -= size
+	p.batch = batch
+	return nil
+}
+
+func (p *parquetWriter) WriteBar(bar Bar) error {
+	err := p.writer.Write(&parquetBar{
+		Time:         bar.GetTime().Unix() * 1000,
+		Open:         bar.GetOpen(),
+		High:         bar.GetHigh(),
+		Low:          bar.GetLow(),
+		Close:        bar.GetClose(),
+		Volume:       bar.GetVolume(),
+		OpenInterest: bar.GetOpenInterest(),
+	})
+	if nil != err {
+		p.logger.Error("Failed to marshal row", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	// Flush the last row group and write the footer
+	err := p.writer.WriteStop()
+	if nil != err {
+		p.logger.Error("Failed to write footer", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func parquetTimeUnit(parquetReader *reader.ParquetReader, path string) (time.Duration, error) {
+	schemaHandler := parquetReader.SchemaHandler
+	inPath, err := schemaHandler.ConvertToInPathStr(path)
+	if nil != err {
+		return 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	element := schemaHandler.SchemaElements[schemaHandler.MapIndex[inPath]]
+	if element.GetType() != parquet.Type_INT64 && element.GetType() != parquet.Type_INT32 {
+		return 0, status.Error(codes.InvalidArgument, "unsupported time column type")
+	}
+	if element.IsSetConvertedType() {
+		switch element.GetConvertedType() {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return time.Millisecond, nil
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return time.Microsecond, nil
+		}
+	}
+	if element.IsSetLogicalType() && element.GetLogicalType().IsSetTIMESTAMP() {
+		unit := element.GetLogicalType().GetTIMESTAMP().GetUnit()
+		switch {
+		case unit.IsSetMILLIS():
+			return time.Millisecond, nil
+		case unit.IsSetMICROS():
+			return time.Microsecond, nil
+		case unit.IsSetNANOS():
+			return time.Nanosecond, nil
+		}
+	}
+	return time.Second, nil
+}
+
+func setParquetColumn(bar *StandardBar, column string, value interface{}, timeUnit time.Duration) {
+	switch column {
+	case TimeColumn:
+		bar.UnixTime = parquetInt(value, 0) / int64(time.Second/timeUnit)
+	case OpenColumn:
+		bar.Open = parquetFloat(value)
+	case HighColumn:
+		bar.High = parquetFloat(value)
+	case LowColumn:
+		bar.Low = parquetFloat(value)
+	case CloseColumn:
+		bar.Close = parquetFloat(value)
+	case VolumeColumn:
+		bar.Volume = parquetFloat(value)
+	case OpenInterestColumn:
+		bar.OpenInterest = parquetInt(value, -1)
+	}
+}
+
+// parquetFloat converts any numeric column to a float, files written by other tools may not use doubles
+func parquetFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	}
+	return 0
+}
+
+// parquetInt converts any numeric column to an int, missing (null) values are returned as the fallback
+func parquetInt(value interface{}, fallback int64) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case float32:
+		return int64(v)
+	}
+	return fallback
+}
@@ -0,0 +1,99 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParquetLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := make([]Bar, 0)
+	for index := 0; index < 50; index++ {
+		bars = append(bars, NewFakeBar(now.Add(time.Duration(index)*time_series.Day)))
+	}
+
+	ctx := context.Background()
+
+	for _, compression := range []parquet_file.Compression{
+		parquet_file.Snappy,
+		parquet_file.Zstd,
+		parquet_file.Gzip,
+		parquet_file.Uncompressed,
+	} {
+		compression := compression
+		t.Run(compression.String(), func(t *testing.T) {
+			// Small batches so we have to stream across several of them
+			loader := NewParquetLoader(parquet_file.Options{Compression: compression, BatchSize: 7})
+
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, bars)
+			require.NoError(t, err)
+			require.Equal(t, buff.Bytes()[:4], []byte("PAR1"))
+
+			// A plain io.Reader is read into memory first
+			output, err := loader.Read(ctx, ioutil.NopCloser(bytes.NewReader(buff.Bytes())))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+		})
+	}
+
+	t.Run("Round trip with the CSV loader", func(t *testing.T) {
+		csvBuff := bytes.NewBuffer([]byte{})
+		err := NewCSVLoader().Write(ctx, csvBuff, bars)
+		require.NoError(t, err)
+		csvBars, err := NewCSVLoader().Read(ctx, csvBuff)
+		require.NoError(t, err)
+
+		loader := NewParquetLoader(parquet_file.DefaultOptions())
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, csvBars)
+		require.NoError(t, err)
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, output, csvBars)
+	})
+
+	t.Run("Column projection", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewParquetLoader(parquet_file.DefaultOptions()).Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		loader := NewParquetLoader(parquet_file.DefaultOptions(), CloseColumn, VolumeColumn)
+		reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		for _, b := range bars {
+			row, err := reader.Next()
+			require.NoError(t, err)
+			require.Equal(t, row.GetTime().String(), b.GetTime().String())
+			require.Equal(t, row.GetClose(), b.GetClose())
+			require.Equal(t, row.GetVolume(), b.GetVolume())
+			require.Equal(t, row.GetOpen(), 0.0)
+			require.Equal(t, row.GetHigh(), 0.0)
+			require.Equal(t, row.GetLow(), 0.0)
+			require.Equal(t, row.GetOpenInterest(), int64(-1))
+		}
+		_, err = reader.Next()
+		require.Equal(t, err, io.EOF)
+
+		_, err = NewParquetLoader(parquet_file.DefaultOptions(), "unknown").NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		_, err := NewParquetLoader(parquet_file.DefaultOptions()).Read(ctx, bytes.NewReader([]byte("not a parquet file")))
+		require.Error(t, err)
+	})
+}
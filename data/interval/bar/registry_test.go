@@ -0,0 +1,171 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/file_format"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFormats(t *testing.T) {
+	t.Run("Built-in formats", func(t *testing.T) {
+		names := make([]string, 0)
+		for _, format := range Formats() {
+			names = append(names, format.Name)
+			require.NotNil(t, format.Loader)
+		}
+		require.Equal(t, names[:9], []string{
+			CSVFormat, JsonNewLineFormat, AvroFormat, ProtoFormat, ParquetFormat, ArrowFileFormat, ArrowStreamFormat, DelimitedProtoFormat, BinaryFormat,
+		})
+
+		format, err := LookupFormat(AvroFormat)
+		require.NoError(t, err)
+		require.Equal(t, format.Extensions, []string{".avro"})
+
+		_, err = LookupFormat("xml")
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		err := RegisterFormat(Format{Format: file_format.Format{Name: "missing loader"}})
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		err = RegisterFormat(Format{Format: file_format.Format{Name: CSVFormat}, Loader: NewCSVLoader})
+		require.Equal(t, status.Code(err), codes.AlreadyExists)
+
+		// A third-party format that shares the ".pb" extension, it's already registered when the tests are repeated
+		magic := []byte("TEST")
+		err = RegisterFormat(Format{
+			Format: file_format.Format{Name: "test_format", Extensions: []string{".pb"}, Sniff: file_format.SniffPrefix(magic)},
+			Loader: func() Loader {
+				return NewLoader(&prefixLoader{prefix: magic, loader: &jsonNewLineLoader{}})
+			},
+		})
+		if status.Code(err) != codes.AlreadyExists {
+			require.NoError(t, err)
+		}
+
+		// December 1st, 2022
+		now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+		bars := []Bar{NewFakeBar(now)}
+		ctx := context.Background()
+
+		buff := bytes.NewBuffer([]byte{})
+		err = NewLoader(&prefixLoader{prefix: magic, loader: &jsonNewLineLoader{}}).Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		output, err := NewAutoLoader("AAPL.pb").Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		requireEqualBar(t, output[0], bars[0])
+
+		// Writing an ambiguous extension uses the first format
+		buff.Reset()
+		err = NewAutoLoader("AAPL.pb").Write(ctx, buff, bars)
+		require.NoError(t, err)
+		require.Equal(t, buff.Bytes()[0], byte(0x0a))
+
+		// Length-delimited bars from other languages are found by their content
+		buff.Reset()
+		err = NewDelimitedProtoLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+		output, err = NewAutoLoader("AAPL.pb").Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		requireEqualBar(t, output[0], bars[0])
+	})
+}
+
+func TestAutoLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := []Bar{
+		NewFakeBar(now),
+		NewFakeBar(now.Add(time_series.Day)),
+		NewFakeBar(now.Add(2 * time_series.Day)),
+	}
+	ctx := context.Background()
+
+	paths := []string{
+		"AAPL.csv",
+		"AAPL.ndjson",
+		"AAPL.jsonl",
+		"AAPL.avro",
+		"AAPL.pb",
+		"AAPL.pbd",
+		"AAPL.parquet",
+		"AAPL.arrow",
+		"AAPL.feather",
+		"AAPL.arrows",
+		"AAPL.csv.gz",
+		"AAPL.avro.zst",
+		"AAPL.PB.GZ",
+		"AAPL.ndjson.sz",
+		"AAPL.parquet.lz4",
+		"AAPL.pbd.zst",
+		"AAPL.bars",
+		"AAPL.bars.lz4",
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := NewAutoLoader(path).Write(ctx, buff, bars)
+			require.NoError(t, err)
+			data := buff.Bytes()
+
+			output, err := NewAutoLoader(path).Read(ctx, bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+
+			// Without a path the compression and format are detected from the content
+			output, err = NewAutoLoader("").Read(ctx, bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+			for index, row := range output {
+				requireEqualBar(t, row, bars[index])
+			}
+		})
+	}
+
+	t.Run("Unknown format", func(t *testing.T) {
+		err := NewAutoLoader("AAPL.xml").Write(ctx, bytes.NewBuffer([]byte{}), bars)
+		require.Equal(t, status.Code(err), codes.NotFound)
+
+		_, err = NewAutoLoader("AAPL.xml").Read(ctx, bytes.NewReader([]byte("<bars/>")))
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+}
+
+// prefixLoader is a test format, which is new line JSON with a magic prefix
+type prefixLoader struct {
+	prefix []byte
+	loader StreamLoader
+}
+
+func (p prefixLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	header := make([]byte, len(p.prefix))
+	_, err := io.ReadFull(input, header)
+	if nil != err {
+		return nil, err
+	}
+	return p.loader.NewReader(ctx, input)
+}
+
+func (p prefixLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	_, err := output.Write(p.prefix)
+	if nil != err {
+		return nil, err
+	}
+	return p.loader.NewWriter(ctx, output)
+}
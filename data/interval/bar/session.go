@@ -0,0 +1,52 @@
+package bar
+
+import (
+	"github.com/ta4g/ta4g/data/time/time_series"
+)
+
+// FilterSessions returns the bars that fall within any of the given sessions, in their original order.
+// Bars that are outside of trading hours are always dropped.
+//
+// Example:
+// 1. FilterSessions(bars, calendar, time_series.Regular) keeps only regular trading hours
+// 2. FilterSessions(bars, calendar, time_series.PreMarket, time_series.AfterHours) keeps only extended hours
+//
+func FilterSessions(bars []Bar, calendar *time_series.SessionCalendar, sessions ...time_series.Session) []Bar {
+	wanted := make(map[time_series.Session]bool, len(sessions))
+	for _, session := range sessions {
+		wanted[session] = true
+	}
+
+	output := make([]Bar, 0, len(bars))
+	for _, b := range bars {
+		session, ok := calendar.SessionAt(b.GetTime())
+		if ok && wanted[session] {
+			output = append(output, b)
+		}
+	}
+	return output
+}
+
+// GroupBySession splits the bars into contiguous groups, one for each session they were traded in.
+// Bars that are outside of trading hours are dropped.
+func GroupBySession(bars []Bar, calendar *time_series.SessionCalendar) [][]Bar {
+	output := make([][]Bar, 0)
+	var current []Bar
+	var currentOpen int64
+	for _, b := range bars {
+		_, open, _, ok := calendar.Bounds(b.GetTime())
+		if !ok {
+			continue
+		}
+		if nil != current && open.Unix() != currentOpen {
+			output = append(output, current)
+			current = nil
+		}
+		current = append(current, b)
+		currentOpen = open.Unix()
+	}
+	if nil != current {
+		output = append(output, current)
+	}
+	return output
+}
@@ -0,0 +1,51 @@
+package bar
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	calendar := time_series.USEquityCalendar()
+
+	// Thursday December 1st, 2022 in New York
+	thursday := time.Date(2022, 12, 1, 0, 0, 0, 0, calendar.Location())
+
+	bars := []Bar{
+		NewFakeBar(thursday.Add(5 * time.Hour)),                   // PreMarket
+		NewFakeBar(thursday.Add(9*time.Hour + 30*time.Minute)),    // Regular
+		NewFakeBar(thursday.Add(12 * time.Hour)),                  // Regular
+		NewFakeBar(thursday.Add(17 * time.Hour)),                  // AfterHours
+		NewFakeBar(thursday.Add(21 * time.Hour)),                  // Overnight
+		NewFakeBar(thursday.AddDate(0, 0, 1).Add(10 * time.Hour)), // Regular
+		NewFakeBar(thursday.AddDate(0, 0, 2).Add(10 * time.Hour)), // Saturday, closed
+	}
+
+	t.Run("FilterSessions", func(t *testing.T) {
+		output := FilterSessions(bars, calendar, time_series.Regular)
+		require.Len(t, output, 3)
+		require.Equal(t, output[0], bars[1])
+		require.Equal(t, output[1], bars[2])
+		require.Equal(t, output[2], bars[5])
+
+		output = FilterSessions(bars, calendar, time_series.PreMarket, time_series.AfterHours)
+		require.Len(t, output, 2)
+		require.Equal(t, output[0], bars[0])
+		require.Equal(t, output[1], bars[3])
+
+		output = FilterSessions(bars, calendar)
+		require.Len(t, output, 0)
+	})
+
+	t.Run("GroupBySession", func(t *testing.T) {
+		output := GroupBySession(bars, calendar)
+		require.Len(t, output, 5)
+		require.Equal(t, output[0], []Bar{bars[0]})
+		require.Equal(t, output[1], []Bar{bars[1], bars[2]})
+		require.Equal(t, output[2], []Bar{bars[3]})
+		require.Equal(t, output[3], []Bar{bars[4]})
+		require.Equal(t, output[4], []Bar{bars[5]})
+	})
+}
@@ -1,6 +1,8 @@
 package bar
 
 import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/json_float"
 	"math"
 	"math/rand"
 	"time"
@@ -9,6 +11,37 @@ import (
 // Compile time type assertion
 var _ Bar = &StandardBar{}
 
+// Column names of a StandardBar, these are shared by every format with named fields (CSV, JSON, Avro, Parquet, etc)
+const (
+	TimeColumn         = "time"
+	OpenColumn         = "open"
+	HighColumn         = "high"
+	LowColumn          = "low"
+	CloseColumn        = "close"
+	VolumeColumn       = "volume"
+	OpenInterestColumn = "open_interest"
+)
+
+// Columns are all of the column names, in the order they are written
+var Columns = []string{
+	TimeColumn,
+	OpenColumn,
+	HighColumn,
+	LowColumn,
+	CloseColumn,
+	VolumeColumn,
+	OpenInterestColumn,
+}
+
+func isColumn(name string) bool {
+	for _, column := range Columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
 type StandardBar struct {
 	UnixTime     int64   `csv:"time" avro:"time" json:"time"`
 	Open         float64 `csv:"open" avro:"open" json:"open"`
@@ -19,6 +52,17 @@ type StandardBar struct {
 	OpenInterest int64   `csv:"open_interest" avro:"open_interest" json:"open_interest"`
 }
 
+// jsonStandardBar is the JSON form of a StandardBar, see json_float.Float
+type jsonStandardBar struct {
+	UnixTime     *int64            `json:"time"`
+	Open         *json_float.Float `json:"open"`
+	High         *json_float.Float `json:"high"`
+	Low          *json_float.Float `json:"low"`
+	Close        *json_float.Float `json:"close"`
+	Volume       *json_float.Float `json:"volume"`
+	OpenInterest *int64            `json:"open_interest"`
+}
+
 func New(
 	t time.Time,
 	openValue,
@@ -106,3 +150,24 @@ func (i StandardBar) Clone() (Bar, error) {
 		OpenInterest: i.OpenInterest,
 	}, nil
 }
+
+func (i *StandardBar) jsonForm() *jsonStandardBar {
+	return &jsonStandardBar{
+		UnixTime:     &i.UnixTime,
+		Open:         (*json_float.Float)(&i.Open),
+		High:         (*json_float.Float)(&i.High),
+		Low:          (*json_float.Float)(&i.Low),
+		Close:        (*json_float.Float)(&i.Close),
+		Volume:       (*json_float.Float)(&i.Volume),
+		OpenInterest: &i.OpenInterest,
+	}
+}
+
+func (i StandardBar) MarshalJSON() ([]byte, error) {
+	value := i.jsonForm()
+	return json.Marshal(value)
+}
+
+func (i *StandardBar) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, i.jsonForm())
+}
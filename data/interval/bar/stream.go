@@ -0,0 +1,113 @@
+package bar
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"io"
+)
+
+// Reader streams bars from an input one at a time, so the whole input never has to fit in memory.
+type Reader interface {
+	// Next returns the next bar in the stream, or io.EOF once there are no more bars
+	Next() (Bar, error)
+}
+
+// Writer streams bars to an output one at a time.
+type Writer interface {
+	// WriteBar appends a single bar to the output
+	WriteBar(bar Bar) error
+
+	// Close flushes any buffered bars to the output.
+	// This does not close the underlying io.Writer, that is still owned by the caller.
+	Close() error
+}
+
+// StreamLoader opens streaming readers and writers for a single format.
+type StreamLoader interface {
+	NewReader(ctx context.Context, input io.Reader) (Reader, error)
+	NewWriter(ctx context.Context, output io.Writer) (Writer, error)
+}
+
+// Compile time type assertions
+var _ Loader = &streamLoader{}
+
+// streamLoader builds the slice based Loader on top of a StreamLoader
+type streamLoader struct {
+	StreamLoader
+}
+
+// NewLoader creates a slice based Loader from any StreamLoader
+func NewLoader(loader StreamLoader) Loader {
+	return &streamLoader{StreamLoader: loader}
+}
+
+func (s streamLoader) Read(ctx context.Context, input io.Reader) ([]Bar, error) {
+	logger := ctxzap.Extract(ctx)
+
+	reader, err := s.NewReader(ctx, input)
+	if nil != err {
+		logger.Error("Failed to open reader", zap.Error(err))
+		return nil, err
+	}
+	return ReadAll(reader)
+}
+
+func (s streamLoader) Write(ctx context.Context, output io.Writer, input []Bar) error {
+	logger := ctxzap.Extract(ctx)
+
+	writer, err := s.NewWriter(ctx, output)
+	if nil != err {
+		logger.Error("Failed to open writer", zap.Error(err))
+		return err
+	}
+	return WriteAll(writer, input)
+}
+
+// ReadAll reads every remaining bar from the stream into memory
+func ReadAll(reader Reader) ([]Bar, error) {
+	output := make([]Bar, 0)
+	for {
+		row, err := reader.Next()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return nil, err
+		}
+		output = append(output, row)
+	}
+	return output, nil
+}
+
+// WriteAll writes every bar to the stream, and then closes it
+func WriteAll(writer Writer, bars []Bar) error {
+	for _, row := range bars {
+		err := writer.WriteBar(row)
+		if nil != err {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Copy streams every bar from the reader to the writer, and then closes the writer.
+// This is useful for converting between formats without loading the whole input into memory.
+func Copy(writer Writer, reader Reader) (int, error) {
+	count := 0
+	for {
+		row, err := reader.Next()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return count, err
+		}
+		err = writer.WriteBar(row)
+		if nil != err {
+			return count, err
+		}
+		count++
+	}
+	return count, writer.Close()
+}
@@ -0,0 +1,150 @@
+package bar
+
+import (
+	"bytes"
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	pb "github.com/ta4g/ta4g/gen/interval/bar"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	bars := []Bar{
+		NewFakeBar(now),
+		NewFakeBar(now.Add(time_series.Day)),
+		NewFakeBar(now.Add(2*time_series.Day)),
+		NewFakeBar(now.Add(3*time_series.Day)),
+		NewFakeBar(now.Add(4*time_series.Day)),
+	}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":             NewCSVLoader(),
+		"JSON New Line":   NewJsonNewLineLoader(),
+		"Avro":            NewAvroLoader(),
+		"Proto":           NewProtoLoader(),
+		"Delimited Proto": NewDelimitedProtoLoader(),
+	}
+
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			writer, err := loader.NewWriter(ctx, buff)
+			require.NoError(t, err)
+			for _, b := range bars {
+				err = writer.WriteBar(b)
+				require.NoError(t, err)
+			}
+			err = writer.Close()
+			require.NoError(t, err)
+
+			reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			for _, b := range bars {
+				row, err := reader.Next()
+				require.NoError(t, err)
+				requireEqualBar(t, row, b)
+			}
+			_, err = reader.Next()
+			require.Equal(t, err, io.EOF)
+
+			// The stream and slice APIs are interchangeable
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(bars))
+
+			// An empty input has no bars
+			reader, err = loader.NewReader(ctx, bytes.NewReader(nil))
+			require.NoError(t, err)
+			_, err = reader.Next()
+			require.Equal(t, err, io.EOF)
+		})
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewCSVLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		// CSV -> Proto without loading everything into memory
+		reader, err := NewCSVLoader().NewReader(ctx, buff)
+		require.NoError(t, err)
+		protoBuff := bytes.NewBuffer([]byte{})
+		writer, err := NewProtoLoader().NewWriter(ctx, protoBuff)
+		require.NoError(t, err)
+		count, err := Copy(writer, reader)
+		require.NoError(t, err)
+		require.Equal(t, count, len(bars))
+
+		output, err := NewProtoLoader().Read(ctx, protoBuff)
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			requireEqualBar(t, row, bars[index])
+		}
+	})
+
+	t.Run("Proto is a StandardBars message", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewProtoLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+
+		// The streamed output can be read as a single message
+		messages := &pb.StandardBars{}
+		err = proto.Unmarshal(buff.Bytes(), messages)
+		require.NoError(t, err)
+		require.Len(t, messages.Bars, len(bars))
+
+		// Appending to an existing file is the same as merging the messages
+		err = NewProtoLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+		output, err := NewProtoLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 2*len(bars))
+
+		// Truncated input is an error, not a short read
+		buff = bytes.NewBuffer([]byte{})
+		err = NewProtoLoader().Write(ctx, buff, bars)
+		require.NoError(t, err)
+		_, err = NewProtoLoader().Read(ctx, bytes.NewReader(buff.Bytes()[:buff.Len()-3]))
+		require.Error(t, err)
+	})
+
+	t.Run("CSV header without rows", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewCSVLoader().Write(ctx, buff, nil)
+		require.NoError(t, err)
+		require.Equal(t, buff.String(), "time,open,high,low,close,volume,open_interest\n")
+
+		output, err := NewCSVLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 0)
+	})
+
+	t.Run("JSON New Line without a trailing new line", func(t *testing.T) {
+		input := "{\"time\":1,\"open\":1}\n\n{\"time\":2,\"open\":2}"
+		output, err := NewJsonNewLineLoader().Read(ctx, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, output, 2)
+		require.Equal(t, output[1].GetOpen(), 2.0)
+	})
+}
+
+func requireEqualBar(t *testing.T, row, b Bar) {
+	require.Equal(t, row.GetTime().String(), b.GetTime().String())
+	require.Equal(t, row.GetOpen(), b.GetOpen())
+	require.Equal(t, row.GetHigh(), b.GetHigh())
+	require.Equal(t, row.GetLow(), b.GetLow())
+	require.Equal(t, row.GetClose(), b.GetClose())
+	require.Equal(t, row.GetVolume(), b.GetVolume())
+	require.Equal(t, row.GetOpenInterest(), b.GetOpenInterest())
+}
@@ -1,5 +1,12 @@
 package constants
 
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
 // Direction indicates if we are purchasing or selling some entity
 type Direction int
 
@@ -21,3 +28,42 @@ func (o Direction) String() string {
 	str, _ := orderDirections[o]
 	return str
 }
+
+// ParseDirection finds the Direction by it's name, ex: "Buy", ignoring case.
+// Numbers are also accepted, so files written before the names were used can still be read.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseDirection(text string) (Direction, error) {
+	for value, name := range orderDirections {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown direction %q", text)
+	}
+	return Direction(value), nil
+}
+
+// MarshalText writes the name of the direction, or it's number when it doesn't have a name
+func (o Direction) MarshalText() ([]byte, error) {
+	return marshalEnum(int(o), o.String()), nil
+}
+
+// UnmarshalText reads the name or number of the direction, see ParseDirection
+func (o *Direction) UnmarshalText(text []byte) error {
+	value, err := ParseDirection(string(text))
+	if nil != err {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+// UnmarshalJSON reads the direction from either a JSON string or number
+func (o *Direction) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, o.UnmarshalText)
+}
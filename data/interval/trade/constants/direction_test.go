@@ -1,7 +1,10 @@
 package constants
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"testing"
 )
 
@@ -10,4 +13,38 @@ func TestOrderDirection(t *testing.T) {
 		require.Equal(t, Sell.String(), sellOrderDirectionStr)
 		require.Equal(t, Buy.String(), buyOrderDirectionStr)
 	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(Buy)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"Buy"`)
+
+		var output Direction
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Buy)
+
+		// Names ignore case, and numbers from older files are still accepted
+		err = json.Unmarshal([]byte(`"sell"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Sell)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Sell)
+		output, err = ParseDirection("2")
+		require.NoError(t, err)
+		require.Equal(t, output, Sell)
+
+		// Values without a name keep their number
+		data, err = json.Marshal(Direction(42))
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"42"`)
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Direction(42))
+
+		_, err = ParseDirection("unknown")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = json.Unmarshal([]byte(`true`), &output)
+		require.Error(t, err)
+	})
 }
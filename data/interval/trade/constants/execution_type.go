@@ -0,0 +1,85 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// ExecutionType is how an order should be filled by the exchange
+type ExecutionType int
+
+const (
+	_                      ExecutionType = iota
+	MarketExecution                      // MarketExecution fills immediately at the next available price
+	LimitExecution                       // LimitExecution fills at the limit price or better
+	StopExecution                        // StopExecution becomes a market order once the stop price is reached
+	StopLimitExecution                   // StopLimitExecution becomes a limit order once the stop price is reached
+	TrailingStopExecution                // TrailingStopExecution is a stop order who's stop price follows the market by an amount or percent
+	MarketOnOpenExecution                // MarketOnOpenExecution fills at the opening price
+	MarketOnCloseExecution               // MarketOnCloseExecution fills at the closing price
+)
+
+const (
+	marketExecutionStr        = "market"
+	limitExecutionStr         = "limit"
+	stopExecutionStr          = "stop"
+	stopLimitExecutionStr     = "stop_limit"
+	trailingStopExecutionStr  = "trailing_stop"
+	marketOnOpenExecutionStr  = "market_on_open"
+	marketOnCloseExecutionStr = "market_on_close"
+)
+
+var executionTypes = map[ExecutionType]string{
+	MarketExecution:        marketExecutionStr,
+	LimitExecution:         limitExecutionStr,
+	StopExecution:          stopExecutionStr,
+	StopLimitExecution:     stopLimitExecutionStr,
+	TrailingStopExecution:  trailingStopExecutionStr,
+	MarketOnOpenExecution:  marketOnOpenExecutionStr,
+	MarketOnCloseExecution: marketOnCloseExecutionStr,
+}
+
+func (e ExecutionType) String() string {
+	return executionTypes[e]
+}
+
+// ParseExecutionType finds the ExecutionType by it's name, ex: "stop_limit", ignoring case.
+// Numbers are also accepted, the same as the other enums.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseExecutionType(text string) (ExecutionType, error) {
+	for value, name := range executionTypes {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown execution type %q", text)
+	}
+	return ExecutionType(value), nil
+}
+
+// MarshalText writes the name of the execution type, or it's number when it doesn't have a name
+func (e ExecutionType) MarshalText() ([]byte, error) {
+	return marshalEnum(int(e), e.String()), nil
+}
+
+// UnmarshalText reads the name or number of the execution type, see ParseExecutionType
+func (e *ExecutionType) UnmarshalText(text []byte) error {
+	value, err := ParseExecutionType(string(text))
+	if nil != err {
+		return err
+	}
+	*e = value
+	return nil
+}
+
+// UnmarshalJSON reads the execution type from either a JSON string or number
+func (e *ExecutionType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, e.UnmarshalText)
+}
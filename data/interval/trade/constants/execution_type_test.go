@@ -0,0 +1,41 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestExecutionType(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, MarketExecution.String(), marketExecutionStr)
+		require.Equal(t, LimitExecution.String(), limitExecutionStr)
+		require.Equal(t, StopExecution.String(), stopExecutionStr)
+		require.Equal(t, StopLimitExecution.String(), stopLimitExecutionStr)
+		require.Equal(t, TrailingStopExecution.String(), trailingStopExecutionStr)
+		require.Equal(t, MarketOnOpenExecution.String(), marketOnOpenExecutionStr)
+		require.Equal(t, MarketOnCloseExecution.String(), marketOnCloseExecutionStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(StopLimitExecution)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"stop_limit"`)
+
+		var output ExecutionType
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, StopLimitExecution)
+
+		err = json.Unmarshal([]byte(`"Market_On_Close"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, MarketOnCloseExecution)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, LimitExecution)
+
+		_, err = ParseExecutionType("fill or kill")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
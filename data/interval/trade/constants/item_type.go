@@ -1,5 +1,12 @@
 package constants
 
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
 type ItemType int
 
 const (
@@ -8,6 +15,8 @@ const (
 	Stock           // Stock is the standard share of stock you can purchase on an exchange
 	Option          // Option is a type of derivative that allows you to "control" more than 1x share of stock for a limited period of time
 	Crypto          // Crypto is any cryptocurrency that is available
+	Future          // Future is an exchange traded contract to buy or sell the underlying at a set price on a future date
+	Forex           // Forex is a currency pair, buying the base currency with the quote currency
 )
 
 const (
@@ -15,6 +24,8 @@ const (
 	stockItemTypeStr  = "Stock"
 	optionItemTypeStr = "Option"
 	cryptoItemTypeStr = "Crypto"
+	futureItemTypeStr = "Future"
+	forexItemTypeStr  = "Forex"
 )
 
 var itemTypes = map[ItemType]string{
@@ -22,8 +33,49 @@ var itemTypes = map[ItemType]string{
 	Stock:  stockItemTypeStr,
 	Option: optionItemTypeStr,
 	Crypto: cryptoItemTypeStr,
+	Future: futureItemTypeStr,
+	Forex:  forexItemTypeStr,
 }
 
 func (i ItemType) String() string {
 	return itemTypes[i]
 }
+
+// ParseItemType finds the ItemType by it's name, ex: "Option", ignoring case.
+// Numbers are also accepted, so files written before the names were used can still be read.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseItemType(text string) (ItemType, error) {
+	for value, name := range itemTypes {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown item type %q", text)
+	}
+	return ItemType(value), nil
+}
+
+// MarshalText writes the name of the item type, or it's number when it doesn't have a name
+func (i ItemType) MarshalText() ([]byte, error) {
+	return marshalEnum(int(i), i.String()), nil
+}
+
+// UnmarshalText reads the name or number of the item type, see ParseItemType
+func (i *ItemType) UnmarshalText(text []byte) error {
+	value, err := ParseItemType(string(text))
+	if nil != err {
+		return err
+	}
+	*i = value
+	return nil
+}
+
+// UnmarshalJSON reads the item type from either a JSON string or number
+func (i *ItemType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, i.UnmarshalText)
+}
@@ -1,7 +1,10 @@
 package constants
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"testing"
 )
 
@@ -11,5 +14,44 @@ func TestItemType(t *testing.T) {
 		require.Equal(t, Stock.String(), stockItemTypeStr)
 		require.Equal(t, Option.String(), optionItemTypeStr)
 		require.Equal(t, Crypto.String(), cryptoItemTypeStr)
+		require.Equal(t, Future.String(), futureItemTypeStr)
+		require.Equal(t, Forex.String(), forexItemTypeStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(Option)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"Option"`)
+
+		var output ItemType
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Option)
+
+		// Names ignore case, and numbers from older files are still accepted
+		err = json.Unmarshal([]byte(`"crypto"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Crypto)
+		err = json.Unmarshal([]byte(`"FOREX"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Forex)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Stock)
+		output, err = ParseItemType("2")
+		require.NoError(t, err)
+		require.Equal(t, output, Stock)
+
+		// Values without a name keep their number
+		data, err = json.Marshal(ItemType(42))
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"42"`)
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, ItemType(42))
+
+		_, err = ParseItemType("unknown")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = json.Unmarshal([]byte(`true`), &output)
+		require.Error(t, err)
 	})
 }
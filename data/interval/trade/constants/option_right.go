@@ -0,0 +1,70 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// OptionRight is the right an option contract gives it's holder
+type OptionRight int
+
+const (
+	_    OptionRight = iota
+	Call             // Call is the right to buy the underlying at the strike price
+	Put              // Put is the right to sell the underlying at the strike price
+)
+
+const (
+	callOptionRightStr = "call"
+	putOptionRightStr  = "put"
+)
+
+var optionRights = map[OptionRight]string{
+	Call: callOptionRightStr,
+	Put:  putOptionRightStr,
+}
+
+func (o OptionRight) String() string {
+	return optionRights[o]
+}
+
+// ParseOptionRight finds the OptionRight by it's name, ex: "call", ignoring case.
+// The single letters used by OCC symbols, "C" and "P", and numbers are also accepted.
+//
+// Errors:
+// - If the text isn't a name, a letter, or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseOptionRight(text string) (OptionRight, error) {
+	for value, name := range optionRights {
+		if strings.EqualFold(name, text) || strings.EqualFold(name[:1], text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown option right %q", text)
+	}
+	return OptionRight(value), nil
+}
+
+// MarshalText writes the name of the option right, or it's number when it doesn't have a name
+func (o OptionRight) MarshalText() ([]byte, error) {
+	return marshalEnum(int(o), o.String()), nil
+}
+
+// UnmarshalText reads the name or number of the option right, see ParseOptionRight
+func (o *OptionRight) UnmarshalText(text []byte) error {
+	value, err := ParseOptionRight(string(text))
+	if nil != err {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+// UnmarshalJSON reads the option right from either a JSON string or number
+func (o *OptionRight) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, o.UnmarshalText)
+}
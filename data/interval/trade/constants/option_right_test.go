@@ -0,0 +1,35 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestOptionRight(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, Call.String(), callOptionRightStr)
+		require.Equal(t, Put.String(), putOptionRightStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(Put)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"put"`)
+
+		var output OptionRight
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Put)
+
+		for _, text := range []string{"CALL", "C", "c", "1"} {
+			output, err = ParseOptionRight(text)
+			require.NoError(t, err)
+			require.Equal(t, output, Call, text)
+		}
+
+		_, err = ParseOptionRight("straddle")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,70 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// OptionStyle is when an option contract can be exercised
+type OptionStyle int
+
+const (
+	_        OptionStyle = iota
+	American             // American options can be exercised on any day until they expire, ex: US equity options
+	European             // European options can only be exercised when they expire, ex: SPX index options
+)
+
+const (
+	americanOptionStyleStr = "american"
+	europeanOptionStyleStr = "european"
+)
+
+var optionStyles = map[OptionStyle]string{
+	American: americanOptionStyleStr,
+	European: europeanOptionStyleStr,
+}
+
+func (o OptionStyle) String() string {
+	return optionStyles[o]
+}
+
+// ParseOptionStyle finds the OptionStyle by it's name, ex: "european", ignoring case.
+// Numbers are also accepted, the same as the other enums.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseOptionStyle(text string) (OptionStyle, error) {
+	for value, name := range optionStyles {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown option style %q", text)
+	}
+	return OptionStyle(value), nil
+}
+
+// MarshalText writes the name of the option style, or it's number when it doesn't have a name
+func (o OptionStyle) MarshalText() ([]byte, error) {
+	return marshalEnum(int(o), o.String()), nil
+}
+
+// UnmarshalText reads the name or number of the option style, see ParseOptionStyle
+func (o *OptionStyle) UnmarshalText(text []byte) error {
+	value, err := ParseOptionStyle(string(text))
+	if nil != err {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+// UnmarshalJSON reads the option style from either a JSON string or number
+func (o *OptionStyle) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, o.UnmarshalText)
+}
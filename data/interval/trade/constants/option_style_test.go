@@ -0,0 +1,36 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestOptionStyle(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, American.String(), americanOptionStyleStr)
+		require.Equal(t, European.String(), europeanOptionStyleStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(European)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"european"`)
+
+		var output OptionStyle
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, European)
+
+		err = json.Unmarshal([]byte(`"American"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, American)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, European)
+
+		_, err = ParseOptionStyle("bermudan")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
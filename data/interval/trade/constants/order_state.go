@@ -0,0 +1,99 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// OrderState is where an order is within it's lifecycle, see orders.Order.Transition for the allowed changes
+type OrderState int
+
+const (
+	_                         OrderState = iota
+	NewOrderState                        // NewOrderState has been created, but not accepted by the broker yet
+	AcceptedOrderState                   // AcceptedOrderState is open and waiting to be filled
+	PartiallyFilledOrderState            // PartiallyFilledOrderState has some fills, and is waiting for the rest
+	FilledOrderState                     // FilledOrderState has been filled completely
+	CancelledOrderState                  // CancelledOrderState was cancelled before it was filled completely
+	RejectedOrderState                   // RejectedOrderState was never accepted by the broker
+	ExpiredOrderState                    // ExpiredOrderState reached the end of it's time in force before it was filled completely
+)
+
+const (
+	newOrderStateStr             = "new"
+	acceptedOrderStateStr        = "accepted"
+	partiallyFilledOrderStateStr = "partially_filled"
+	filledOrderStateStr          = "filled"
+	cancelledOrderStateStr       = "cancelled"
+	rejectedOrderStateStr        = "rejected"
+	expiredOrderStateStr         = "expired"
+)
+
+var orderStates = map[OrderState]string{
+	NewOrderState:             newOrderStateStr,
+	AcceptedOrderState:        acceptedOrderStateStr,
+	PartiallyFilledOrderState: partiallyFilledOrderStateStr,
+	FilledOrderState:          filledOrderStateStr,
+	CancelledOrderState:       cancelledOrderStateStr,
+	RejectedOrderState:        rejectedOrderStateStr,
+	ExpiredOrderState:         expiredOrderStateStr,
+}
+
+func (o OrderState) String() string {
+	return orderStates[o]
+}
+
+// IsTerminal is true once the order can't change any more, ex: it's filled or cancelled
+func (o OrderState) IsTerminal() bool {
+	switch o {
+	case FilledOrderState, CancelledOrderState, RejectedOrderState, ExpiredOrderState:
+		return true
+	}
+	return false
+}
+
+// IsOpen is true while the order can still be filled
+func (o OrderState) IsOpen() bool {
+	return o == AcceptedOrderState || o == PartiallyFilledOrderState
+}
+
+// ParseOrderState finds the OrderState by it's name, ex: "partially_filled", ignoring case.
+// Numbers are also accepted, the same as the other enums.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseOrderState(text string) (OrderState, error) {
+	for value, name := range orderStates {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown order state %q", text)
+	}
+	return OrderState(value), nil
+}
+
+// MarshalText writes the name of the order state, or it's number when it doesn't have a name
+func (o OrderState) MarshalText() ([]byte, error) {
+	return marshalEnum(int(o), o.String()), nil
+}
+
+// UnmarshalText reads the name or number of the order state, see ParseOrderState
+func (o *OrderState) UnmarshalText(text []byte) error {
+	value, err := ParseOrderState(string(text))
+	if nil != err {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+// UnmarshalJSON reads the order state from either a JSON string or number
+func (o *OrderState) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, o.UnmarshalText)
+}
@@ -0,0 +1,53 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestOrderState(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, NewOrderState.String(), newOrderStateStr)
+		require.Equal(t, AcceptedOrderState.String(), acceptedOrderStateStr)
+		require.Equal(t, PartiallyFilledOrderState.String(), partiallyFilledOrderStateStr)
+		require.Equal(t, FilledOrderState.String(), filledOrderStateStr)
+		require.Equal(t, CancelledOrderState.String(), cancelledOrderStateStr)
+		require.Equal(t, RejectedOrderState.String(), rejectedOrderStateStr)
+		require.Equal(t, ExpiredOrderState.String(), expiredOrderStateStr)
+	})
+	t.Run("Terminal", func(t *testing.T) {
+		for _, state := range []OrderState{NewOrderState, AcceptedOrderState, PartiallyFilledOrderState} {
+			require.False(t, state.IsTerminal(), state.String())
+		}
+		for _, state := range []OrderState{FilledOrderState, CancelledOrderState, RejectedOrderState, ExpiredOrderState} {
+			require.True(t, state.IsTerminal(), state.String())
+			require.False(t, state.IsOpen(), state.String())
+		}
+		require.True(t, AcceptedOrderState.IsOpen())
+		require.True(t, PartiallyFilledOrderState.IsOpen())
+		require.False(t, NewOrderState.IsOpen())
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(PartiallyFilledOrderState)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"partially_filled"`)
+
+		var output OrderState
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, PartiallyFilledOrderState)
+
+		err = json.Unmarshal([]byte(`"Cancelled"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, CancelledOrderState)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, AcceptedOrderState)
+
+		_, err = ParseOrderState("pending")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
@@ -1,5 +1,12 @@
 package constants
 
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
 type OrderType int
 
 const (
@@ -23,3 +30,42 @@ var orderTypes = map[OrderType]string{
 func (o OrderType) String() string {
 	return orderTypes[o]
 }
+
+// ParseOrderType finds the OrderType by it's name, ex: "enter", ignoring case.
+// Numbers are also accepted, so files written before the names were used can still be read.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseOrderType(text string) (OrderType, error) {
+	for value, name := range orderTypes {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown order type %q", text)
+	}
+	return OrderType(value), nil
+}
+
+// MarshalText writes the name of the order type, or it's number when it doesn't have a name
+func (o OrderType) MarshalText() ([]byte, error) {
+	return marshalEnum(int(o), o.String()), nil
+}
+
+// UnmarshalText reads the name or number of the order type, see ParseOrderType
+func (o *OrderType) UnmarshalText(text []byte) error {
+	value, err := ParseOrderType(string(text))
+	if nil != err {
+		return err
+	}
+	*o = value
+	return nil
+}
+
+// UnmarshalJSON reads the order type from either a JSON string or number
+func (o *OrderType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, o.UnmarshalText)
+}
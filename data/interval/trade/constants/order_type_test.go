@@ -1,7 +1,10 @@
 package constants
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"testing"
 )
 
@@ -11,4 +14,38 @@ func TestOrderType(t *testing.T) {
 		require.Equal(t, ExitOrderType.String(), exitOrderTypeStr)
 		require.Equal(t, AdjustmentOrderType.String(), adjustmentOrderTypeStr)
 	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(EnterOrderType)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"enter"`)
+
+		var output OrderType
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, EnterOrderType)
+
+		// Names ignore case, and numbers from older files are still accepted
+		err = json.Unmarshal([]byte(`"EXIT"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, ExitOrderType)
+		err = json.Unmarshal([]byte(`3`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, AdjustmentOrderType)
+		output, err = ParseOrderType("3")
+		require.NoError(t, err)
+		require.Equal(t, output, AdjustmentOrderType)
+
+		// Values without a name keep their number
+		data, err = json.Marshal(OrderType(42))
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"42"`)
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, OrderType(42))
+
+		_, err = ParseOrderType("unknown")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = json.Unmarshal([]byte(`true`), &output)
+		require.Error(t, err)
+	})
 }
@@ -0,0 +1,79 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// ReliefMethod is how the tax lots of a position are picked when part of it is closed
+type ReliefMethod int
+
+const (
+	_           ReliefMethod = iota
+	FIFO                     // FIFO closes the oldest lots first
+	LIFO                     // LIFO closes the newest lots first
+	HIFO                     // HIFO closes the lots with the highest price first
+	AverageCost              // AverageCost gives every lot the average price of the position, and closes the oldest lots first
+	SpecificLot              // SpecificLot closes the lots that are picked for each order
+)
+
+const (
+	fifoReliefMethodStr        = "fifo"
+	lifoReliefMethodStr        = "lifo"
+	hifoReliefMethodStr        = "hifo"
+	averageCostReliefMethodStr = "average_cost"
+	specificLotReliefMethodStr = "specific_lot"
+)
+
+var reliefMethods = map[ReliefMethod]string{
+	FIFO:        fifoReliefMethodStr,
+	LIFO:        lifoReliefMethodStr,
+	HIFO:        hifoReliefMethodStr,
+	AverageCost: averageCostReliefMethodStr,
+	SpecificLot: specificLotReliefMethodStr,
+}
+
+func (r ReliefMethod) String() string {
+	return reliefMethods[r]
+}
+
+// ParseReliefMethod finds the ReliefMethod by it's name, ex: "hifo", ignoring case.
+// Numbers are also accepted, the same as the other enums.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseReliefMethod(text string) (ReliefMethod, error) {
+	for value, name := range reliefMethods {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown relief method %q", text)
+	}
+	return ReliefMethod(value), nil
+}
+
+// MarshalText writes the name of the relief method, or it's number when it doesn't have a name
+func (r ReliefMethod) MarshalText() ([]byte, error) {
+	return marshalEnum(int(r), r.String()), nil
+}
+
+// UnmarshalText reads the name or number of the relief method, see ParseReliefMethod
+func (r *ReliefMethod) UnmarshalText(text []byte) error {
+	value, err := ParseReliefMethod(string(text))
+	if nil != err {
+		return err
+	}
+	*r = value
+	return nil
+}
+
+// UnmarshalJSON reads the relief method from either a JSON string or number
+func (r *ReliefMethod) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, r.UnmarshalText)
+}
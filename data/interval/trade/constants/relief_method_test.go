@@ -0,0 +1,39 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestReliefMethod(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, FIFO.String(), fifoReliefMethodStr)
+		require.Equal(t, LIFO.String(), lifoReliefMethodStr)
+		require.Equal(t, HIFO.String(), hifoReliefMethodStr)
+		require.Equal(t, AverageCost.String(), averageCostReliefMethodStr)
+		require.Equal(t, SpecificLot.String(), specificLotReliefMethodStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(AverageCost)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"average_cost"`)
+
+		var output ReliefMethod
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, AverageCost)
+
+		err = json.Unmarshal([]byte(`"HIFO"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, HIFO)
+		err = json.Unmarshal([]byte(`2`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, LIFO)
+
+		_, err = ParseReliefMethod("random")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,31 @@
+package constants
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// marshalEnum writes the name of an enum value, or it's number when it doesn't have a name, so nothing is lost
+func marshalEnum(value int, name string) []byte {
+	if name != "" {
+		return []byte(name)
+	}
+	return []byte(strconv.Itoa(value))
+}
+
+// unmarshalEnumJSON reads a JSON string with unmarshalText, JSON numbers are passed as their text so older files can still be read
+func unmarshalEnumJSON(data []byte, unmarshalText func(text []byte) error) error {
+	// Leave the value alone, the same as the standard library does for null numbers
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		err := json.Unmarshal(data, &text)
+		if nil != err {
+			return err
+		}
+		return unmarshalText([]byte(text))
+	}
+	return unmarshalText(data)
+}
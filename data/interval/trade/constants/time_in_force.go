@@ -0,0 +1,80 @@
+package constants
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+)
+
+// TimeInForce is how long an order stays open before it expires.
+// Orders without one stay open until they are filled or cancelled, the same as GoodTillCancelledTimeInForce.
+type TimeInForce int
+
+const (
+	_                            TimeInForce = iota
+	DayTimeInForce                           // DayTimeInForce expires at the end of the day the order was placed
+	GoodTillCancelledTimeInForce             // GoodTillCancelledTimeInForce stays open until it's filled or cancelled
+	ImmediateOrCancelTimeInForce             // ImmediateOrCancelTimeInForce fills what it can straight away, and cancels the rest
+	FillOrKillTimeInForce                    // FillOrKillTimeInForce must be filled completely straight away, or not at all
+	GoodTillDateTimeInForce                  // GoodTillDateTimeInForce stays open until it's expire time
+)
+
+const (
+	dayTimeInForceStr               = "day"
+	goodTillCancelledTimeInForceStr = "gtc"
+	immediateOrCancelTimeInForceStr = "ioc"
+	fillOrKillTimeInForceStr        = "fok"
+	goodTillDateTimeInForceStr      = "gtd"
+)
+
+var timeInForces = map[TimeInForce]string{
+	DayTimeInForce:               dayTimeInForceStr,
+	GoodTillCancelledTimeInForce: goodTillCancelledTimeInForceStr,
+	ImmediateOrCancelTimeInForce: immediateOrCancelTimeInForceStr,
+	FillOrKillTimeInForce:        fillOrKillTimeInForceStr,
+	GoodTillDateTimeInForce:      goodTillDateTimeInForceStr,
+}
+
+func (t TimeInForce) String() string {
+	return timeInForces[t]
+}
+
+// ParseTimeInForce finds the TimeInForce by it's name, ex: "gtc", ignoring case.
+// Numbers are also accepted, the same as the other enums.
+//
+// Errors:
+// - If the text isn't a name or a number an error with GRPC status InvalidArgument will be returned
+//
+func ParseTimeInForce(text string) (TimeInForce, error) {
+	for value, name := range timeInForces {
+		if strings.EqualFold(name, text) {
+			return value, nil
+		}
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if nil != err {
+		return 0, status.Errorf(codes.InvalidArgument, "unknown time in force %q", text)
+	}
+	return TimeInForce(value), nil
+}
+
+// MarshalText writes the name of the time in force, or it's number when it doesn't have a name
+func (t TimeInForce) MarshalText() ([]byte, error) {
+	return marshalEnum(int(t), t.String()), nil
+}
+
+// UnmarshalText reads the name or number of the time in force, see ParseTimeInForce
+func (t *TimeInForce) UnmarshalText(text []byte) error {
+	value, err := ParseTimeInForce(string(text))
+	if nil != err {
+		return err
+	}
+	*t = value
+	return nil
+}
+
+// UnmarshalJSON reads the time in force from either a JSON string or number
+func (t *TimeInForce) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, t.UnmarshalText)
+}
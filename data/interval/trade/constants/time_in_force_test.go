@@ -0,0 +1,39 @@
+package constants
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestTimeInForce(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, DayTimeInForce.String(), dayTimeInForceStr)
+		require.Equal(t, GoodTillCancelledTimeInForce.String(), goodTillCancelledTimeInForceStr)
+		require.Equal(t, ImmediateOrCancelTimeInForce.String(), immediateOrCancelTimeInForceStr)
+		require.Equal(t, FillOrKillTimeInForce.String(), fillOrKillTimeInForceStr)
+		require.Equal(t, GoodTillDateTimeInForce.String(), goodTillDateTimeInForceStr)
+	})
+	t.Run("Text", func(t *testing.T) {
+		data, err := json.Marshal(FillOrKillTimeInForce)
+		require.NoError(t, err)
+		require.Equal(t, string(data), `"fok"`)
+
+		var output TimeInForce
+		err = json.Unmarshal(data, &output)
+		require.NoError(t, err)
+		require.Equal(t, output, FillOrKillTimeInForce)
+
+		err = json.Unmarshal([]byte(`"GTD"`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, GoodTillDateTimeInForce)
+		err = json.Unmarshal([]byte(`1`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, DayTimeInForce)
+
+		_, err = ParseTimeInForce("week")
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
@@ -31,7 +31,7 @@ func (n *RampUpCostModel) BalanceChangeOnClose(order *orders.Order) (float64, fl
 }
 
 func (n *RampUpCostModel) increase() {
-	for _, fee := range []*Fees{n.USD, n.Stock, n.Option, n.Crypto} {
+	for _, fee := range n.fees() {
 		fee.Exchange += fee.Exchange * n.IncreasePct
 		fee.Order += fee.Order * n.IncreasePct
 		fee.Amount += fee.Amount * n.IncreasePct
@@ -39,9 +39,20 @@ func (n *RampUpCostModel) increase() {
 }
 
 func (n *RampUpCostModel) decrease() {
-	for _, fee := range []*Fees{n.USD, n.Stock, n.Option, n.Crypto} {
+	for _, fee := range n.fees() {
 		fee.Exchange -= fee.Exchange * n.IncreasePct
 		fee.Order -= fee.Order * n.IncreasePct
 		fee.Amount -= fee.Amount * n.IncreasePct
 	}
 }
+
+// fees are the fees of each item type, skipping any that aren't set
+func (n *RampUpCostModel) fees() []*Fees {
+	output := make([]*Fees, 0, 6)
+	for _, fee := range []*Fees{n.USD, n.Stock, n.Option, n.Crypto, n.Future, n.Forex} {
+		if nil != fee {
+			output = append(output, fee)
+		}
+	}
+	return output
+}
@@ -1,11 +1,27 @@
 package cost_model
 
-import "testing"
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"testing"
+	"time"
+)
 
 func TestRampUpCostModel(t *testing.T) {
 	t.Run("New", func(t *testing.T) {})
 	t.Run("BalanceChangeOnOpen", func(t *testing.T) {})
 	t.Run("BalanceChangeOnClose", func(t *testing.T) {})
-	t.Run("increase", func(t *testing.T) {})
-}
+	t.Run("increase", func(t *testing.T) {
+		// Item types without fees are skipped
+		standard := &StandardCostModel{Stock: &Fees{Exchange: 1}, Future: &Fees{Amount: 2}}
+		costModel := NewRampUpCostModel(0.5, standard)
+		order := orders.NewOrder(time.Now(), orders.NewStockOrderItem(constants.Buy, "ABC", 1, 10))
 
+		orderCost, _, err := costModel.BalanceChangeOnOpen(order)
+		require.NoError(t, err)
+		require.Equal(t, orderCost, -11.5)
+		require.Equal(t, standard.Future.Amount, 3.0)
+		require.Nil(t, standard.Forex)
+	})
+}
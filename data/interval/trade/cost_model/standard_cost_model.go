@@ -23,9 +23,12 @@ type StandardCostModel struct {
 	Stock  *Fees `csv:"stock" avro:"stock" json:"stock"`
 	Option *Fees `csv:"option" avro:"option" json:"option"`
 	Crypto *Fees `csv:"crypto" avro:"crypto" json:"crypto"`
+	Future *Fees `csv:"future" avro:"future" json:"future"`
+	Forex  *Fees `csv:"forex" avro:"forex" json:"forex"`
 }
 
-// NewStandardCostModel creates a new CostModel instance using the given fees
+// NewStandardCostModel creates a new CostModel instance using the given fees.
+// There aren't any fees for futures or forex, so ordering them fails, see NewStandardCostModelWithFuturesAndForex.
 func NewStandardCostModel(usd, stock, option, crypto *Fees) CostModel {
 	return &StandardCostModel{
 		USD:    usd,
@@ -35,10 +38,22 @@ func NewStandardCostModel(usd, stock, option, crypto *Fees) CostModel {
 	}
 }
 
+// NewStandardCostModelWithFuturesAndForex creates a new CostModel instance using the given fees, including futures and forex
+func NewStandardCostModelWithFuturesAndForex(usd, stock, option, crypto, future, forex *Fees) CostModel {
+	return &StandardCostModel{
+		USD:    usd,
+		Stock:  stock,
+		Option: option,
+		Crypto: crypto,
+		Future: future,
+		Forex:  forex,
+	}
+}
+
 // DefaultStandardCostModel is the pre-canned cost model using the fees currently posted
 // to TD Ameritrade and Coinbase.
 func DefaultStandardCostModel() CostModel {
-	return NewStandardCostModel(
+	return NewStandardCostModelWithFuturesAndForex(
 		// USD, free to hold and exchange
 		&Fees{},
 		// Stocks are free to buy and sell, but there is an exchange fee
@@ -47,6 +62,10 @@ func DefaultStandardCostModel() CostModel {
 		&Fees{Exchange: 0.75, Amount: 0.65},
 		// Crypto is a flat-fee (estimation for simplicity)
 		&Fees{Order: 0.99},
+		// Futures have a per-contract fee
+		&Fees{Amount: 2.25},
+		// Forex is paid for within the spread, so there aren't any fees
+		&Fees{},
 	)
 }
 
@@ -65,9 +84,16 @@ func (s StandardCostModel) BalanceChangeOnOpen(order *orders.Order) (float64, fl
 			fee = s.Option
 		case constants.Crypto:
 			fee = s.Crypto
+		case constants.Future:
+			fee = s.Future
+		case constants.Forex:
+			fee = s.Forex
 		default:
 			return 0, 0, status.Error(codes.OutOfRange, "unknown fee type")
 		}
+		if nil == fee {
+			return 0, 0, status.Errorf(codes.FailedPrecondition, "no fees for %s items", item.ItemType)
+		}
 
 		orderCost += item.CalculatePrice(fee.Exchange, fee.Order, fee.Amount)
 		marginRequirement += item.MarginRequirement()
@@ -4,6 +4,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ta4g/ta4g/data/interval/trade/constants"
 	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"testing"
 	"time"
 )
@@ -79,4 +81,61 @@ func TestStandardCostModel(t *testing.T) {
 		require.Equal(t, orderCost, -899.35)
 		require.Equal(t, marginRequirement, 900.0)
 	})
+
+	t.Run("BuyFutureOrder", func(t *testing.T) {
+		// E-mini S&P 500, $12.50 per 0.25 point tick, and $12,000 initial margin per contract.
+		// Only the $2.25 per contract fee is paid, the margin is held instead of paying for the contracts.
+		contract := orders.NewFutureContract("ES", 0.25, 12.5, 12000, time.Now().AddDate(0, 1, 0))
+		order := orders.NewOrder(time.Now(), orders.NewFutureOrderItem(constants.Buy, "ESZ22", contract, 2, 4000))
+
+		orderCost, marginRequirement, err := costModel.BalanceChangeOnOpen(order)
+		require.NoError(t, err)
+		require.Equal(t, orderCost, -4.5)
+		require.Equal(t, marginRequirement, 24000.0)
+
+		orderCost, marginRequirement, err = costModel.BalanceChangeOnClose(order)
+		require.NoError(t, err)
+		require.Equal(t, orderCost, 4.5)
+		require.Equal(t, marginRequirement, -24000.0)
+	})
+
+	t.Run("SellForexOrder", func(t *testing.T) {
+		// 50:1 leverage
+		pair := orders.NewForexPair("EUR", "USD")
+		pair.MarginRate = 0.02
+		order := orders.NewOrder(time.Now(), orders.NewForexOrderItem(constants.Sell, pair, 1, 1.05))
+
+		orderCost, marginRequirement, err := costModel.BalanceChangeOnOpen(order)
+		require.NoError(t, err)
+		require.InDelta(t, orderCost, 105000, 1e-6)
+		require.InDelta(t, marginRequirement, -2100, 1e-6)
+	})
+
+	t.Run("Missing fees", func(t *testing.T) {
+		costModel := NewStandardCostModel(&Fees{}, &Fees{}, nil, nil)
+		_, _, err := costModel.BalanceChangeOnOpen(buyStockOrder)
+		require.NoError(t, err)
+		_, _, err = costModel.BalanceChangeOnOpen(buyCoveredCallOrder)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+		// Futures and forex don't have fees unless they're given
+		contract := orders.NewFutureContract("ES", 0.25, 12.5, 12000, time.Now().AddDate(0, 1, 0))
+		future := orders.NewOrder(time.Now(), orders.NewFutureOrderItem(constants.Buy, "ESZ22", contract, 1, 4000))
+		forex := orders.NewOrder(time.Now(), orders.NewForexOrderItem(constants.Buy, orders.NewForexPair("EUR", "USD"), 1, 1.05))
+		_, _, err = costModel.BalanceChangeOnOpen(future)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+		_, _, err = costModel.BalanceChangeOnOpen(forex)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+		costModel = NewStandardCostModelWithFuturesAndForex(&Fees{}, &Fees{}, nil, nil, &Fees{Amount: 1}, &Fees{})
+		orderCost, _, err := costModel.BalanceChangeOnOpen(future)
+		require.NoError(t, err)
+		require.Equal(t, orderCost, -1.0)
+		_, _, err = costModel.BalanceChangeOnOpen(forex)
+		require.NoError(t, err)
+
+		order := orders.NewOrder(time.Now(), &orders.OrderItem{ItemType: constants.ItemType(100)})
+		_, _, err = costModel.BalanceChangeOnOpen(order)
+		require.Equal(t, status.Code(err), codes.OutOfRange)
+	})
 }
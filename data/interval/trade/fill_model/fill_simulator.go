@@ -0,0 +1,194 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+)
+
+// FillSimulator decides how much of an order is filled during a bar, and at what price.
+//
+// Each call to Fill is the next bar after the order was placed, until the order is closed:
+// 1. The execution decides if the order fills, and at what price, see orders.Execution.Fill.
+//    Stops that gap through their stop price fill at the open, not the stop price.
+// 2. Limit orders fill when the bar touches the limit price, or only when it trades through it with TradeThrough.
+//    Bars don't show the queue at the limit price, so trading through it is the conservative choice.
+// 3. The slippage model moves the price against us, but never past the limit price of a limit order
+// 4. Each item is capped at MaxParticipation of the bar's volume, and the rest waits for the next bar.
+//    Option and future bars count their volume in contracts, and every other bar counts it in units, ex: shares.
+//    Stocks, options, and futures are filled in whole units, cash, crypto, and forex may be filled in fractions.
+// 5. The fills are added to the order, see orders.Order.AddFills.
+//    Immediate or cancel orders are cancelled after their first bar, and fill or kill orders are cancelled
+//    when the bar can't fill them completely.
+//
+// Bars are for a single symbol, so orders with more than one item must be market orders, which fill each item against the bar.
+//
+type FillSimulator struct {
+	// Slippage moves the price against us, nil has no slippage
+	Slippage SlippageModel `csv:"-" avro:"-" json:"-"`
+	// MaxParticipation is the largest share of the bar's volume a single item can fill, ex: 0.1 is 10%, 0 has no limit
+	MaxParticipation float64 `csv:"max_participation" avro:"max_participation" json:"max_participation"`
+	// TradeThrough only fills limit orders when the bar trades through the limit price, instead of just touching it
+	TradeThrough bool `csv:"trade_through" avro:"trade_through" json:"trade_through"`
+	// Calendar decides when day orders expire, nil is the time_series.USEquityCalendar
+	Calendar *time_series.SessionCalendar `csv:"-" avro:"-" json:"-"`
+}
+
+// NewFillSimulator creates a FillSimulator with the slippage model and volume participation limit
+func NewFillSimulator(slippage SlippageModel, maxParticipation float64, tradeThrough bool) *FillSimulator {
+	return &FillSimulator{
+		Slippage:         slippage,
+		MaxParticipation: maxParticipation,
+		TradeThrough:     tradeThrough,
+	}
+}
+
+// Fill simulates the order during the bar, and returns the fills that were added to it.
+// Orders that have reached the end of their time in force are expired instead of filled, see orders.Order.IsExpired.
+//
+// Errors:
+// - If the order has more than one item, and it's execution isn't a market order an error with GRPC status InvalidArgument will be returned
+// - If the order isn't open an error with GRPC status FailedPrecondition will be returned
+//
+func (f *FillSimulator) Fill(order *orders.Order, b bar.Bar) ([]*orders.Fill, error) {
+	if !order.CurrentState().IsOpen() {
+		return nil, status.Errorf(codes.FailedPrecondition, "can't fill a %s order", order.CurrentState())
+	}
+	if len(order.OrderItems) > 1 && !isMarket(order.Execution) {
+		return nil, status.Error(codes.InvalidArgument, "orders with more than one item must be market orders")
+	}
+	t := b.GetTime()
+	if order.IsExpired(t, f.Calendar) {
+		return nil, order.Expire(t)
+	}
+
+	fills := make([]*orders.Fill, 0, len(order.OrderItems))
+	complete := true
+	for index, item := range order.OrderItems {
+		remaining := order.RemainingAmount(index)
+		if remaining <= 0 {
+			continue
+		}
+		price, filled := f.price(order.Execution, item.Direction, b)
+		if !filled {
+			complete = false
+			continue
+		}
+
+		amount := math.Min(remaining, f.maxAmount(item, b))
+		if amount < remaining {
+			complete = false
+		}
+		if amount <= 0 {
+			continue
+		}
+		fills = append(fills, &orders.Fill{
+			UnixTime: t.Unix(),
+			Item:     index,
+			Amount:   amount,
+			Price:    f.slip(order.Execution, item.Direction, price, volumeQuantity(item, amount), b),
+		})
+	}
+
+	if len(fills) == 0 || (!complete && order.TimeInForce == constants.FillOrKillTimeInForce) {
+		switch order.TimeInForce {
+		case constants.ImmediateOrCancelTimeInForce:
+			return nil, order.Cancel(t, "immediate or cancel")
+		case constants.FillOrKillTimeInForce:
+			return nil, order.Cancel(t, "fill or kill")
+		}
+		return nil, nil
+	}
+
+	err := order.AddFills(t, fills...)
+	if nil != err {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// price is the price the execution fills at during the bar, before slippage
+func (f *FillSimulator) price(execution *orders.Execution, direction constants.Direction, b bar.Bar) (float64, bool) {
+	price, filled := execution.Fill(direction, b)
+	if !filled || !f.TradeThrough || nil == execution || !hasLimit(execution) {
+		return price, filled
+	}
+
+	// Filled at the limit price when the bar only touched it
+	if price == execution.LimitPrice && b.GetOpen() != execution.LimitPrice {
+		if direction == constants.Buy && b.GetLow() >= execution.LimitPrice {
+			return 0, false
+		}
+		if direction == constants.Sell && b.GetHigh() <= execution.LimitPrice {
+			return 0, false
+		}
+	}
+	return price, true
+}
+
+// maxAmount is the largest amount of the item that can be filled during the bar
+func (f *FillSimulator) maxAmount(item *orders.OrderItem, b bar.Bar) float64 {
+	if f.MaxParticipation <= 0 {
+		return math.Inf(1)
+	}
+	volume := b.GetVolume()
+	if !(volume > 0) {
+		return 0
+	}
+
+	amount := f.MaxParticipation * volume / volumeQuantity(item, 1)
+	switch item.ItemType {
+	case constants.Stock, constants.Option, constants.Future:
+		return math.Floor(amount)
+	}
+	return amount
+}
+
+// slip applies the slippage model, limit orders are never filled past their limit price
+func (f *FillSimulator) slip(execution *orders.Execution, direction constants.Direction, price, quantity float64, b bar.Bar) float64 {
+	if nil == f.Slippage {
+		return price
+	}
+	output := f.Slippage.Apply(direction, price, quantity, b)
+	if nil == execution || !hasLimit(execution) {
+		return output
+	}
+	if direction == constants.Buy {
+		return math.Min(output, math.Max(price, execution.LimitPrice))
+	}
+	return math.Max(output, math.Min(price, execution.LimitPrice))
+}
+
+// volumeQuantity is the amount of the item in the same unit as the bar's volume.
+// Option and future volume is the number of contracts, which is the amount, every other volume is the number of units.
+func volumeQuantity(item *orders.OrderItem, amount float64) float64 {
+	switch item.ItemType {
+	case constants.Option, constants.Future:
+		return amount
+	}
+	if item.QuantityPerAmount <= 0 {
+		return amount
+	}
+	return amount * item.QuantityPerAmount
+}
+
+// isMarket checks if the execution fills at the market price, without any price conditions
+func isMarket(execution *orders.Execution) bool {
+	if nil == execution {
+		return true
+	}
+	switch execution.Type {
+	case constants.MarketExecution, constants.MarketOnOpenExecution, constants.MarketOnCloseExecution:
+		return true
+	}
+	return false
+}
+
+// hasLimit checks if the execution has a limit price
+func hasLimit(execution *orders.Execution) bool {
+	return execution.Type == constants.LimitExecution || execution.Type == constants.StopLimitExecution
+}
@@ -0,0 +1,217 @@
+package fill_model
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestFillSimulator(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	// Opens at 10, trades between 9 and 12, and closes at 11
+	b := bar.New(now, 10, 12, 9, 11, 1000, -1)
+
+	newOrder := func(execution *orders.Execution, items ...*orders.OrderItem) *orders.Order {
+		if len(items) == 0 {
+			items = append(items, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01))
+		}
+		order := orders.NewOrder(now, items...)
+		order.Execution = execution
+		require.NoError(t, order.Accept(now))
+		return order
+	}
+
+	t.Run("Market", func(t *testing.T) {
+		simulator := NewFillSimulator(NewFixedSlippageModel(0.05), 0, false)
+		order := newOrder(nil)
+		fills, err := simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Equal(t, fills, []*orders.Fill{{UnixTime: now.Unix(), Item: 0, Amount: 100, Price: 10.05}})
+		require.Equal(t, order.State, constants.FilledOrderState)
+		require.Equal(t, order.Fills, fills)
+
+		// Filled orders are closed
+		_, err = simulator.Fill(order, b)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+	})
+
+	t.Run("Market on close with more than one item", func(t *testing.T) {
+		simulator := NewFillSimulator(NewFixedSlippageModel(0.05), 0, false)
+		order := newOrder(
+			orders.NewMarketOnCloseExecution(),
+			orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+			orders.NewStockOrderItem(constants.Sell, "ABC", 50, 10.01),
+		)
+		fills, err := simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Len(t, fills, 2)
+		require.Equal(t, fills[0].Price, 11.05)
+		require.Equal(t, fills[1].Price, 10.95)
+	})
+
+	t.Run("Limit orders", func(t *testing.T) {
+		touch := NewFillSimulator(nil, 0, false)
+		through := NewFillSimulator(nil, 0, true)
+
+		// The low of the bar is exactly the limit price
+		touched := bar.New(now, 10, 12, 9.5, 11, 1000, -1)
+		fills, err := touch.Fill(newOrder(orders.NewLimitExecution(9.5)), touched)
+		require.NoError(t, err)
+		require.Len(t, fills, 1)
+		require.Equal(t, fills[0].Price, 9.5)
+
+		order := newOrder(orders.NewLimitExecution(9.5))
+		fills, err = through.Fill(order, touched)
+		require.NoError(t, err)
+		require.Empty(t, fills)
+		require.Equal(t, order.State, constants.AcceptedOrderState)
+
+		// Trading through the limit fills at the limit price
+		fills, err = through.Fill(order, b)
+		require.NoError(t, err)
+		require.Len(t, fills, 1)
+		require.Equal(t, fills[0].Price, 9.5)
+
+		// Gapping through the limit fills at the open
+		fills, err = through.Fill(newOrder(orders.NewLimitExecution(10.5)), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 10.0)
+	})
+
+	t.Run("Slippage never passes the limit", func(t *testing.T) {
+		simulator := NewFillSimulator(NewFixedSlippageModel(1), 0, false)
+		fills, err := simulator.Fill(newOrder(orders.NewLimitExecution(9.5)), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 9.5)
+
+		// Gapping through the limit leaves room for some slippage
+		fills, err = simulator.Fill(newOrder(orders.NewLimitExecution(10.5)), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 10.5)
+
+		fills, err = simulator.Fill(newOrder(orders.NewStopExecution(11)), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 12.0)
+	})
+
+	t.Run("Stops gap through", func(t *testing.T) {
+		simulator := NewFillSimulator(nil, 0, false)
+		sell := orders.NewStockOrderItem(constants.Sell, "ABC", 100, 10.01)
+		fills, err := simulator.Fill(newOrder(orders.NewStopExecution(10.5), sell), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 10.0)
+		fills, err = simulator.Fill(newOrder(orders.NewStopExecution(9.5), sell), b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Price, 9.5)
+	})
+
+	t.Run("Volume participation", func(t *testing.T) {
+		simulator := NewFillSimulator(nil, 0.05, false)
+		order := newOrder(nil)
+
+		// 5% of 1000 shares is 50 shares per bar
+		fills, err := simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 50.0)
+		require.Equal(t, order.State, constants.PartiallyFilledOrderState)
+
+		fills, err = simulator.Fill(order, bar.New(now.Add(time.Hour), 10, 12, 9, 11, 1000, -1))
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 50.0)
+		require.Equal(t, order.State, constants.FilledOrderState)
+		require.Len(t, order.Fills, 2)
+
+		// Option volume is in contracts, so 5% of 30 contracts is 1.5, which fills a whole contract
+		option := newOrder(nil, orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 10, 1.01))
+		fills, err = simulator.Fill(option, bar.New(now, 1, 1.2, 0.9, 1.1, 30, -1))
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 1.0)
+
+		// Future volume is in contracts too, 5% of 3500 contracts is 175 contracts
+		contract := orders.NewFutureContract("ES", 0.25, 12.5, 12000, now.AddDate(0, 1, 0))
+		future := newOrder(nil, orders.NewFutureOrderItem(constants.Buy, "ESZ22", contract, 200, 4000))
+		fills, err = simulator.Fill(future, bar.New(now, 4000, 4010, 3990, 4005, 3500, -1))
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 175.0)
+		fills, err = simulator.Fill(future, bar.New(now, 4000, 4010, 3990, 4005, 250, -1))
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 12.0)
+
+		// Bars without any volume can't fill
+		fills, err = simulator.Fill(newOrder(nil), bar.New(now, 10, 12, 9, 11, 0, -1))
+		require.NoError(t, err)
+		require.Empty(t, fills)
+
+		// Crypto can be filled in fractions
+		crypto := newOrder(nil, orders.NewCryptoOrderItem(constants.Buy, "BTC", 1, 17000))
+		fills, err = simulator.Fill(crypto, bar.New(now, 17000, 17100, 16900, 17050, 2.5, -1))
+		require.NoError(t, err)
+		require.Equal(t, fills[0].Amount, 0.125)
+	})
+
+	t.Run("Volume relative slippage", func(t *testing.T) {
+		simulator := NewFillSimulator(NewSquareRootImpactSlippageModel(1, 0.1), 0, false)
+
+		// 4 contracts of 100 contracts is 4% of the volume, so the impact is 10% * sqrt(0.04) = 2% of the price
+		option := newOrder(nil, orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 4, 1.01))
+		fills, err := simulator.Fill(option, bar.New(now, 1, 1.2, 0.9, 1.1, 100, -1))
+		require.NoError(t, err)
+		require.InDelta(t, fills[0].Price, 1.02, 1e-9)
+	})
+
+	t.Run("Time in force", func(t *testing.T) {
+		simulator := NewFillSimulator(nil, 0.05, false)
+
+		order := orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01))
+		order.TimeInForce = constants.ImmediateOrCancelTimeInForce
+		require.NoError(t, order.Accept(now))
+		fills, err := simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Len(t, fills, 1)
+		require.Equal(t, order.State, constants.CancelledOrderState)
+
+		order = orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01))
+		order.TimeInForce = constants.FillOrKillTimeInForce
+		require.NoError(t, order.Accept(now))
+		fills, err = simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Empty(t, fills)
+		require.Equal(t, order.State, constants.CancelledOrderState)
+		require.Empty(t, order.Fills)
+
+		order = orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01))
+		order.TimeInForce = constants.DayTimeInForce
+		order.Execution = orders.NewLimitExecution(5)
+		require.NoError(t, order.Accept(now))
+		fills, err = simulator.Fill(order, b)
+		require.NoError(t, err)
+		require.Empty(t, fills)
+		require.Equal(t, order.State, constants.AcceptedOrderState)
+		fills, err = simulator.Fill(order, bar.New(now.Add(time_series.Day), 10, 12, 9, 11, 1000, -1))
+		require.NoError(t, err)
+		require.Empty(t, fills)
+		require.Equal(t, order.State, constants.ExpiredOrderState)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		simulator := NewFillSimulator(nil, 0, false)
+		order := newOrder(
+			orders.NewLimitExecution(10),
+			orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+			orders.NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01),
+		)
+		_, err := simulator.Fill(order, b)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		_, err = simulator.Fill(orders.NewOrder(now), b)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+	})
+}
@@ -0,0 +1,19 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+)
+
+// FixedSlippageModel moves the price by a fixed amount per item, ex: $0.01 is half of a typical spread
+type FixedSlippageModel struct {
+	Amount float64 `csv:"amount" avro:"amount" json:"amount"`
+}
+
+func NewFixedSlippageModel(amount float64) SlippageModel {
+	return &FixedSlippageModel{Amount: amount}
+}
+
+func (f FixedSlippageModel) Apply(direction constants.Direction, price, _ float64, _ bar.Bar) float64 {
+	return slip(direction, price, f.Amount)
+}
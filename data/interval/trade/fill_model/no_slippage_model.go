@@ -0,0 +1,18 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+)
+
+// NoSlippageModel fills at the exact price, this is useful for a theoretical back test
+// and should only be used as a benchmark for testing
+type NoSlippageModel struct{}
+
+func NewNoSlippageModel() SlippageModel {
+	return &NoSlippageModel{}
+}
+
+func (n NoSlippageModel) Apply(_ constants.Direction, price, _ float64, _ bar.Bar) float64 {
+	return price
+}
@@ -0,0 +1,18 @@
+package fill_model
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"testing"
+	"time"
+)
+
+func TestNoSlippageModel(t *testing.T) {
+	// December 1st, 2022
+	b := bar.New(time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC), 10, 12, 9, 11, 1000, -1)
+
+	model := NewNoSlippageModel()
+	require.Equal(t, model.Apply(constants.Buy, 10, 100, b), 10.0)
+	require.Equal(t, model.Apply(constants.Sell, 10, 100, b), 10.0)
+}
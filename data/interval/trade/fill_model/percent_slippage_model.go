@@ -0,0 +1,19 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+)
+
+// PercentSlippageModel moves the price by a percent of the price, ex: 0.05 is 0.05%
+type PercentSlippageModel struct {
+	Percent float64 `csv:"percent" avro:"percent" json:"percent"`
+}
+
+func NewPercentSlippageModel(percent float64) SlippageModel {
+	return &PercentSlippageModel{Percent: percent}
+}
+
+func (p PercentSlippageModel) Apply(direction constants.Direction, price, _ float64, _ bar.Bar) float64 {
+	return slip(direction, price, price*p.Percent/100)
+}
@@ -0,0 +1,18 @@
+package fill_model
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"testing"
+	"time"
+)
+
+func TestPercentSlippageModel(t *testing.T) {
+	// December 1st, 2022
+	b := bar.New(time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC), 10, 12, 9, 11, 1000, -1)
+
+	model := NewPercentSlippageModel(5)
+	require.InDelta(t, model.Apply(constants.Buy, 10, 100, b), 10.5, 1e-9)
+	require.InDelta(t, model.Apply(constants.Sell, 10, 100, b), 9.5, 1e-9)
+}
@@ -0,0 +1,37 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+)
+
+// SlippageModel moves the price of a fill against us, to account for the spread and our own impact on the market.
+//
+// There are multiple types of slippage model listed out in the type assertions below:
+// 1. NoSlippageModel - fills are at the exact price, this is only useful as a benchmark
+// 2. FixedSlippageModel - a fixed amount per item, ex: half of a typical $0.02 spread
+// 3. PercentSlippageModel - a percent of the price, ex: 0.05%
+// 4. VolatilitySlippageModel - a multiple of the bar's range, so it's worse when the market is moving quickly
+// 5. SquareRootImpactSlippageModel - grows with the square root of the quantity as a share of the bar's volume,
+//    so large orders move the market more than small ones
+//
+type SlippageModel interface {
+	// Apply returns the price after slippage, buying is more expensive and selling is cheaper.
+	// The quantity is in the same unit as the bar's volume, ex: 100 shares, or 1 contract for options and futures.
+	Apply(direction constants.Direction, price, quantity float64, b bar.Bar) float64
+}
+
+// Compile type type enforcement
+var _ SlippageModel = &NoSlippageModel{}
+var _ SlippageModel = &FixedSlippageModel{}
+var _ SlippageModel = &PercentSlippageModel{}
+var _ SlippageModel = &VolatilitySlippageModel{}
+var _ SlippageModel = &SquareRootImpactSlippageModel{}
+
+// slip moves the price against the direction by the amount
+func slip(direction constants.Direction, price, amount float64) float64 {
+	if direction == constants.Sell {
+		return price - amount
+	}
+	return price + amount
+}
@@ -0,0 +1,37 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"math"
+)
+
+// SquareRootImpactSlippageModel is the square root market impact model:
+//
+//   slippage = price * Coefficient * volatility * sqrt(quantity / volume)
+//
+// 1. The Coefficient is usually close to 1, ex: 0.5 to 1.5 depending on the market
+// 2. The Volatility is the volatility of returns over the bar, ex: 0.02 is 2%.
+//    When it's 0 the bar's range as a percent of it's open is used instead.
+// 3. Bars without any volume have no impact, since there's nothing to compare the quantity to
+//
+type SquareRootImpactSlippageModel struct {
+	Coefficient float64 `csv:"coefficient" avro:"coefficient" json:"coefficient"`
+	Volatility  float64 `csv:"volatility" avro:"volatility" json:"volatility"`
+}
+
+func NewSquareRootImpactSlippageModel(coefficient, volatility float64) SlippageModel {
+	return &SquareRootImpactSlippageModel{Coefficient: coefficient, Volatility: volatility}
+}
+
+func (s SquareRootImpactSlippageModel) Apply(direction constants.Direction, price, quantity float64, b bar.Bar) float64 {
+	volume := b.GetVolume()
+	if !(volume > 0) || !(quantity > 0) {
+		return price
+	}
+	volatility := s.Volatility
+	if volatility == 0 && b.GetOpen() != 0 {
+		volatility = (b.GetHigh() - b.GetLow()) / b.GetOpen()
+	}
+	return slip(direction, price, price*s.Coefficient*volatility*math.Sqrt(quantity/volume))
+}
@@ -0,0 +1,36 @@
+package fill_model
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"testing"
+	"time"
+)
+
+func TestSquareRootImpactSlippageModel(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	b := bar.New(now, 10, 12, 9, 11, 10000, -1)
+
+	t.Run("Fixed volatility", func(t *testing.T) {
+		model := NewSquareRootImpactSlippageModel(1, 0.02)
+		// 1% of the volume is 10% of the volatility
+		require.InDelta(t, model.Apply(constants.Buy, 10, 100, b), 10.02, 1e-9)
+		require.InDelta(t, model.Apply(constants.Sell, 10, 100, b), 9.98, 1e-9)
+		// 4x the quantity is 2x the impact
+		require.InDelta(t, model.Apply(constants.Buy, 10, 400, b), 10.04, 1e-9)
+	})
+
+	t.Run("Bar volatility", func(t *testing.T) {
+		model := NewSquareRootImpactSlippageModel(0.5, 0)
+		// The range is 30% of the open
+		require.InDelta(t, model.Apply(constants.Buy, 10, 100, b), 10+10*0.5*0.3*0.1, 1e-9)
+	})
+
+	t.Run("No volume", func(t *testing.T) {
+		model := NewSquareRootImpactSlippageModel(1, 0.02)
+		require.Equal(t, model.Apply(constants.Buy, 10, 100, bar.New(now, 10, 12, 9, 11, 0, -1)), 10.0)
+		require.Equal(t, model.Apply(constants.Buy, 10, 0, b), 10.0)
+	})
+}
@@ -0,0 +1,20 @@
+package fill_model
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+)
+
+// VolatilitySlippageModel moves the price by a multiple of the bar's range (high - low),
+// so fills are worse when the market is moving quickly, ex: 0.1 is 10% of the range
+type VolatilitySlippageModel struct {
+	Multiplier float64 `csv:"multiplier" avro:"multiplier" json:"multiplier"`
+}
+
+func NewVolatilitySlippageModel(multiplier float64) SlippageModel {
+	return &VolatilitySlippageModel{Multiplier: multiplier}
+}
+
+func (v VolatilitySlippageModel) Apply(direction constants.Direction, price, _ float64, b bar.Bar) float64 {
+	return slip(direction, price, v.Multiplier*(b.GetHigh()-b.GetLow()))
+}
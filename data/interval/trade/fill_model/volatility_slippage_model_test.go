@@ -0,0 +1,25 @@
+package fill_model
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"testing"
+	"time"
+)
+
+func TestVolatilitySlippageModel(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	model := NewVolatilitySlippageModel(0.1)
+
+	// A range of 3
+	b := bar.New(now, 10, 12, 9, 11, 1000, -1)
+	require.InDelta(t, model.Apply(constants.Buy, 10, 100, b), 10.3, 1e-9)
+	require.InDelta(t, model.Apply(constants.Sell, 10, 100, b), 9.7, 1e-9)
+
+	// A quiet bar has less slippage
+	b = bar.New(now, 10, 10.5, 10, 10.25, 1000, -1)
+	require.InDelta(t, model.Apply(constants.Buy, 10, 100, b), 10.05, 1e-9)
+}
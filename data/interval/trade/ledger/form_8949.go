@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+//
+// Form 8949 Export
+//
+// The closed lots are written as a CSV with the columns of IRS Form 8949, Sales and Other Dispositions of Capital Assets:
+//
+//   description,date_acquired,date_sold,proceeds,cost_basis,code,adjustment,gain_or_loss,term
+//   100 ABC,01/03/2022,03/01/2022,800.00,1000.00,W,200.00,0.00,short
+//   100 ABC,01/17/2022,06/01/2022,1200.00,1100.00,,,100.00,short
+//   100 XYZ,01/04/2021,12/01/2022,2000.00,1000.00,,,1000.00,long
+//
+// 1. The short term sales (Part I) are written first, then the long term sales (Part II), each in the order they were closed
+// 2. The description is the number of units and the symbol
+// 3. The date acquired is when the holding period started, which is earlier than the purchase for the replacements of wash sales.
+//    Short sales are acquired and sold on the day they were closed, the same as the IRS instructions.
+// 4. The code is "W" for wash sales, and the adjustment is the loss that was disallowed, they are empty for other sales
+// 5. The term is "short" or "long", which is the part of the form the sale belongs in
+//
+
+const (
+	form8949DateFormat = "01/02/2006"
+	form8949WashCode   = "W"
+	form8949ShortTerm  = "short"
+	form8949LongTerm   = "long"
+)
+
+// form8949Columns are all of the column names, in the order they are written
+var form8949Columns = []string{
+	"description",
+	"date_acquired",
+	"date_sold",
+	"proceeds",
+	"cost_basis",
+	"code",
+	"adjustment",
+	"gain_or_loss",
+	"term",
+}
+
+// WriteForm8949 writes the lots that were closed in the tax year, 0 writes the lots of every year
+//
+// Errors:
+// - If the CSV can't be written an error with GRPC status Internal will be returned
+//
+func (l *Ledger) WriteForm8949(ctx context.Context, output io.Writer, year int) error {
+	logger := ctxzap.Extract(ctx)
+
+	writer := csv.NewWriter(output)
+	err := writer.Write(form8949Columns)
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for _, longTerm := range []bool{false, true} {
+		for _, closed := range l.Closed {
+			if closed.LongTerm != longTerm || (year != 0 && date(closed.CloseTime).Year() != year) {
+				continue
+			}
+			err = writer.Write(formatForm8949(closed))
+			if nil != err {
+				logger.Error("Failed to write row", zap.Error(err))
+				return status.Error(codes.Internal, err.Error())
+			}
+		}
+	}
+
+	writer.Flush()
+	err = writer.Error()
+	if nil != err {
+		logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// formatForm8949 is the value of each column for the closed lot
+func formatForm8949(closed *ClosedLot) []string {
+	acquired := closed.OpenTime
+	if closed.Quantity < 0 {
+		acquired = closed.CloseTime
+	}
+	code, adjustment, term := "", "", form8949ShortTerm
+	if closed.WashSale {
+		code = form8949WashCode
+	}
+	if closed.Adjustment != 0 {
+		adjustment = formatForm8949Amount(closed.Adjustment)
+	}
+	if closed.LongTerm {
+		term = form8949LongTerm
+	}
+	return []string{
+		strconv.FormatFloat(math.Abs(closed.Quantity), 'f', -1, 64) + " " + closed.Symbol,
+		time.Unix(acquired, 0).UTC().Format(form8949DateFormat),
+		time.Unix(closed.CloseTime, 0).UTC().Format(form8949DateFormat),
+		formatForm8949Amount(closed.Proceeds),
+		formatForm8949Amount(closed.CostBasis),
+		code,
+		adjustment,
+		formatForm8949Amount(closed.Gain()),
+		term,
+	}
+}
+
+// formatForm8949Amount rounds the amount to cents, without a sign on amounts that round to 0
+func formatForm8949Amount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*100)/100+0, 'f', 2, 64)
+}
@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"testing"
+	"time"
+)
+
+func TestWriteForm8949(t *testing.T) {
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	ledger := NewLedger(constants.FIFO)
+	ledger.WashSales = NewWashSaleRule()
+	trades := []*orders.Order{
+		orders.NewOrder(day(2021, 1, 4), orders.NewStockOrderItem(constants.Buy, "XYZ", 100, 10)),
+		orders.NewOrder(day(2021, 6, 1), orders.NewStockOrderItem(constants.Sell, "DEF", 10, 30)),
+		orders.NewOrder(day(2021, 6, 15), orders.NewStockOrderItem(constants.Buy, "DEF", 10, 25)),
+		orders.NewOrder(day(2022, 1, 3), orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10)),
+		orders.NewOrder(day(2022, 3, 1), orders.NewStockOrderItem(constants.Sell, "ABC", 100, 8)),
+		orders.NewOrder(day(2022, 3, 15), orders.NewStockOrderItem(constants.Buy, "ABC", 100, 9)),
+		orders.NewOrder(day(2022, 6, 1), orders.NewStockOrderItem(constants.Sell, "ABC", 100, 12)),
+		orders.NewOrder(day(2022, 12, 1), orders.NewStockOrderItem(constants.Sell, "XYZ", 100, 20)),
+	}
+	for _, order := range trades {
+		require.NoError(t, ledger.AddOrder(order))
+	}
+
+	ctx := context.Background()
+	t.Run("Every year", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		require.NoError(t, ledger.WriteForm8949(ctx, buff, 0))
+		require.Equal(t, buff.String(), "description,date_acquired,date_sold,proceeds,cost_basis,code,adjustment,gain_or_loss,term\n"+
+			"10 DEF,06/15/2021,06/15/2021,300.00,250.00,,,50.00,short\n"+
+			"100 ABC,01/03/2022,03/01/2022,800.00,1000.00,W,200.00,0.00,short\n"+
+			"100 ABC,01/17/2022,06/01/2022,1200.00,1100.00,,,100.00,short\n"+
+			"100 XYZ,01/04/2021,12/01/2022,2000.00,1000.00,,,1000.00,long\n")
+	})
+
+	t.Run("Tax year", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		require.NoError(t, ledger.WriteForm8949(ctx, buff, 2021))
+		require.Equal(t, buff.String(), "description,date_acquired,date_sold,proceeds,cost_basis,code,adjustment,gain_or_loss,term\n"+
+			"10 DEF,06/15/2021,06/15/2021,300.00,250.00,,,50.00,short\n")
+	})
+}
@@ -0,0 +1,301 @@
+package ledger
+
+import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Ledger tracks the open tax lots of each symbol from the fills of orders, and the lots that were closed.
+//
+// Each fill that buys (or sells short) opens a new lot, and each fill in the other direction closes lots,
+// picked by the relief method of the ledger:
+// 1. FIFO - the oldest lots are closed first
+// 2. LIFO - the newest lots are closed first
+// 3. HIFO - the lots with the highest price are closed first, which realizes the smallest gain
+// 4. AverageCost - every lot is given the average price of the position, then the oldest lots are closed first
+// 5. SpecificLot - the lots are picked for each order, see AddOrderWithLots
+//
+// A fill that closes more than is held closes every lot, and the rest of it opens a lot in the other direction.
+// The quantities are in units, ex: 100 per option contract, the same as the positions of a trade.
+// The losses of wash sales are moved to the lots that replaced them when WashSales is set, see WashSaleRule.
+//
+type Ledger struct {
+	// Method picks the lots that are closed
+	Method constants.ReliefMethod `csv:"method" avro:"method" json:"method"`
+	// Lots that are still open by their symbol, oldest first
+	Lots map[string][]*Lot `csv:"lots" avro:"lots" json:"lots"`
+	// Closed are the parts of the lots that were closed, in the order they were closed
+	Closed []*ClosedLot `csv:"closed" avro:"closed" json:"closed"`
+	// NextID is the ID of the next lot that is opened
+	NextID int64 `csv:"next_id" avro:"next_id" json:"next_id"`
+	// WashSales disallows the losses of wash sales when it's set, see WashSaleRule
+	WashSales *WashSaleRule `csv:"wash_sales" avro:"wash_sales" json:"wash_sales,omitempty"`
+}
+
+func NewLedger(method constants.ReliefMethod) *Ledger {
+	return &Ledger{
+		Method: method,
+		Lots:   make(map[string][]*Lot),
+		Closed: make([]*ClosedLot, 0),
+		NextID: 1,
+	}
+}
+
+// AddOrder records each fill of the order, closing lots with the relief method of the ledger.
+// Orders without any fills are recorded as filled at the price of each item when they were placed,
+// the same as market orders when back-testing.
+//
+// Errors:
+// - If a fill is for an item that isn't in the order an error with GRPC status InvalidArgument will be returned
+// - If the order closes lots and the method is SpecificLot, or isn't known an error with GRPC status FailedPrecondition will be returned
+//
+func (l *Ledger) AddOrder(order *orders.Order) error {
+	return l.addOrder(order, nil)
+}
+
+// AddOrderWithLots records each fill of the order, closing the lots with the IDs in the order they are given,
+// whatever the relief method of the ledger is.
+//
+// Errors:
+// - If any of the lots isn't open an error with GRPC status NotFound will be returned
+// - If the lots don't cover the quantity the order closes an error with GRPC status InvalidArgument will be returned
+// - See AddOrder
+//
+func (l *Ledger) AddOrderWithLots(order *orders.Order, lotIDs ...string) error {
+	if nil == lotIDs {
+		lotIDs = make([]string, 0)
+	}
+	return l.addOrder(order, lotIDs)
+}
+
+// Position is the number of units held of the symbol, negative for short positions
+func (l *Ledger) Position(symbol string) float64 {
+	output := 0.0
+	for _, lot := range l.Lots[symbol] {
+		output += lot.Quantity
+	}
+	return output
+}
+
+// OpenLots are copies of the lots of the symbol that are still open, oldest first
+func (l *Ledger) OpenLots(symbol string) []*Lot {
+	output := make([]*Lot, 0, len(l.Lots[symbol]))
+	for _, lot := range l.Lots[symbol] {
+		output = append(output, lot.Clone())
+	}
+	return output
+}
+
+// RealizedGains are the gains (or losses) of every lot that was closed, split into short and long term
+func (l *Ledger) RealizedGains() *Gains {
+	output := &Gains{}
+	for _, closed := range l.Closed {
+		if closed.LongTerm {
+			output.LongTerm += closed.Gain()
+		} else {
+			output.ShortTerm += closed.Gain()
+		}
+	}
+	return output
+}
+
+// addOrder records the fills on a copy of the ledger, so the ledger is unchanged when the order can't be recorded.
+// The lots are picked by the relief method when lotIDs is nil.
+func (l *Ledger) addOrder(order *orders.Order, lotIDs []string) error {
+	fills := order.Fills
+	if len(fills) == 0 {
+		fills = make([]*orders.Fill, 0, len(order.OrderItems))
+		for index, item := range order.OrderItems {
+			fills = append(fills, &orders.Fill{UnixTime: order.UnixTime, Item: index, Amount: item.Amount, Price: item.Price})
+		}
+	}
+
+	working := l.clone()
+	if nil != lotIDs {
+		for _, id := range lotIDs {
+			if !working.isOpen(id) {
+				return status.Errorf(codes.NotFound, "lot %q isn't open", id)
+			}
+		}
+	}
+	for _, fill := range fills {
+		if fill.Item < 0 || fill.Item >= len(order.OrderItems) {
+			return status.Errorf(codes.InvalidArgument, "fill for item %d isn't in the order", fill.Item)
+		}
+		item := order.OrderItems[fill.Item]
+		units := fill.Amount * item.QuantityPerAmount
+		if item.Direction == constants.Sell {
+			units *= -1
+		}
+		err := working.fill(item.Symbol, fill.UnixTime, units, fill.Price, lotIDs)
+		if nil != err {
+			return err
+		}
+	}
+	*l = *working
+	return nil
+}
+
+// fill closes the lots of the symbol that are in the other direction, and opens a lot with the rest of the units
+func (l *Ledger) fill(symbol string, unixTime int64, units, price float64, lotIDs []string) error {
+	closed := len(l.Closed)
+	lots := l.Lots[symbol]
+	if len(lots) > 0 && lots[0].IsShort() != (units < 0) {
+		relief, err := l.relief(lots, lotIDs)
+		if nil != err {
+			return err
+		}
+		for _, index := range relief {
+			if units == 0 {
+				break
+			}
+			lot := lots[index]
+			quantity := math.Min(math.Abs(units), math.Abs(lot.Quantity))
+			if lot.IsShort() {
+				quantity *= -1
+			}
+			l.close(lot, unixTime, quantity, price)
+			units += quantity
+			if math.Abs(units) < 1e-9 {
+				units = 0
+			}
+		}
+
+		open := make([]*Lot, 0, len(lots))
+		for _, lot := range lots {
+			if lot.Quantity != 0 {
+				open = append(open, lot)
+			}
+		}
+		lots = open
+		if units != 0 && len(lots) > 0 {
+			return status.Errorf(codes.InvalidArgument, "the lots don't cover the %v units of %q that are closed", math.Abs(units), symbol)
+		}
+	}
+
+	var opened *Lot
+	if units != 0 {
+		opened = &Lot{
+			ID:       strconv.FormatInt(l.NextID, 10),
+			Symbol:   symbol,
+			UnixTime: unixTime,
+			Quantity: units,
+			Price:    price,
+		}
+		lots = append(lots, opened)
+		l.NextID++
+	}
+	if len(lots) == 0 {
+		delete(l.Lots, symbol)
+	} else {
+		l.Lots[symbol] = lots
+	}
+
+	if nil != l.WashSales {
+		l.washSales(append([]*ClosedLot{}, l.Closed[closed:]...), opened)
+	}
+	return nil
+}
+
+// close the quantity of the lot at the price, and record the closed part of the lot
+func (l *Ledger) close(lot *Lot, unixTime int64, quantity, price float64) {
+	closed := &ClosedLot{
+		LotID:     lot.ID,
+		Symbol:    lot.Symbol,
+		OpenTime:  lot.holdingTime(),
+		CloseTime: unixTime,
+		Quantity:  quantity,
+		Proceeds:  math.Abs(quantity) * price,
+		CostBasis: math.Abs(quantity) * (lot.Price + lot.Adjustment),
+		LongTerm:  !lot.IsShort() && isLongTerm(lot.holdingTime(), unixTime),
+	}
+	if lot.IsShort() {
+		closed.Proceeds, closed.CostBasis = closed.CostBasis, closed.Proceeds
+	}
+	l.Closed = append(l.Closed, closed)
+
+	lot.Quantity -= quantity
+	if math.Abs(lot.Quantity) < 1e-9 {
+		lot.Quantity = 0
+	}
+}
+
+// relief is the indexes of the lots in the order they are closed
+func (l *Ledger) relief(lots []*Lot, lotIDs []string) ([]int, error) {
+	output := make([]int, 0, len(lots))
+	if nil != lotIDs {
+		for _, id := range lotIDs {
+			for index, lot := range lots {
+				if lot.ID == id {
+					output = append(output, index)
+				}
+			}
+		}
+		return output, nil
+	}
+
+	for index := range lots {
+		output = append(output, index)
+	}
+	switch l.Method {
+	case constants.FIFO:
+	case constants.LIFO:
+		for i, j := 0, len(output)-1; i < j; i, j = i+1, j-1 {
+			output[i], output[j] = output[j], output[i]
+		}
+	case constants.HIFO:
+		sort.SliceStable(output, func(i, j int) bool {
+			return lots[output[i]].Price > lots[output[j]].Price
+		})
+	case constants.AverageCost:
+		quantity, cost := 0.0, 0.0
+		for _, lot := range lots {
+			quantity += lot.Quantity
+			cost += lot.Quantity * lot.Price
+		}
+		for _, lot := range lots {
+			lot.Price = cost / quantity
+		}
+	case constants.SpecificLot:
+		return nil, status.Error(codes.FailedPrecondition, "specific lot relief needs the lots for each order, see AddOrderWithLots")
+	default:
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown relief method %d", l.Method)
+	}
+	return output, nil
+}
+
+// isOpen checks if the lot with the ID is open for any symbol
+func (l *Ledger) isOpen(id string) bool {
+	for _, lots := range l.Lots {
+		for _, lot := range lots {
+			if lot.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clone copies the ledger, it's open lots, and it's closed lots
+func (l *Ledger) clone() *Ledger {
+	output := *l
+	output.Lots = make(map[string][]*Lot, len(l.Lots))
+	for symbol, lots := range l.Lots {
+		clones := make([]*Lot, 0, len(lots))
+		for _, lot := range lots {
+			clones = append(clones, lot.Clone())
+		}
+		output.Lots[symbol] = clones
+	}
+	output.Closed = make([]*ClosedLot, 0, len(l.Closed))
+	for _, closed := range l.Closed {
+		clone := *closed
+		output.Closed = append(output.Closed, &clone)
+	}
+	return &output
+}
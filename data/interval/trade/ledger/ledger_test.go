@@ -0,0 +1,153 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+// newLedger buys 100 shares of ABC three times, the first lot is held for more than a year by December 1st, 2022
+func newLedger(t *testing.T, method constants.ReliefMethod) *Ledger {
+	ledger := NewLedger(method)
+	buys := []struct {
+		day   time.Time
+		price float64
+	}{
+		{time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC), 10},
+		{time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), 15},
+		{time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), 12},
+	}
+	for _, buy := range buys {
+		require.NoError(t, ledger.AddOrder(orders.NewOrder(buy.day, orders.NewStockOrderItem(constants.Buy, "ABC", 100, buy.price))))
+	}
+	require.Equal(t, ledger.Position("ABC"), 300.0)
+	return ledger
+}
+
+func TestLedger(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	sell := orders.NewOrder(now, orders.NewStockOrderItem(constants.Sell, "ABC", 150, 20))
+
+	type args struct {
+		method constants.ReliefMethod
+		lotIDs []string
+		// open lots left by their ID and quantity
+		open  map[string]float64
+		gains *Gains
+	}
+	tests := map[string]args{
+		"FIFO": {
+			method: constants.FIFO,
+			open:   map[string]float64{"2": 50, "3": 100},
+			gains:  &Gains{ShortTerm: 250, LongTerm: 1000},
+		},
+		"LIFO": {
+			method: constants.LIFO,
+			open:   map[string]float64{"1": 100, "2": 50},
+			gains:  &Gains{ShortTerm: 1050},
+		},
+		"HIFO": {
+			method: constants.HIFO,
+			open:   map[string]float64{"1": 100, "3": 50},
+			gains:  &Gains{ShortTerm: 900},
+		},
+		"Average cost": {
+			// Every lot costs $12.33 on average
+			method: constants.AverageCost,
+			open:   map[string]float64{"2": 50, "3": 100},
+			gains:  &Gains{ShortTerm: 50 * (20 - 37.0/3), LongTerm: 100 * (20 - 37.0/3)},
+		},
+		"Specific lot": {
+			method: constants.SpecificLot,
+			lotIDs: []string{"3", "1"},
+			open:   map[string]float64{"1": 50, "2": 100},
+			gains:  &Gains{ShortTerm: 800, LongTerm: 500},
+		},
+		"Specific lots with another method": {
+			method: constants.FIFO,
+			lotIDs: []string{"2", "3"},
+			open:   map[string]float64{"1": 100, "3": 50},
+			gains:  &Gains{ShortTerm: 900},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			ledger := newLedger(t, test.method)
+			if nil == test.lotIDs {
+				require.NoError(t, ledger.AddOrder(sell))
+			} else {
+				require.NoError(t, ledger.AddOrderWithLots(sell, test.lotIDs...))
+			}
+			require.Equal(t, ledger.Position("ABC"), 150.0)
+
+			open := make(map[string]float64)
+			for _, lot := range ledger.OpenLots("ABC") {
+				open[lot.ID] = lot.Quantity
+			}
+			require.Equal(t, open, test.open)
+
+			gains := ledger.RealizedGains()
+			require.InDelta(t, gains.ShortTerm, test.gains.ShortTerm, 1e-9)
+			require.InDelta(t, gains.LongTerm, test.gains.LongTerm, 1e-9)
+		})
+	}
+
+	t.Run("Short sales", func(t *testing.T) {
+		ledger := NewLedger(constants.FIFO)
+		require.NoError(t, ledger.AddOrder(orders.NewOrder(now, orders.NewOptionOrderItem(constants.Sell, "ABC CALL @ 12", 1, 1.5))))
+		require.Equal(t, ledger.Position("ABC CALL @ 12"), -100.0)
+
+		// Buying back 2 contracts closes the short lot, and opens a long lot with the other contract
+		require.NoError(t, ledger.AddOrder(orders.NewOrder(now.AddDate(2, 0, 0), orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 2, 0.5))))
+		require.Equal(t, ledger.Closed, []*ClosedLot{{
+			LotID:     "1",
+			Symbol:    "ABC CALL @ 12",
+			OpenTime:  now.Unix(),
+			CloseTime: now.AddDate(2, 0, 0).Unix(),
+			Quantity:  -100,
+			Proceeds:  150,
+			CostBasis: 50,
+		}})
+		require.Equal(t, ledger.OpenLots("ABC CALL @ 12"), []*Lot{{ID: "2", Symbol: "ABC CALL @ 12", UnixTime: now.AddDate(2, 0, 0).Unix(), Quantity: 100, Price: 0.5}})
+		require.Equal(t, ledger.RealizedGains(), &Gains{ShortTerm: 100})
+	})
+
+	t.Run("Fills", func(t *testing.T) {
+		ledger := NewLedger(constants.FIFO)
+		order := orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10))
+		order.Fills = []*orders.Fill{
+			{UnixTime: now.Unix(), Item: 0, Amount: 60, Price: 10},
+			{UnixTime: now.Add(time.Hour).Unix(), Item: 0, Amount: 40, Price: 10.5},
+		}
+		require.NoError(t, ledger.AddOrder(order))
+		require.Equal(t, ledger.OpenLots("ABC"), []*Lot{
+			{ID: "1", Symbol: "ABC", UnixTime: now.Unix(), Quantity: 60, Price: 10},
+			{ID: "2", Symbol: "ABC", UnixTime: now.Add(time.Hour).Unix(), Quantity: 40, Price: 10.5},
+		})
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ledger := newLedger(t, constants.SpecificLot)
+		require.Equal(t, status.Code(ledger.AddOrder(sell)), codes.FailedPrecondition)
+		require.Equal(t, status.Code(ledger.AddOrderWithLots(sell, "9")), codes.NotFound)
+		require.Equal(t, status.Code(ledger.AddOrderWithLots(sell, "3")), codes.InvalidArgument)
+
+		order := orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10))
+		order.Fills = []*orders.Fill{{UnixTime: now.Unix(), Item: 1, Amount: 100, Price: 10}}
+		require.Equal(t, status.Code(ledger.AddOrder(order)), codes.InvalidArgument)
+
+		unknown := newLedger(t, constants.ReliefMethod(9))
+		require.Equal(t, status.Code(unknown.AddOrder(sell)), codes.FailedPrecondition)
+
+		// The ledger is unchanged by the orders that failed
+		require.Equal(t, ledger.Position("ABC"), 300.0)
+		require.Len(t, ledger.OpenLots("ABC"), 3)
+		require.Empty(t, ledger.Closed)
+	})
+}
@@ -0,0 +1,101 @@
+package ledger
+
+import (
+	"time"
+)
+
+// Lot is a single purchase (or short sale) of a symbol, which stays open until all of it's quantity is closed
+type Lot struct {
+	// ID of the lot within the ledger, used to pick the lots for SpecificLot relief
+	ID string `csv:"id" avro:"id" json:"id"`
+	// Symbol of the item that was bought or sold
+	Symbol string `csv:"symbol" avro:"symbol" json:"symbol"`
+	// UnixTime the lot was opened
+	UnixTime int64 `csv:"time" avro:"time" json:"time"`
+	// Quantity is the number of units still open, negative for short lots, ex: 100 per option contract
+	Quantity float64 `csv:"quantity" avro:"quantity" json:"quantity"`
+	// Price per unit the lot was opened at
+	Price float64 `csv:"price" avro:"price" json:"price"`
+	// Adjustment is added to the cost basis of each unit, ex: the loss of a wash sale that the lot replaced
+	Adjustment float64 `csv:"adjustment" avro:"adjustment" json:"adjustment,omitempty"`
+	// HoldingTime is the unix time the holding period starts, 0 is when the lot was opened.
+	// The lots that replace a wash sale also include the holding period of the lot that was sold.
+	HoldingTime int64 `csv:"holding_time" avro:"holding_time" json:"holding_time,omitempty"`
+	// Replacement is set when the lot replaced a wash sale, a lot can only replace a single sale
+	Replacement bool `csv:"replacement" avro:"replacement" json:"replacement,omitempty"`
+}
+
+// IsShort checks if the lot was opened by a short sale
+func (l *Lot) IsShort() bool {
+	return l.Quantity < 0
+}
+
+// holdingTime is the unix time the holding period of the lot starts
+func (l *Lot) holdingTime() int64 {
+	if l.HoldingTime == 0 {
+		return l.UnixTime
+	}
+	return l.HoldingTime
+}
+
+// Clone copies the lot
+func (l *Lot) Clone() *Lot {
+	output := *l
+	return &output
+}
+
+// ClosedLot is the part of a lot that was closed, and the gain (or loss) that was realized by closing it
+type ClosedLot struct {
+	// LotID is the ID of the lot that was closed
+	LotID string `csv:"lot_id" avro:"lot_id" json:"lot_id"`
+	// Symbol of the lot
+	Symbol string `csv:"symbol" avro:"symbol" json:"symbol"`
+	// OpenTime is the unix time the holding period of the lot started, see Lot.HoldingTime
+	OpenTime int64 `csv:"open_time" avro:"open_time" json:"open_time"`
+	// CloseTime is the unix time the lot was closed
+	CloseTime int64 `csv:"close_time" avro:"close_time" json:"close_time"`
+	// Quantity is the number of units closed, negative for short lots
+	Quantity float64 `csv:"quantity" avro:"quantity" json:"quantity"`
+	// Proceeds are what the units were sold for, which is when they were opened for short lots
+	Proceeds float64 `csv:"proceeds" avro:"proceeds" json:"proceeds"`
+	// CostBasis is what the units were bought for, which is when they were closed for short lots
+	CostBasis float64 `csv:"cost_basis" avro:"cost_basis" json:"cost_basis"`
+	// LongTerm is set when the lot was held for more than a year, short lots are always short term
+	LongTerm bool `csv:"long_term" avro:"long_term" json:"long_term"`
+	// Adjustment is added to the gain, ex: the loss of a wash sale that isn't allowed
+	Adjustment float64 `csv:"adjustment" avro:"adjustment" json:"adjustment,omitempty"`
+	// WashSale is set when the loss was disallowed by the wash sale rule, and added to the basis of the replacement lot
+	WashSale bool `csv:"wash_sale" avro:"wash_sale" json:"wash_sale,omitempty"`
+}
+
+// Gain is the profit (or loss) of closing the units, including the adjustment
+func (c *ClosedLot) Gain() float64 {
+	return c.Proceeds - c.CostBasis + c.Adjustment
+}
+
+// Gains are the realized gains (or losses) of closed lots, split by how long the lots were held
+type Gains struct {
+	// ShortTerm are the gains of lots held for a year or less
+	ShortTerm float64 `csv:"short_term" avro:"short_term" json:"short_term"`
+	// LongTerm are the gains of lots held for more than a year
+	LongTerm float64 `csv:"long_term" avro:"long_term" json:"long_term"`
+}
+
+// Total is the sum of the short and long term gains
+func (g *Gains) Total() float64 {
+	return g.ShortTerm + g.LongTerm
+}
+
+// isLongTerm checks if a lot opened at the first time and closed at the second was held for more than a year.
+// The holding period starts the day after the lot was opened, so a lot closed on the anniversary is still short term.
+func isLongTerm(opened, closed int64) bool {
+	openDate := date(opened)
+	closeDate := date(closed)
+	return closeDate.After(openDate.AddDate(1, 0, 0))
+}
+
+// date is midnight UTC on the day of the unix time
+func date(unixTime int64) time.Time {
+	t := time.Unix(unixTime, 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
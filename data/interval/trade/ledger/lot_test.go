@@ -0,0 +1,37 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLot(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Long term", func(t *testing.T) {
+		opened := now.Add(15 * time.Hour).Unix()
+		require.False(t, isLongTerm(opened, now.AddDate(0, 6, 0).Unix()))
+		// The anniversary is still short term, even later in the day
+		require.False(t, isLongTerm(opened, now.AddDate(1, 0, 0).Add(23*time.Hour).Unix()))
+		require.True(t, isLongTerm(opened, now.AddDate(1, 0, 1).Unix()))
+	})
+
+	t.Run("Gains", func(t *testing.T) {
+		long := &ClosedLot{Quantity: 10, Proceeds: 150, CostBasis: 100}
+		short := &ClosedLot{Quantity: -10, Proceeds: 100, CostBasis: 120}
+		require.Equal(t, long.Gain(), 50.0)
+		require.Equal(t, short.Gain(), -20.0)
+		require.Equal(t, (&Gains{ShortTerm: -20, LongTerm: 50}).Total(), 30.0)
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		lot := &Lot{ID: "1", Symbol: "ABC", Quantity: -10, Price: 12}
+		clone := lot.Clone()
+		require.Equal(t, clone, lot)
+		require.True(t, clone.IsShort())
+		clone.Quantity = 5
+		require.Equal(t, lot.Quantity, -10.0)
+	})
+}
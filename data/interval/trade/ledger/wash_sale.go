@@ -0,0 +1,200 @@
+package ledger
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultWashSaleWindow is the number of days before and after a sale at a loss that buying the same security washes the loss
+const DefaultWashSaleWindow = 30
+
+// WashSaleRule is the US wash sale rule, a loss on a sale isn't allowed when a substantially identical security
+// is bought within the window before or after the sale.
+//
+// 1. The loss is added to the cost basis of the lot that replaced the sold lot, so it's realized when that lot is closed
+// 2. The holding period of the replacement lot includes the holding period of the sold lot
+// 3. Each lot can only replace a single sale, lots that are only partly a replacement are split in two
+// 4. Sales that are only partly replaced are split in two, so only the replaced part of the loss is disallowed
+// 5. Only the losses of long lots are washed, short sales aren't tracked
+//
+// Every symbol is identical to itself, and Identical makes symbols identical to each other, ex: a stock and it's options.
+//
+type WashSaleRule struct {
+	// Window is the number of days before and after the sale
+	Window int `csv:"window" avro:"window" json:"window"`
+	// Groups of the substantially identical symbols, symbols that are identical have the same group
+	Groups map[string]string `csv:"groups" avro:"groups" json:"groups"`
+}
+
+func NewWashSaleRule() *WashSaleRule {
+	return &WashSaleRule{
+		Window: DefaultWashSaleWindow,
+		Groups: make(map[string]string),
+	}
+}
+
+// Identical makes the symbols substantially identical to each other, and to any symbols they are already identical to
+func (r *WashSaleRule) Identical(symbols ...string) *WashSaleRule {
+	if len(symbols) == 0 {
+		return r
+	}
+	group := r.group(symbols[0])
+	for _, symbol := range symbols {
+		previous := r.group(symbol)
+		for member, name := range r.Groups {
+			if name == previous {
+				r.Groups[member] = group
+			}
+		}
+		r.Groups[strings.ToUpper(symbol)] = group
+	}
+	return r
+}
+
+// IsIdentical checks if the symbols are substantially identical, ignoring case
+func (r *WashSaleRule) IsIdentical(a, b string) bool {
+	return r.group(a) == r.group(b)
+}
+
+// InWindow checks if the purchase at the second unix time is within the window of the sale at the first unix time
+func (r *WashSaleRule) InWindow(sale, purchase int64) bool {
+	days := date(purchase).Sub(date(sale)).Hours() / 24
+	return math.Abs(days) <= float64(r.Window)
+}
+
+// group of the symbol, which is the symbol itself when it isn't in a group
+func (r *WashSaleRule) group(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if group, ok := r.Groups[symbol]; ok {
+		return group
+	}
+	return symbol
+}
+
+// washSales washes the losses of the lots that were just closed with the lots bought before them,
+// and the losses of earlier sales with the lot that was just opened
+func (l *Ledger) washSales(closed []*ClosedLot, opened *Lot) {
+	for _, sale := range closed {
+		for nil != sale && l.isWashable(sale) {
+			replacement := l.findReplacement(sale)
+			if nil == replacement {
+				break
+			}
+			sale, _ = l.wash(sale, replacement)
+		}
+	}
+
+	for nil != opened && !opened.IsShort() {
+		var sale *ClosedLot
+		for _, closed := range l.Closed {
+			if l.isWashable(closed) && closed.CloseTime <= opened.UnixTime && l.isReplacement(closed, opened) {
+				sale = closed
+				break
+			}
+		}
+		if nil == sale {
+			break
+		}
+		_, opened = l.wash(sale, opened)
+	}
+}
+
+// isWashable checks if the closed lot is a loss on a long lot that hasn't been washed
+func (l *Ledger) isWashable(sale *ClosedLot) bool {
+	return sale.Quantity > 0 && !sale.WashSale && sale.Gain() < 0
+}
+
+// isReplacement checks if the lot can replace the sale
+func (l *Ledger) isReplacement(sale *ClosedLot, lot *Lot) bool {
+	return !lot.IsShort() &&
+		!lot.Replacement &&
+		lot.ID != sale.LotID &&
+		l.WashSales.IsIdentical(sale.Symbol, lot.Symbol) &&
+		l.WashSales.InWindow(sale.CloseTime, lot.UnixTime)
+}
+
+// findReplacement is the oldest open lot bought before the sale that can replace it
+func (l *Ledger) findReplacement(sale *ClosedLot) *Lot {
+	candidates := make([]*Lot, 0)
+	for _, lots := range l.Lots {
+		for _, lot := range lots {
+			if lot.UnixTime <= sale.CloseTime && l.isReplacement(sale, lot) {
+				candidates = append(candidates, lot)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].UnixTime != candidates[j].UnixTime {
+			return candidates[i].UnixTime < candidates[j].UnixTime
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	return candidates[0]
+}
+
+// wash moves the loss of the sale to the replacement lot, for as many units as both of them have.
+// The rest of the sale and the rest of the lot are split off, and returned so they can be washed too.
+func (l *Ledger) wash(sale *ClosedLot, lot *Lot) (*ClosedLot, *Lot) {
+	quantity := math.Min(sale.Quantity, lot.Quantity)
+	restOfSale := l.splitSale(sale, quantity)
+	restOfLot := l.splitLot(lot, quantity)
+
+	loss := -sale.Gain()
+	sale.Adjustment += loss
+	sale.WashSale = true
+	lot.Adjustment += loss / quantity
+	lot.HoldingTime = lot.holdingTime() - (sale.CloseTime - sale.OpenTime)
+	lot.Replacement = true
+	return restOfSale, restOfLot
+}
+
+// splitSale keeps the quantity in the sale, and splits the rest into a new closed lot right after it
+func (l *Ledger) splitSale(sale *ClosedLot, quantity float64) *ClosedLot {
+	if sale.Quantity-quantity < 1e-9 {
+		return nil
+	}
+	rest := *sale
+	share := quantity / sale.Quantity
+	rest.Quantity = sale.Quantity - quantity
+	rest.Proceeds = sale.Proceeds * (1 - share)
+	rest.CostBasis = sale.CostBasis * (1 - share)
+	rest.Adjustment = sale.Adjustment * (1 - share)
+	sale.Quantity = quantity
+	sale.Proceeds *= share
+	sale.CostBasis *= share
+	sale.Adjustment *= share
+
+	for index, closed := range l.Closed {
+		if closed == sale {
+			l.Closed = append(l.Closed[:index+1], append([]*ClosedLot{&rest}, l.Closed[index+1:]...)...)
+			break
+		}
+	}
+	return &rest
+}
+
+// splitLot keeps the quantity in the lot, and splits the rest into a new lot right after it
+func (l *Ledger) splitLot(lot *Lot, quantity float64) *Lot {
+	if lot.Quantity-quantity < 1e-9 {
+		return nil
+	}
+	rest := *lot
+	rest.ID = strconv.FormatInt(l.NextID, 10)
+	rest.Quantity = lot.Quantity - quantity
+	lot.Quantity = quantity
+	l.NextID++
+
+	lots := l.Lots[lot.Symbol]
+	for index, open := range lots {
+		if open == lot {
+			l.Lots[lot.Symbol] = append(lots[:index+1], append([]*Lot{&rest}, lots[index+1:]...)...)
+			break
+		}
+	}
+	return &rest
+}
@@ -0,0 +1,127 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"testing"
+	"time"
+)
+
+func TestWashSaleRule(t *testing.T) {
+	sale := time.Date(2022, 3, 1, 15, 0, 0, 0, time.UTC).Unix()
+
+	t.Run("Window", func(t *testing.T) {
+		rule := NewWashSaleRule()
+		require.True(t, rule.InWindow(sale, time.Date(2022, 1, 30, 0, 0, 0, 0, time.UTC).Unix()))
+		require.False(t, rule.InWindow(sale, time.Date(2022, 1, 29, 23, 0, 0, 0, time.UTC).Unix()))
+		require.True(t, rule.InWindow(sale, time.Date(2022, 3, 31, 23, 0, 0, 0, time.UTC).Unix()))
+		require.False(t, rule.InWindow(sale, time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC).Unix()))
+	})
+
+	t.Run("Identical", func(t *testing.T) {
+		rule := NewWashSaleRule().Identical("ABC", "ABC CALL @ 12").Identical("abc.b", "abc")
+		require.True(t, rule.IsIdentical("ABC", "abc"))
+		require.True(t, rule.IsIdentical("ABC CALL @ 12", "ABC.B"))
+		require.False(t, rule.IsIdentical("ABC", "XYZ"))
+	})
+}
+
+func TestWashSales(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2022, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	stock := func(t *testing.T, ledger *Ledger, at time.Time, direction constants.Direction, amount, price float64) {
+		require.NoError(t, ledger.AddOrder(orders.NewOrder(at, orders.NewStockOrderItem(direction, "ABC", amount, price))))
+	}
+	newLedger := func() *Ledger {
+		ledger := NewLedger(constants.FIFO)
+		ledger.WashSales = NewWashSaleRule()
+		return ledger
+	}
+
+	t.Run("Bought after the sale", func(t *testing.T) {
+		ledger := newLedger()
+		stock(t, ledger, day(1, 3), constants.Buy, 100, 10)
+		stock(t, ledger, day(3, 1), constants.Sell, 100, 8)
+		require.Equal(t, ledger.RealizedGains(), &Gains{ShortTerm: -200})
+
+		// The $200 loss moves to the replacement lot, which is also held from January 3rd
+		stock(t, ledger, day(3, 15), constants.Buy, 100, 9)
+		require.Equal(t, ledger.RealizedGains(), &Gains{})
+		require.True(t, ledger.Closed[0].WashSale)
+		require.Equal(t, ledger.Closed[0].Adjustment, 200.0)
+		require.Equal(t, ledger.OpenLots("ABC"), []*Lot{{
+			ID:          "2",
+			Symbol:      "ABC",
+			UnixTime:    day(3, 15).Unix(),
+			Quantity:    100,
+			Price:       9,
+			Adjustment:  2,
+			HoldingTime: day(1, 17).Unix(),
+			Replacement: true,
+		}})
+
+		// The loss is realized when the replacement is sold
+		stock(t, ledger, day(6, 1), constants.Sell, 100, 12)
+		require.Equal(t, ledger.Closed[1].CostBasis, 1100.0)
+		require.Equal(t, ledger.Closed[1].OpenTime, day(1, 17).Unix())
+		require.Equal(t, ledger.RealizedGains(), &Gains{ShortTerm: 100})
+	})
+
+	t.Run("Bought before the sale", func(t *testing.T) {
+		ledger := newLedger()
+		stock(t, ledger, day(1, 3), constants.Buy, 100, 10)
+		stock(t, ledger, day(2, 20), constants.Buy, 50, 9)
+
+		// Only half of the sale is replaced, so only half of the loss is disallowed
+		stock(t, ledger, day(3, 1), constants.Sell, 100, 8)
+		require.Equal(t, ledger.Closed, []*ClosedLot{
+			{LotID: "1", Symbol: "ABC", OpenTime: day(1, 3).Unix(), CloseTime: day(3, 1).Unix(), Quantity: 50, Proceeds: 400, CostBasis: 500, Adjustment: 100, WashSale: true},
+			{LotID: "1", Symbol: "ABC", OpenTime: day(1, 3).Unix(), CloseTime: day(3, 1).Unix(), Quantity: 50, Proceeds: 400, CostBasis: 500},
+		})
+		require.Equal(t, ledger.RealizedGains(), &Gains{ShortTerm: -100})
+
+		// Half of the next purchase replaces the rest of the sale, so it's split in two
+		stock(t, ledger, day(3, 20), constants.Buy, 100, 8.5)
+		require.Equal(t, ledger.RealizedGains(), &Gains{})
+		lots := ledger.OpenLots("ABC")
+		require.Len(t, lots, 3)
+		require.Equal(t, []string{lots[0].ID, lots[1].ID, lots[2].ID}, []string{"2", "3", "4"})
+		require.Equal(t, []float64{lots[0].Quantity, lots[1].Quantity, lots[2].Quantity}, []float64{50, 50, 50})
+		require.Equal(t, []float64{lots[0].Adjustment, lots[1].Adjustment, lots[2].Adjustment}, []float64{2, 2, 0})
+		require.False(t, lots[2].Replacement)
+	})
+
+	t.Run("Substantially identical", func(t *testing.T) {
+		ledger := newLedger()
+		ledger.WashSales.Identical("ABC", "ABC CALL @ 12")
+		stock(t, ledger, day(1, 3), constants.Buy, 100, 10)
+		stock(t, ledger, day(3, 1), constants.Sell, 100, 8)
+		require.NoError(t, ledger.AddOrder(orders.NewOrder(day(3, 2), orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 1, 0.5))))
+		require.Equal(t, ledger.RealizedGains(), &Gains{})
+		require.Equal(t, ledger.OpenLots("ABC CALL @ 12")[0].Adjustment, 2.0)
+	})
+
+	t.Run("Not a wash sale", func(t *testing.T) {
+		tests := map[string]func(ledger *Ledger){
+			"Outside the window": func(ledger *Ledger) {
+				stock(t, ledger, day(4, 1), constants.Buy, 100, 9)
+			},
+			"Different symbol": func(ledger *Ledger) {
+				require.NoError(t, ledger.AddOrder(orders.NewOrder(day(3, 2), orders.NewStockOrderItem(constants.Buy, "XYZ", 100, 9))))
+			},
+			"Without the rule": func(ledger *Ledger) {
+				ledger.WashSales = nil
+				stock(t, ledger, day(3, 2), constants.Buy, 100, 9)
+			},
+		}
+		for name, test := range tests {
+			ledger := newLedger()
+			stock(t, ledger, day(1, 3), constants.Buy, 100, 10)
+			stock(t, ledger, day(3, 1), constants.Sell, 100, 8)
+			test(ledger)
+			require.Equal(t, ledger.RealizedGains(), &Gains{ShortTerm: -200}, name)
+		}
+	})
+}
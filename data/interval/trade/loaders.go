@@ -0,0 +1,501 @@
+package trade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/hamba/avro"
+	"github.com/ta4g/ta4g/data/avro_file"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	pb "github.com/ta4g/ta4g/gen/interval/trade"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Loader reads and writes the trade Records to the desired format.
+// There are several loaders to choose from, each of which are self-contained with their own schemas:
+// 1. CSV
+// 2. JSON New Line
+// 3. Avro, which embeds the order schema from the orders package
+// 4. Proto, which embeds the `Order` message
+//
+type Loader interface {
+	Read(ctx context.Context, input io.Reader) ([]*Record, error)
+	Write(ctx context.Context, output io.Writer, input []*Record) error
+}
+
+// Compile time type assertions
+var _ Loader = &csvLoader{}
+var _ Loader = &jsonNewLineLoader{}
+var _ Loader = &avroLoader{}
+var _ Loader = &protoLoader{}
+
+type csvLoader struct{}
+type jsonNewLineLoader struct{}
+type avroLoader struct {
+	options avro_file.Options
+}
+type protoLoader struct{}
+
+// The schema refers to the order schema by it's name, which is parsed when the orders package is loaded
+//go:embed schema.avro
+var schemaStr string
+var avroSchema avro.Schema
+
+func init() {
+	schema, err := avro.Parse(schemaStr)
+	if nil != err {
+		panic(err)
+	} else {
+		avroSchema = schema
+	}
+}
+
+//
+// CSV Loader
+//
+// Each record is a single row, and the orders are JSON objects in the same format as the orders JSON New Line Loader:
+//
+//   holding_bars,fees,holding_cost,realized_pnl,entry,adjustments,exit
+//   5,1.5,0,98.5,"{""time"":1669852800,""items"":[...]}",[],"{""time"":1670284800,""items"":[...]}"
+//
+// 1. The adjustments are a JSON array, which is empty for trades without any adjustments
+// 2. The exit is empty while the trade is open
+// 3. The columns are found by their name in the header, so they may be in any order
+//
+
+const (
+	csvHoldingBarsColumn = "holding_bars"
+	csvFeesColumn        = "fees"
+	csvHoldingCostColumn = "holding_cost"
+	csvRealizedPnLColumn = "realized_pnl"
+	csvEntryColumn       = "entry"
+	csvAdjustmentsColumn = "adjustments"
+	csvExitColumn        = "exit"
+)
+
+// csvColumns are all of the column names, in the order they are written
+var csvColumns = []string{
+	csvHoldingBarsColumn,
+	csvFeesColumn,
+	csvHoldingCostColumn,
+	csvRealizedPnLColumn,
+	csvEntryColumn,
+	csvAdjustmentsColumn,
+	csvExitColumn,
+}
+
+func NewCSVLoader() Loader {
+	return &csvLoader{}
+}
+
+func (c csvLoader) Read(ctx context.Context, input io.Reader) ([]*Record, error) {
+	logger := ctxzap.Extract(ctx)
+
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if nil != err && err == io.EOF {
+		return []*Record{}, nil
+	}
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Find each column in the header
+	indexes := make(map[string]int, len(csvColumns))
+	for index, name := range header {
+		indexes[strings.TrimSpace(name)] = index
+	}
+	for _, column := range csvColumns {
+		if _, ok := indexes[column]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "missing column: %s", column)
+		}
+	}
+
+	output := make([]*Record, 0)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			logger.Error("Failed to read row", zap.Error(err))
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		value := func(column string) string {
+			index := indexes[column]
+			if index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		record, err := parseCSVRecord(value)
+		if nil != err {
+			return nil, status.Errorf(codes.InvalidArgument, "line %d: %s", line, err.Error())
+		}
+		output = append(output, record)
+	}
+	return output, nil
+}
+
+func (c csvLoader) Write(ctx context.Context, output io.Writer, input []*Record) error {
+	logger := ctxzap.Extract(ctx)
+
+	writer := csv.NewWriter(output)
+	err := writer.Write(csvColumns)
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for _, record := range input {
+		row, err := formatCSVRecord(record)
+		if nil != err {
+			logger.Error("Failed to marshal row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+		err = writer.Write(row)
+		if nil != err {
+			logger.Error("Failed to write row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	writer.Flush()
+	err = writer.Error()
+	if nil != err {
+		logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// formatCSVRecord is the value of each column, in the same order as csvColumns
+func formatCSVRecord(record *Record) ([]string, error) {
+	entry, err := json.Marshal(record.Entry)
+	if nil != err {
+		return nil, err
+	}
+	adjustments := record.Adjustments
+	if nil == adjustments {
+		adjustments = make([]*orders.Order, 0)
+	}
+	adjustmentsData, err := json.Marshal(adjustments)
+	if nil != err {
+		return nil, err
+	}
+	exit := ""
+	if nil != record.Exit {
+		data, err := json.Marshal(record.Exit)
+		if nil != err {
+			return nil, err
+		}
+		exit = string(data)
+	}
+	return []string{
+		strconv.FormatInt(record.HoldingBars, 10),
+		strconv.FormatFloat(record.Fees, 'f', -1, 64),
+		strconv.FormatFloat(record.HoldingCost, 'f', -1, 64),
+		strconv.FormatFloat(record.RealizedPnL, 'f', -1, 64),
+		string(entry),
+		string(adjustmentsData),
+		exit,
+	}, nil
+}
+
+// parseCSVRecord reads the columns of a single row, empty numbers are 0
+func parseCSVRecord(value func(column string) string) (*Record, error) {
+	var err error
+	output := &Record{Adjustments: make([]*orders.Order, 0)}
+	if value(csvHoldingBarsColumn) != "" {
+		output.HoldingBars, err = strconv.ParseInt(value(csvHoldingBarsColumn), 10, 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvHoldingBarsColumn, err.Error())
+		}
+	}
+	fields := map[string]*float64{
+		csvFeesColumn:        &output.Fees,
+		csvHoldingCostColumn: &output.HoldingCost,
+		csvRealizedPnLColumn: &output.RealizedPnL,
+	}
+	for _, column := range []string{csvFeesColumn, csvHoldingCostColumn, csvRealizedPnLColumn} {
+		if value(column) == "" {
+			continue
+		}
+		*fields[column], err = strconv.ParseFloat(value(column), 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", column, err.Error())
+		}
+	}
+
+	if value(csvEntryColumn) == "" {
+		return nil, fmt.Errorf("missing %s", csvEntryColumn)
+	}
+	err = json.Unmarshal([]byte(value(csvEntryColumn)), &output.Entry)
+	if nil != err {
+		return nil, fmt.Errorf("invalid %s: %s", csvEntryColumn, err.Error())
+	}
+	if value(csvAdjustmentsColumn) != "" {
+		err = json.Unmarshal([]byte(value(csvAdjustmentsColumn)), &output.Adjustments)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvAdjustmentsColumn, err.Error())
+		}
+	}
+	if value(csvExitColumn) != "" {
+		err = json.Unmarshal([]byte(value(csvExitColumn)), &output.Exit)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvExitColumn, err.Error())
+		}
+	}
+	return output, nil
+}
+
+//
+// JSON New Line Loader
+//
+
+func NewJsonNewLineLoader() Loader {
+	return &jsonNewLineLoader{}
+}
+
+func (j jsonNewLineLoader) Read(ctx context.Context, input io.Reader) ([]*Record, error) {
+	logger := ctxzap.Extract(ctx)
+
+	reader := bufio.NewReader(input)
+	output := make([]*Record, 0)
+	for {
+		// Read the rows line by line, the last line may not have a trailing new line
+		data, err := reader.ReadBytes('\n')
+		if nil != err && err != io.EOF {
+			logger.Error("Failed to read line", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			if nil != err {
+				break
+			}
+			continue
+		}
+
+		// Now parse the JSON and add it to the output
+		record := &Record{Adjustments: make([]*orders.Order, 0)}
+		err = json.Unmarshal(data, record)
+		if nil != err {
+			logger.Error("Failed to unmarshal row", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		output = append(output, record)
+	}
+
+	return output, nil
+}
+
+func (j jsonNewLineLoader) Write(ctx context.Context, writer io.Writer, input []*Record) error {
+	logger := ctxzap.Extract(ctx)
+
+	for _, record := range input {
+		// Serialize as json
+		data, err := json.Marshal(record)
+		if nil != err {
+			logger.Error("Failed to marshal row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		// Write the record
+		_, err = writer.Write(data)
+		if nil != err {
+			logger.Error("Failed to write line", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		// Now write the delimiter
+		_, err = writer.Write([]byte("\n"))
+		if nil != err {
+			logger.Error("Failed to write line", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	return nil
+}
+
+//
+// Avro Loader
+//
+// Records are written as an avro object container file, which embeds the schema it was written with,
+// so older files are still readable after new fields are added to `schema.avro` (as long as they have a default).
+//
+
+// NewAvroLoader creates a Loader for deflate compressed avro files
+func NewAvroLoader() Loader {
+	return NewAvroFileLoader(avro_file.DefaultOptions())
+}
+
+// NewAvroFileLoader creates a Loader for avro files with the given compression and block length
+func NewAvroFileLoader(options avro_file.Options) Loader {
+	return &avroLoader{options: options.WithDefaults()}
+}
+
+func (a avroLoader) Read(ctx context.Context, input io.Reader) ([]*Record, error) {
+	logger := ctxzap.Extract(ctx)
+
+	decoder, err := avro_file.NewDecoder(input, avroSchema)
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, err
+	}
+
+	output := make([]*Record, 0)
+	for {
+		record := &Record{}
+		err := decoder.Decode(record)
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			logger.Error("Failed to unmarshal row", zap.Error(err))
+			if _, ok := status.FromError(err); !ok {
+				err = status.Error(codes.Internal, err.Error())
+			}
+			return nil, err
+		}
+		// Empty arrays are decoded as nil slices
+		if nil == record.Adjustments {
+			record.Adjustments = make([]*orders.Order, 0)
+		}
+		output = append(output, record)
+	}
+	return output, nil
+}
+
+func (a avroLoader) Write(ctx context.Context, output io.Writer, input []*Record) error {
+	logger := ctxzap.Extract(ctx)
+
+	encoder, err := avro_file.NewEncoder(output, avroSchema, a.options)
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return err
+	}
+	for _, record := range input {
+		err := encoder.Encode(record)
+		if nil != err {
+			logger.Error("Failed to marshal row", zap.Error(err))
+			return err
+		}
+	}
+
+	// Flush the last block
+	err = encoder.Close()
+	if nil != err {
+		logger.Error("Failed to write block", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+//
+// Proto Loader
+//
+
+func NewProtoLoader() Loader {
+	return &protoLoader{}
+}
+
+func (a protoLoader) Read(ctx context.Context, input io.Reader) ([]*Record, error) {
+	logger := ctxzap.Extract(ctx)
+
+	data, err := ioutil.ReadAll(input)
+	if nil != err {
+		logger.Error("Failed to read all rows", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	messages := &pb.TradeRecords{}
+	err = proto.Unmarshal(data, messages)
+	if nil != err {
+		logger.Error("Failed to unmarshal rows", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	output := make([]*Record, 0, len(messages.Records))
+	for _, message := range messages.Records {
+		output = append(output, fromProto(message))
+	}
+	return output, nil
+}
+
+func (a protoLoader) Write(ctx context.Context, output io.Writer, input []*Record) error {
+	logger := ctxzap.Extract(ctx)
+
+	messages := make([]*pb.TradeRecord, 0, len(input))
+	for _, record := range input {
+		messages = append(messages, toProto(record))
+	}
+
+	data, err := proto.Marshal(&pb.TradeRecords{Records: messages})
+	if nil != err {
+		logger.Error("Failed to marshal rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	_, err = io.Copy(output, bytes.NewReader(data))
+	if nil != err {
+		logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
+
+func fromProto(message *pb.TradeRecord) *Record {
+	output := &Record{
+		Adjustments: make([]*orders.Order, 0, len(message.Adjustments)),
+		HoldingBars: message.HoldingBars,
+		Fees:        message.Fees,
+		HoldingCost: message.HoldingCost,
+		RealizedPnL: message.RealizedPnl,
+	}
+	if nil != message.Entry {
+		output.Entry = orders.FromProto(message.Entry)
+	}
+	for _, adjustment := range message.Adjustments {
+		output.Adjustments = append(output.Adjustments, orders.FromProto(adjustment))
+	}
+	if nil != message.Exit {
+		output.Exit = orders.FromProto(message.Exit)
+	}
+	return output
+}
+
+func toProto(record *Record) *pb.TradeRecord {
+	output := &pb.TradeRecord{
+		Adjustments: make([]*pb.Order, 0, len(record.Adjustments)),
+		HoldingBars: record.HoldingBars,
+		Fees:        record.Fees,
+		HoldingCost: record.HoldingCost,
+		RealizedPnl: record.RealizedPnL,
+	}
+	if nil != record.Entry {
+		output.Entry = orders.ToProto(record.Entry)
+	}
+	for _, adjustment := range record.Adjustments {
+		output.Adjustments = append(output.Adjustments, orders.ToProto(adjustment))
+	}
+	if nil != record.Exit {
+		output.Exit = orders.ToProto(record.Exit)
+	}
+	return output
+}
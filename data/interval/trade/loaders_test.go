@@ -0,0 +1,87 @@
+package trade
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	closed := newCoveredCall(t, now, nil)
+	adjustment := orders.NewOrder(now.Add(time_series.Day), orders.NewStockOrderItem(constants.Buy, "ABC", 100, 12))
+	adjustment.ClientTag = "add, with a comma"
+	require.NoError(t, closed.Adjust(adjustment))
+	require.NoError(t, closed.AddBar())
+	require.NoError(t, closed.Close(orders.NewOrder(
+		now.Add(2*time_series.Day),
+		orders.NewStockOrderItem(constants.Sell, "ABC", 200, 13),
+		orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 1, 0.5),
+	)))
+
+	// Open trades don't have an exit
+	open := newCoveredCall(t, now.Add(3*time_series.Day), nil)
+	records := []*Record{closed.Record(), open.Record()}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":           NewCSVLoader(),
+		"JSON New Line": NewJsonNewLineLoader(),
+		"Avro":          NewAvroLoader(),
+		"Proto":         NewProtoLoader(),
+	}
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, records)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, output, records)
+		})
+	}
+
+	t.Run("Special floats", func(t *testing.T) {
+		record := open.Record()
+		record.Fees = math.NaN()
+		record.RealizedPnL = math.Inf(-1)
+		for name, loader := range loaders {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, []*Record{record})
+			require.NoError(t, err, name)
+
+			output, err := loader.Read(ctx, buff)
+			require.NoError(t, err, name)
+			require.Len(t, output, 1, name)
+			require.True(t, math.IsNaN(output[0].Fees), name)
+			require.True(t, math.IsInf(output[0].RealizedPnL, -1), name)
+			require.Equal(t, output[0].Entry, record.Entry, name)
+		}
+	})
+
+	t.Run("CSV errors", func(t *testing.T) {
+		tests := map[string]string{
+			"Missing column": "holding_bars,fees,holding_cost,realized_pnl,entry,adjustments\n",
+			"Missing entry":  "holding_bars,fees,holding_cost,realized_pnl,entry,adjustments,exit\n1,0,0,0,,[],\n",
+			"Invalid fees":   "holding_bars,fees,holding_cost,realized_pnl,entry,adjustments,exit\n1,abc,0,0,{},[],\n",
+			"Invalid exit":   "holding_bars,fees,holding_cost,realized_pnl,entry,adjustments,exit\n1,0,0,0,{},[],{\n",
+		}
+		for name, input := range tests {
+			_, err := NewCSVLoader().Read(ctx, strings.NewReader(input))
+			require.Equal(t, status.Code(err), codes.InvalidArgument, name)
+		}
+	})
+}
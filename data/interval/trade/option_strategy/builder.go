@@ -0,0 +1,161 @@
+package option_strategy
+
+import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Builder creates the orders for common option strategies on a single underlying.
+//
+// Each leg is priced with the Quote, and the quantity is the number of contracts of each leg:
+// 1. Vertical - buy one strike and sell another of the same right, ex: a bull call spread
+// 2. Straddle - buy (or sell) a call and a put at the same strike
+// 3. Strangle - buy (or sell) a put at a lower strike and a call at a higher strike
+// 4. IronCondor - sell a put spread and a call spread around the price, for a credit
+// 5. Calendar - sell the near expiration and buy the far expiration at the same strike
+//
+type Builder struct {
+	// Underlying symbol of the options, ex: "AAPL"
+	Underlying string `csv:"underlying" avro:"underlying" json:"underlying"`
+	// Multiplier of each contract, usually orders.DefaultOptionMultiplier
+	Multiplier float64 `csv:"multiplier" avro:"multiplier" json:"multiplier"`
+	// Style of each contract
+	Style constants.OptionStyle `csv:"style" avro:"style" json:"style"`
+	// Quote prices each leg
+	Quote Quote `csv:"-" avro:"-" json:"-"`
+}
+
+// NewBuilder creates a Builder for standard American contracts of 100 shares
+func NewBuilder(underlying string, quote Quote) *Builder {
+	return &Builder{
+		Underlying: underlying,
+		Multiplier: orders.DefaultOptionMultiplier,
+		Style:      constants.American,
+		Quote:      quote,
+	}
+}
+
+// Vertical buys the long strike and sells the short strike, both with the same right and expiration.
+// A lower long strike is a bull call spread or a bull put spread, and a higher long strike is a bear spread.
+//
+// Errors:
+// - If the strikes are the same, or any of the arguments are invalid an error with GRPC status InvalidArgument will be returned
+// - If any of the legs can't be quoted the error of the quote will be returned
+//
+func (b *Builder) Vertical(t time.Time, expiration time.Time, right constants.OptionRight, longStrike, shortStrike, quantity float64) (*Strategy, error) {
+	if longStrike == shortStrike {
+		return nil, status.Error(codes.InvalidArgument, "vertical spreads need two different strikes")
+	}
+	return b.build(
+		t,
+		VerticalStrategy,
+		quantity,
+		b.leg(constants.Buy, expiration, right, longStrike),
+		b.leg(constants.Sell, expiration, right, shortStrike),
+	)
+}
+
+// Straddle buys a call and a put at the same strike, or sells them when the direction is Sell
+//
+// Errors:
+// - See Vertical
+//
+func (b *Builder) Straddle(t time.Time, expiration time.Time, direction constants.Direction, strike, quantity float64) (*Strategy, error) {
+	return b.build(
+		t,
+		StraddleStrategy,
+		quantity,
+		b.leg(direction, expiration, constants.Put, strike),
+		b.leg(direction, expiration, constants.Call, strike),
+	)
+}
+
+// Strangle buys a put at the put strike and a call at the higher call strike, or sells them when the direction is Sell
+//
+// Errors:
+// - If the put strike isn't below the call strike an error with GRPC status InvalidArgument will be returned
+// - See Vertical
+//
+func (b *Builder) Strangle(t time.Time, expiration time.Time, direction constants.Direction, putStrike, callStrike, quantity float64) (*Strategy, error) {
+	if putStrike >= callStrike {
+		return nil, status.Error(codes.InvalidArgument, "strangles need the put strike below the call strike")
+	}
+	return b.build(
+		t,
+		StrangleStrategy,
+		quantity,
+		b.leg(direction, expiration, constants.Put, putStrike),
+		b.leg(direction, expiration, constants.Call, callStrike),
+	)
+}
+
+// IronCondor sells a put spread and a call spread, the strikes are from lowest to highest:
+// 1. longPut is bought to limit the loss below the short put
+// 2. shortPut is sold
+// 3. shortCall is sold
+// 4. longCall is bought to limit the loss above the short call
+//
+// Errors:
+// - If the strikes aren't in order from lowest to highest an error with GRPC status InvalidArgument will be returned
+// - See Vertical
+//
+func (b *Builder) IronCondor(t time.Time, expiration time.Time, longPut, shortPut, shortCall, longCall, quantity float64) (*Strategy, error) {
+	if !(longPut < shortPut && shortPut <= shortCall && shortCall < longCall) {
+		return nil, status.Error(codes.InvalidArgument, "iron condor strikes must go from the long put up to the long call")
+	}
+	return b.build(
+		t,
+		IronCondorStrategy,
+		quantity,
+		b.leg(constants.Buy, expiration, constants.Put, longPut),
+		b.leg(constants.Sell, expiration, constants.Put, shortPut),
+		b.leg(constants.Sell, expiration, constants.Call, shortCall),
+		b.leg(constants.Buy, expiration, constants.Call, longCall),
+	)
+}
+
+// Calendar sells the near expiration and buys the far expiration, both at the same strike and right.
+// The legs expire on different days, so it can't be analyzed without a pricing model, see Strategy.Analyze.
+//
+// Errors:
+// - If the near expiration isn't before the far expiration an error with GRPC status InvalidArgument will be returned
+// - See Vertical
+//
+func (b *Builder) Calendar(t time.Time, nearExpiration, farExpiration time.Time, right constants.OptionRight, strike, quantity float64) (*Strategy, error) {
+	near := b.leg(constants.Sell, nearExpiration, right, strike)
+	far := b.leg(constants.Buy, farExpiration, right, strike)
+	if near.Option.Expiration >= far.Option.Expiration {
+		return nil, status.Error(codes.InvalidArgument, "calendar spreads need the near expiration before the far expiration")
+	}
+	return b.build(t, CalendarStrategy, quantity, near, far)
+}
+
+// leg creates a single contract of the option, without a price
+func (b *Builder) leg(direction constants.Direction, expiration time.Time, right constants.OptionRight, strike float64) *orders.OrderItem {
+	contract := orders.NewOptionContract(b.Underlying, expiration, right, strike)
+	contract.Style = b.Style
+	contract.Multiplier = b.Multiplier
+	return orders.NewOptionContractOrderItem(direction, contract, 1, 0)
+}
+
+// build validates and prices the legs, and creates the order for the quantity of each leg
+func (b *Builder) build(t time.Time, name string, quantity float64, legs ...*orders.OrderItem) (*Strategy, error) {
+	if !(quantity > 0) {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+	for _, leg := range legs {
+		err := leg.Option.Validate()
+		if nil != err {
+			return nil, err
+		}
+		leg.Amount = quantity
+		leg.Price, err = b.Quote(leg)
+		if nil != err {
+			return nil, err
+		}
+	}
+	return NewStrategy(name, orders.NewOrder(t, legs...)), nil
+}
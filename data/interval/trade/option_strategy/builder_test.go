@@ -0,0 +1,147 @@
+package option_strategy
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testPrices are the prices of ABC's options on December 1st, 2022, with ABC at $100
+var testPrices = map[string]float64{
+	"P1216@90": 1, "P1216@95": 2, "P1216@100": 4,
+	"C1216@95": 7, "C1216@100": 5, "C1216@105": 2, "C1216@110": 1,
+	"C1230@100": 6.5,
+	"ABC":       100,
+}
+
+// newQuote prices each option by it's right, expiration, and strike, ex: "C1216@105" is the December 16th $105 call
+func newQuote(prices map[string]float64) Quote {
+	return func(item *orders.OrderItem) (float64, error) {
+		if item.ItemType == constants.Stock {
+			return prices[item.Symbol], nil
+		}
+		key := "C"
+		if item.Option.Right == constants.Put {
+			key = "P"
+		}
+		key += time.Unix(item.Option.Expiration, 0).UTC().Format("0102")
+		key += "@" + strconv.FormatFloat(item.Option.Strike, 'f', -1, 64)
+		price, ok := prices[key]
+		if !ok {
+			return 0, status.Errorf(codes.NotFound, "no quote for %s", key)
+		}
+		return price, nil
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	// December 16th, 2022
+	expiration := now.Add(15 * time_series.Day)
+	// December 30th, 2022
+	farExpiration := now.Add(29 * time_series.Day)
+
+	builder := NewBuilder("ABC", newQuote(testPrices))
+
+	t.Run("Vertical", func(t *testing.T) {
+		strategy, err := builder.Vertical(now, expiration, constants.Call, 95, 105, 2)
+		require.NoError(t, err)
+		require.Equal(t, strategy.Name, VerticalStrategy)
+		require.Equal(t, strategy.Order.UnixTime, now.Unix())
+		require.Len(t, strategy.Order.OrderItems, 2)
+
+		long, short := strategy.Order.OrderItems[0], strategy.Order.OrderItems[1]
+		require.Equal(t, long.Direction, constants.Buy)
+		require.Equal(t, long.Symbol, "ABC   221216C00095000")
+		require.Equal(t, long.Amount, 2.0)
+		require.Equal(t, long.Price, 7.0)
+		require.Equal(t, short.Direction, constants.Sell)
+		require.Equal(t, short.Option.Strike, 105.0)
+		require.Equal(t, short.Price, 2.0)
+		require.Equal(t, strategy.NetPrice(), -1000.0)
+		require.False(t, strategy.IsCredit())
+
+		_, err = builder.Vertical(now, expiration, constants.Call, 95, 95, 1)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Straddle", func(t *testing.T) {
+		strategy, err := builder.Straddle(now, expiration, constants.Sell, 100, 1)
+		require.NoError(t, err)
+		require.Equal(t, strategy.Name, StraddleStrategy)
+		for _, item := range strategy.Order.OrderItems {
+			require.Equal(t, item.Direction, constants.Sell)
+			require.Equal(t, item.Option.Strike, 100.0)
+		}
+		require.Equal(t, strategy.Order.OrderItems[0].Option.Right, constants.Put)
+		require.Equal(t, strategy.Order.OrderItems[1].Option.Right, constants.Call)
+		require.Equal(t, strategy.NetPrice(), 900.0)
+		require.True(t, strategy.IsCredit())
+	})
+
+	t.Run("Strangle", func(t *testing.T) {
+		strategy, err := builder.Strangle(now, expiration, constants.Buy, 95, 105, 1)
+		require.NoError(t, err)
+		require.Equal(t, strategy.NetPrice(), -400.0)
+
+		_, err = builder.Strangle(now, expiration, constants.Buy, 105, 95, 1)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Iron condor", func(t *testing.T) {
+		strategy, err := builder.IronCondor(now, expiration, 90, 95, 105, 110, 1)
+		require.NoError(t, err)
+		require.Equal(t, strategy.Name, IronCondorStrategy)
+		directions := make([]constants.Direction, 0)
+		for _, item := range strategy.Order.OrderItems {
+			directions = append(directions, item.Direction)
+		}
+		require.Equal(t, directions, []constants.Direction{constants.Buy, constants.Sell, constants.Sell, constants.Buy})
+		require.Equal(t, strategy.NetPrice(), 200.0)
+
+		_, err = builder.IronCondor(now, expiration, 95, 90, 105, 110, 1)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Calendar", func(t *testing.T) {
+		strategy, err := builder.Calendar(now, expiration, farExpiration, constants.Call, 100, 1)
+		require.NoError(t, err)
+		require.Equal(t, strategy.Order.OrderItems[0].Direction, constants.Sell)
+		require.Equal(t, strategy.Order.OrderItems[0].Option.ExpirationTime(), expiration)
+		require.Equal(t, strategy.Order.OrderItems[1].Direction, constants.Buy)
+		require.Equal(t, strategy.Order.OrderItems[1].Option.ExpirationTime(), farExpiration)
+		require.Equal(t, strategy.NetPrice(), -150.0)
+
+		_, err = builder.Calendar(now, farExpiration, expiration, constants.Call, 100, 1)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Contracts", func(t *testing.T) {
+		builder := NewBuilder("ABC", builder.Quote)
+		builder.Multiplier = 10
+		builder.Style = constants.European
+		strategy, err := builder.Vertical(now, expiration, constants.Put, 100, 95, 1)
+		require.NoError(t, err)
+		for _, item := range strategy.Order.OrderItems {
+			require.Equal(t, item.QuantityPerAmount, 10.0)
+			require.Equal(t, item.Option.Style, constants.European)
+		}
+		require.Equal(t, strategy.NetPrice(), -20.0)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := builder.Vertical(now, expiration, constants.Call, 95, 105, 0)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = builder.Vertical(now, expiration, constants.Call, -5, 105, 1)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = builder.Vertical(now, expiration, constants.Call, 95, 120, 1)
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+}
@@ -0,0 +1,143 @@
+package option_strategy
+
+import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sort"
+)
+
+// Analysis is the profit and loss of a strategy that is held until it expires
+type Analysis struct {
+	// NetPrice is the premium received (positive) or paid (negative) to open the strategy
+	NetPrice float64 `csv:"net_price" avro:"net_price" json:"net_price"`
+	// MaxProfit is the most the strategy can make, +Inf when it's unlimited
+	MaxProfit float64 `csv:"max_profit" avro:"max_profit" json:"max_profit"`
+	// MaxLoss is the most the strategy can lose as a negative number, -Inf when it's unlimited
+	MaxLoss float64 `csv:"max_loss" avro:"max_loss" json:"max_loss"`
+	// Breakevens are the prices of the underlying where the strategy neither makes nor loses money, lowest first
+	Breakevens []float64 `csv:"breakevens" avro:"breakevens" json:"breakevens"`
+}
+
+// Payoff is the profit (positive) or loss (negative) of the strategy when it expires with the underlying at the price.
+// Stock legs are valued at the price, and option legs at their intrinsic value.
+//
+// Errors:
+// - If the legs can't be valued at expiration an error with GRPC status FailedPrecondition will be returned, see expiration
+// - If a leg isn't a stock or an option an error with GRPC status InvalidArgument will be returned
+//
+func (s *Strategy) Payoff(price float64) (float64, error) {
+	_, err := s.expiration()
+	if nil != err {
+		return 0, err
+	}
+	return s.payoff(price)
+}
+
+func (s *Strategy) payoff(price float64) (float64, error) {
+	output := s.NetPrice()
+	for _, item := range s.Order.OrderItems {
+		value := 0.0
+		switch item.ItemType {
+		case constants.Stock:
+			value = price
+		case constants.Option:
+			value = item.Option.IntrinsicValue(price)
+		default:
+			return 0, status.Errorf(codes.InvalidArgument, "can't value %s legs at expiration", item.ItemType)
+		}
+		value *= item.Amount * item.QuantityPerAmount
+		if item.Direction == constants.Sell {
+			value *= -1
+		}
+		output += value
+	}
+	return output, nil
+}
+
+// Analyze finds the max profit, max loss, and breakevens of the strategy at expiration.
+//
+// The payoff is a straight line between the strikes, so it only needs to be checked at 0, at each strike,
+// and the slope above the highest strike to know if the profit or loss is unlimited.
+//
+// Errors:
+// - See Payoff
+//
+func (s *Strategy) Analyze() (*Analysis, error) {
+	_, err := s.expiration()
+	if nil != err {
+		return nil, err
+	}
+
+	// The prices where the payoff can change direction, lowest first
+	prices := []float64{0}
+	for _, item := range s.Order.OrderItems {
+		if nil != item.Option {
+			prices = append(prices, item.Option.Strike)
+		}
+	}
+	sort.Float64s(prices)
+
+	payoffs := make([]float64, 0, len(prices))
+	for _, price := range prices {
+		payoff, err := s.payoff(price)
+		if nil != err {
+			return nil, err
+		}
+		payoffs = append(payoffs, round(payoff))
+	}
+	last := prices[len(prices)-1]
+	above, err := s.payoff(last + 1)
+	if nil != err {
+		return nil, err
+	}
+	slope := round(above - payoffs[len(payoffs)-1])
+
+	output := &Analysis{
+		NetPrice:   s.NetPrice(),
+		MaxProfit:  math.Inf(-1),
+		MaxLoss:    math.Inf(1),
+		Breakevens: make([]float64, 0),
+	}
+	for index, payoff := range payoffs {
+		output.MaxProfit = math.Max(output.MaxProfit, payoff)
+		output.MaxLoss = math.Min(output.MaxLoss, payoff)
+
+		if payoff == 0 {
+			output.Breakevens = appendBreakeven(output.Breakevens, prices[index])
+		}
+		if index > 0 && payoffs[index-1]*payoff < 0 {
+			previous := prices[index-1]
+			breakeven := previous - payoffs[index-1]*(prices[index]-previous)/(payoff-payoffs[index-1])
+			output.Breakevens = appendBreakeven(output.Breakevens, breakeven)
+		}
+	}
+
+	// Above the highest strike the payoff keeps going in the same direction forever
+	final := payoffs[len(payoffs)-1]
+	if slope > 0 {
+		output.MaxProfit = math.Inf(1)
+	}
+	if slope < 0 {
+		output.MaxLoss = math.Inf(-1)
+	}
+	if (final < 0 && slope > 0) || (final > 0 && slope < 0) {
+		output.Breakevens = appendBreakeven(output.Breakevens, last-final/slope)
+	}
+	return output, nil
+}
+
+// appendBreakeven adds the price, unless it was just added from the other side of a strike
+func appendBreakeven(breakevens []float64, price float64) []float64 {
+	price = round(price)
+	if len(breakevens) > 0 && breakevens[len(breakevens)-1] == price {
+		return breakevens
+	}
+	return append(breakevens, price)
+}
+
+// round removes the floating point noise from adding up the premiums, ex: 99.99999999999 is 100
+func round(value float64) float64 {
+	return math.Round(value*1e6) / 1e6
+}
@@ -0,0 +1,95 @@
+package option_strategy
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	// December 16th, 2022
+	expiration := now.Add(15 * time_series.Day)
+	builder := NewBuilder("ABC", newQuote(testPrices))
+
+	newStrategy := func(strategy *Strategy, err error) *Strategy {
+		require.NoError(t, err)
+		return strategy
+	}
+	coveredCall := NewStrategy("covered_call", orders.NewOrder(
+		now,
+		orders.NewStockOrderItem(constants.Buy, "ABC", 100, 100),
+		orders.NewOptionContractOrderItem(constants.Sell, orders.NewOptionContract("ABC", expiration, constants.Call, 105), 1, 2),
+	))
+
+	type args struct {
+		strategy *Strategy
+		analysis *Analysis
+	}
+	tests := map[string]args{
+		"Bull call spread": {
+			strategy: newStrategy(builder.Vertical(now, expiration, constants.Call, 95, 105, 1)),
+			analysis: &Analysis{NetPrice: -500, MaxProfit: 500, MaxLoss: -500, Breakevens: []float64{100}},
+		},
+		"Bear put spread": {
+			strategy: newStrategy(builder.Vertical(now, expiration, constants.Put, 100, 90, 1)),
+			analysis: &Analysis{NetPrice: -300, MaxProfit: 700, MaxLoss: -300, Breakevens: []float64{97}},
+		},
+		"Long straddle": {
+			strategy: newStrategy(builder.Straddle(now, expiration, constants.Buy, 100, 1)),
+			analysis: &Analysis{NetPrice: -900, MaxProfit: math.Inf(1), MaxLoss: -900, Breakevens: []float64{91, 109}},
+		},
+		"Short strangle": {
+			strategy: newStrategy(builder.Strangle(now, expiration, constants.Sell, 95, 105, 2)),
+			analysis: &Analysis{NetPrice: 800, MaxProfit: 800, MaxLoss: math.Inf(-1), Breakevens: []float64{91, 109}},
+		},
+		"Iron condor": {
+			strategy: newStrategy(builder.IronCondor(now, expiration, 90, 95, 105, 110, 1)),
+			analysis: &Analysis{NetPrice: 200, MaxProfit: 200, MaxLoss: -300, Breakevens: []float64{93, 107}},
+		},
+		"Covered call": {
+			strategy: coveredCall,
+			analysis: &Analysis{NetPrice: -9800, MaxProfit: 700, MaxLoss: -9800, Breakevens: []float64{98}},
+		},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			analysis, err := test.strategy.Analyze()
+			require.NoError(t, err)
+			require.Equal(t, analysis, test.analysis)
+		})
+	}
+
+	t.Run("Payoff", func(t *testing.T) {
+		strategy := tests["Iron condor"].strategy
+		for price, expected := range map[float64]float64{0: -300, 92: -100, 93: 0, 100: 200, 106: 100, 200: -300} {
+			payoff, err := strategy.Payoff(price)
+			require.NoError(t, err)
+			require.InDelta(t, payoff, expected, 1e-9, price)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		calendar := newStrategy(builder.Calendar(now, expiration, expiration.Add(14*time_series.Day), constants.Call, 100, 1))
+		_, err := calendar.Analyze()
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+		_, err = calendar.Payoff(100)
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+		withoutContract := NewStrategy("", orders.NewOrder(now, orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1)))
+		_, err = withoutContract.Analyze()
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+		crypto := NewStrategy("", orders.NewOrder(now, orders.NewCryptoOrderItem(constants.Buy, "BTC", 1, 17000)))
+		_, err = crypto.Analyze()
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
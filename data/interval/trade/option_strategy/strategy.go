@@ -0,0 +1,102 @@
+package option_strategy
+
+import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Names of the strategies made by the Builder
+const (
+	VerticalStrategy   = "vertical"
+	StraddleStrategy   = "straddle"
+	StrangleStrategy   = "strangle"
+	IronCondorStrategy = "iron_condor"
+	CalendarStrategy   = "calendar"
+)
+
+// Quote is the price per unit of the item, ex: $1.01 per share for an option contract of 100 shares
+type Quote func(item *orders.OrderItem) (float64, error)
+
+// Strategy is a multi-leg order, where each leg is an item of the order
+type Strategy struct {
+	// Name of the strategy, ex: "iron_condor"
+	Name string `csv:"name" avro:"name" json:"name"`
+	// Order with one item per leg
+	Order *orders.Order `csv:"order" avro:"order" json:"order"`
+}
+
+// NewStrategy creates a strategy from an order, so orders made by hand can be analyzed too
+func NewStrategy(name string, order *orders.Order) *Strategy {
+	return &Strategy{Name: name, Order: order}
+}
+
+// NetPrice is the premium received (positive) or paid (negative) to open the strategy, without any fees
+func (s *Strategy) NetPrice() float64 {
+	output := 0.0
+	for _, item := range s.Order.OrderItems {
+		output += item.CalculatePrice(0, 0, 0)
+	}
+	return output
+}
+
+// IsCredit checks if opening the strategy receives more premium than it pays
+func (s *Strategy) IsCredit() bool {
+	return s.NetPrice() > 0
+}
+
+// ClosingOrder creates the order that closes every leg of the strategy, at the quoted price of each leg
+//
+// Errors:
+// - If any of the legs can't be quoted the error of the quote will be returned
+//
+func (s *Strategy) ClosingOrder(t time.Time, quote Quote) (*orders.Order, error) {
+	items := make([]*orders.OrderItem, 0, len(s.Order.OrderItems))
+	for _, item := range s.Order.OrderItems {
+		closing := item.Clone()
+		closing.Direction = opposite(item.Direction)
+		price, err := quote(closing)
+		if nil != err {
+			return nil, err
+		}
+		closing.Price = price
+		items = append(items, closing)
+	}
+	output := orders.NewOrder(t, items...)
+	output.ClientTag = s.Order.ClientTag
+	return output, nil
+}
+
+// opposite is the direction that closes a position opened in the direction
+func opposite(direction constants.Direction) constants.Direction {
+	if direction == constants.Buy {
+		return constants.Sell
+	}
+	return constants.Buy
+}
+
+// expiration is the expiration shared by every option leg of the strategy
+//
+// Errors:
+// - If an option leg doesn't have a contract an error with GRPC status FailedPrecondition will be returned
+// - If the legs expire on different days an error with GRPC status FailedPrecondition will be returned,
+//   since the legs that expire later still have time value that depends on a pricing model
+//
+func (s *Strategy) expiration() (int64, error) {
+	output := int64(0)
+	for _, item := range s.Order.OrderItems {
+		if item.ItemType != constants.Option {
+			continue
+		}
+		if nil == item.Option {
+			return 0, status.Errorf(codes.FailedPrecondition, "option %q doesn't have a contract", item.Symbol)
+		}
+		if output != 0 && output != item.Option.Expiration {
+			return 0, status.Error(codes.FailedPrecondition, "the payoff of legs with different expirations needs a pricing model")
+		}
+		output = item.Option.Expiration
+	}
+	return output, nil
+}
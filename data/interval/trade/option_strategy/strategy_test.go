@@ -0,0 +1,58 @@
+package option_strategy
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestStrategy(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	// December 16th, 2022
+	expiration := now.Add(15 * time_series.Day)
+
+	strategy, err := NewBuilder("ABC", newQuote(testPrices)).IronCondor(now, expiration, 90, 95, 105, 110, 1)
+	require.NoError(t, err)
+	strategy.Order.ClientTag = "weekly condor"
+
+	t.Run("Closing order", func(t *testing.T) {
+		// A week later, everything is worth half as much
+		later := now.Add(7 * time_series.Day)
+		closing, err := strategy.ClosingOrder(later, func(item *orders.OrderItem) (float64, error) {
+			price, err := newQuote(testPrices)(item)
+			return price / 2, err
+		})
+		require.NoError(t, err)
+		require.Equal(t, closing.UnixTime, later.Unix())
+		require.Equal(t, closing.ClientTag, "weekly condor")
+		require.Len(t, closing.OrderItems, 4)
+		for index, item := range closing.OrderItems {
+			opening := strategy.Order.OrderItems[index]
+			require.NotEqual(t, item.Direction, opening.Direction)
+			require.Equal(t, item.Symbol, opening.Symbol)
+			require.Equal(t, item.Option, opening.Option)
+			require.Equal(t, item.Amount, opening.Amount)
+			require.Equal(t, item.Price, opening.Price/2)
+		}
+
+		// Half of the credit is kept
+		closed := NewStrategy(IronCondorStrategy, closing)
+		require.Equal(t, strategy.NetPrice()+closed.NetPrice(), 100.0)
+
+		// The opening order is unchanged
+		require.Equal(t, strategy.Order.OrderItems[0].Direction, constants.Buy)
+	})
+
+	t.Run("Closing order errors", func(t *testing.T) {
+		_, err := strategy.ClosingOrder(now, func(item *orders.OrderItem) (float64, error) {
+			return 0, status.Error(codes.Unavailable, "no quotes")
+		})
+		require.Equal(t, status.Code(err), codes.Unavailable)
+	})
+}
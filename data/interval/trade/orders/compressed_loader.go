@@ -0,0 +1,66 @@
+package orders
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/ta4g/ta4g/data/compression"
+	"go.uber.org/zap"
+	"io"
+)
+
+//
+// Compressed Loader
+//
+// Wraps the streams of another loader, so orders are decompressed and compressed as they are read and written,
+// and the whole file is never held in memory.
+//
+
+// Compile time type assertion
+var _ StreamLoader = &compressedLoader{}
+
+type compressedLoader struct {
+	loader StreamLoader
+	codec  compression.Compression
+}
+
+// NewCompressedLoader wraps any StreamLoader with compression:
+// 1. Inputs are decompressed with the compression detected from their magic bytes, uncompressed inputs are read as-is
+// 2. Outputs are compressed with the given compression, use compression.Uncompressed to write the format as-is
+//
+// Example: NewCompressedLoader(NewAvroLoader(), compression.Zstd) reads and writes ".avro.zst" files
+//
+func NewCompressedLoader(loader StreamLoader, codec compression.Compression) Loader {
+	return NewLoader(&compressedLoader{loader: loader, codec: codec})
+}
+
+func (c compressedLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	logger := ctxzap.Extract(ctx)
+
+	decompressed, _, err := compression.Decompress(input)
+	if nil != err {
+		logger.Error("Failed to decompress input", zap.Error(err))
+		return nil, err
+	}
+	reader, err := c.loader.NewReader(ctx, decompressed)
+	if nil != err {
+		decompressed.Close()
+		return nil, err
+	}
+	return &closingReader{Reader: reader, closer: decompressed}, nil
+}
+
+func (c compressedLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	logger := ctxzap.Extract(ctx)
+
+	compressed, err := compression.NewWriter(output, c.codec)
+	if nil != err {
+		logger.Error("Failed to compress output", zap.Error(err))
+		return nil, err
+	}
+	writer, err := c.loader.NewWriter(ctx, compressed)
+	if nil != err {
+		compressed.Close()
+		return nil, err
+	}
+	return &closingWriter{Writer: writer, closer: compressed}, nil
+}
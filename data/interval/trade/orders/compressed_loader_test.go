@@ -0,0 +1,62 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/compression"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestCompressedLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	orders := make([]*Order, 0)
+	for index := 0; index < 100; index++ {
+		orders = append(orders, NewOrder(
+			now.Add(time.Duration(index)*time_series.Day),
+			NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+			NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+		))
+	}
+	ctx := context.Background()
+
+	codecs := []compression.Compression{
+		compression.Uncompressed,
+		compression.Gzip,
+		compression.Zstd,
+		compression.Snappy,
+		compression.LZ4,
+	}
+	for _, codec := range codecs {
+		codec := codec
+		t.Run(codec.String(), func(t *testing.T) {
+			loader := NewCompressedLoader(NewJsonNewLineLoader(), codec)
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+			if codec != compression.Uncompressed {
+				require.True(t, bytes.HasPrefix(buff.Bytes(), compression.Magic[codec]))
+			}
+
+			output, err := loader.Read(ctx, buff)
+			require.NoError(t, err)
+			require.Len(t, output, len(orders))
+			for index, row := range output {
+				require.Equal(t, row.UnixTime, orders[index].UnixTime)
+				require.Equal(t, row.OrderItems, orders[index].OrderItems)
+			}
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		err := NewCompressedLoader(NewAvroLoader(), compression.Compression(0)).Write(ctx, bytes.NewBuffer([]byte{}), orders)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
@@ -0,0 +1,287 @@
+package orders
+
+import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/json_float"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+)
+
+// Execution describes how an order should be filled.
+// An order without an Execution is a market order, the same as orders before executions were added.
+type Execution struct {
+	// Type of execution, ex: a limit order
+	Type constants.ExecutionType `avro:"type" json:"type"`
+
+	// LimitPrice is the worst price a limit or stop limit order will be filled at
+	LimitPrice float64 `avro:"limit_price" json:"limit_price,omitempty"`
+
+	// StopPrice triggers a stop or stop limit order once the market reaches it.
+	// Trailing stops move their stop price as the market moves in their favour, it's set by the first bar when it's empty.
+	StopPrice float64 `avro:"stop_price" json:"stop_price,omitempty"`
+
+	// TrailingAmount is the distance of a trailing stop from the best price, ex: $1.50
+	TrailingAmount float64 `avro:"trailing_amount" json:"trailing_amount,omitempty"`
+
+	// TrailingPercent is the distance of a trailing stop from the best price as a percent, ex: 5 is 5%
+	TrailingPercent float64 `avro:"trailing_percent" json:"trailing_percent,omitempty"`
+
+	// Triggered is set once a stop limit order reaches it's stop price, and is waiting for it's limit price
+	Triggered bool `avro:"triggered" json:"triggered,omitempty"`
+}
+
+// jsonExecution is the JSON form of an Execution, see json_float.Float
+type jsonExecution struct {
+	Type            *constants.ExecutionType `json:"type"`
+	LimitPrice      *json_float.Float        `json:"limit_price,omitempty"`
+	StopPrice       *json_float.Float        `json:"stop_price,omitempty"`
+	TrailingAmount  *json_float.Float        `json:"trailing_amount,omitempty"`
+	TrailingPercent *json_float.Float        `json:"trailing_percent,omitempty"`
+	Triggered       *bool                    `json:"triggered,omitempty"`
+}
+
+func NewMarketExecution() *Execution {
+	return &Execution{Type: constants.MarketExecution}
+}
+
+func NewLimitExecution(limitPrice float64) *Execution {
+	return &Execution{Type: constants.LimitExecution, LimitPrice: limitPrice}
+}
+
+func NewStopExecution(stopPrice float64) *Execution {
+	return &Execution{Type: constants.StopExecution, StopPrice: stopPrice}
+}
+
+func NewStopLimitExecution(stopPrice, limitPrice float64) *Execution {
+	return &Execution{Type: constants.StopLimitExecution, StopPrice: stopPrice, LimitPrice: limitPrice}
+}
+
+// NewTrailingStopExecution creates a trailing stop that follows the best price by a fixed amount
+func NewTrailingStopExecution(amount float64) *Execution {
+	return &Execution{Type: constants.TrailingStopExecution, TrailingAmount: amount}
+}
+
+// NewTrailingStopPercentExecution creates a trailing stop that follows the best price by a percent, ex: 5 is 5%
+func NewTrailingStopPercentExecution(percent float64) *Execution {
+	return &Execution{Type: constants.TrailingStopExecution, TrailingPercent: percent}
+}
+
+func NewMarketOnOpenExecution() *Execution {
+	return &Execution{Type: constants.MarketOnOpenExecution}
+}
+
+func NewMarketOnCloseExecution() *Execution {
+	return &Execution{Type: constants.MarketOnCloseExecution}
+}
+
+func (e *Execution) Clone() *Execution {
+	if nil == e {
+		return nil
+	}
+	output := *e
+	return &output
+}
+
+func (e *Execution) jsonForm() *jsonExecution {
+	return &jsonExecution{
+		Type:            &e.Type,
+		LimitPrice:      (*json_float.Float)(&e.LimitPrice),
+		StopPrice:       (*json_float.Float)(&e.StopPrice),
+		TrailingAmount:  (*json_float.Float)(&e.TrailingAmount),
+		TrailingPercent: (*json_float.Float)(&e.TrailingPercent),
+		Triggered:       &e.Triggered,
+	}
+}
+
+func (e Execution) MarshalJSON() ([]byte, error) {
+	value := e.jsonForm()
+	// omitempty only leaves out pointers that are nil, so the empty fields are cleared here
+	if e.LimitPrice == 0 {
+		value.LimitPrice = nil
+	}
+	if e.StopPrice == 0 {
+		value.StopPrice = nil
+	}
+	if e.TrailingAmount == 0 {
+		value.TrailingAmount = nil
+	}
+	if e.TrailingPercent == 0 {
+		value.TrailingPercent = nil
+	}
+	if !e.Triggered {
+		value.Triggered = nil
+	}
+	return json.Marshal(value)
+}
+
+func (e *Execution) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, e.jsonForm())
+}
+
+// Validate checks the execution has the prices it's type needs, a nil execution is a valid market order
+//
+// Errors:
+// - If the type is unknown, or a price is missing or negative an error with GRPC status InvalidArgument will be returned
+//
+func (e *Execution) Validate() error {
+	if nil == e {
+		return nil
+	}
+	for _, value := range []float64{e.LimitPrice, e.StopPrice, e.TrailingAmount, e.TrailingPercent} {
+		if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+			return status.Error(codes.InvalidArgument, "execution prices must be positive")
+		}
+	}
+
+	switch e.Type {
+	case constants.MarketExecution, constants.MarketOnOpenExecution, constants.MarketOnCloseExecution:
+		return nil
+	case constants.LimitExecution:
+		if e.LimitPrice == 0 {
+			return status.Error(codes.InvalidArgument, "limit orders need a limit price")
+		}
+	case constants.StopExecution:
+		if e.StopPrice == 0 {
+			return status.Error(codes.InvalidArgument, "stop orders need a stop price")
+		}
+	case constants.StopLimitExecution:
+		if e.StopPrice == 0 || e.LimitPrice == 0 {
+			return status.Error(codes.InvalidArgument, "stop limit orders need a stop price and a limit price")
+		}
+	case constants.TrailingStopExecution:
+		if (e.TrailingAmount == 0) == (e.TrailingPercent == 0) {
+			return status.Error(codes.InvalidArgument, "trailing stops need either a trailing amount or a trailing percent")
+		}
+		if e.TrailingPercent >= 100 {
+			return status.Error(codes.InvalidArgument, "trailing percent must be less than 100")
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown execution type %d", e.Type)
+	}
+	return nil
+}
+
+// Fill decides if an order in the direction is filled during the bar, and at what price.
+// Call it with each bar after the order is placed until it's filled, a nil execution is a market order.
+//
+// Bars only have their open, high, low, and close prices, so the fills are conservative:
+// 1. Market and market on open orders fill at the open, and market on close orders fill at the close
+// 2. Limit orders fill at the open when it gaps through the limit price, otherwise at the limit price when the bar reaches it
+// 3. Stop orders fill at the open when it gaps through the stop price, otherwise at the stop price when the bar reaches it
+// 4. Stop limit orders are triggered like a stop order, and then fill like a limit order.
+//    When the bar triggers the order at a price that's worse than the limit, it isn't filled until a later bar reaches the limit.
+// 5. Trailing stops fill like a stop order, and then move their stop price with the best price of the bar for the next bar.
+//    The best price is the high for selling, and the low for buying.
+//
+// Stop limit and trailing stop orders keep their state within the execution, so they must not be shared between orders.
+func (e *Execution) Fill(direction constants.Direction, b bar.Bar) (float64, bool) {
+	if nil == e {
+		return b.GetOpen(), true
+	}
+
+	switch e.Type {
+	case constants.MarketExecution, constants.MarketOnOpenExecution:
+		return b.GetOpen(), true
+	case constants.MarketOnCloseExecution:
+		return b.GetClose(), true
+	case constants.LimitExecution:
+		return fillLimit(direction, e.LimitPrice, b)
+	case constants.StopExecution:
+		return fillStop(direction, e.StopPrice, b)
+	case constants.StopLimitExecution:
+		return e.fillStopLimit(direction, b)
+	case constants.TrailingStopExecution:
+		return e.fillTrailingStop(direction, b)
+	}
+	return 0, false
+}
+
+func (e *Execution) fillStopLimit(direction constants.Direction, b bar.Bar) (float64, bool) {
+	if e.Triggered {
+		return fillLimit(direction, e.LimitPrice, b)
+	}
+	price, triggered := fillStop(direction, e.StopPrice, b)
+	if !triggered {
+		return 0, false
+	}
+	e.Triggered = true
+
+	// Filled straight away when the price it was triggered at is within the limit
+	if (direction == constants.Buy && price <= e.LimitPrice) || (direction == constants.Sell && price >= e.LimitPrice) {
+		return price, true
+	}
+	return 0, false
+}
+
+func (e *Execution) fillTrailingStop(direction constants.Direction, b bar.Bar) (float64, bool) {
+	if e.StopPrice == 0 {
+		e.StopPrice = e.trail(direction, b.GetOpen())
+	}
+	price, filled := fillStop(direction, e.StopPrice, b)
+	if filled {
+		return price, true
+	}
+
+	// Follow the best price of the bar, the stop only ever moves in our favour
+	if direction == constants.Sell {
+		e.StopPrice = math.Max(e.StopPrice, e.trail(direction, b.GetHigh()))
+	} else {
+		e.StopPrice = math.Min(e.StopPrice, e.trail(direction, b.GetLow()))
+	}
+	return 0, false
+}
+
+// trail is the stop price when the best price is the price
+func (e *Execution) trail(direction constants.Direction, price float64) float64 {
+	distance := e.TrailingAmount
+	if e.TrailingPercent != 0 {
+		distance = price * e.TrailingPercent / 100
+	}
+	if direction == constants.Sell {
+		return price - distance
+	}
+	return price + distance
+}
+
+// fillLimit fills at the limit price or better
+func fillLimit(direction constants.Direction, limitPrice float64, b bar.Bar) (float64, bool) {
+	if direction == constants.Buy {
+		if b.GetOpen() <= limitPrice {
+			return b.GetOpen(), true
+		}
+		if b.GetLow() <= limitPrice {
+			return limitPrice, true
+		}
+		return 0, false
+	}
+	if b.GetOpen() >= limitPrice {
+		return b.GetOpen(), true
+	}
+	if b.GetHigh() >= limitPrice {
+		return limitPrice, true
+	}
+	return 0, false
+}
+
+// fillStop fills once the price reaches the stop price, buying above the market or selling below it
+func fillStop(direction constants.Direction, stopPrice float64, b bar.Bar) (float64, bool) {
+	if direction == constants.Buy {
+		if b.GetOpen() >= stopPrice {
+			return b.GetOpen(), true
+		}
+		if b.GetHigh() >= stopPrice {
+			return stopPrice, true
+		}
+		return 0, false
+	}
+	if b.GetOpen() <= stopPrice {
+		return b.GetOpen(), true
+	}
+	if b.GetLow() <= stopPrice {
+		return stopPrice, true
+	}
+	return 0, false
+}
@@ -0,0 +1,198 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExecutionFill(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	// Opens at 10, trades between 9 and 12, and closes at 11
+	b := bar.New(now, 10, 12, 9, 11, 1000, -1)
+
+	type args struct {
+		execution *Execution
+		direction constants.Direction
+		price     float64
+		filled    bool
+	}
+	tests := map[string]args{
+		"No execution is a market order":  {execution: nil, direction: constants.Buy, price: 10, filled: true},
+		"Market":                          {execution: NewMarketExecution(), direction: constants.Sell, price: 10, filled: true},
+		"Market on open":                  {execution: NewMarketOnOpenExecution(), direction: constants.Buy, price: 10, filled: true},
+		"Market on close":                 {execution: NewMarketOnCloseExecution(), direction: constants.Buy, price: 11, filled: true},
+		"Buy limit reached":               {execution: NewLimitExecution(9.5), direction: constants.Buy, price: 9.5, filled: true},
+		"Buy limit gapped through":        {execution: NewLimitExecution(10.5), direction: constants.Buy, price: 10, filled: true},
+		"Buy limit not reached":           {execution: NewLimitExecution(8), direction: constants.Buy, filled: false},
+		"Sell limit reached":              {execution: NewLimitExecution(11.5), direction: constants.Sell, price: 11.5, filled: true},
+		"Sell limit gapped through":       {execution: NewLimitExecution(9.5), direction: constants.Sell, price: 10, filled: true},
+		"Sell limit not reached":          {execution: NewLimitExecution(13), direction: constants.Sell, filled: false},
+		"Buy stop reached":                {execution: NewStopExecution(11), direction: constants.Buy, price: 11, filled: true},
+		"Buy stop gapped through":         {execution: NewStopExecution(9.5), direction: constants.Buy, price: 10, filled: true},
+		"Buy stop not reached":            {execution: NewStopExecution(13), direction: constants.Buy, filled: false},
+		"Sell stop reached":               {execution: NewStopExecution(9.5), direction: constants.Sell, price: 9.5, filled: true},
+		"Sell stop gapped through":        {execution: NewStopExecution(10.5), direction: constants.Sell, price: 10, filled: true},
+		"Sell stop not reached":           {execution: NewStopExecution(8), direction: constants.Sell, filled: false},
+		"Buy stop limit within the limit": {execution: NewStopLimitExecution(11, 11.5), direction: constants.Buy, price: 11, filled: true},
+		"Buy stop limit past the limit":   {execution: NewStopLimitExecution(11, 10.5), direction: constants.Buy, filled: false},
+		"Sell stop limit within limit":    {execution: NewStopLimitExecution(9.5, 9), direction: constants.Sell, price: 9.5, filled: true},
+		"Sell stop limit not triggered":   {execution: NewStopLimitExecution(8, 7.5), direction: constants.Sell, filled: false},
+		"Sell trailing stop reached":      {execution: NewTrailingStopExecution(0.5), direction: constants.Sell, price: 9.5, filled: true},
+		"Sell trailing stop not reached":  {execution: NewTrailingStopExecution(2), direction: constants.Sell, filled: false},
+		"Buy trailing stop percent":       {execution: NewTrailingStopPercentExecution(10), direction: constants.Buy, price: 11, filled: true},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			price, filled := test.execution.Fill(test.direction, b)
+			require.Equal(t, filled, test.filled)
+			require.Equal(t, price, test.price)
+		})
+	}
+
+	t.Run("Stop limit waits for the limit once triggered", func(t *testing.T) {
+		execution := NewStopLimitExecution(11, 10.5)
+		_, filled := execution.Fill(constants.Buy, b)
+		require.False(t, filled)
+		require.True(t, execution.Triggered)
+
+		// The next bar never goes back above the stop, but reaches the limit
+		price, filled := execution.Fill(constants.Buy, bar.New(now.Add(time_series.Day), 10.8, 10.9, 10.2, 10.4, 1000, -1))
+		require.True(t, filled)
+		require.Equal(t, price, 10.5)
+	})
+
+	t.Run("Trailing stop follows the best price", func(t *testing.T) {
+		execution := NewTrailingStopExecution(2)
+		_, filled := execution.Fill(constants.Sell, b)
+		require.False(t, filled)
+		// The stop starts 2 below the open, and then follows the high of 12
+		require.Equal(t, execution.StopPrice, 10.0)
+
+		// A higher high moves the stop up, but a lower one never moves it down
+		_, filled = execution.Fill(constants.Sell, bar.New(now.Add(time_series.Day), 11, 13, 10.5, 12.5, 1000, -1))
+		require.False(t, filled)
+		require.Equal(t, execution.StopPrice, 11.0)
+		_, filled = execution.Fill(constants.Sell, bar.New(now.Add(2*time_series.Day), 12, 12.5, 11.5, 12, 1000, -1))
+		require.False(t, filled)
+		require.Equal(t, execution.StopPrice, 11.0)
+
+		price, filled := execution.Fill(constants.Sell, bar.New(now.Add(3*time_series.Day), 11.8, 12, 10, 10.5, 1000, -1))
+		require.True(t, filled)
+		require.Equal(t, price, 11.0)
+	})
+}
+
+func TestExecutionValidate(t *testing.T) {
+	var nilExecution *Execution
+	require.NoError(t, nilExecution.Validate())
+
+	valid := []*Execution{
+		NewMarketExecution(),
+		NewMarketOnOpenExecution(),
+		NewMarketOnCloseExecution(),
+		NewLimitExecution(10),
+		NewStopExecution(10),
+		NewStopLimitExecution(10, 10.5),
+		NewTrailingStopExecution(1),
+		NewTrailingStopPercentExecution(5),
+	}
+	for _, execution := range valid {
+		require.NoError(t, execution.Validate(), execution.Type.String())
+	}
+
+	invalid := []*Execution{
+		{},
+		{Type: 42},
+		NewLimitExecution(0),
+		NewLimitExecution(-1),
+		NewLimitExecution(math.NaN()),
+		NewStopExecution(0),
+		NewStopLimitExecution(10, 0),
+		NewTrailingStopExecution(0),
+		NewTrailingStopPercentExecution(100),
+		{Type: constants.TrailingStopExecution, TrailingAmount: 1, TrailingPercent: 5},
+	}
+	for _, execution := range invalid {
+		require.Equal(t, status.Code(execution.Validate()), codes.InvalidArgument, execution)
+	}
+}
+
+func TestExecutionLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	limitOrder := NewOrder(now, NewStockOrderItem(constants.Buy, "ABC", 100, 10.01))
+	limitOrder.Execution = NewLimitExecution(10)
+	trailingOrder := NewOrder(now.Add(time_series.Day), NewStockOrderItem(constants.Sell, "ABC", 100, 10.01))
+	trailingOrder.Execution = &Execution{Type: constants.TrailingStopExecution, StopPrice: 9.5, TrailingPercent: 5}
+	stopLimitOrder := NewOrder(now.Add(2*time_series.Day), NewStockOrderItem(constants.Buy, "XYZ", 5, 11.25))
+	stopLimitOrder.Execution = &Execution{Type: constants.StopLimitExecution, StopPrice: 11, LimitPrice: 11.5, Triggered: true}
+	marketOrder := NewOrder(now.Add(3*time_series.Day), NewStockOrderItem(constants.Buy, "XYZ", 1, 1))
+	orders := []*Order{limitOrder, trailingOrder, stopLimitOrder, marketOrder}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":             NewCSVLoader(),
+		"JSON New Line":   NewJsonNewLineLoader(),
+		"Avro":            NewAvroLoader(),
+		"Proto":           NewProtoLoader(),
+		"Delimited Proto": NewDelimitedProtoLoader(),
+		"Parquet":         NewParquetLoader(parquet_file.DefaultOptions()),
+	}
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(orders))
+			for index, row := range output {
+				require.Equal(t, row.Execution, orders[index].Execution)
+				require.Equal(t, row.OrderItems, orders[index].OrderItems)
+			}
+		})
+	}
+
+	t.Run("Parquet files without executions", func(t *testing.T) {
+		// The schema before executions were added
+		type parquetOrderWithoutExecution struct {
+			Time  int64              `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+			Items []parquetOrderItem `parquet:"name=items, repetitiontype=REPEATED"`
+		}
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := parquet_file.NewWriter(buff, new(parquetOrderWithoutExecution), parquet_file.DefaultOptions())
+		require.NoError(t, err)
+		row := toParquet(marketOrder)
+		require.NoError(t, writer.Write(&parquetOrderWithoutExecution{Time: row.Time, Items: row.Items}))
+		require.NoError(t, writer.WriteStop())
+
+		output, err := NewParquetLoader(parquet_file.DefaultOptions()).Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Nil(t, output[0].Execution)
+		require.Equal(t, output[0].OrderItems, marketOrder.OrderItems)
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		clone := stopLimitOrder.Clone()
+		require.Equal(t, clone.Execution, stopLimitOrder.Execution)
+		clone.Execution.Triggered = false
+		require.True(t, stopLimitOrder.Execution.Triggered)
+	})
+}
@@ -0,0 +1,155 @@
+package orders
+
+import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/json_float"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"strings"
+)
+
+// DefaultForexLotSize is the number of units of the base currency in a standard lot
+const DefaultForexLotSize = 100000
+
+// ForexPair is a currency pair, where the price is how much of the quote currency buys a single unit of the base currency.
+// Ex: EUR/USD at 1.05 costs $1.05 for each euro.
+type ForexPair struct {
+	// Base is the currency being bought or sold, ex: "EUR"
+	Base string `avro:"base" json:"base"`
+	// Quote is the currency the price is in, ex: "USD"
+	Quote string `avro:"quote" json:"quote"`
+	// PipSize is the size of a pip in the quote currency, ex: 0.0001, or 0.01 for the yen
+	PipSize float64 `avro:"pip_size" json:"pip_size"`
+	// LotSize is the number of units of the base currency per lot, usually DefaultForexLotSize
+	LotSize float64 `avro:"lot_size" json:"lot_size"`
+	// MarginRate is the share of the position's value held as margin, ex: 0.02 is 50:1 leverage, 0 is the full value
+	MarginRate float64 `avro:"margin_rate" json:"margin_rate"`
+}
+
+// jsonForexPair is the JSON form of a ForexPair, see json_float.Float
+type jsonForexPair struct {
+	Base       *string           `json:"base"`
+	Quote      *string           `json:"quote"`
+	PipSize    *json_float.Float `json:"pip_size"`
+	LotSize    *json_float.Float `json:"lot_size"`
+	MarginRate *json_float.Float `json:"margin_rate"`
+}
+
+// NewForexPair creates a standard pair, with 0.0001 pips or 0.01 pips when quoted in yen, and standard lots
+func NewForexPair(base, quote string) *ForexPair {
+	base = strings.ToUpper(base)
+	quote = strings.ToUpper(quote)
+	pipSize := 0.0001
+	if quote == "JPY" {
+		pipSize = 0.01
+	}
+	return &ForexPair{
+		Base:    base,
+		Quote:   quote,
+		PipSize: pipSize,
+		LotSize: DefaultForexLotSize,
+	}
+}
+
+// ParseForexSymbol reads a standard pair from it's symbol, ex: "EUR/USD", "EUR.USD", or "EURUSD"
+//
+// Errors:
+// - If the symbol isn't two 3 letter currencies an error with GRPC status InvalidArgument will be returned
+//
+func ParseForexSymbol(symbol string) (*ForexPair, error) {
+	symbol = strings.TrimSpace(symbol)
+	var base, quote string
+	switch {
+	case len(symbol) == 7 && (symbol[3] == '/' || symbol[3] == '.'):
+		base, quote = symbol[:3], symbol[4:]
+	case len(symbol) == 6:
+		base, quote = symbol[:3], symbol[3:]
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid forex symbol %q", symbol)
+	}
+	output := NewForexPair(base, quote)
+	if !isCurrency(output.Base) || !isCurrency(output.Quote) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid forex symbol %q", symbol)
+	}
+	return output, nil
+}
+
+// Symbol is the name of the pair, ex: "EUR/USD"
+func (p *ForexPair) Symbol() string {
+	return p.Base + "/" + p.Quote
+}
+
+// Pips is the number of pips the price moved from one price to another, negative when it went down
+func (p *ForexPair) Pips(from, to float64) float64 {
+	return math.Round((to-from)/p.PipSize*1e6) / 1e6
+}
+
+// PipValue is how much a single pip is worth in the quote currency, for the number of lots
+func (p *ForexPair) PipValue(lots float64) float64 {
+	return lots * p.LotSize * p.PipSize
+}
+
+// Validate checks the pair is two different 3 letter currencies, with a positive pip and lot size, and a margin rate within [0, 1]
+//
+// Errors:
+// - If any of the fields are missing or invalid an error with GRPC status InvalidArgument will be returned
+//
+func (p *ForexPair) Validate() error {
+	if !isCurrency(p.Base) || !isCurrency(p.Quote) {
+		return status.Errorf(codes.InvalidArgument, "invalid forex pair %q", p.Symbol())
+	}
+	if p.Base == p.Quote {
+		return status.Error(codes.InvalidArgument, "forex pairs need two different currencies")
+	}
+	if !(p.PipSize > 0) || math.IsInf(p.PipSize, 0) {
+		return status.Error(codes.InvalidArgument, "forex pip size must be positive")
+	}
+	if !(p.LotSize > 0) || math.IsInf(p.LotSize, 0) {
+		return status.Error(codes.InvalidArgument, "forex lot size must be positive")
+	}
+	if !(p.MarginRate >= 0 && p.MarginRate <= 1) {
+		return status.Error(codes.InvalidArgument, "forex margin rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func (p *ForexPair) jsonForm() *jsonForexPair {
+	return &jsonForexPair{
+		Base:       &p.Base,
+		Quote:      &p.Quote,
+		PipSize:    (*json_float.Float)(&p.PipSize),
+		LotSize:    (*json_float.Float)(&p.LotSize),
+		MarginRate: (*json_float.Float)(&p.MarginRate),
+	}
+}
+
+func (p ForexPair) MarshalJSON() ([]byte, error) {
+	value := p.jsonForm()
+	return json.Marshal(value)
+}
+
+func (p *ForexPair) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, p.jsonForm())
+}
+
+func (p *ForexPair) Clone() *ForexPair {
+	if nil == p {
+		return nil
+	}
+	output := *p
+	return &output
+}
+
+// isCurrency checks the code is 3 upper case letters, ex: "USD"
+func isCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, letter := range code {
+		if letter < 'A' || letter > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package orders
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestForexPair(t *testing.T) {
+	t.Run("New", func(t *testing.T) {
+		pair := NewForexPair("eur", "usd")
+		require.Equal(t, pair, &ForexPair{Base: "EUR", Quote: "USD", PipSize: 0.0001, LotSize: DefaultForexLotSize})
+		require.Equal(t, pair.Symbol(), "EUR/USD")
+		require.NoError(t, pair.Validate())
+
+		// Pairs quoted in yen have bigger pips
+		require.Equal(t, NewForexPair("USD", "JPY").PipSize, 0.01)
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		for _, symbol := range []string{"EUR/USD", "EUR.USD", "EURUSD", " eurusd "} {
+			pair, err := ParseForexSymbol(symbol)
+			require.NoError(t, err, symbol)
+			require.Equal(t, pair, NewForexPair("EUR", "USD"), symbol)
+		}
+		for _, symbol := range []string{"", "EUR", "EUR-USD", "EUR/US", "EUR/USDX", "EU1USD"} {
+			_, err := ParseForexSymbol(symbol)
+			require.Equal(t, status.Code(err), codes.InvalidArgument, symbol)
+		}
+	})
+
+	t.Run("Pips", func(t *testing.T) {
+		pair := NewForexPair("EUR", "USD")
+		require.Equal(t, pair.Pips(1.05, 1.0525), 25.0)
+		require.Equal(t, pair.Pips(1.05, 1.0499), -1.0)
+		require.InDelta(t, pair.PipValue(1), 10, 1e-9)
+		require.InDelta(t, NewForexPair("USD", "JPY").PipValue(0.1), 100, 1e-9)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		tests := map[string]func(p *ForexPair){
+			"Base":        func(p *ForexPair) { p.Base = "EU" },
+			"Quote":       func(p *ForexPair) { p.Quote = "usd" },
+			"Same":        func(p *ForexPair) { p.Quote = p.Base },
+			"Pip size":    func(p *ForexPair) { p.PipSize = 0 },
+			"Lot size":    func(p *ForexPair) { p.LotSize = -1 },
+			"Margin rate": func(p *ForexPair) { p.MarginRate = 1.5 },
+		}
+		for name, change := range tests {
+			pair := NewForexPair("EUR", "USD")
+			change(pair)
+			require.Equal(t, status.Code(pair.Validate()), codes.InvalidArgument, name)
+		}
+	})
+
+	t.Run("Order items", func(t *testing.T) {
+		pair := NewForexPair("EUR", "USD")
+		pair.MarginRate = 0.02
+		item := NewForexOrderItem(constants.Buy, pair, 2, 1.05)
+		require.Equal(t, item.ItemType, constants.Forex)
+		require.Equal(t, item.Symbol, "EUR/USD")
+		require.Equal(t, item.QuantityPerAmount, 100000.0)
+
+		// The per-unit fee is per lot
+		require.InDelta(t, item.CalculatePrice(0, 0, 3), -210006, 1e-6)
+		require.InDelta(t, item.MarginRequirement(), 4200, 1e-6)
+
+		clone := item.Clone()
+		require.Equal(t, clone, item)
+		clone.Forex.MarginRate = 0.05
+		require.Equal(t, item.Forex.MarginRate, 0.02)
+
+		// Without a margin rate the margin is the full value
+		item.Forex.MarginRate = 0
+		require.InDelta(t, item.MarginRequirement(), 210000, 1e-6)
+	})
+}
@@ -0,0 +1,148 @@
+package orders
+
+import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/json_float"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"time"
+)
+
+// FutureContract is the specification of a single futures contract, ex: the December 2022 E-mini S&P 500
+type FutureContract struct {
+	// Root symbol of the product, ex: "ES"
+	Root string `avro:"root" json:"root"`
+	// TickSize is the smallest change in price, ex: 0.25 points
+	TickSize float64 `avro:"tick_size" json:"tick_size"`
+	// TickValue is how much a single tick is worth per contract, ex: $12.50
+	TickValue float64 `avro:"tick_value" json:"tick_value"`
+	// Multiplier is how much a 1 point move is worth per contract, ex: $50, it's always TickValue / TickSize
+	Multiplier float64 `avro:"multiplier" json:"multiplier"`
+	// InitialMargin is the margin needed per contract to open a position
+	InitialMargin float64 `avro:"initial_margin" json:"initial_margin"`
+	// MaintenanceMargin is the margin needed per contract to keep the position open
+	MaintenanceMargin float64 `avro:"maintenance_margin" json:"maintenance_margin"`
+	// Expiration is the unix time of midnight UTC on the last day the contract trades
+	Expiration int64 `avro:"expiration" json:"expiration"`
+	// RollDate is the unix time positions should move to the next contract, 0 doesn't roll
+	RollDate int64 `avro:"roll_date" json:"roll_date"`
+}
+
+// jsonFutureContract is the JSON form of a FutureContract, see json_float.Float
+type jsonFutureContract struct {
+	Root              *string           `json:"root"`
+	TickSize          *json_float.Float `json:"tick_size"`
+	TickValue         *json_float.Float `json:"tick_value"`
+	Multiplier        *json_float.Float `json:"multiplier"`
+	InitialMargin     *json_float.Float `json:"initial_margin"`
+	MaintenanceMargin *json_float.Float `json:"maintenance_margin"`
+	Expiration        *int64            `json:"expiration"`
+	RollDate          *int64            `json:"roll_date"`
+}
+
+// NewFutureContract creates a contract with the tick size and value, the maintenance margin is the same as the initial margin
+func NewFutureContract(root string, tickSize, tickValue, initialMargin float64, expiration time.Time) *FutureContract {
+	return &FutureContract{
+		Root:              root,
+		TickSize:          tickSize,
+		TickValue:         tickValue,
+		Multiplier:        tickValue / tickSize,
+		InitialMargin:     initialMargin,
+		MaintenanceMargin: initialMargin,
+		Expiration:        expirationDate(expiration).Unix(),
+	}
+}
+
+// ExpirationTime is midnight UTC on the last day the contract trades
+func (c *FutureContract) ExpirationTime() time.Time {
+	return time.Unix(c.Expiration, 0).UTC()
+}
+
+// IsExpired checks if the contract has stopped trading by the time, contracts still trade on the day they expire
+func (c *FutureContract) IsExpired(t time.Time) bool {
+	return !t.Before(c.ExpirationTime().AddDate(0, 0, 1))
+}
+
+// ShouldRoll checks if positions in the contract should have moved to the next contract by the time
+func (c *FutureContract) ShouldRoll(t time.Time) bool {
+	if c.RollDate == 0 {
+		return c.IsExpired(t)
+	}
+	return !t.Before(time.Unix(c.RollDate, 0))
+}
+
+// RoundToTick rounds the price to the nearest tick, ex: 4000.30 is 4000.25 with 0.25 point ticks
+func (c *FutureContract) RoundToTick(price float64) float64 {
+	if !(c.TickSize > 0) {
+		return price
+	}
+	return math.Round(price/c.TickSize) * c.TickSize
+}
+
+// Ticks is the number of ticks the price moved from one price to another, negative when it went down
+func (c *FutureContract) Ticks(from, to float64) float64 {
+	return math.Round((to-from)/c.TickSize*1e6) / 1e6
+}
+
+// Validate checks the contract has a root, positive ticks that match the multiplier, sensible margins, and an expiration
+//
+// Errors:
+// - If any of the fields are missing or invalid an error with GRPC status InvalidArgument will be returned
+//
+func (c *FutureContract) Validate() error {
+	if c.Root == "" {
+		return status.Error(codes.InvalidArgument, "future contracts need a root symbol")
+	}
+	for name, value := range map[string]float64{"tick size": c.TickSize, "tick value": c.TickValue, "multiplier": c.Multiplier} {
+		if !(value > 0) || math.IsInf(value, 0) {
+			return status.Errorf(codes.InvalidArgument, "future %s must be positive", name)
+		}
+	}
+	if math.Abs(c.TickSize*c.Multiplier-c.TickValue) > 1e-9*c.TickValue {
+		return status.Error(codes.InvalidArgument, "future tick value must be the tick size times the multiplier")
+	}
+	if c.InitialMargin < 0 || c.MaintenanceMargin < 0 {
+		return status.Error(codes.InvalidArgument, "future margins can't be negative")
+	}
+	if c.MaintenanceMargin > c.InitialMargin {
+		return status.Error(codes.InvalidArgument, "future maintenance margin can't be more than the initial margin")
+	}
+	if c.Expiration == 0 {
+		return status.Error(codes.InvalidArgument, "future contracts need an expiration")
+	}
+	if c.RollDate > c.Expiration {
+		return status.Error(codes.InvalidArgument, "future contracts must roll before they expire")
+	}
+	return nil
+}
+
+func (c *FutureContract) jsonForm() *jsonFutureContract {
+	return &jsonFutureContract{
+		Root:              &c.Root,
+		TickSize:          (*json_float.Float)(&c.TickSize),
+		TickValue:         (*json_float.Float)(&c.TickValue),
+		Multiplier:        (*json_float.Float)(&c.Multiplier),
+		InitialMargin:     (*json_float.Float)(&c.InitialMargin),
+		MaintenanceMargin: (*json_float.Float)(&c.MaintenanceMargin),
+		Expiration:        &c.Expiration,
+		RollDate:          &c.RollDate,
+	}
+}
+
+func (c FutureContract) MarshalJSON() ([]byte, error) {
+	value := c.jsonForm()
+	return json.Marshal(value)
+}
+
+func (c *FutureContract) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, c.jsonForm())
+}
+
+func (c *FutureContract) Clone() *FutureContract {
+	if nil == c {
+		return nil
+	}
+	output := *c
+	return &output
+}
@@ -0,0 +1,158 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFutureContract(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	// E-mini S&P 500, $12.50 per 0.25 point tick, and $12,000 initial margin per contract
+	newContract := func() *FutureContract {
+		return NewFutureContract("ES", 0.25, 12.5, 12000, now.Add(15*time_series.Day+10*time.Hour))
+	}
+
+	t.Run("New", func(t *testing.T) {
+		contract := newContract()
+		require.Equal(t, contract.Multiplier, 50.0)
+		require.Equal(t, contract.MaintenanceMargin, 12000.0)
+		require.Equal(t, contract.ExpirationTime(), now.Add(15*time_series.Day))
+		require.NoError(t, contract.Validate())
+	})
+
+	t.Run("Ticks", func(t *testing.T) {
+		contract := newContract()
+		require.Equal(t, contract.RoundToTick(4000.3), 4000.25)
+		require.Equal(t, contract.RoundToTick(4000.4), 4000.5)
+		require.Equal(t, contract.Ticks(4000, 4001.25), 5.0)
+		require.Equal(t, contract.Ticks(4000, 3999.5), -2.0)
+	})
+
+	t.Run("Expiration and roll", func(t *testing.T) {
+		contract := newContract()
+		require.False(t, contract.IsExpired(now.Add(15*time_series.Day+23*time.Hour)))
+		require.True(t, contract.IsExpired(now.Add(16*time_series.Day)))
+
+		// Contracts without a roll date roll when they expire
+		require.False(t, contract.ShouldRoll(now.Add(8*time_series.Day)))
+		require.True(t, contract.ShouldRoll(now.Add(16*time_series.Day)))
+		contract.RollDate = now.Add(8 * time_series.Day).Unix()
+		require.False(t, contract.ShouldRoll(now.Add(7*time_series.Day)))
+		require.True(t, contract.ShouldRoll(now.Add(8*time_series.Day)))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		tests := map[string]func(c *FutureContract){
+			"Root":               func(c *FutureContract) { c.Root = "" },
+			"Tick size":          func(c *FutureContract) { c.TickSize = 0 },
+			"Tick value":         func(c *FutureContract) { c.TickValue = -1 },
+			"Multiplier":         func(c *FutureContract) { c.Multiplier = 10 },
+			"Margin":             func(c *FutureContract) { c.InitialMargin = -1 },
+			"Maintenance margin": func(c *FutureContract) { c.MaintenanceMargin = 13000 },
+			"Expiration":         func(c *FutureContract) { c.Expiration = 0 },
+			"Roll date":          func(c *FutureContract) { c.RollDate = c.Expiration + 1 },
+		}
+		for name, change := range tests {
+			contract := newContract()
+			change(contract)
+			require.Equal(t, status.Code(contract.Validate()), codes.InvalidArgument, name)
+		}
+	})
+
+	t.Run("Order items", func(t *testing.T) {
+		item := NewFutureOrderItem(constants.Buy, "ESZ22", newContract(), 2, 4000)
+		require.Equal(t, item.ItemType, constants.Future)
+		require.Equal(t, item.QuantityPerAmount, 50.0)
+		// Only the fees are paid, the $400,000 value of the contracts isn't exchanged
+		require.Equal(t, item.CalculatePrice(0, 0, 2.25), -4.5)
+		require.Equal(t, item.Notional(), 400000.0)
+		require.Equal(t, item.MarginRequirement(), 24000.0)
+
+		// Without a contract the margin is the full value
+		item.Future = nil
+		require.Equal(t, item.MarginRequirement(), 400000.0)
+		item.Direction = constants.Sell
+		require.Equal(t, item.MarginRequirement(), -400000.0)
+	})
+}
+
+func TestFutureAndForexLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	contract := NewFutureContract("ES", 0.25, 12.5, 12000, now.Add(15*time_series.Day))
+	contract.MaintenanceMargin = 11000
+	contract.RollDate = now.Add(8 * time_series.Day).Unix()
+	pair := NewForexPair("EUR", "USD")
+	pair.MarginRate = 0.02
+
+	future := NewOrder(now, NewFutureOrderItem(constants.Sell, "ESZ22", contract, 1, 4000.25))
+	forex := NewOrder(now.Add(time_series.Day), NewForexOrderItem(constants.Buy, pair, 0.1, 1.0525))
+	// Both in a single order, next to a stock
+	mixed := NewOrder(
+		now.Add(2*time_series.Day),
+		NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+		NewFutureOrderItem(constants.Buy, "ESZ22", NewFutureContract("ES", 0.25, 12.5, 12000, now.Add(15*time_series.Day)), 1, 4001),
+		NewForexOrderItem(constants.Sell, NewForexPair("USD", "JPY"), 1, 135.5),
+	)
+	orders := []*Order{future, forex, mixed}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":             NewCSVLoader(),
+		"JSON New Line":   NewJsonNewLineLoader(),
+		"Avro":            NewAvroLoader(),
+		"Proto":           NewProtoLoader(),
+		"Delimited Proto": NewDelimitedProtoLoader(),
+		"Parquet":         NewParquetLoader(parquet_file.DefaultOptions()),
+	}
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, output, orders)
+		})
+	}
+
+	t.Run("Special floats", func(t *testing.T) {
+		contract := NewFutureContract("ES", 0.25, 12.5, math.NaN(), now.Add(15*time_series.Day))
+		pair := NewForexPair("EUR", "USD")
+		pair.MarginRate = math.Inf(1)
+		input := []*Order{NewOrder(
+			now,
+			NewFutureOrderItem(constants.Buy, "ESZ22", contract, 1, 4000),
+			NewForexOrderItem(constants.Buy, pair, 1, 1.05),
+		)}
+
+		loader := NewJsonNewLineLoader()
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, input)
+		require.NoError(t, err)
+		require.Contains(t, buff.String(), `"initial_margin":"NaN"`)
+		require.Contains(t, buff.String(), `"margin_rate":"Infinity"`)
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.True(t, math.IsNaN(output[0].OrderItems[0].Future.InitialMargin))
+		require.Equal(t, output[0].OrderItems[0].Future.TickValue, 12.5)
+		require.True(t, math.IsInf(output[0].OrderItems[1].Forex.MarginRate, 1))
+		require.Equal(t, output[0].OrderItems[1].Forex.LotSize, float64(DefaultForexLotSize))
+	})
+}
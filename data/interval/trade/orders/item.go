@@ -1,6 +1,10 @@
 package orders
 
-import "github.com/ta4g/ta4g/data/interval/trade/constants"
+import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/json_float"
+)
 
 type OrderItem struct {
 	// Direction - are we buying or selling?
@@ -26,6 +30,28 @@ type OrderItem struct {
 	// 1. A stock is $10 so it's $10 per share
 	// 2. An option is $1 per 100 shares, so it's $100 for the contract
 	Price float64 `csv:"price" avro:"price" json:"price"`
+
+	// Option is the contract of an option item, nil when it's unknown or the item isn't an option
+	Option *OptionContract `csv:"-" avro:"option" json:"option,omitempty"`
+
+	// Future is the contract of a future item, nil when it's unknown or the item isn't a future
+	Future *FutureContract `csv:"-" avro:"future" json:"future,omitempty"`
+
+	// Forex is the currency pair of a forex item, nil when it's unknown or the item isn't forex
+	Forex *ForexPair `csv:"-" avro:"forex" json:"forex,omitempty"`
+}
+
+// jsonOrderItem is the JSON form of an OrderItem, see json_float.Float
+type jsonOrderItem struct {
+	Direction         *constants.Direction `json:"direction"`
+	ItemType          *constants.ItemType  `json:"item_type"`
+	Symbol            *string              `json:"symbol"`
+	Amount            *json_float.Float    `json:"amount"`
+	QuantityPerAmount *json_float.Float    `json:"quantity_per_amount"`
+	Price             *json_float.Float    `json:"price"`
+	Option            **OptionContract     `json:"option,omitempty"`
+	Future            **FutureContract     `json:"future,omitempty"`
+	Forex             **ForexPair          `json:"forex,omitempty"`
 }
 
 func NewUSDOrderItem(direction constants.Direction, symbol string, amount, price float64) *OrderItem {
@@ -50,14 +76,31 @@ func NewStockOrderItem(direction constants.Direction, symbol string, amount, pri
 	}
 }
 
+// NewOptionOrderItem creates an option item for 100 shares per contract, OCC symbols also fill in the Option contract
 func NewOptionOrderItem(direction constants.Direction, symbol string, amount, price float64) *OrderItem {
+	// Free-form symbols are still allowed, they just don't have a contract
+	contract, _ := ParseOCCSymbol(symbol)
 	return &OrderItem{
 		Direction:         direction,
 		ItemType:          constants.Option,
 		Symbol:            symbol,
 		Amount:            amount,
-		QuantityPerAmount: 100,
+		QuantityPerAmount: DefaultOptionMultiplier,
 		Price:             price,
+		Option:            contract,
+	}
+}
+
+// NewOptionContractOrderItem creates an option item for the contract, the symbol is it's OCC symbol
+func NewOptionContractOrderItem(direction constants.Direction, contract *OptionContract, amount, price float64) *OrderItem {
+	return &OrderItem{
+		Direction:         direction,
+		ItemType:          constants.Option,
+		Symbol:            contract.OCCSymbol(),
+		Amount:            amount,
+		QuantityPerAmount: contract.Multiplier,
+		Price:             price,
+		Option:            contract.Clone(),
 	}
 }
 
@@ -72,14 +115,55 @@ func NewCryptoOrderItem(direction constants.Direction, symbol string, amount, pr
 	}
 }
 
+// NewFutureOrderItem creates a future item for the contract, ex: "ESZ22" for the December 2022 E-mini S&P 500.
+// The price is in points, so each contract is worth the price times the contract's multiplier.
+func NewFutureOrderItem(direction constants.Direction, symbol string, contract *FutureContract, amount, price float64) *OrderItem {
+	return &OrderItem{
+		Direction:         direction,
+		ItemType:          constants.Future,
+		Symbol:            symbol,
+		Amount:            amount,
+		QuantityPerAmount: contract.Multiplier,
+		Price:             price,
+		Future:            contract.Clone(),
+	}
+}
+
+// NewForexOrderItem creates a forex item for the pair, the amount is in lots, and the price is in the quote currency
+func NewForexOrderItem(direction constants.Direction, pair *ForexPair, lots, price float64) *OrderItem {
+	return &OrderItem{
+		Direction:         direction,
+		ItemType:          constants.Forex,
+		Symbol:            pair.Symbol(),
+		Amount:            lots,
+		QuantityPerAmount: pair.LotSize,
+		Price:             price,
+		Forex:             pair.Clone(),
+	}
+}
+
 func (s *OrderItem) Clone() *OrderItem {
 	output := *s
+	output.Option = s.Option.Clone()
+	output.Future = s.Future.Clone()
+	output.Forex = s.Forex.Clone()
 	return &output
 }
 
+// CalculatePrice is the cash paid (negative) or received (positive) for the item, including the fees.
+//
+// 1. Futures don't exchange their value when a position is opened or closed, so only their fees are paid.
+//    The margin is held instead, see MarginRequirement, and the profit or loss is realized from the change in price
+//    by the trade or the ledger, see Notional for their value.
+// 2. Forex is priced in the quote currency, so the value of a pair that isn't quoted in USD is in the quote currency.
+//    The per-unit fee is charged per lot.
+//
 func (s *OrderItem) CalculatePrice(exchangeFee, perOrderFee, perUnitFee float64) float64 {
 	// The amount we are paying or getting paid
-	output := s.Amount * s.QuantityPerAmount * s.Price
+	output := 0.0
+	if s.ItemType != constants.Future {
+		output = s.Notional()
+	}
 	// Per-contract or per-share fees we must pay
 	output += s.Amount * perUnitFee
 	// Exchange fees per order-item
@@ -96,7 +180,14 @@ func (s *OrderItem) CalculatePrice(exchangeFee, perOrderFee, perUnitFee float64)
 	return output
 }
 
+// MarginRequirement is the margin used by the item, it's negative for sells.
+//
+// 1. Futures use the initial margin of their contract per contract, or their full value without a contract
+// 2. Forex uses the margin rate of it's pair times it's value, or the full value without a pair or a margin rate
+// 3. Everything else uses it's full value, except USD which doesn't use any margin
+//
 func (s *OrderItem) MarginRequirement() float64 {
+	output := 0.0
 	switch s.ItemType {
 	case constants.USD:
 		return 0.0
@@ -105,13 +196,62 @@ func (s *OrderItem) MarginRequirement() float64 {
 	case constants.Option:
 		fallthrough
 	case constants.Crypto:
-		output := s.Amount * s.QuantityPerAmount * s.Price
-		// When we are buying we need to pay
-		// When we are selling we are getting paid, but that also uses up margin
-		if s.Direction == constants.Sell {
-			output *= -1.0
+		output = s.Notional()
+	case constants.Future:
+		output = s.Notional()
+		if nil != s.Future {
+			output = s.Amount * s.Future.InitialMargin
+		}
+	case constants.Forex:
+		output = s.Notional()
+		if nil != s.Forex && s.Forex.MarginRate > 0 {
+			output *= s.Forex.MarginRate
 		}
-		return output
+	default:
+		return 0.0
+	}
+	// When we are buying we need to pay
+	// When we are selling we are getting paid, but that also uses up margin
+	if s.Direction == constants.Sell {
+		output *= -1.0
 	}
-	return 0.0
+	return output
+}
+
+// Notional is the value of the item without any fees, ex: futures are worth their price times the contract's multiplier per contract
+func (s *OrderItem) Notional() float64 {
+	return s.Amount * s.QuantityPerAmount * s.Price
+}
+
+func (s *OrderItem) jsonForm() *jsonOrderItem {
+	return &jsonOrderItem{
+		Direction:         &s.Direction,
+		ItemType:          &s.ItemType,
+		Symbol:            &s.Symbol,
+		Amount:            (*json_float.Float)(&s.Amount),
+		QuantityPerAmount: (*json_float.Float)(&s.QuantityPerAmount),
+		Price:             (*json_float.Float)(&s.Price),
+		Option:            &s.Option,
+		Future:            &s.Future,
+		Forex:             &s.Forex,
+	}
+}
+
+func (s OrderItem) MarshalJSON() ([]byte, error) {
+	value := s.jsonForm()
+	// omitempty only leaves out pointers that are nil, so the empty fields are cleared here
+	if nil == s.Option {
+		value.Option = nil
+	}
+	if nil == s.Future {
+		value.Future = nil
+	}
+	if nil == s.Forex {
+		value.Forex = nil
+	}
+	return json.Marshal(value)
+}
+
+func (s *OrderItem) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, s.jsonForm())
 }
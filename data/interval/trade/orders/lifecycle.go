@@ -0,0 +1,308 @@
+package orders
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/json_float"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"time"
+)
+
+//
+// Order Lifecycle
+//
+// Orders move through their states one transition at a time, and every transition is recorded in the event log:
+//
+//   new ──> accepted ──> partially_filled ──> filled
+//    │         │               │
+//    │         └───────────────┴──> cancelled, expired
+//    └──> rejected, cancelled
+//
+// 1. Accept opens the order, and gives it an id when it doesn't have one yet
+// 2. AddFills records the fills, and moves the order to partially filled or filled
+// 3. Cancel, Expire, and Reject close the order before it's filled completely
+// 4. Orders written before the lifecycle was added don't have a state, and are treated as new orders
+//
+
+// Fill is part of an order item that was bought or sold
+type Fill struct {
+	// UnixTime the fill happened
+	UnixTime int64 `avro:"time" json:"time"`
+	// Item is the index of the order item that was filled
+	Item int `avro:"item" json:"item"`
+	// Amount of the item that was filled, ex: 50 of the 100 shares
+	Amount float64 `avro:"amount" json:"amount"`
+	// Price per item of the fill
+	Price float64 `avro:"price" json:"price"`
+}
+
+// jsonFill is the JSON form of a Fill, see json_float.Float
+type jsonFill struct {
+	UnixTime *int64            `json:"time"`
+	Item     *int              `json:"item"`
+	Amount   *json_float.Float `json:"amount"`
+	Price    *json_float.Float `json:"price"`
+}
+
+func (f *Fill) jsonForm() *jsonFill {
+	return &jsonFill{
+		UnixTime: &f.UnixTime,
+		Item:     &f.Item,
+		Amount:   (*json_float.Float)(&f.Amount),
+		Price:    (*json_float.Float)(&f.Price),
+	}
+}
+
+func (f Fill) MarshalJSON() ([]byte, error) {
+	value := f.jsonForm()
+	return json.Marshal(value)
+}
+
+func (f *Fill) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, f.jsonForm())
+}
+
+// Event is a single transition of an order
+type Event struct {
+	// UnixTime of the transition
+	UnixTime int64 `avro:"time" json:"time"`
+	// State the order moved to
+	State constants.OrderState `avro:"state" json:"state"`
+	// Reason for the transition, ex: why an order was rejected
+	Reason string `avro:"reason" json:"reason,omitempty"`
+}
+
+// transitions are the states each state can move to
+var transitions = map[constants.OrderState][]constants.OrderState{
+	constants.NewOrderState: {
+		constants.AcceptedOrderState,
+		constants.RejectedOrderState,
+		constants.CancelledOrderState,
+	},
+	constants.AcceptedOrderState: {
+		constants.PartiallyFilledOrderState,
+		constants.FilledOrderState,
+		constants.CancelledOrderState,
+		constants.ExpiredOrderState,
+	},
+	constants.PartiallyFilledOrderState: {
+		constants.PartiallyFilledOrderState,
+		constants.FilledOrderState,
+		constants.CancelledOrderState,
+		constants.ExpiredOrderState,
+	},
+}
+
+// NewOrderID creates a random id for an order, ex: "9f86d081884c7d659a2feaa0c55ad015"
+func NewOrderID() string {
+	data := make([]byte, 16)
+	_, err := rand.Read(data)
+	if nil != err {
+		panic(err)
+	}
+	return hex.EncodeToString(data)
+}
+
+// CurrentState is the state of the order, orders without a state are new
+func (s *Order) CurrentState() constants.OrderState {
+	if s.State == 0 {
+		return constants.NewOrderState
+	}
+	return s.State
+}
+
+// CanTransition checks if the order can move to the state
+func (s *Order) CanTransition(state constants.OrderState) bool {
+	for _, next := range transitions[s.CurrentState()] {
+		if next == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the order to the state, and records the transition in the event log
+//
+// Errors:
+// - If the order can't move from it's current state to the state an error with GRPC status FailedPrecondition will be returned
+//
+func (s *Order) Transition(t time.Time, state constants.OrderState, reason string) error {
+	if !s.CanTransition(state) {
+		return status.Errorf(codes.FailedPrecondition, "order can't move from %s to %s", s.CurrentState(), state)
+	}
+	s.State = state
+	s.Events = append(s.Events, &Event{UnixTime: t.Unix(), State: state, Reason: reason})
+	return nil
+}
+
+// Accept opens the order so it can be filled, and gives it a new id when it doesn't have one
+//
+// Errors:
+// - If a good till date order doesn't have an expire time an error with GRPC status InvalidArgument will be returned
+// - If the order isn't new an error with GRPC status FailedPrecondition will be returned
+//
+func (s *Order) Accept(t time.Time) error {
+	if s.TimeInForce == constants.GoodTillDateTimeInForce && s.ExpireTime == 0 {
+		return status.Error(codes.InvalidArgument, "good till date orders need an expire time")
+	}
+	err := s.Transition(t, constants.AcceptedOrderState, "")
+	if nil != err {
+		return err
+	}
+	if s.ID == "" {
+		s.ID = NewOrderID()
+	}
+	return nil
+}
+
+// Reject closes a new order that was never accepted
+func (s *Order) Reject(t time.Time, reason string) error {
+	return s.Transition(t, constants.RejectedOrderState, reason)
+}
+
+// Cancel closes the order, keeping any fills it already has
+func (s *Order) Cancel(t time.Time, reason string) error {
+	return s.Transition(t, constants.CancelledOrderState, reason)
+}
+
+// Expire closes the order at the end of it's time in force, keeping any fills it already has
+func (s *Order) Expire(t time.Time) error {
+	return s.Transition(t, constants.ExpiredOrderState, "")
+}
+
+// IsExpired checks if the order's time in force has ended by the time, a nil calendar is the time_series.USEquityCalendar.
+//
+// 1. Day orders end at the close of the trading day they were placed in, which is the close of the calendar's last session.
+//    Orders placed while the market is closed end at the close of the next trading day.
+// 2. Good till date orders end at their expire time
+// 3. Immediate or cancel and fill or kill orders never expire, they're closed by AddFills or cancelled when they can't be filled
+//
+func (s *Order) IsExpired(t time.Time, calendar *time_series.SessionCalendar) bool {
+	switch s.TimeInForce {
+	case constants.DayTimeInForce:
+		if nil == calendar {
+			calendar = usEquityCalendar
+		}
+		return !t.Before(dayEnd(time.Unix(s.UnixTime, 0), calendar))
+	case constants.GoodTillDateTimeInForce:
+		return t.Unix() >= s.ExpireTime
+	}
+	return false
+}
+
+// usEquityCalendar is the default calendar of day orders, it's never changed so it can be shared
+var usEquityCalendar = time_series.USEquityCalendar()
+
+// dayEnd is the close of the last session of the first trading day that closes after the time.
+// Calendars without a trading day in the next two weeks end the day at midnight in the calendar's location.
+func dayEnd(placed time.Time, calendar *time_series.SessionCalendar) time.Time {
+	local := placed.In(calendar.Location())
+	for index := 0; index <= 14; index++ {
+		date := local.AddDate(0, 0, index)
+		output := time_series.TimeZero
+		for _, session := range []time_series.Session{time_series.Overnight, time_series.PreMarket, time_series.Regular, time_series.AfterHours} {
+			closeTime, err := calendar.Close(date, session)
+			if nil == err && closeTime.After(output) {
+				output = closeTime
+			}
+		}
+		if output.After(placed) {
+			return output
+		}
+	}
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, calendar.Location())
+}
+
+// AddFills records the fills of an open order, and moves it to partially filled or filled.
+// All of the fills are added at once, or none of them are:
+// 1. Fill or kill orders must be filled completely by the fills
+// 2. Immediate or cancel orders are cancelled when the fills don't fill them completely
+//
+// Errors:
+// - If a fill is for an unknown item, or it's amount isn't positive, or is more than the remaining amount an error with GRPC status InvalidArgument will be returned
+// - If the order isn't open, or a fill or kill order isn't filled completely an error with GRPC status FailedPrecondition will be returned
+//
+func (s *Order) AddFills(t time.Time, fills ...*Fill) error {
+	if !s.CurrentState().IsOpen() {
+		return status.Errorf(codes.FailedPrecondition, "can't fill a %s order", s.CurrentState())
+	}
+
+	remaining := make([]float64, len(s.OrderItems))
+	for index := range s.OrderItems {
+		remaining[index] = s.RemainingAmount(index)
+	}
+	for _, fill := range fills {
+		if fill.Item < 0 || fill.Item >= len(s.OrderItems) {
+			return status.Errorf(codes.InvalidArgument, "unknown order item %d", fill.Item)
+		}
+		if !(fill.Amount > 0) || math.IsInf(fill.Amount, 0) {
+			return status.Error(codes.InvalidArgument, "fill amount must be positive")
+		}
+		if fill.Amount > remaining[fill.Item] {
+			return status.Errorf(codes.InvalidArgument, "fill of %v is more than the remaining %v of item %d", fill.Amount, remaining[fill.Item], fill.Item)
+		}
+		remaining[fill.Item] -= fill.Amount
+	}
+
+	filled := true
+	for _, amount := range remaining {
+		if amount > 0 {
+			filled = false
+		}
+	}
+	if !filled && s.TimeInForce == constants.FillOrKillTimeInForce {
+		return status.Error(codes.FailedPrecondition, "fill or kill orders must be filled completely")
+	}
+
+	for _, fill := range fills {
+		clone := *fill
+		s.Fills = append(s.Fills, &clone)
+	}
+	if filled {
+		return s.Transition(t, constants.FilledOrderState, "")
+	}
+	err := s.Transition(t, constants.PartiallyFilledOrderState, "")
+	if nil != err {
+		return err
+	}
+	if s.TimeInForce == constants.ImmediateOrCancelTimeInForce {
+		return s.Cancel(t, "immediate or cancel")
+	}
+	return nil
+}
+
+// FilledAmount is the total amount of the item that has been filled
+func (s *Order) FilledAmount(item int) float64 {
+	output := 0.0
+	for _, fill := range s.Fills {
+		if fill.Item == item {
+			output += fill.Amount
+		}
+	}
+	return output
+}
+
+// RemainingAmount is the amount of the item that is still waiting to be filled
+func (s *Order) RemainingAmount(item int) float64 {
+	return math.Max(0, s.OrderItems[item].Amount-s.FilledAmount(item))
+}
+
+// AverageFillPrice is the average price of the fills of the item weighted by their amount, or 0 when it has no fills
+func (s *Order) AverageFillPrice(item int) float64 {
+	amount, total := 0.0, 0.0
+	for _, fill := range s.Fills {
+		if fill.Item == item {
+			amount += fill.Amount
+			total += fill.Amount * fill.Price
+		}
+	}
+	if amount == 0 {
+		return 0
+	}
+	return total / amount
+}
@@ -0,0 +1,254 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	newOrder := func(timeInForce constants.TimeInForce) *Order {
+		order := NewOrder(
+			now,
+			NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+			NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+		)
+		order.TimeInForce = timeInForce
+		return order
+	}
+
+	t.Run("Filled", func(t *testing.T) {
+		order := newOrder(constants.GoodTillCancelledTimeInForce)
+		require.Equal(t, order.CurrentState(), constants.NewOrderState)
+		require.Empty(t, order.ID)
+
+		require.NoError(t, order.Accept(now))
+		require.Equal(t, order.State, constants.AcceptedOrderState)
+		require.Len(t, order.ID, 32)
+
+		// Accepting gives the order an id, but keeps an existing one
+		id := order.ID
+		require.Equal(t, status.Code(order.Accept(now)), codes.FailedPrecondition)
+		require.Equal(t, order.ID, id)
+
+		err := order.AddFills(now.Add(time.Minute), &Fill{UnixTime: now.Unix(), Item: 0, Amount: 40, Price: 10})
+		require.NoError(t, err)
+		require.Equal(t, order.State, constants.PartiallyFilledOrderState)
+		require.Equal(t, order.RemainingAmount(0), 60.0)
+		require.Equal(t, order.RemainingAmount(1), 1.0)
+
+		err = order.AddFills(
+			now.Add(2*time.Minute),
+			&Fill{UnixTime: now.Unix(), Item: 0, Amount: 60, Price: 10.05},
+			&Fill{UnixTime: now.Unix(), Item: 1, Amount: 1, Price: 101},
+		)
+		require.NoError(t, err)
+		require.Equal(t, order.State, constants.FilledOrderState)
+		require.Equal(t, order.FilledAmount(0), 100.0)
+		require.InDelta(t, order.AverageFillPrice(0), 10.03, 1e-9)
+		require.Equal(t, order.AverageFillPrice(1), 101.0)
+		require.Len(t, order.Fills, 3)
+
+		require.Equal(t, order.Events, []*Event{
+			{UnixTime: now.Unix(), State: constants.AcceptedOrderState},
+			{UnixTime: now.Add(time.Minute).Unix(), State: constants.PartiallyFilledOrderState},
+			{UnixTime: now.Add(2 * time.Minute).Unix(), State: constants.FilledOrderState},
+		})
+
+		// Filled orders can't change any more
+		require.Equal(t, status.Code(order.Cancel(now, "too late")), codes.FailedPrecondition)
+		require.Equal(t, status.Code(order.AddFills(now, &Fill{Item: 0, Amount: 1})), codes.FailedPrecondition)
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		order := newOrder(0)
+		require.Equal(t, status.Code(order.AddFills(now, &Fill{Item: 0, Amount: 1})), codes.FailedPrecondition)
+		require.Equal(t, status.Code(order.Expire(now)), codes.FailedPrecondition)
+		require.NoError(t, order.Reject(now, "insufficient buying power"))
+		require.Equal(t, order.Events, []*Event{{UnixTime: now.Unix(), State: constants.RejectedOrderState, Reason: "insufficient buying power"}})
+		require.Equal(t, status.Code(order.Accept(now)), codes.FailedPrecondition)
+
+		order = newOrder(0)
+		require.NoError(t, order.Cancel(now, ""))
+		require.True(t, order.State.IsTerminal())
+
+		order = newOrder(0)
+		require.NoError(t, order.Accept(now))
+		require.Equal(t, status.Code(order.Reject(now, "")), codes.FailedPrecondition)
+		require.NoError(t, order.AddFills(now, &Fill{Item: 0, Amount: 1, Price: 10}))
+		require.NoError(t, order.Expire(now))
+		require.Equal(t, order.FilledAmount(0), 1.0)
+	})
+
+	t.Run("Invalid fills", func(t *testing.T) {
+		order := newOrder(0)
+		require.NoError(t, order.Accept(now))
+
+		for _, fill := range []*Fill{
+			{Item: -1, Amount: 1},
+			{Item: 2, Amount: 1},
+			{Item: 0, Amount: 0},
+			{Item: 0, Amount: -1},
+			{Item: 0, Amount: 101},
+		} {
+			require.Equal(t, status.Code(order.AddFills(now, fill)), codes.InvalidArgument, fill)
+		}
+
+		// Nothing is added when any of the fills are invalid
+		err := order.AddFills(now, &Fill{Item: 0, Amount: 60}, &Fill{Item: 0, Amount: 60})
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		require.Empty(t, order.Fills)
+		require.Equal(t, order.State, constants.AcceptedOrderState)
+	})
+
+	t.Run("Immediate or cancel", func(t *testing.T) {
+		order := newOrder(constants.ImmediateOrCancelTimeInForce)
+		require.NoError(t, order.Accept(now))
+		require.NoError(t, order.AddFills(now, &Fill{Item: 0, Amount: 50, Price: 10}))
+		require.Equal(t, order.State, constants.CancelledOrderState)
+		require.Equal(t, order.FilledAmount(0), 50.0)
+		require.Equal(t, order.Events[len(order.Events)-1].Reason, "immediate or cancel")
+	})
+
+	t.Run("Fill or kill", func(t *testing.T) {
+		order := newOrder(constants.FillOrKillTimeInForce)
+		require.NoError(t, order.Accept(now))
+		err := order.AddFills(now, &Fill{Item: 0, Amount: 100, Price: 10})
+		require.Equal(t, status.Code(err), codes.FailedPrecondition)
+		require.Empty(t, order.Fills)
+
+		err = order.AddFills(now, &Fill{Item: 0, Amount: 100, Price: 10}, &Fill{Item: 1, Amount: 1, Price: 101})
+		require.NoError(t, err)
+		require.Equal(t, order.State, constants.FilledOrderState)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		// Placed at 10:00 in New York, so it ends when the after hours session closes at 20:00 the same day
+		order := newOrder(constants.DayTimeInForce)
+		order.UnixTime = now.Add(15 * time.Hour).Unix()
+		require.False(t, order.IsExpired(now.Add(24*time.Hour+59*time.Minute), nil))
+		require.True(t, order.IsExpired(now.Add(25*time.Hour), nil))
+
+		// Placed at 19:30 in New York, so it ends at 20:00 instead of lasting through the next day's sessions
+		order.UnixTime = now.Add(30 * time.Minute).Unix()
+		require.False(t, order.IsExpired(now.Add(59*time.Minute), nil))
+		require.True(t, order.IsExpired(now.Add(time.Hour), nil))
+
+		// Placed on Friday after the close, so it's for Monday which starts with Sunday's overnight session
+		order.UnixTime = now.Add(2*time_series.Day + 2*time.Hour).Unix()
+		require.False(t, order.IsExpired(now.Add(5*time_series.Day), nil))
+		require.True(t, order.IsExpired(now.Add(5*time_series.Day+time.Hour), nil))
+
+		// Calendars with only the regular session end the day at the regular close
+		calendar, err := time_series.NewSessionCalendar(
+			time.UTC,
+			[]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			time_series.SessionHours{Session: time_series.Regular, Open: 8 * time.Hour, Close: 16*time.Hour + 30*time.Minute},
+		)
+		require.NoError(t, err)
+		order.UnixTime = now.Add(15 * time.Hour).Unix()
+		require.False(t, order.IsExpired(now.Add(16*time.Hour), calendar))
+		require.True(t, order.IsExpired(now.Add(16*time.Hour+30*time.Minute), calendar))
+
+		order = newOrder(constants.GoodTillDateTimeInForce)
+		require.Equal(t, status.Code(order.Accept(now)), codes.InvalidArgument)
+		order.ExpireTime = now.Add(7 * time_series.Day).Unix()
+		require.NoError(t, order.Accept(now))
+		require.False(t, order.IsExpired(now.Add(6*time_series.Day), nil))
+		require.True(t, order.IsExpired(now.Add(7*time_series.Day), nil))
+
+		for _, timeInForce := range []constants.TimeInForce{0, constants.GoodTillCancelledTimeInForce, constants.ImmediateOrCancelTimeInForce, constants.FillOrKillTimeInForce} {
+			require.False(t, newOrder(timeInForce).IsExpired(now.AddDate(10, 0, 0), nil), timeInForce.String())
+		}
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		order := newOrder(constants.DayTimeInForce)
+		order.ClientTag = "my-strategy"
+		require.NoError(t, order.Accept(now))
+		require.NoError(t, order.AddFills(now, &Fill{Item: 0, Amount: 50, Price: 10}))
+
+		clone := order.Clone()
+		require.Equal(t, clone, order)
+		clone.Fills[0].Amount = 1
+		clone.Events[0].Reason = "changed"
+		require.Equal(t, order.Fills[0].Amount, 50.0)
+		require.Empty(t, order.Events[0].Reason)
+	})
+}
+
+func TestLifecycleLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	filled := NewOrder(now, NewStockOrderItem(constants.Buy, "ABC", 100, 10.01), NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 101))
+	filled.ClientTag = "covered call, with a comma"
+	filled.TimeInForce = constants.GoodTillDateTimeInForce
+	filled.ExpireTime = now.Add(7 * time_series.Day).Unix()
+	filled.Execution = NewLimitExecution(10)
+	require.NoError(t, filled.Accept(now))
+	require.NoError(t, filled.AddFills(now.Add(time.Minute), &Fill{UnixTime: now.Add(time.Minute).Unix(), Item: 0, Amount: 100, Price: 10}))
+	require.NoError(t, filled.Cancel(now.Add(time.Hour), `the "option" wasn't filled`))
+
+	rejected := NewOrder(now.Add(time_series.Day), NewStockOrderItem(constants.Sell, "XYZ", 5, 1.5))
+	rejected.TimeInForce = constants.DayTimeInForce
+	require.NoError(t, rejected.Reject(now.Add(time_series.Day), "market closed"))
+
+	// Orders without a lifecycle are unchanged
+	plain := NewOrder(now.Add(2*time_series.Day), NewStockOrderItem(constants.Buy, "XYZ", 1, 1))
+	orders := []*Order{filled, rejected, plain}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":             NewCSVLoader(),
+		"JSON New Line":   NewJsonNewLineLoader(),
+		"Avro":            NewAvroLoader(),
+		"Proto":           NewProtoLoader(),
+		"Delimited Proto": NewDelimitedProtoLoader(),
+		"Parquet":         NewParquetLoader(parquet_file.DefaultOptions()),
+	}
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, output, orders)
+		})
+	}
+
+	t.Run("Parquet files without the lifecycle", func(t *testing.T) {
+		// The schema before the lifecycle was added
+		type parquetOrderWithoutLifecycle struct {
+			Time      int64              `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+			Items     []parquetOrderItem `parquet:"name=items, repetitiontype=REPEATED"`
+			Execution *parquetExecution  `parquet:"name=execution, repetitiontype=OPTIONAL"`
+		}
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := parquet_file.NewWriter(buff, new(parquetOrderWithoutLifecycle), parquet_file.DefaultOptions())
+		require.NoError(t, err)
+		row := toParquet(filled)
+		require.NoError(t, writer.Write(&parquetOrderWithoutLifecycle{Time: row.Time, Items: row.Items, Execution: row.Execution}))
+		require.NoError(t, writer.WriteStop())
+
+		output, err := NewParquetLoader(parquet_file.DefaultOptions()).Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].Execution, filled.Execution)
+		require.Equal(t, output[0].CurrentState(), constants.NewOrderState)
+		require.Empty(t, output[0].Fills)
+	})
+}
@@ -5,12 +5,15 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/hamba/avro"
-	"github.com/jszwec/csvutil"
+	"github.com/ta4g/ta4g/data/avro_file"
 	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/proto_stream"
 	pb "github.com/ta4g/ta4g/gen/interval/trade"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -18,6 +21,8 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,22 +30,34 @@ import (
 // There are several loaders to choose from, each of which are self-contained with their own schemas:
 // 1. CSV
 // 2. Avro
-// 3. Proto
+// 3. Proto, see also NewDelimitedProtoLoader for length-delimited streams
+// 4. Parquet
+//
+// Every Loader is also a StreamLoader, the same as bar.Loader, so orders can be processed one at a time.
+// Only the delimited proto format is streamed from the input, the readers of the other formats read the whole input
+// when they're opened, and their writers write every order when they're closed.
+// NewAutoLoader picks the format from a file's path or content, see RegisterFormat to add new formats.
+// NewCompressedLoader adds gzip, zstd, snappy, or lz4 compression to any format.
 type Loader interface {
+	StreamLoader
 	Read(ctx context.Context, input io.Reader) ([]*Order, error)
 	Write(ctx context.Context, output io.Writer, input []*Order) error
 }
 
 // Compile time type assertions
-var _ Loader = &csvLoader{}
-var _ Loader = &jsonNewLineLoader{}
-var _ Loader = &avroLoader{}
-var _ Loader = &protoLoader{}
+var _ sliceLoader = &csvLoader{}
+var _ sliceLoader = &jsonNewLineLoader{}
+var _ sliceLoader = &avroLoader{}
+var _ sliceLoader = &protoLoader{}
+var _ StreamLoader = &delimitedProtoLoader{}
 
 type csvLoader struct{}
 type jsonNewLineLoader struct{}
-type avroLoader struct{}
+type avroLoader struct {
+	options avro_file.Options
+}
 type protoLoader struct{}
+type delimitedProtoLoader struct{}
 
 //go:embed schema.avro
 var schemaStr string
@@ -58,33 +75,199 @@ func init() {
 //
 // CSV Loader
 //
+// CSV files are flat, so each order is flattened into one row per item, and the rows of an order share it's id and time:
+//
+//   order_id,time,direction,item_type,symbol,amount,quantity_per_amount,price,execution_type,limit_price,stop_price,trailing_amount,trailing_percent,triggered,id,client_tag,time_in_force,expire_time,state,fills,events,option_underlying,option_strike,option_expiration,option_right,option_style,option_multiplier,future_root,...,forex_margin_rate
+//   1,1669852800,Buy,Stock,ABC,100,1,10.01,,,,,,,,,,,,,,,,,,,,...,
+//   1,1669852800,Sell,Option,ABC   221216C00010000,1,100,101,,,,,,,,,,,,,,ABC,10,1671148800,call,american,100,,...,
+//   2,1670716800,Sell,Stock,ABC,100,1,10.01,limit,10.5,0,0,0,false,9f86d081,my-strategy,gtc,,filled,"[{""time"":1670716800,""item"":0,""amount"":100,""price"":10.5}]","[{""time"":1670716800,""state"":""accepted""},{""time"":1670716800,""state"":""filled""}]",,,,,,,...,
+//
+// 1. The order id only groups the rows of an order within the file, the orders are numbered from 1 in the order they are written
+// 2. The items of an order are written in their original order, and are read back in the order their rows appear
+// 3. An order without any items is a single row, with only the order id and time
+// 4. The columns are found by their name in the header, so they may be in any order
+// 5. The direction and item type are written by name, their numbers from older files are still accepted
+// 6. The execution is repeated on every row of the order, and is empty for orders without one.
+//    The execution columns are optional, so files written before executions were added can still be read.
+// 7. The lifecycle is repeated on every row of the order too, with the fills and events as JSON arrays.
+//    The order id groups the rows, and the id column is the order's own id, see Order.ID.
+//    The lifecycle columns are optional, so files written before the lifecycle was added can still be read.
+// 8. The option contract is on the row of it's item, and is empty for items without one.
+//    The option columns are optional, so files written before option contracts were added can still be read.
+// 9. The future contract and forex pair are on the row of their item too, in the future_* and forex_* columns.
+//    They are also optional, so files written before futures and forex were added can still be read.
+//
+
+const (
+	csvOrderIDColumn                 = "order_id"
+	csvTimeColumn                    = "time"
+	csvDirectionColumn               = "direction"
+	csvItemTypeColumn                = "item_type"
+	csvSymbolColumn                  = "symbol"
+	csvAmountColumn                  = "amount"
+	csvQuantityPerAmountColumn       = "quantity_per_amount"
+	csvPriceColumn                   = "price"
+	csvExecutionTypeColumn           = "execution_type"
+	csvLimitPriceColumn              = "limit_price"
+	csvStopPriceColumn               = "stop_price"
+	csvTrailingAmountColumn          = "trailing_amount"
+	csvTrailingPercentColumn         = "trailing_percent"
+	csvTriggeredColumn               = "triggered"
+	csvIDColumn                      = "id"
+	csvClientTagColumn               = "client_tag"
+	csvTimeInForceColumn             = "time_in_force"
+	csvExpireTimeColumn              = "expire_time"
+	csvStateColumn                   = "state"
+	csvFillsColumn                   = "fills"
+	csvEventsColumn                  = "events"
+	csvOptionUnderlyingColumn        = "option_underlying"
+	csvOptionStrikeColumn            = "option_strike"
+	csvOptionExpirationColumn        = "option_expiration"
+	csvOptionRightColumn             = "option_right"
+	csvOptionStyleColumn             = "option_style"
+	csvOptionMultiplierColumn        = "option_multiplier"
+	csvFutureRootColumn              = "future_root"
+	csvFutureTickSizeColumn          = "future_tick_size"
+	csvFutureTickValueColumn         = "future_tick_value"
+	csvFutureMultiplierColumn        = "future_multiplier"
+	csvFutureInitialMarginColumn     = "future_initial_margin"
+	csvFutureMaintenanceMarginColumn = "future_maintenance_margin"
+	csvFutureExpirationColumn        = "future_expiration"
+	csvFutureRollDateColumn          = "future_roll_date"
+	csvForexBaseColumn               = "forex_base"
+	csvForexQuoteColumn              = "forex_quote"
+	csvForexPipSizeColumn            = "forex_pip_size"
+	csvForexLotSizeColumn            = "forex_lot_size"
+	csvForexMarginRateColumn         = "forex_margin_rate"
+)
+
+// csvColumns are all of the column names, in the order they are written
+var csvColumns = []string{
+	csvOrderIDColumn,
+	csvTimeColumn,
+	csvDirectionColumn,
+	csvItemTypeColumn,
+	csvSymbolColumn,
+	csvAmountColumn,
+	csvQuantityPerAmountColumn,
+	csvPriceColumn,
+	csvExecutionTypeColumn,
+	csvLimitPriceColumn,
+	csvStopPriceColumn,
+	csvTrailingAmountColumn,
+	csvTrailingPercentColumn,
+	csvTriggeredColumn,
+	csvIDColumn,
+	csvClientTagColumn,
+	csvTimeInForceColumn,
+	csvExpireTimeColumn,
+	csvStateColumn,
+	csvFillsColumn,
+	csvEventsColumn,
+	csvOptionUnderlyingColumn,
+	csvOptionStrikeColumn,
+	csvOptionExpirationColumn,
+	csvOptionRightColumn,
+	csvOptionStyleColumn,
+	csvOptionMultiplierColumn,
+	csvFutureRootColumn,
+	csvFutureTickSizeColumn,
+	csvFutureTickValueColumn,
+	csvFutureMultiplierColumn,
+	csvFutureInitialMarginColumn,
+	csvFutureMaintenanceMarginColumn,
+	csvFutureExpirationColumn,
+	csvFutureRollDateColumn,
+	csvForexBaseColumn,
+	csvForexQuoteColumn,
+	csvForexPipSizeColumn,
+	csvForexLotSizeColumn,
+	csvForexMarginRateColumn,
+}
+
+// csvRequiredColumns must be in every file, the execution, lifecycle, option, future, and forex columns were added later
+var csvRequiredColumns = csvColumns[:8]
 
 func NewCSVLoader() Loader {
-	return &csvLoader{}
+	return newBufferedLoader(&csvLoader{})
 }
 
 func (c csvLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
 	logger := ctxzap.Extract(ctx)
 
-	// Pull in the CSV
-	data, err := ioutil.ReadAll(input)
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if nil != err && err == io.EOF {
+		return []*Order{}, nil
+	}
 	if nil != err {
-		logger.Error("Failed to read all rows", zap.Error(err))
-		return nil, status.Error(codes.Internal, err.Error())
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	// Read the rows
-	var bars []Order
-	err = csvutil.Unmarshal(data, &bars)
-	if nil != err {
-		logger.Error("Failed to unmarshal rows", zap.Error(err))
-		return nil, status.Error(codes.Internal, err.Error())
+	// Find each column in the header
+	indexes := make(map[string]int, len(csvColumns))
+	for index, name := range header {
+		indexes[strings.TrimSpace(name)] = index
+	}
+	for _, column := range csvRequiredColumns {
+		if _, ok := indexes[column]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "missing column: %s", column)
+		}
 	}
 
-	// Type conversion
-	output := make([]*Order, 0, len(bars))
-	for _, item := range bars {
-		output = append(output, item.Clone())
+	output := make([]*Order, 0)
+	orders := make(map[string]*Order)
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			logger.Error("Failed to read row", zap.Error(err))
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		value := func(column string) string {
+			index, ok := indexes[column]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		orderID := value(csvOrderIDColumn)
+		unixTime, err := strconv.ParseInt(value(csvTimeColumn), 10, 64)
+		if nil != err {
+			return nil, status.Errorf(codes.InvalidArgument, "line %d: invalid time: %s", line, err.Error())
+		}
+		order, ok := orders[orderID]
+		if !ok {
+			execution, err := parseCSVExecution(value)
+			if nil != err {
+				return nil, status.Errorf(codes.InvalidArgument, "line %d: %s", line, err.Error())
+			}
+			order = &Order{UnixTime: unixTime, OrderItems: make([]*OrderItem, 0), Execution: execution}
+			err = parseCSVLifecycle(value, order)
+			if nil != err {
+				return nil, status.Errorf(codes.InvalidArgument, "line %d: %s", line, err.Error())
+			}
+			orders[orderID] = order
+			output = append(output, order)
+		}
+		if order.UnixTime != unixTime {
+			return nil, status.Errorf(codes.InvalidArgument, "line %d: order %s has more than one time", line, orderID)
+		}
+
+		// An order without any items
+		if value(csvDirectionColumn) == "" {
+			continue
+		}
+		item, err := parseCSVItem(value)
+		if nil != err {
+			return nil, status.Errorf(codes.InvalidArgument, "line %d: %s", line, err.Error())
+		}
+		order.OrderItems = append(order.OrderItems, item)
 	}
 	return output, nil
 }
@@ -92,24 +275,380 @@ func (c csvLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error)
 func (c csvLoader) Write(ctx context.Context, output io.Writer, input []*Order) error {
 	logger := ctxzap.Extract(ctx)
 
-	// Type conversion
-	items := make([]Order, 0, len(input))
-	for _, value := range input {
-		items = append(items, *value)
-	}
-
-	data, err := csvutil.Marshal(items)
+	writer := csv.NewWriter(output)
+	err := writer.Write(csvColumns)
 	if nil != err {
-		logger.Error("Failed to marshal rows", zap.Error(err))
+		logger.Error("Failed to write header", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
 
-	_, err = io.Copy(output, bytes.NewReader(data))
+	for index, order := range input {
+		orderID := strconv.Itoa(index + 1)
+		unixTime := strconv.FormatInt(order.UnixTime, 10)
+		execution := formatCSVExecution(order.Execution)
+		lifecycle, err := formatCSVLifecycle(order)
+		if nil != err {
+			logger.Error("Failed to marshal row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+		execution = append(execution, lifecycle...)
+		if len(order.OrderItems) == 0 {
+			row := append([]string{orderID, unixTime, "", "", "", "", "", ""}, execution...)
+			err = writer.Write(append(row, formatCSVContracts(&OrderItem{})...))
+		}
+		for _, item := range order.OrderItems {
+			direction, _ := item.Direction.MarshalText()
+			itemType, _ := item.ItemType.MarshalText()
+			row := append([]string{
+				orderID,
+				unixTime,
+				string(direction),
+				string(itemType),
+				item.Symbol,
+				strconv.FormatFloat(item.Amount, 'f', -1, 64),
+				strconv.FormatFloat(item.QuantityPerAmount, 'f', -1, 64),
+				strconv.FormatFloat(item.Price, 'f', -1, 64),
+			}, execution...)
+			err = writer.Write(append(row, formatCSVContracts(item)...))
+			if nil != err {
+				break
+			}
+		}
+		if nil != err {
+			logger.Error("Failed to write row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	writer.Flush()
+	err = writer.Error()
 	if nil != err {
 		logger.Error("Failed to write all rows", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
+	return nil
+}
+
+// parseCSVItem reads the item columns of a single row
+func parseCSVItem(value func(column string) string) (*OrderItem, error) {
+	direction, err := constants.ParseDirection(value(csvDirectionColumn))
+	if nil != err {
+		return nil, fmt.Errorf("invalid direction: %s", status.Convert(err).Message())
+	}
+	itemType, err := constants.ParseItemType(value(csvItemTypeColumn))
+	if nil != err {
+		return nil, fmt.Errorf("invalid item_type: %s", status.Convert(err).Message())
+	}
+	floats := make(map[string]float64, 3)
+	for _, column := range []string{csvAmountColumn, csvQuantityPerAmountColumn, csvPriceColumn} {
+		floats[column], err = strconv.ParseFloat(value(column), 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", column, err.Error())
+		}
+	}
+	option, err := parseCSVOption(value)
+	if nil != err {
+		return nil, err
+	}
+	future, err := parseCSVFuture(value)
+	if nil != err {
+		return nil, err
+	}
+	forex, err := parseCSVForex(value)
+	if nil != err {
+		return nil, err
+	}
+	return &OrderItem{
+		Direction:         direction,
+		ItemType:          itemType,
+		Symbol:            value(csvSymbolColumn),
+		Amount:            floats[csvAmountColumn],
+		QuantityPerAmount: floats[csvQuantityPerAmountColumn],
+		Price:             floats[csvPriceColumn],
+		Option:            option,
+		Future:            future,
+		Forex:             forex,
+	}, nil
+}
+
+// formatCSVContracts is the value of the option, future, and forex columns of the item
+func formatCSVContracts(item *OrderItem) []string {
+	output := formatCSVOption(item.Option)
+	output = append(output, formatCSVFuture(item.Future)...)
+	return append(output, formatCSVForex(item.Forex)...)
+}
 
+// formatCSVOption is the value of each option column, which are empty for items without an option contract
+func formatCSVOption(option *OptionContract) []string {
+	if nil == option {
+		return []string{"", "", "", "", "", ""}
+	}
+	right, _ := option.Right.MarshalText()
+	style, _ := option.Style.MarshalText()
+	return []string{
+		option.Underlying,
+		strconv.FormatFloat(option.Strike, 'f', -1, 64),
+		strconv.FormatInt(option.Expiration, 10),
+		string(right),
+		string(style),
+		strconv.FormatFloat(option.Multiplier, 'f', -1, 64),
+	}
+}
+
+// formatCSVFuture is the value of each future column, which are empty for items without a future contract
+func formatCSVFuture(future *FutureContract) []string {
+	if nil == future {
+		return []string{"", "", "", "", "", "", "", ""}
+	}
+	return []string{
+		future.Root,
+		strconv.FormatFloat(future.TickSize, 'f', -1, 64),
+		strconv.FormatFloat(future.TickValue, 'f', -1, 64),
+		strconv.FormatFloat(future.Multiplier, 'f', -1, 64),
+		strconv.FormatFloat(future.InitialMargin, 'f', -1, 64),
+		strconv.FormatFloat(future.MaintenanceMargin, 'f', -1, 64),
+		strconv.FormatInt(future.Expiration, 10),
+		strconv.FormatInt(future.RollDate, 10),
+	}
+}
+
+// formatCSVForex is the value of each forex column, which are empty for items without a forex pair
+func formatCSVForex(forex *ForexPair) []string {
+	if nil == forex {
+		return []string{"", "", "", "", ""}
+	}
+	return []string{
+		forex.Base,
+		forex.Quote,
+		strconv.FormatFloat(forex.PipSize, 'f', -1, 64),
+		strconv.FormatFloat(forex.LotSize, 'f', -1, 64),
+		strconv.FormatFloat(forex.MarginRate, 'f', -1, 64),
+	}
+}
+
+// parseCSVOption reads the option columns of a single row, empty numbers are 0
+func parseCSVOption(value func(column string) string) (*OptionContract, error) {
+	if value(csvOptionUnderlyingColumn) == "" {
+		return nil, nil
+	}
+	option := &OptionContract{Underlying: value(csvOptionUnderlyingColumn)}
+	floats := map[string]*float64{
+		csvOptionStrikeColumn:     &option.Strike,
+		csvOptionMultiplierColumn: &option.Multiplier,
+	}
+	err := parseCSVFloats(value, floats)
+	if nil != err {
+		return nil, err
+	}
+	if value(csvOptionExpirationColumn) != "" {
+		option.Expiration, err = strconv.ParseInt(value(csvOptionExpirationColumn), 10, 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvOptionExpirationColumn, err.Error())
+		}
+	}
+	if value(csvOptionRightColumn) != "" {
+		option.Right, err = constants.ParseOptionRight(value(csvOptionRightColumn))
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvOptionRightColumn, status.Convert(err).Message())
+		}
+	}
+	if value(csvOptionStyleColumn) != "" {
+		option.Style, err = constants.ParseOptionStyle(value(csvOptionStyleColumn))
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvOptionStyleColumn, status.Convert(err).Message())
+		}
+	}
+	return option, nil
+}
+
+// parseCSVFuture reads the future columns of a single row, empty numbers are 0
+func parseCSVFuture(value func(column string) string) (*FutureContract, error) {
+	if value(csvFutureRootColumn) == "" {
+		return nil, nil
+	}
+	future := &FutureContract{Root: value(csvFutureRootColumn)}
+	floats := map[string]*float64{
+		csvFutureTickSizeColumn:          &future.TickSize,
+		csvFutureTickValueColumn:         &future.TickValue,
+		csvFutureMultiplierColumn:        &future.Multiplier,
+		csvFutureInitialMarginColumn:     &future.InitialMargin,
+		csvFutureMaintenanceMarginColumn: &future.MaintenanceMargin,
+	}
+	err := parseCSVFloats(value, floats)
+	if nil != err {
+		return nil, err
+	}
+	for column, field := range map[string]*int64{csvFutureExpirationColumn: &future.Expiration, csvFutureRollDateColumn: &future.RollDate} {
+		if value(column) == "" {
+			continue
+		}
+		*field, err = strconv.ParseInt(value(column), 10, 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", column, err.Error())
+		}
+	}
+	return future, nil
+}
+
+// parseCSVForex reads the forex columns of a single row, empty numbers are 0
+func parseCSVForex(value func(column string) string) (*ForexPair, error) {
+	if value(csvForexBaseColumn) == "" {
+		return nil, nil
+	}
+	forex := &ForexPair{Base: value(csvForexBaseColumn), Quote: value(csvForexQuoteColumn)}
+	floats := map[string]*float64{
+		csvForexPipSizeColumn:    &forex.PipSize,
+		csvForexLotSizeColumn:    &forex.LotSize,
+		csvForexMarginRateColumn: &forex.MarginRate,
+	}
+	err := parseCSVFloats(value, floats)
+	if nil != err {
+		return nil, err
+	}
+	return forex, nil
+}
+
+// parseCSVFloats reads each of the columns into it's field, empty columns are skipped
+func parseCSVFloats(value func(column string) string, fields map[string]*float64) error {
+	var err error
+	for column, field := range fields {
+		if value(column) == "" {
+			continue
+		}
+		*field, err = strconv.ParseFloat(value(column), 64)
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", column, err.Error())
+		}
+	}
+	return nil
+}
+
+// formatCSVExecution is the value of each execution column, which are empty for orders without an execution
+func formatCSVExecution(execution *Execution) []string {
+	if nil == execution {
+		return []string{"", "", "", "", "", ""}
+	}
+	executionType, _ := execution.Type.MarshalText()
+	return []string{
+		string(executionType),
+		strconv.FormatFloat(execution.LimitPrice, 'f', -1, 64),
+		strconv.FormatFloat(execution.StopPrice, 'f', -1, 64),
+		strconv.FormatFloat(execution.TrailingAmount, 'f', -1, 64),
+		strconv.FormatFloat(execution.TrailingPercent, 'f', -1, 64),
+		strconv.FormatBool(execution.Triggered),
+	}
+}
+
+// parseCSVExecution reads the execution columns of a single row, empty numbers are 0
+func parseCSVExecution(value func(column string) string) (*Execution, error) {
+	if value(csvExecutionTypeColumn) == "" {
+		return nil, nil
+	}
+	executionType, err := constants.ParseExecutionType(value(csvExecutionTypeColumn))
+	if nil != err {
+		return nil, fmt.Errorf("invalid execution_type: %s", status.Convert(err).Message())
+	}
+	floats := make(map[string]float64, 4)
+	for _, column := range []string{csvLimitPriceColumn, csvStopPriceColumn, csvTrailingAmountColumn, csvTrailingPercentColumn} {
+		if value(column) == "" {
+			continue
+		}
+		floats[column], err = strconv.ParseFloat(value(column), 64)
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", column, err.Error())
+		}
+	}
+	triggered := false
+	if value(csvTriggeredColumn) != "" {
+		triggered, err = strconv.ParseBool(value(csvTriggeredColumn))
+		if nil != err {
+			return nil, fmt.Errorf("invalid %s: %s", csvTriggeredColumn, err.Error())
+		}
+	}
+	return &Execution{
+		Type:            executionType,
+		LimitPrice:      floats[csvLimitPriceColumn],
+		StopPrice:       floats[csvStopPriceColumn],
+		TrailingAmount:  floats[csvTrailingAmountColumn],
+		TrailingPercent: floats[csvTrailingPercentColumn],
+		Triggered:       triggered,
+	}, nil
+}
+
+// formatCSVLifecycle is the value of each lifecycle column, the fills and events are JSON arrays
+func formatCSVLifecycle(order *Order) ([]string, error) {
+	timeInForce, _ := order.TimeInForce.MarshalText()
+	state, _ := order.State.MarshalText()
+	if order.TimeInForce == 0 {
+		timeInForce = nil
+	}
+	if order.State == 0 {
+		state = nil
+	}
+	expireTime, fills, events := "", "", ""
+	if order.ExpireTime != 0 {
+		expireTime = strconv.FormatInt(order.ExpireTime, 10)
+	}
+	if len(order.Fills) > 0 {
+		data, err := json.Marshal(order.Fills)
+		if nil != err {
+			return nil, err
+		}
+		fills = string(data)
+	}
+	if len(order.Events) > 0 {
+		data, err := json.Marshal(order.Events)
+		if nil != err {
+			return nil, err
+		}
+		events = string(data)
+	}
+	return []string{
+		order.ID,
+		order.ClientTag,
+		string(timeInForce),
+		expireTime,
+		string(state),
+		fills,
+		events,
+	}, nil
+}
+
+// parseCSVLifecycle reads the lifecycle columns of a single row into the order
+func parseCSVLifecycle(value func(column string) string, order *Order) error {
+	var err error
+	order.ID = value(csvIDColumn)
+	order.ClientTag = value(csvClientTagColumn)
+	if value(csvTimeInForceColumn) != "" {
+		order.TimeInForce, err = constants.ParseTimeInForce(value(csvTimeInForceColumn))
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", csvTimeInForceColumn, status.Convert(err).Message())
+		}
+	}
+	if value(csvExpireTimeColumn) != "" {
+		order.ExpireTime, err = strconv.ParseInt(value(csvExpireTimeColumn), 10, 64)
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", csvExpireTimeColumn, err.Error())
+		}
+	}
+	if value(csvStateColumn) != "" {
+		order.State, err = constants.ParseOrderState(value(csvStateColumn))
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", csvStateColumn, status.Convert(err).Message())
+		}
+	}
+	if value(csvFillsColumn) != "" {
+		err = json.Unmarshal([]byte(value(csvFillsColumn)), &order.Fills)
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", csvFillsColumn, err.Error())
+		}
+	}
+	if value(csvEventsColumn) != "" {
+		err = json.Unmarshal([]byte(value(csvEventsColumn)), &order.Events)
+		if nil != err {
+			return fmt.Errorf("invalid %s: %s", csvEventsColumn, err.Error())
+		}
+	}
 	return nil
 }
 
@@ -118,7 +657,7 @@ func (c csvLoader) Write(ctx context.Context, output io.Writer, input []*Order)
 //
 
 func NewJsonNewLineLoader() Loader {
-	return &jsonNewLineLoader{}
+	return newBufferedLoader(&jsonNewLineLoader{})
 }
 
 func (j jsonNewLineLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
@@ -128,17 +667,17 @@ func (j jsonNewLineLoader) Read(ctx context.Context, input io.Reader) ([]*Order,
 	reader := bufio.NewReader(input)
 	output := make([]*Order, 0)
 	for {
-		// Read the rows line by line
+		// Read the rows line by line, the last line may not have a trailing new line
 		data, err := reader.ReadBytes('\n')
-		if nil != err && err == io.EOF {
-			break
-		}
-		if nil != err {
+		if nil != err && err != io.EOF {
 			logger.Error("Failed to read line", zap.Error(err))
 			return nil, status.Error(codes.Internal, err.Error())
 		}
-		if len(data) == 0 {
-			break
+		if len(bytes.TrimSpace(data)) == 0 {
+			if nil != err {
+				break
+			}
+			continue
 		}
 
 		// Now parse the JSON and add it to the output
@@ -185,15 +724,45 @@ func (j jsonNewLineLoader) Write(ctx context.Context, writer io.Writer, input []
 //
 // Avro Loader
 //
+// Orders are written as an avro object container file, which embeds the schema it was written with,
+// so older files are still readable after new fields are added to `schema.avro` (as long as they have a default).
+//
+// Inputs without the container file header are read as a stream of raw datums with the current schema,
+// which is how orders were written before the container files.
+//
 
+// NewAvroLoader creates a Loader for deflate compressed avro files
 func NewAvroLoader() Loader {
-	return &avroLoader{}
+	return NewAvroFileLoader(avro_file.DefaultOptions())
+}
+
+// NewAvroFileLoader creates a Loader for avro files with the given compression and block length
+func NewAvroFileLoader(options avro_file.Options) Loader {
+	return newBufferedLoader(&avroLoader{options: options.WithDefaults()})
+}
+
+// avroDecoder is implemented by both the container file and raw datum decoders
+type avroDecoder interface {
+	Decode(value interface{}) error
 }
 
 func (a avroLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
 	logger := ctxzap.Extract(ctx)
 
-	decoder := avro.NewDecoderForSchema(avroSchema, input)
+	buffered := bufio.NewReader(input)
+	container, err := avro_file.IsContainer(buffered)
+	if nil != err {
+		logger.Error("Failed to read header", zap.Error(err))
+		return nil, err
+	}
+	var decoder avroDecoder = avro.NewDecoderForSchema(avroSchema, buffered)
+	if container {
+		decoder, err = avro_file.NewDecoder(buffered, avroSchema)
+		if nil != err {
+			logger.Error("Failed to read header", zap.Error(err))
+			return nil, err
+		}
+	}
 
 	output := make([]*Order, 0)
 	for {
@@ -204,7 +773,10 @@ func (a avroLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error)
 		}
 		if nil != err {
 			logger.Error("Failed to unmarshal row", zap.Error(err))
-			return nil, status.Error(codes.Internal, err.Error())
+			if _, ok := status.FromError(err); !ok {
+				err = status.Error(codes.Internal, err.Error())
+			}
+			return nil, err
 		}
 		output = append(output, stdOrder)
 	}
@@ -214,14 +786,25 @@ func (a avroLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error)
 func (a avroLoader) Write(ctx context.Context, output io.Writer, input []*Order) error {
 	logger := ctxzap.Extract(ctx)
 
-	encoder := avro.NewEncoderForSchema(avroSchema, output)
+	encoder, err := avro_file.NewEncoder(output, avroSchema, a.options)
+	if nil != err {
+		logger.Error("Failed to write header", zap.Error(err))
+		return err
+	}
 	for _, item := range input {
 		err := encoder.Encode(item)
 		if nil != err {
 			logger.Error("Failed to marshal row", zap.Error(err))
-			return status.Error(codes.Internal, err.Error())
+			return err
 		}
 	}
+
+	// Flush the last block
+	err = encoder.Close()
+	if nil != err {
+		logger.Error("Failed to write block", zap.Error(err))
+		return err
+	}
 	return nil
 }
 
@@ -230,7 +813,7 @@ func (a avroLoader) Write(ctx context.Context, output io.Writer, input []*Order)
 //
 
 func NewProtoLoader() Loader {
-	return &protoLoader{}
+	return newBufferedLoader(&protoLoader{})
 }
 
 func (a protoLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
@@ -249,30 +832,9 @@ func (a protoLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// Convert the rows
-	output := make([]*Order, 0)
+	output := make([]*Order, 0, len(messages.Orders))
 	for _, pbOrder := range messages.Orders {
-		// Convert them to OrderItems
-		orderItems := make([]*OrderItem, 0, len(pbOrder.Items))
-		for _, item := range pbOrder.Items {
-			orderItems = append(
-				orderItems,
-				&OrderItem{
-					Direction:         constants.Direction(item.Direction),
-					ItemType:          constants.ItemType(item.ItemType),
-					Symbol:            item.Symbol,
-					Amount:            item.Amount,
-					QuantityPerAmount: item.QuantityPerAmount,
-					Price:             item.Price,
-				},
-			)
-		}
-		// Now make an order
-		row := NewOrder(
-			pbOrder.GetTime().AsTime(),
-			orderItems...,
-		)
-		output = append(output, row)
+		output = append(output, FromProto(pbOrder))
 	}
 	return output, nil
 }
@@ -280,40 +842,312 @@ func (a protoLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error
 func (a protoLoader) Write(ctx context.Context, output io.Writer, input []*Order) error {
 	logger := ctxzap.Extract(ctx)
 
-	// Type conversion
 	pbOrders := make([]*pb.Order, 0, len(input))
-	for _, orderItem := range input {
-		items := orderItem.OrderItems
-		orderItems := make([]*pb.OrderItem, 0, len(items))
-		for _, item := range items {
-			orderItems = append(orderItems, &pb.OrderItem{
-				Direction:         int64(item.Direction),
-				ItemType:          int64(item.ItemType),
+	for _, order := range input {
+		pbOrders = append(pbOrders, ToProto(order))
+	}
+
+	data, err := proto.Marshal(&pb.Orders{Orders: pbOrders})
+	if nil != err {
+		logger.Error("Failed to marshal rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	_, err = io.Copy(output, bytes.NewReader(data))
+	if nil != err {
+		logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return nil
+}
+
+// FromProto converts the proto message into an order, so other messages can embed orders
+func FromProto(order *pb.Order) *Order {
+	items := make([]*OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(
+			items,
+			&OrderItem{
+				Direction:         constants.Direction(item.Direction),
+				ItemType:          constants.ItemType(item.ItemType),
 				Symbol:            item.Symbol,
 				Amount:            item.Amount,
 				QuantityPerAmount: item.QuantityPerAmount,
 				Price:             item.Price,
-			})
+				Option:            fromProtoOption(item.GetOption()),
+				Future:            fromProtoFuture(item.GetFuture()),
+				Forex:             fromProtoForex(item.GetForex()),
+			},
+		)
+	}
+	output := NewOrder(order.GetTime().AsTime(), items...)
+	if execution := order.GetExecution(); nil != execution {
+		output.Execution = &Execution{
+			Type:            constants.ExecutionType(execution.GetType()),
+			LimitPrice:      execution.GetLimitPrice(),
+			StopPrice:       execution.GetStopPrice(),
+			TrailingAmount:  execution.GetTrailingAmount(),
+			TrailingPercent: execution.GetTrailingPercent(),
+			Triggered:       execution.GetTriggered(),
+		}
+	}
+	output.ID = order.GetId()
+	output.ClientTag = order.GetClientTag()
+	output.TimeInForce = constants.TimeInForce(order.GetTimeInForce())
+	if nil != order.GetExpireTime() {
+		output.ExpireTime = order.GetExpireTime().AsTime().Unix()
+	}
+	output.State = constants.OrderState(order.GetState())
+	for _, fill := range order.GetFills() {
+		output.Fills = append(output.Fills, &Fill{
+			UnixTime: fill.GetTime().AsTime().Unix(),
+			Item:     int(fill.GetItem()),
+			Amount:   fill.GetAmount(),
+			Price:    fill.GetPrice(),
+		})
+	}
+	for _, event := range order.GetEvents() {
+		output.Events = append(output.Events, &Event{
+			UnixTime: event.GetTime().AsTime().Unix(),
+			State:    constants.OrderState(event.GetState()),
+			Reason:   event.GetReason(),
+		})
+	}
+	return output
+}
+
+// ToProto converts the order into it's proto message, so other messages can embed orders
+func ToProto(order *Order) *pb.Order {
+	items := make([]*pb.OrderItem, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		items = append(items, &pb.OrderItem{
+			Direction:         int64(item.Direction),
+			ItemType:          int64(item.ItemType),
+			Symbol:            item.Symbol,
+			Amount:            item.Amount,
+			QuantityPerAmount: item.QuantityPerAmount,
+			Price:             item.Price,
+			Option:            toProtoOption(item.Option),
+			Future:            toProtoFuture(item.Future),
+			Forex:             toProtoForex(item.Forex),
+		})
+	}
+	output := &pb.Order{
+		Time:  timestamppb.New(time.Unix(order.UnixTime, 0)),
+		Items: items,
+	}
+	if execution := order.Execution; nil != execution {
+		output.Execution = &pb.Execution{
+			Type:            int64(execution.Type),
+			LimitPrice:      execution.LimitPrice,
+			StopPrice:       execution.StopPrice,
+			TrailingAmount:  execution.TrailingAmount,
+			TrailingPercent: execution.TrailingPercent,
+			Triggered:       execution.Triggered,
 		}
+	}
+	output.Id = order.ID
+	output.ClientTag = order.ClientTag
+	output.TimeInForce = int64(order.TimeInForce)
+	if order.ExpireTime != 0 {
+		output.ExpireTime = timestamppb.New(time.Unix(order.ExpireTime, 0))
+	}
+	output.State = int64(order.State)
+	for _, fill := range order.Fills {
+		output.Fills = append(output.Fills, &pb.Fill{
+			Time:   timestamppb.New(time.Unix(fill.UnixTime, 0)),
+			Item:   int64(fill.Item),
+			Amount: fill.Amount,
+			Price:  fill.Price,
+		})
+	}
+	for _, event := range order.Events {
+		output.Events = append(output.Events, &pb.Event{
+			Time:   timestamppb.New(time.Unix(event.UnixTime, 0)),
+			State:  int64(event.State),
+			Reason: event.Reason,
+		})
+	}
+	return output
+}
 
-		value := &pb.Order{
-			Time:  timestamppb.New(time.Unix(orderItem.UnixTime, 0)),
-			Items: orderItems,
+func fromProtoOption(option *pb.OptionContract) *OptionContract {
+	if nil == option {
+		return nil
+	}
+	output := &OptionContract{
+		Underlying: option.GetUnderlying(),
+		Strike:     option.GetStrike(),
+		Right:      constants.OptionRight(option.GetRight()),
+		Style:      constants.OptionStyle(option.GetStyle()),
+		Multiplier: option.GetMultiplier(),
+	}
+	if nil != option.GetExpiration() {
+		output.Expiration = option.GetExpiration().AsTime().Unix()
+	}
+	return output
+}
+
+func toProtoOption(option *OptionContract) *pb.OptionContract {
+	if nil == option {
+		return nil
+	}
+	return &pb.OptionContract{
+		Underlying: option.Underlying,
+		Strike:     option.Strike,
+		Expiration: timestamppb.New(time.Unix(option.Expiration, 0)),
+		Right:      int64(option.Right),
+		Style:      int64(option.Style),
+		Multiplier: option.Multiplier,
+	}
+}
+
+func fromProtoFuture(future *pb.FutureContract) *FutureContract {
+	if nil == future {
+		return nil
+	}
+	output := &FutureContract{
+		Root:              future.GetRoot(),
+		TickSize:          future.GetTickSize(),
+		TickValue:         future.GetTickValue(),
+		Multiplier:        future.GetMultiplier(),
+		InitialMargin:     future.GetInitialMargin(),
+		MaintenanceMargin: future.GetMaintenanceMargin(),
+	}
+	if nil != future.GetExpiration() {
+		output.Expiration = future.GetExpiration().AsTime().Unix()
+	}
+	if nil != future.GetRollDate() {
+		output.RollDate = future.GetRollDate().AsTime().Unix()
+	}
+	return output
+}
+
+func toProtoFuture(future *FutureContract) *pb.FutureContract {
+	if nil == future {
+		return nil
+	}
+	output := &pb.FutureContract{
+		Root:              future.Root,
+		TickSize:          future.TickSize,
+		TickValue:         future.TickValue,
+		Multiplier:        future.Multiplier,
+		InitialMargin:     future.InitialMargin,
+		MaintenanceMargin: future.MaintenanceMargin,
+		Expiration:        timestamppb.New(time.Unix(future.Expiration, 0)),
+	}
+	if future.RollDate != 0 {
+		output.RollDate = timestamppb.New(time.Unix(future.RollDate, 0))
+	}
+	return output
+}
+
+func fromProtoForex(forex *pb.ForexPair) *ForexPair {
+	if nil == forex {
+		return nil
+	}
+	return &ForexPair{
+		Base:       forex.GetBase(),
+		Quote:      forex.GetQuote(),
+		PipSize:    forex.GetPipSize(),
+		LotSize:    forex.GetLotSize(),
+		MarginRate: forex.GetMarginRate(),
+	}
+}
+
+func toProtoForex(forex *ForexPair) *pb.ForexPair {
+	if nil == forex {
+		return nil
+	}
+	return &pb.ForexPair{
+		Base:       forex.Base,
+		Quote:      forex.Quote,
+		PipSize:    forex.PipSize,
+		LotSize:    forex.LotSize,
+		MarginRate: forex.MarginRate,
+	}
+}
+
+//
+// Delimited Proto Loader
+//
+// The output is a stream of `Order` messages, each one prefixed with it's length as a varint.
+// This is the same framing as `writeDelimitedTo` / `parseDelimitedFrom` in the Java, C++, and C# protobuf libraries,
+// so the files can be shared with services written in other languages, and streamed one order at a time.
+//
+
+// NewDelimitedProtoLoader creates a Loader for length-delimited `Order` messages
+func NewDelimitedProtoLoader() Loader {
+	return NewLoader(&delimitedProtoLoader{})
+}
+
+type delimitedProtoReader struct {
+	logger *zap.Logger
+	reader *bufio.Reader
+}
+
+type delimitedProtoWriter struct {
+	logger *zap.Logger
+	writer *bufio.Writer
+}
+
+func (d delimitedProtoLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	return &delimitedProtoReader{
+		logger: ctxzap.Extract(ctx),
+		reader: bufio.NewReader(input),
+	}, nil
+}
+
+func (d delimitedProtoLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	return &delimitedProtoWriter{
+		logger: ctxzap.Extract(ctx),
+		writer: bufio.NewWriter(output),
+	}, nil
+}
+
+func (d *delimitedProtoReader) Next() (*Order, error) {
+	data, err := proto_stream.ReadDelimited(d.reader)
+	if nil != err && err == io.EOF {
+		return nil, io.EOF
+	}
+	if nil != err {
+		d.logger.Error("Failed to read row", zap.Error(err))
+		if _, ok := status.FromError(err); ok {
+			return nil, err
 		}
-		pbOrders = append(pbOrders, value)
+		return nil, status.Error(codes.DataLoss, err.Error())
 	}
 
-	data, err := proto.Marshal(&pb.Orders{Orders: pbOrders})
+	message := &pb.Order{}
+	err = proto.Unmarshal(data, message)
 	if nil != err {
-		logger.Error("Failed to marshal rows", zap.Error(err))
+		d.logger.Error("Failed to unmarshal row", zap.Error(err))
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
+	return FromProto(message), nil
+}
+
+func (d *delimitedProtoWriter) WriteOrder(order *Order) error {
+	data, err := proto.Marshal(ToProto(order))
+	if nil != err {
+		d.logger.Error("Failed to marshal row", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
 
-	_, err = io.Copy(output, bytes.NewReader(data))
+	err = proto_stream.WriteDelimited(d.writer, data)
 	if nil != err {
-		logger.Error("Failed to write all rows", zap.Error(err))
+		d.logger.Error("Failed to write row", zap.Error(err))
 		return status.Error(codes.Internal, err.Error())
 	}
+	return nil
+}
 
+func (d *delimitedProtoWriter) Close() error {
+	err := d.writer.Flush()
+	if nil != err {
+		d.logger.Error("Failed to write all rows", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
 	return nil
 }
@@ -3,9 +3,21 @@ package orders
 import (
 	"bytes"
 	"context"
+	"github.com/hamba/avro"
 	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/avro_file"
+	"github.com/ta4g/ta4g/data/interval/bar"
 	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/proto_stream"
 	"github.com/ta4g/ta4g/data/time/time_series"
+	pb "github.com/ta4g/ta4g/gen/interval/trade"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -32,22 +44,82 @@ func TestCSVLoader(t *testing.T) {
 
 	buff := bytes.NewBuffer([]byte{})
 	err := loader.Write(ctx, buff, orders)
-	require.Error(t, err)
-
-	// NOTE: CSV serialization of nested structures is not supported yet, so this is marked as a TODO
-	//
-	//lines := strings.Split(buff.String(), "\n")
-	//require.Len(t, lines, len(orders)+2)
-	//require.Empty(t, lines[len(orders)+1]) // Last line is blank
-	//
-	//reader := bytes.NewReader(buff.Bytes())
-	//output, err := loader.Read(ctx, reader)
-	//require.NoError(t, err)
-	//require.Len(t, output, len(orders))
-	//for index, row := range output {
-	//	b := orders[index]
-	//	require.Equal(t, row.UnixTime, b.UnixTime)
-	//}
+	require.NoError(t, err)
+
+	// One row per item, plus the header
+	lines := strings.Split(buff.String(), "\n")
+	require.Len(t, lines, 4+2)
+	require.Equal(t, lines[0], "order_id,time,direction,item_type,symbol,amount,quantity_per_amount,price,execution_type,limit_price,stop_price,trailing_amount,trailing_percent,triggered,id,client_tag,time_in_force,expire_time,state,fills,events,option_underlying,option_strike,option_expiration,option_right,option_style,option_multiplier,future_root,future_tick_size,future_tick_value,future_multiplier,future_initial_margin,future_maintenance_margin,future_expiration,future_roll_date,forex_base,forex_quote,forex_pip_size,forex_lot_size,forex_margin_rate")
+	require.Equal(t, lines[1], "1,1669852800,Buy,Stock,ABC,100,1,10.01,,,,,,,,,,,,,,,,,,,"+strings.Repeat(",", 13))
+	require.Empty(t, lines[5]) // Last line is blank
+
+	reader := bytes.NewReader(buff.Bytes())
+	output, err := loader.Read(ctx, reader)
+	require.NoError(t, err)
+	require.Len(t, output, len(orders))
+	for index, row := range output {
+		b := orders[index]
+		require.Equal(t, row.UnixTime, b.UnixTime)
+		require.Equal(t, row.OrderItems, b.OrderItems)
+	}
+
+	t.Run("Orders without items", func(t *testing.T) {
+		input := []*Order{NewOrder(now), buyCoveredCallOrder, NewOrder(now.Add(time_series.Day))}
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, input)
+		require.NoError(t, err)
+		require.Contains(t, buff.String(), "\n1,1669852800,,,,,,,,,,,,,,,,,,,,,,,,,"+strings.Repeat(",", 13)+"\n")
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Equal(t, output, input)
+	})
+
+	t.Run("Columns in any order, and interleaved orders", func(t *testing.T) {
+		input := "symbol,time,order_id,direction,item_type,amount,quantity_per_amount,price\n" +
+			"ABC,1669852800,a,1,2,100,1,10.01\n" +
+			"XYZ,1669939200,b,2,2,5,1,1.5\n" +
+			"ABC CALL @ 10.0,1669852800,a,2,3,1,100,101\n"
+		output, err := loader.Read(ctx, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, output, 2)
+		require.Len(t, output[0].OrderItems, 2)
+		require.Equal(t, output[0].OrderItems[1].Symbol, "ABC CALL @ 10.0")
+		require.Equal(t, output[0].OrderItems[1].QuantityPerAmount, 100.0)
+		require.Equal(t, output[1].OrderItems[0].Direction, constants.Sell)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		output, err := loader.Read(ctx, strings.NewReader(""))
+		require.NoError(t, err)
+		require.Empty(t, output)
+
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, []*Order{})
+		require.NoError(t, err)
+		output, err = loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Empty(t, output)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		header := "order_id,time,direction,item_type,symbol,amount,quantity_per_amount,price\n"
+		tests := map[string]string{
+			"Missing column":      "order_id,time,direction\n1,1669852800,1\n",
+			"Invalid time":        header + "1,yesterday,1,2,ABC,100,1,10.01\n",
+			"Invalid amount":      header + "1,1669852800,1,2,ABC,lots,1,10.01\n",
+			"Invalid direction":   header + "1,1669852800,Up,2,ABC,100,1,10.01\n",
+			"Different times":     header + "1,1669852800,1,2,ABC,100,1,10.01\n1,1669939200,1,2,XYZ,100,1,10.01\n",
+			"Unterminated quotes": header + "1,1669852800,1,2,\"ABC,100,1,10.01\n",
+		}
+		for key, input := range tests {
+			input := input
+			t.Run(key, func(t *testing.T) {
+				_, err := loader.Read(ctx, strings.NewReader(input))
+				require.Equal(t, status.Code(err), codes.InvalidArgument)
+			})
+		}
+	})
 }
 
 func TestJsonNewLineLoader(t *testing.T) {
@@ -90,6 +162,71 @@ func TestJsonNewLineLoader(t *testing.T) {
 			require.Equal(t, orderItem, bOrderItem)
 		}
 	}
+
+	t.Run("Human readable enums", func(t *testing.T) {
+		require.Contains(t, lines[0], `"direction":"Buy","item_type":"Stock"`)
+
+		// Files written before the enums had names used their numbers, and the last line may not have a new line
+		legacy := `{"time":1669852800,"items":[{"direction":2,"item_type":3,"symbol":"ABC CALL @ 10.0","amount":1,"quantity_per_amount":100,"price":101}]}`
+		output, err := loader.Read(ctx, strings.NewReader(legacy))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].OrderItems, []*OrderItem{NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 101)})
+	})
+
+	t.Run("Special floats", func(t *testing.T) {
+		input := []*Order{NewOrder(now, NewStockOrderItem(constants.Buy, "ABC", math.Inf(1), math.NaN()))}
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, input)
+		require.NoError(t, err)
+		require.Contains(t, buff.String(), `"amount":"Infinity"`)
+		require.Contains(t, buff.String(), `"price":"NaN"`)
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.True(t, math.IsInf(output[0].OrderItems[0].Amount, 1))
+		require.True(t, math.IsNaN(output[0].OrderItems[0].Price))
+	})
+
+	t.Run("Special execution prices", func(t *testing.T) {
+		// A trailing stop that starts on a bar with a NaN open has a NaN stop price
+		order := NewOrder(now, NewStockOrderItem(constants.Sell, "ABC", 100, 10))
+		order.Execution = NewTrailingStopExecution(1)
+		_, filled := order.Execution.Fill(constants.Sell, bar.New(now, math.NaN(), 12, 9, 11, 1000, -1))
+		require.False(t, filled)
+		require.True(t, math.IsNaN(order.Execution.StopPrice))
+
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, []*Order{order})
+		require.NoError(t, err)
+		require.Contains(t, buff.String(), `"stop_price":"NaN"`)
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].Execution.Type, constants.TrailingStopExecution)
+		require.Equal(t, output[0].Execution.TrailingAmount, 1.0)
+		require.True(t, math.IsNaN(output[0].Execution.StopPrice))
+	})
+
+	t.Run("Special fill prices", func(t *testing.T) {
+		order := NewOrder(now, NewStockOrderItem(constants.Buy, "ABC", 100, 10))
+		require.NoError(t, order.Accept(now))
+		require.NoError(t, order.AddFills(now, &Fill{UnixTime: now.Unix(), Item: 0, Amount: 50, Price: math.NaN()}))
+
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, []*Order{order})
+		require.NoError(t, err)
+		require.Contains(t, buff.String(), `"amount":50,"price":"NaN"`)
+
+		output, err := loader.Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Len(t, output[0].Fills, 1)
+		require.Equal(t, output[0].Fills[0].Amount, 50.0)
+		require.True(t, math.IsNaN(output[0].Fills[0].Price))
+	})
 }
 
 func TestAvroLoader(t *testing.T) {
@@ -128,6 +265,44 @@ func TestAvroLoader(t *testing.T) {
 			require.Equal(t, orderItem, bOrderItem)
 		}
 	}
+
+	t.Run("Snappy", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewAvroFileLoader(avro_file.Options{Compression: avro_file.Snappy, BlockLength: 1}).Write(ctx, buff, orders)
+		require.NoError(t, err)
+		require.Equal(t, buff.Bytes()[:4], avro_file.Magic)
+
+		output, err := NewAvroLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, len(orders))
+		require.Equal(t, output[1].OrderItems, orders[1].OrderItems)
+	})
+
+	t.Run("Raw datums", func(t *testing.T) {
+		// Files written before the container format are still readable
+		buff := bytes.NewBuffer([]byte{})
+		encoder := avro.NewEncoderForSchema(avroSchema, buff)
+		for _, order := range orders {
+			require.NoError(t, encoder.Encode(order))
+		}
+		output, err := NewAvroLoader().Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, len(orders))
+		require.Equal(t, output[0].OrderItems, orders[0].OrderItems)
+	})
+
+	t.Run("Incompatible schema", func(t *testing.T) {
+		// Orders without items can't be read by the current schema
+		schema := avro.MustParse(`{"type": "record", "name": "order", "namespace": "ta4g.ta4g", "fields": [{"name": "time", "type": "long"}]}`)
+		buff := bytes.NewBuffer([]byte{})
+		encoder, err := avro_file.NewEncoder(buff, schema, avro_file.DefaultOptions())
+		require.NoError(t, err)
+		require.NoError(t, encoder.Encode(map[string]interface{}{"time": int64(1)}))
+		require.NoError(t, encoder.Close())
+
+		_, err = NewAvroLoader().Read(ctx, buff)
+		require.Error(t, err)
+	})
 }
 
 func TestProtoLoader(t *testing.T) {
@@ -167,3 +342,93 @@ func TestProtoLoader(t *testing.T) {
 		}
 	}
 }
+
+func TestDelimitedProtoLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	buyCoveredCallOrder := NewOrder(
+		now,
+		NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+		NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+	)
+	sellCoveredCallOrder := NewOrder(
+		now.Add(10*time_series.Day),
+		NewStockOrderItem(constants.Sell, "ABC", 100, 10.01),
+		NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1.01*100),
+	)
+	orders := []*Order{buyCoveredCallOrder, NewOrder(now.Add(time_series.Day)), sellCoveredCallOrder}
+
+	ctx := context.Background()
+	loader := NewDelimitedProtoLoader()
+
+	t.Run("Round trip", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := loader.Write(ctx, buff, orders)
+		require.NoError(t, err)
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, len(orders))
+		for index, row := range output {
+			require.Equal(t, row.UnixTime, orders[index].UnixTime)
+			require.Equal(t, row.OrderItems, orders[index].OrderItems)
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := loader.NewWriter(ctx, buff)
+		require.NoError(t, err)
+		for _, row := range orders {
+			require.NoError(t, writer.WriteOrder(row))
+		}
+		require.NoError(t, writer.Close())
+
+		reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		for _, order := range orders {
+			row, err := reader.Next()
+			require.NoError(t, err)
+			require.Equal(t, row.UnixTime, order.UnixTime)
+			require.Equal(t, row.OrderItems, order.OrderItems)
+		}
+		_, err = reader.Next()
+		require.Equal(t, err, io.EOF)
+	})
+
+	t.Run("Compatible with writeDelimitedTo", func(t *testing.T) {
+		// The same framing as `writeDelimitedTo`, a varint length followed by the message
+		message, err := proto.Marshal(&pb.Order{
+			Time: timestamppb.New(now),
+			Items: []*pb.OrderItem{
+				{Direction: int64(constants.Buy), ItemType: int64(constants.Stock), Symbol: "ABC", Amount: 100, QuantityPerAmount: 1, Price: 10.01},
+			},
+		})
+		require.NoError(t, err)
+		data := protowire.AppendVarint(nil, uint64(len(message)))
+		data = append(data, message...)
+
+		output, err := loader.Read(ctx, bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].UnixTime, now.Unix())
+		require.Equal(t, output[0].OrderItems, []*OrderItem{NewStockOrderItem(constants.Buy, "ABC", 100, 10.01)})
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		output, err := loader.Read(ctx, bytes.NewReader([]byte{}))
+		require.NoError(t, err)
+		require.Empty(t, output)
+
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, orders[:1])
+		require.NoError(t, err)
+		data := buff.Bytes()
+
+		_, err = loader.Read(ctx, bytes.NewReader(data[:len(data)-1]))
+		require.Equal(t, status.Code(err), codes.DataLoss)
+		_, err = loader.Read(ctx, bytes.NewReader(protowire.AppendVarint(nil, proto_stream.MaxMessageSize+1)))
+		require.Equal(t, status.Code(err), codes.OutOfRange)
+	})
+}
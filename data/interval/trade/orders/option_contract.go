@@ -0,0 +1,201 @@
+package orders
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/json_float"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOptionMultiplier is the number of shares controlled by a standard equity option contract
+const DefaultOptionMultiplier = 100
+
+// occDateFormat is the expiration date within an OCC symbol, ex: "221216" is December 16th, 2022
+const occDateFormat = "060102"
+
+// OptionContract describes a single option contract, ex: the AAPL December 16th, 2022 $150 call
+type OptionContract struct {
+	// Underlying symbol the option is for, ex: "AAPL"
+	Underlying string `avro:"underlying" json:"underlying"`
+	// Strike price of the option
+	Strike float64 `avro:"strike" json:"strike"`
+	// Expiration is the unix time of midnight UTC on the day the option expires
+	Expiration int64 `avro:"expiration" json:"expiration"`
+	// Right - is it a call or a put?
+	Right constants.OptionRight `avro:"right" json:"right"`
+	// Style - when can the option be exercised?
+	Style constants.OptionStyle `avro:"style" json:"style"`
+	// Multiplier is the number of units of the underlying per contract, usually DefaultOptionMultiplier
+	Multiplier float64 `avro:"multiplier" json:"multiplier"`
+}
+
+// jsonOptionContract is the JSON form of an OptionContract, see json_float.Float
+type jsonOptionContract struct {
+	Underlying *string                `json:"underlying"`
+	Strike     *json_float.Float      `json:"strike"`
+	Expiration *int64                 `json:"expiration"`
+	Right      *constants.OptionRight `json:"right"`
+	Style      *constants.OptionStyle `json:"style"`
+	Multiplier *json_float.Float      `json:"multiplier"`
+}
+
+// NewOptionContract creates a standard American option contract for 100 shares of the underlying
+func NewOptionContract(underlying string, expiration time.Time, right constants.OptionRight, strike float64) *OptionContract {
+	return &OptionContract{
+		Underlying: underlying,
+		Strike:     strike,
+		Expiration: expirationDate(expiration).Unix(),
+		Right:      right,
+		Style:      constants.American,
+		Multiplier: DefaultOptionMultiplier,
+	}
+}
+
+// ParseOCCSymbol reads the option contract from it's OCC symbol, ex: "AAPL  221216C00150000".
+//
+// The symbol is the underlying padded to 6 characters, the expiration date as YYMMDD, C or P for the right,
+// and the strike price multiplied by 1000 as 8 digits. The padding is optional, ex: "AAPL221216C00150000".
+// OCC symbols don't include the style or multiplier, so the contracts are American with a multiplier of 100.
+//
+// Errors:
+// - If the symbol isn't an OCC symbol an error with GRPC status InvalidArgument will be returned
+//
+func ParseOCCSymbol(symbol string) (*OptionContract, error) {
+	symbol = strings.TrimSpace(symbol)
+	// The underlying is followed by 6 digits for the date, 1 letter for the right, and 8 digits for the strike
+	if len(symbol) < 16 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid OCC symbol %q", symbol)
+	}
+	suffix := symbol[len(symbol)-15:]
+	underlying := strings.TrimSpace(symbol[:len(symbol)-15])
+	if underlying == "" || len(underlying) > 6 || strings.ContainsAny(underlying, " \t") {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid OCC symbol %q: invalid underlying", symbol)
+	}
+
+	expiration, err := time.ParseInLocation(occDateFormat, suffix[:6], time.UTC)
+	if nil != err {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid OCC symbol %q: invalid expiration", symbol)
+	}
+	// Only the letters are allowed, ParseOptionRight also accepts numbers and lower case names
+	var right constants.OptionRight
+	switch suffix[6] {
+	case 'C':
+		right = constants.Call
+	case 'P':
+		right = constants.Put
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid OCC symbol %q: invalid right", symbol)
+	}
+	strike, err := strconv.ParseUint(suffix[7:], 10, 64)
+	if nil != err {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid OCC symbol %q: invalid strike", symbol)
+	}
+
+	return &OptionContract{
+		Underlying: underlying,
+		Strike:     float64(strike) / 1000,
+		Expiration: expiration.Unix(),
+		Right:      right,
+		Style:      constants.American,
+		Multiplier: DefaultOptionMultiplier,
+	}, nil
+}
+
+// OCCSymbol is the OCC symbol of the contract, ex: "AAPL  221216C00150000", see ParseOCCSymbol
+func (c *OptionContract) OCCSymbol() string {
+	right := "C"
+	if c.Right == constants.Put {
+		right = "P"
+	}
+	return fmt.Sprintf(
+		"%-6s%s%s%08d",
+		c.Underlying,
+		c.ExpirationTime().Format(occDateFormat),
+		right,
+		int64(math.Round(c.Strike*1000)),
+	)
+}
+
+// ExpirationTime is midnight UTC on the day the option expires
+func (c *OptionContract) ExpirationTime() time.Time {
+	return time.Unix(c.Expiration, 0).UTC()
+}
+
+// IsExpired checks if the option has expired by the time, options can still trade on the day they expire
+func (c *OptionContract) IsExpired(t time.Time) bool {
+	return !t.Before(c.ExpirationTime().AddDate(0, 0, 1))
+}
+
+// IntrinsicValue is the value of a single unit of the option when the underlying is at the price, ex: $5 for a $150 call at $155
+func (c *OptionContract) IntrinsicValue(price float64) float64 {
+	if c.Right == constants.Put {
+		return math.Max(0, c.Strike-price)
+	}
+	return math.Max(0, price-c.Strike)
+}
+
+// Validate checks the contract has an underlying, a positive strike and multiplier, an expiration, and a known right and style
+//
+// Errors:
+// - If any of the fields are missing or invalid an error with GRPC status InvalidArgument will be returned
+//
+func (c *OptionContract) Validate() error {
+	if c.Underlying == "" {
+		return status.Error(codes.InvalidArgument, "option contracts need an underlying")
+	}
+	if !(c.Strike > 0) || math.IsInf(c.Strike, 0) {
+		return status.Error(codes.InvalidArgument, "option strike must be positive")
+	}
+	if c.Expiration == 0 {
+		return status.Error(codes.InvalidArgument, "option contracts need an expiration")
+	}
+	if c.Right != constants.Call && c.Right != constants.Put {
+		return status.Errorf(codes.InvalidArgument, "unknown option right %d", c.Right)
+	}
+	if c.Style != constants.American && c.Style != constants.European {
+		return status.Errorf(codes.InvalidArgument, "unknown option style %d", c.Style)
+	}
+	if !(c.Multiplier > 0) || math.IsInf(c.Multiplier, 0) {
+		return status.Error(codes.InvalidArgument, "option multiplier must be positive")
+	}
+	return nil
+}
+
+func (c *OptionContract) jsonForm() *jsonOptionContract {
+	return &jsonOptionContract{
+		Underlying: &c.Underlying,
+		Strike:     (*json_float.Float)(&c.Strike),
+		Expiration: &c.Expiration,
+		Right:      &c.Right,
+		Style:      &c.Style,
+		Multiplier: (*json_float.Float)(&c.Multiplier),
+	}
+}
+
+func (c OptionContract) MarshalJSON() ([]byte, error) {
+	value := c.jsonForm()
+	return json.Marshal(value)
+}
+
+func (c *OptionContract) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, c.jsonForm())
+}
+
+func (c *OptionContract) Clone() *OptionContract {
+	if nil == c {
+		return nil
+	}
+	output := *c
+	return &output
+}
+
+// expirationDate is midnight UTC on the date of the time
+func expirationDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
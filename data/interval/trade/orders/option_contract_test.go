@@ -0,0 +1,233 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOCCSymbol(t *testing.T) {
+	// December 16th, 2022
+	expiration := time.Date(2022, 12, 16, 0, 0, 0, 0, time.UTC)
+
+	type args struct {
+		symbol   string
+		contract *OptionContract
+	}
+	tests := map[string]args{
+		"Call":              {symbol: "AAPL  221216C00150000", contract: NewOptionContract("AAPL", expiration, constants.Call, 150)},
+		"Put":               {symbol: "AAPL  221216P00150000", contract: NewOptionContract("AAPL", expiration, constants.Put, 150)},
+		"Fractional strike": {symbol: "ABC   221216C00010500", contract: NewOptionContract("ABC", expiration, constants.Call, 10.5)},
+		"Six letters":       {symbol: "GOOGLL221216P01234567", contract: NewOptionContract("GOOGLL", expiration, constants.Put, 1234.567)},
+		"One letter":        {symbol: "F     221216C00012000", contract: NewOptionContract("F", expiration, constants.Call, 12)},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.contract.OCCSymbol(), test.symbol)
+
+			contract, err := ParseOCCSymbol(test.symbol)
+			require.NoError(t, err)
+			require.Equal(t, contract, test.contract)
+			require.NoError(t, contract.Validate())
+		})
+	}
+
+	t.Run("Without padding", func(t *testing.T) {
+		contract, err := ParseOCCSymbol("AAPL221216C00150000")
+		require.NoError(t, err)
+		require.Equal(t, contract, tests["Call"].contract)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, symbol := range []string{
+			"",
+			"ABC CALL @ 10.0",
+			"221216C00150000",
+			"TOOLONG221216C00150000",
+			"AB CD 221216C00150000",
+			"AAPL  221316C00150000",
+			"AAPL  221216X00150000",
+			"AAPL  221216c00150000",
+			"AAPL  221216p00150000",
+			"AAPL  221216100150000",
+			"AAPL  221216200150000",
+			"AAPL  221216C0015000X",
+			"AAPL  221216C-0150000",
+		} {
+			_, err := ParseOCCSymbol(symbol)
+			require.Equal(t, status.Code(err), codes.InvalidArgument, symbol)
+		}
+	})
+}
+
+func TestOptionContract(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Expiration", func(t *testing.T) {
+		// The time of day is dropped
+		contract := NewOptionContract("ABC", now.Add(15*time_series.Day+10*time.Hour), constants.Call, 10)
+		require.Equal(t, contract.ExpirationTime(), now.Add(15*time_series.Day))
+		require.False(t, contract.IsExpired(now))
+		require.False(t, contract.IsExpired(now.Add(15*time_series.Day+23*time.Hour)))
+		require.True(t, contract.IsExpired(now.Add(16*time_series.Day)))
+	})
+
+	t.Run("Intrinsic value", func(t *testing.T) {
+		call := NewOptionContract("ABC", now, constants.Call, 10)
+		put := NewOptionContract("ABC", now, constants.Put, 10)
+		require.Equal(t, call.IntrinsicValue(12), 2.0)
+		require.Equal(t, call.IntrinsicValue(8), 0.0)
+		require.Equal(t, put.IntrinsicValue(12), 0.0)
+		require.Equal(t, put.IntrinsicValue(8), 2.0)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		tests := map[string]func(c *OptionContract){
+			"Underlying":  func(c *OptionContract) { c.Underlying = "" },
+			"Strike":      func(c *OptionContract) { c.Strike = 0 },
+			"Expiration":  func(c *OptionContract) { c.Expiration = 0 },
+			"Right":       func(c *OptionContract) { c.Right = 0 },
+			"Style":       func(c *OptionContract) { c.Style = 0 },
+			"Multiplier":  func(c *OptionContract) { c.Multiplier = -100 },
+			"Right range": func(c *OptionContract) { c.Right = constants.OptionRight(100) },
+		}
+		for name, change := range tests {
+			contract := NewOptionContract("ABC", now, constants.Call, 10)
+			require.NoError(t, contract.Validate())
+			change(contract)
+			require.Equal(t, status.Code(contract.Validate()), codes.InvalidArgument, name)
+		}
+	})
+
+	t.Run("Order items", func(t *testing.T) {
+		contract := NewOptionContract("ABC", now.Add(15*time_series.Day), constants.Call, 10)
+		contract.Multiplier = 10
+		item := NewOptionContractOrderItem(constants.Sell, contract, 2, 1.01)
+		require.Equal(t, item.Symbol, "ABC   221216C00010000")
+		require.Equal(t, item.ItemType, constants.Option)
+		require.Equal(t, item.QuantityPerAmount, 10.0)
+		require.Equal(t, item.Option, contract)
+
+		// OCC symbols are parsed into their contract, other symbols don't have one
+		require.Equal(t, NewOptionOrderItem(constants.Buy, "ABC   221216C00010000", 1, 1.01).Option.Strike, 10.0)
+		require.Nil(t, NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1.01).Option)
+
+		clone := item.Clone()
+		require.Equal(t, clone, item)
+		clone.Option.Strike = 11
+		require.Equal(t, item.Option.Strike, 10.0)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		contract := NewOptionContract("ABC", now.Add(15*time_series.Day), constants.Put, 10)
+		data, err := json.Marshal(contract)
+		require.NoError(t, err)
+		require.JSONEq(t, string(data), `{"underlying":"ABC","strike":10,"expiration":1671148800,"right":"put","style":"american","multiplier":100}`)
+
+		output := &OptionContract{}
+		require.NoError(t, json.Unmarshal(data, output))
+		require.Equal(t, output, contract)
+
+		// NaN and ±Inf are kept, json.Marshal would fail on them otherwise
+		contract.Strike = math.NaN()
+		contract.Multiplier = math.Inf(1)
+		data, err = json.Marshal(contract)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"strike":"NaN"`)
+		require.Contains(t, string(data), `"multiplier":"Infinity"`)
+
+		output = &OptionContract{}
+		require.NoError(t, json.Unmarshal(data, output))
+		require.True(t, math.IsNaN(output.Strike))
+		require.True(t, math.IsInf(output.Multiplier, 1))
+	})
+}
+
+func TestOptionContractLoaders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	european := NewOptionContract("SPX", now.Add(15*time_series.Day), constants.Put, 3900.5)
+	european.Style = constants.European
+	coveredCall := NewOrder(
+		now,
+		NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+		NewOptionContractOrderItem(constants.Sell, NewOptionContract("ABC", now.Add(15*time_series.Day), constants.Call, 10), 1, 1.01),
+	)
+	hedge := NewOrder(now.Add(time_series.Day), NewOptionContractOrderItem(constants.Buy, european, 2, 45.25))
+	// Options without a contract are unchanged
+	plain := NewOrder(now.Add(2*time_series.Day), NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1.01))
+	orders := []*Order{coveredCall, hedge, plain}
+
+	ctx := context.Background()
+	loaders := map[string]Loader{
+		"CSV":             NewCSVLoader(),
+		"JSON New Line":   NewJsonNewLineLoader(),
+		"Avro":            NewAvroLoader(),
+		"Proto":           NewProtoLoader(),
+		"Delimited Proto": NewDelimitedProtoLoader(),
+		"Parquet":         NewParquetLoader(parquet_file.DefaultOptions()),
+	}
+	for name, loader := range loaders {
+		loader := loader
+		t.Run(name, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, output, orders)
+		})
+	}
+
+	t.Run("Parquet files without option contracts", func(t *testing.T) {
+		// The item schema before option contracts were added
+		type parquetOrderItemWithoutOption struct {
+			Direction         int32   `parquet:"name=direction, type=INT32"`
+			ItemType          int32   `parquet:"name=item_type, type=INT32"`
+			Symbol            string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+			Amount            float64 `parquet:"name=amount, type=DOUBLE"`
+			QuantityPerAmount float64 `parquet:"name=quantity_per_amount, type=DOUBLE"`
+			Price             float64 `parquet:"name=price, type=DOUBLE"`
+		}
+		type parquetOrderWithoutOption struct {
+			Time  int64                           `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+			Items []parquetOrderItemWithoutOption `parquet:"name=items, repetitiontype=REPEATED"`
+		}
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := parquet_file.NewWriter(buff, new(parquetOrderWithoutOption), parquet_file.DefaultOptions())
+		require.NoError(t, err)
+		row := &parquetOrderWithoutOption{Time: coveredCall.UnixTime * 1000}
+		for _, item := range toParquet(coveredCall).Items {
+			row.Items = append(row.Items, parquetOrderItemWithoutOption{
+				Direction:         item.Direction,
+				ItemType:          item.ItemType,
+				Symbol:            item.Symbol,
+				Amount:            item.Amount,
+				QuantityPerAmount: item.QuantityPerAmount,
+				Price:             item.Price,
+			})
+		}
+		require.NoError(t, writer.Write(row))
+		require.NoError(t, writer.WriteStop())
+
+		output, err := NewParquetLoader(parquet_file.DefaultOptions()).Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Len(t, output[0].OrderItems, 2)
+		require.Equal(t, output[0].OrderItems[1].Symbol, "ABC   221216C00010000")
+		require.Nil(t, output[0].OrderItems[1].Option)
+	})
+}
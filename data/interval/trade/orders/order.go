@@ -1,15 +1,34 @@
 package orders
 
 import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
 	"time"
 )
 
 // Order represents a collection of the items that are purchased or sold in a single batch
 type Order struct {
-	// UnixTime the order was placed, for back-testing we will assume all orders are filled immediately.
+	// UnixTime the order was placed
 	UnixTime int64 `csv:"time" avro:"time" json:"time"`
 	// OrderItems are all of the items that are purchased or sold
 	OrderItems []*OrderItem `csv:"items" avro:"items" json:"items"`
+	// Execution is how the order is filled, see Execution.Fill.
+	// For back-testing, orders without an execution are assumed to be market orders that are filled immediately.
+	Execution *Execution `csv:"-" avro:"execution" json:"execution,omitempty"`
+
+	// ID of the order, which is given to the order when it's accepted, see NewOrderID
+	ID string `csv:"-" avro:"id" json:"id,omitempty"`
+	// ClientTag is any text the client uses to find the order again, ex: the name of the strategy that placed it
+	ClientTag string `csv:"-" avro:"client_tag" json:"client_tag,omitempty"`
+	// TimeInForce is how long the order stays open, orders without one stay open until they are filled or cancelled
+	TimeInForce constants.TimeInForce `csv:"-" avro:"time_in_force" json:"time_in_force,omitempty"`
+	// ExpireTime is the unix time a good till date order expires
+	ExpireTime int64 `csv:"-" avro:"expire_time" json:"expire_time,omitempty"`
+	// State of the order within it's lifecycle, see Transition
+	State constants.OrderState `csv:"-" avro:"state" json:"state,omitempty"`
+	// Fills of the order items so far
+	Fills []*Fill `csv:"-" avro:"fills" json:"fills,omitempty"`
+	// Events are every transition of the order, oldest first
+	Events []*Event `csv:"-" avro:"events" json:"events,omitempty"`
 }
 
 func NewOrder(t time.Time, items ...*OrderItem) *Order {
@@ -47,8 +66,23 @@ func (s *Order) RemoveItem(index int) {
 }
 
 func (s *Order) Clone() *Order {
-	return NewOrder(
+	output := NewOrder(
 		time.Unix(s.UnixTime, 0),
 		s.OrderItems...,
 	)
+	output.Execution = s.Execution.Clone()
+	output.ID = s.ID
+	output.ClientTag = s.ClientTag
+	output.TimeInForce = s.TimeInForce
+	output.ExpireTime = s.ExpireTime
+	output.State = s.State
+	for _, fill := range s.Fills {
+		clone := *fill
+		output.Fills = append(output.Fills, &clone)
+	}
+	for _, event := range s.Events {
+		clone := *event
+		output.Events = append(output.Events, &clone)
+	}
+	return output
 }
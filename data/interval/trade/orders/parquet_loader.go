@@ -0,0 +1,370 @@
+package orders
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"time"
+)
+
+//
+// Parquet Loader
+//
+// Each order is a single row, and it's items are stored as a repeated group within the row.
+// The rows are read one batch at a time, so only a single batch of orders is decoded at once.
+// Files written before orders had an execution, a lifecycle, or contracts don't have those columns, and are read without them.
+//
+
+// Compile time type assertion
+var _ sliceLoader = &parquetLoader{}
+
+// parquetOrder is the parquet schema of a single order
+type parquetOrder struct {
+	Time        int64              `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Items       []parquetOrderItem `parquet:"name=items, repetitiontype=REPEATED"`
+	Execution   *parquetExecution  `parquet:"name=execution, repetitiontype=OPTIONAL"`
+	ID          string             `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ClientTag   string             `parquet:"name=client_tag, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TimeInForce int32              `parquet:"name=time_in_force, type=INT32"`
+	ExpireTime  int64              `parquet:"name=expire_time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	State       int32              `parquet:"name=state, type=INT32"`
+	Fills       []parquetFill      `parquet:"name=fills, repetitiontype=REPEATED"`
+	Events      []parquetEvent     `parquet:"name=events, repetitiontype=REPEATED"`
+}
+
+// parquetExecution is the parquet schema of an order's execution
+type parquetExecution struct {
+	Type            int32   `parquet:"name=type, type=INT32"`
+	LimitPrice      float64 `parquet:"name=limit_price, type=DOUBLE"`
+	StopPrice       float64 `parquet:"name=stop_price, type=DOUBLE"`
+	TrailingAmount  float64 `parquet:"name=trailing_amount, type=DOUBLE"`
+	TrailingPercent float64 `parquet:"name=trailing_percent, type=DOUBLE"`
+	Triggered       bool    `parquet:"name=triggered, type=BOOLEAN"`
+}
+
+// parquetFill is the parquet schema of a single fill within an order
+type parquetFill struct {
+	Time   int64   `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Item   int32   `parquet:"name=item, type=INT32"`
+	Amount float64 `parquet:"name=amount, type=DOUBLE"`
+	Price  float64 `parquet:"name=price, type=DOUBLE"`
+}
+
+// parquetEvent is the parquet schema of a single event within an order
+type parquetEvent struct {
+	Time   int64  `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	State  int32  `parquet:"name=state, type=INT32"`
+	Reason string `parquet:"name=reason, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// parquetOrderItem is the parquet schema of a single item within an order
+type parquetOrderItem struct {
+	Direction         int32                  `parquet:"name=direction, type=INT32"`
+	ItemType          int32                  `parquet:"name=item_type, type=INT32"`
+	Symbol            string                 `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Amount            float64                `parquet:"name=amount, type=DOUBLE"`
+	QuantityPerAmount float64                `parquet:"name=quantity_per_amount, type=DOUBLE"`
+	Price             float64                `parquet:"name=price, type=DOUBLE"`
+	Option            *parquetOptionContract `parquet:"name=option, repetitiontype=OPTIONAL"`
+	Future            *parquetFutureContract `parquet:"name=future, repetitiontype=OPTIONAL"`
+	Forex             *parquetForexPair      `parquet:"name=forex, repetitiontype=OPTIONAL"`
+}
+
+// parquetOptionContract is the parquet schema of an option item's contract
+type parquetOptionContract struct {
+	Underlying string  `parquet:"name=underlying, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Strike     float64 `parquet:"name=strike, type=DOUBLE"`
+	Expiration int64   `parquet:"name=expiration, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Right      int32   `parquet:"name=right, type=INT32"`
+	Style      int32   `parquet:"name=style, type=INT32"`
+	Multiplier float64 `parquet:"name=multiplier, type=DOUBLE"`
+}
+
+// parquetFutureContract is the parquet schema of a future item's contract
+type parquetFutureContract struct {
+	Root              string  `parquet:"name=root, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TickSize          float64 `parquet:"name=tick_size, type=DOUBLE"`
+	TickValue         float64 `parquet:"name=tick_value, type=DOUBLE"`
+	Multiplier        float64 `parquet:"name=multiplier, type=DOUBLE"`
+	InitialMargin     float64 `parquet:"name=initial_margin, type=DOUBLE"`
+	MaintenanceMargin float64 `parquet:"name=maintenance_margin, type=DOUBLE"`
+	Expiration        int64   `parquet:"name=expiration, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	RollDate          int64   `parquet:"name=roll_date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+}
+
+// parquetForexPair is the parquet schema of a forex item's currency pair
+type parquetForexPair struct {
+	Base       string  `parquet:"name=base, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Quote      string  `parquet:"name=quote, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PipSize    float64 `parquet:"name=pip_size, type=DOUBLE"`
+	LotSize    float64 `parquet:"name=lot_size, type=DOUBLE"`
+	MarginRate float64 `parquet:"name=margin_rate, type=DOUBLE"`
+}
+
+type parquetLoader struct {
+	options parquet_file.Options
+}
+
+func NewParquetLoader(options parquet_file.Options) Loader {
+	return newBufferedLoader(&parquetLoader{options: options.WithDefaults()})
+}
+
+func (p parquetLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
+	logger := ctxzap.Extract(ctx)
+
+	file, err := parquet_file.NewFile(input)
+	if nil != err {
+		logger.Error("Failed to open file", zap.Error(err))
+		return nil, err
+	}
+	fileReader, err := parquet_file.NewReader(file, new(parquetOrder), 1)
+	if nil != err {
+		logger.Error("Failed to read footer", zap.Error(err))
+		return nil, err
+	}
+	defer fileReader.ReadStop()
+
+	remaining := fileReader.GetNumRows()
+	output := make([]*Order, 0, remaining)
+	for remaining > 0 {
+		size := int64(p.options.BatchSize)
+		if size > remaining {
+			size = remaining
+		}
+
+		rows := make([]parquetOrder, size)
+		err = fileReader.Read(&rows)
+		if nil != err {
+			logger.Error("Failed to unmarshal rows", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if int64(len(rows)) != size {
+			logger.Error("Failed to unmarshal rows", zap.Int("rows", len(rows)))
+			return nil, status.Error(codes.DataLoss, "short read")
+		}
+
+		for _, row := range rows {
+			output = append(output, fromParquet(row))
+		}
+		remaining -= size
+	}
+	return output, nil
+}
+
+func (p parquetLoader) Write(ctx context.Context, output io.Writer, input []*Order) error {
+	logger := ctxzap.Extract(ctx)
+
+	fileWriter, err := parquet_file.NewWriter(output, new(parquetOrder), p.options)
+	if nil != err {
+		logger.Error("Failed to create writer", zap.Error(err))
+		return err
+	}
+
+	for _, order := range input {
+		err = fileWriter.Write(toParquet(order))
+		if nil != err {
+			logger.Error("Failed to marshal row", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	// Flush the last row group and write the footer
+	err = fileWriter.WriteStop()
+	if nil != err {
+		logger.Error("Failed to write footer", zap.Error(err))
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func toParquet(order *Order) *parquetOrder {
+	items := make([]parquetOrderItem, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		items = append(items, parquetOrderItem{
+			Direction:         int32(item.Direction),
+			ItemType:          int32(item.ItemType),
+			Symbol:            item.Symbol,
+			Amount:            item.Amount,
+			QuantityPerAmount: item.QuantityPerAmount,
+			Price:             item.Price,
+			Option:            toParquetOption(item.Option),
+			Future:            toParquetFuture(item.Future),
+			Forex:             toParquetForex(item.Forex),
+		})
+	}
+	output := &parquetOrder{
+		Time:  order.UnixTime * 1000,
+		Items: items,
+	}
+	if execution := order.Execution; nil != execution {
+		output.Execution = &parquetExecution{
+			Type:            int32(execution.Type),
+			LimitPrice:      execution.LimitPrice,
+			StopPrice:       execution.StopPrice,
+			TrailingAmount:  execution.TrailingAmount,
+			TrailingPercent: execution.TrailingPercent,
+			Triggered:       execution.Triggered,
+		}
+	}
+	output.ID = order.ID
+	output.ClientTag = order.ClientTag
+	output.TimeInForce = int32(order.TimeInForce)
+	output.ExpireTime = order.ExpireTime * 1000
+	output.State = int32(order.State)
+	for _, fill := range order.Fills {
+		output.Fills = append(output.Fills, parquetFill{
+			Time:   fill.UnixTime * 1000,
+			Item:   int32(fill.Item),
+			Amount: fill.Amount,
+			Price:  fill.Price,
+		})
+	}
+	for _, event := range order.Events {
+		output.Events = append(output.Events, parquetEvent{
+			Time:   event.UnixTime * 1000,
+			State:  int32(event.State),
+			Reason: event.Reason,
+		})
+	}
+	return output
+}
+
+func fromParquet(row parquetOrder) *Order {
+	items := make([]*OrderItem, 0, len(row.Items))
+	for _, item := range row.Items {
+		items = append(items, &OrderItem{
+			Direction:         constants.Direction(item.Direction),
+			ItemType:          constants.ItemType(item.ItemType),
+			Symbol:            item.Symbol,
+			Amount:            item.Amount,
+			QuantityPerAmount: item.QuantityPerAmount,
+			Price:             item.Price,
+			Option:            fromParquetOption(item.Option),
+			Future:            fromParquetFuture(item.Future),
+			Forex:             fromParquetForex(item.Forex),
+		})
+	}
+	output := NewOrder(time.Unix(0, row.Time*int64(time.Millisecond)), items...)
+	if execution := row.Execution; nil != execution {
+		output.Execution = &Execution{
+			Type:            constants.ExecutionType(execution.Type),
+			LimitPrice:      execution.LimitPrice,
+			StopPrice:       execution.StopPrice,
+			TrailingAmount:  execution.TrailingAmount,
+			TrailingPercent: execution.TrailingPercent,
+			Triggered:       execution.Triggered,
+		}
+	}
+	output.ID = row.ID
+	output.ClientTag = row.ClientTag
+	output.TimeInForce = constants.TimeInForce(row.TimeInForce)
+	output.ExpireTime = row.ExpireTime / 1000
+	output.State = constants.OrderState(row.State)
+	for _, fill := range row.Fills {
+		output.Fills = append(output.Fills, &Fill{
+			UnixTime: fill.Time / 1000,
+			Item:     int(fill.Item),
+			Amount:   fill.Amount,
+			Price:    fill.Price,
+		})
+	}
+	for _, event := range row.Events {
+		output.Events = append(output.Events, &Event{
+			UnixTime: event.Time / 1000,
+			State:    constants.OrderState(event.State),
+			Reason:   event.Reason,
+		})
+	}
+	return output
+}
+
+func toParquetOption(option *OptionContract) *parquetOptionContract {
+	if nil == option {
+		return nil
+	}
+	return &parquetOptionContract{
+		Underlying: option.Underlying,
+		Strike:     option.Strike,
+		Expiration: option.Expiration * 1000,
+		Right:      int32(option.Right),
+		Style:      int32(option.Style),
+		Multiplier: option.Multiplier,
+	}
+}
+
+func fromParquetOption(option *parquetOptionContract) *OptionContract {
+	if nil == option {
+		return nil
+	}
+	return &OptionContract{
+		Underlying: option.Underlying,
+		Strike:     option.Strike,
+		Expiration: option.Expiration / 1000,
+		Right:      constants.OptionRight(option.Right),
+		Style:      constants.OptionStyle(option.Style),
+		Multiplier: option.Multiplier,
+	}
+}
+
+func toParquetFuture(future *FutureContract) *parquetFutureContract {
+	if nil == future {
+		return nil
+	}
+	return &parquetFutureContract{
+		Root:              future.Root,
+		TickSize:          future.TickSize,
+		TickValue:         future.TickValue,
+		Multiplier:        future.Multiplier,
+		InitialMargin:     future.InitialMargin,
+		MaintenanceMargin: future.MaintenanceMargin,
+		Expiration:        future.Expiration * 1000,
+		RollDate:          future.RollDate * 1000,
+	}
+}
+
+func fromParquetFuture(future *parquetFutureContract) *FutureContract {
+	if nil == future {
+		return nil
+	}
+	return &FutureContract{
+		Root:              future.Root,
+		TickSize:          future.TickSize,
+		TickValue:         future.TickValue,
+		Multiplier:        future.Multiplier,
+		InitialMargin:     future.InitialMargin,
+		MaintenanceMargin: future.MaintenanceMargin,
+		Expiration:        future.Expiration / 1000,
+		RollDate:          future.RollDate / 1000,
+	}
+}
+
+func toParquetForex(forex *ForexPair) *parquetForexPair {
+	if nil == forex {
+		return nil
+	}
+	return &parquetForexPair{
+		Base:       forex.Base,
+		Quote:      forex.Quote,
+		PipSize:    forex.PipSize,
+		LotSize:    forex.LotSize,
+		MarginRate: forex.MarginRate,
+	}
+}
+
+func fromParquetForex(forex *parquetForexPair) *ForexPair {
+	if nil == forex {
+		return nil
+	}
+	return &ForexPair{
+		Base:       forex.Base,
+		Quote:      forex.Quote,
+		PipSize:    forex.PipSize,
+		LotSize:    forex.LotSize,
+		MarginRate: forex.MarginRate,
+	}
+}
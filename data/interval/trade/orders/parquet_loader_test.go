@@ -0,0 +1,80 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"testing"
+	"time"
+)
+
+func TestParquetLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	buyCoveredCallOrder := NewOrder(
+		now,
+		NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+		NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+	)
+	sellCoveredCallOrder := NewOrder(
+		now.Add(10*time_series.Day),
+		NewStockOrderItem(constants.Sell, "ABC", 100, 10.01),
+		NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1.01*100),
+	)
+	emptyOrder := NewOrder(now.Add(11 * time_series.Day))
+	orders := []*Order{buyCoveredCallOrder, sellCoveredCallOrder, emptyOrder}
+
+	ctx := context.Background()
+
+	for _, compression := range []parquet_file.Compression{parquet_file.Snappy, parquet_file.Zstd} {
+		compression := compression
+		t.Run(compression.String(), func(t *testing.T) {
+			loader := NewParquetLoader(parquet_file.Options{Compression: compression, BatchSize: 2})
+
+			buff := bytes.NewBuffer([]byte{})
+			err := loader.Write(ctx, buff, orders)
+			require.NoError(t, err)
+
+			output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err)
+			require.Len(t, output, len(orders))
+			for index, row := range output {
+				b := orders[index]
+				require.Equal(t, row.UnixTime, b.UnixTime)
+				require.Len(t, row.OrderItems, len(b.OrderItems))
+				for itemIndex, orderItem := range row.OrderItems {
+					bOrderItem := b.OrderItems[itemIndex]
+					require.Equal(t, orderItem, bOrderItem)
+				}
+			}
+		})
+	}
+
+	t.Run("Round trip with the Avro loader", func(t *testing.T) {
+		avroBuff := bytes.NewBuffer([]byte{})
+		err := NewAvroLoader().Write(ctx, avroBuff, orders)
+		require.NoError(t, err)
+		avroOrders, err := NewAvroLoader().Read(ctx, avroBuff)
+		require.NoError(t, err)
+
+		loader := NewParquetLoader(parquet_file.DefaultOptions())
+		buff := bytes.NewBuffer([]byte{})
+		err = loader.Write(ctx, buff, avroOrders)
+		require.NoError(t, err)
+
+		output, err := loader.Read(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		require.Len(t, output, len(avroOrders))
+		for index, row := range output {
+			require.Equal(t, row.UnixTime, avroOrders[index].UnixTime)
+			require.Len(t, row.OrderItems, len(avroOrders[index].OrderItems))
+			for itemIndex, orderItem := range row.OrderItems {
+				require.Equal(t, orderItem, avroOrders[index].OrderItems[itemIndex])
+			}
+		}
+	})
+}
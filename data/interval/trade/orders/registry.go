@@ -0,0 +1,214 @@
+package orders
+
+import (
+	"context"
+	"github.com/ta4g/ta4g/data/avro_file"
+	"github.com/ta4g/ta4g/data/file_format"
+	"github.com/ta4g/ta4g/data/parquet_file"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync"
+)
+
+// Names of the built-in formats
+const (
+	CSVFormat            = "csv"
+	JsonNewLineFormat    = "ndjson"
+	AvroFormat           = "avro"
+	ProtoFormat          = "proto"
+	ParquetFormat        = "parquet"
+	DelimitedProtoFormat = "proto_delimited"
+)
+
+// parquetMagic are the first bytes of every parquet file
+var parquetMagic = []byte("PAR1")
+
+// Format is an order file format that can be found by it's name, file extension, or content
+type Format struct {
+	file_format.Format
+	// Loader creates a new Loader for the format
+	Loader func() Loader
+}
+
+var formats = file_format.NewRegistry()
+var formatLoaders = map[string]func() Loader{}
+var formatLoadersMutex sync.RWMutex
+
+func init() {
+	builtin := []Format{
+		{
+			Format: file_format.Format{Name: CSVFormat, Extensions: []string{".csv"}, Sniff: file_format.SniffDelimited(',')},
+			Loader: NewCSVLoader,
+		},
+		{
+			Format: file_format.Format{Name: JsonNewLineFormat, Extensions: []string{".ndjson", ".jsonl"}, Sniff: file_format.SniffJSON},
+			Loader: NewJsonNewLineLoader,
+		},
+		{
+			Format: file_format.Format{Name: AvroFormat, Extensions: []string{".avro"}, Sniff: file_format.SniffPrefix(avro_file.Magic)},
+			Loader: NewAvroLoader,
+		},
+		{
+			// Every order is field 1 of the Orders message, so the first byte is always the same tag
+			Format: file_format.Format{Name: ProtoFormat, Extensions: []string{".pb"}, Sniff: file_format.SniffPrefix([]byte{0x0a})},
+			Loader: NewProtoLoader,
+		},
+		{
+			Format: file_format.Format{Name: ParquetFormat, Extensions: []string{".parquet"}, Sniff: file_format.SniffPrefix(parquetMagic)},
+			Loader: func() Loader {
+				return NewParquetLoader(parquet_file.DefaultOptions())
+			},
+		},
+		{
+			// Shares .pb with the proto format, the first byte is the length of an order instead of the tag of Orders.orders
+			Format: file_format.Format{Name: DelimitedProtoFormat, Extensions: []string{".pbd", ".pb"}, Sniff: file_format.SniffLengthDelimited(0x0a)},
+			Loader: func() Loader {
+				return NewDelimitedProtoLoader()
+			},
+		},
+	}
+	for _, format := range builtin {
+		err := RegisterFormat(format)
+		if nil != err {
+			panic(err)
+		}
+	}
+}
+
+// RegisterFormat adds a new order format, so it can be found by NewAutoLoader and LookupFormat.
+// Third-party packages usually register their formats from an `init()` function.
+//
+// Errors:
+// - If the Loader is missing an error with GRPC status InvalidArgument will be returned
+// - See file_format.Registry.Register
+//
+func RegisterFormat(format Format) error {
+	if nil == format.Loader {
+		return status.Error(codes.InvalidArgument, "format loader is required")
+	}
+	formatLoadersMutex.Lock()
+	defer formatLoadersMutex.Unlock()
+	err := formats.Register(format.Format)
+	if nil != err {
+		return err
+	}
+	formatLoaders[format.Name] = format.Loader
+	return nil
+}
+
+// LookupFormat finds an order format by it's name
+//
+// Errors:
+// - If there is no format with the name an error with GRPC status NotFound will be returned
+//
+func LookupFormat(name string) (Format, error) {
+	format, err := formats.Lookup(name)
+	if nil != err {
+		return Format{}, err
+	}
+	return Format{Format: format, Loader: formatLoader(name)}, nil
+}
+
+// Formats lists every order format in the order they were registered
+func Formats() []Format {
+	output := make([]Format, 0)
+	for _, format := range formats.Formats() {
+		output = append(output, Format{Format: format, Loader: formatLoader(format.Name)})
+	}
+	return output
+}
+
+func formatLoader(name string) func() Loader {
+	formatLoadersMutex.RLock()
+	defer formatLoadersMutex.RUnlock()
+	return formatLoaders[name]
+}
+
+//
+// Auto Loader
+//
+
+// Compile time type assertion
+var _ StreamLoader = &autoLoader{}
+
+type autoLoader struct {
+	path string
+}
+
+// closingReader closes the decompressed input once the stream has ended, and keeps returning the error that ended it
+type closingReader struct {
+	Reader
+	closer io.Closer
+	err    error
+}
+
+// closingWriter closes the compressed output after the format's writer has been flushed
+type closingWriter struct {
+	Writer
+	closer io.Closer
+}
+
+// NewAutoLoader picks the format from the file's path, see file_format.Registry.Match:
+// 1. A compression extension, ex: "orders.csv.gz", is decompressed while reading and compressed while writing.
+//    Compressed inputs without a compression extension are detected from their magic bytes.
+// 2. The remaining extension chooses the format, ex: ".csv"
+// 3. When the extension is ambiguous or unknown the content is sniffed, an empty path always sniffs the content
+//
+// Errors:
+// - If no format matches an error with GRPC status NotFound will be returned
+//
+func NewAutoLoader(path string) Loader {
+	return NewLoader(&autoLoader{path: path})
+}
+
+func (a autoLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	format, decompressed, err := formats.Open(a.path, input)
+	if nil != err {
+		return nil, err
+	}
+	reader, err := formatLoader(format.Name)().NewReader(ctx, decompressed)
+	if nil != err {
+		decompressed.Close()
+		return nil, err
+	}
+	return &closingReader{Reader: reader, closer: decompressed}, nil
+}
+
+func (a autoLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	format, compressed, err := formats.Create(a.path, output)
+	if nil != err {
+		return nil, err
+	}
+	writer, err := formatLoader(format.Name)().NewWriter(ctx, compressed)
+	if nil != err {
+		compressed.Close()
+		return nil, err
+	}
+	return &closingWriter{Writer: writer, closer: compressed}, nil
+}
+
+func (c *closingReader) Next() (*Order, error) {
+	if nil != c.err {
+		return nil, c.err
+	}
+	row, err := c.Reader.Next()
+	if nil != err {
+		c.err = err
+		c.closer.Close()
+	}
+	return row, err
+}
+
+func (c *closingWriter) Close() error {
+	err := c.Writer.Close()
+	if nil != err {
+		c.closer.Close()
+		return err
+	}
+	err = c.closer.Close()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/file_format"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestFormats(t *testing.T) {
+	names := make([]string, 0)
+	for _, format := range Formats() {
+		names = append(names, format.Name)
+		require.NotNil(t, format.Loader)
+	}
+	require.Equal(t, names[:6], []string{CSVFormat, JsonNewLineFormat, AvroFormat, ProtoFormat, ParquetFormat, DelimitedProtoFormat})
+
+	format, err := LookupFormat(ParquetFormat)
+	require.NoError(t, err)
+	require.Equal(t, format.Extensions, []string{".parquet"})
+
+	_, err = LookupFormat("xml")
+	require.Equal(t, status.Code(err), codes.NotFound)
+
+	err = RegisterFormat(Format{Format: file_format.Format{Name: "missing loader"}})
+	require.Equal(t, status.Code(err), codes.InvalidArgument)
+	err = RegisterFormat(Format{Format: file_format.Format{Name: AvroFormat}, Loader: NewAvroLoader})
+	require.Equal(t, status.Code(err), codes.AlreadyExists)
+}
+
+func TestAutoLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	orders := []*Order{
+		NewOrder(
+			now,
+			NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+			NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+		),
+		NewOrder(
+			now.Add(10*time_series.Day),
+			NewStockOrderItem(constants.Sell, "ABC", 100, 10.01),
+			NewOptionOrderItem(constants.Buy, "ABC CALL @ 10.0", 1, 1.01*100),
+		),
+	}
+	ctx := context.Background()
+
+	paths := []string{
+		"orders.csv",
+		"orders.csv.gz",
+		"orders.ndjson",
+		"orders.avro",
+		"orders.pb",
+		"orders.parquet",
+		"orders.jsonl.gz",
+		"orders.avro.zst",
+		"orders.pb.lz4",
+		"orders.pbd",
+		"orders.pbd.gz",
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			buff := bytes.NewBuffer([]byte{})
+			err := NewAutoLoader(path).Write(ctx, buff, orders)
+			require.NoError(t, err)
+			data := buff.Bytes()
+
+			output, err := NewAutoLoader(path).Read(ctx, bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Len(t, output, len(orders))
+			for index, row := range output {
+				require.Equal(t, row.UnixTime, orders[index].UnixTime)
+				require.Equal(t, row.OrderItems, orders[index].OrderItems)
+			}
+
+			// Without a path the compression and format are detected from the content
+			output, err = NewAutoLoader("").Read(ctx, bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Len(t, output, len(orders))
+		})
+	}
+
+	t.Run("Delimited proto sharing the .pb extension", func(t *testing.T) {
+		buff := bytes.NewBuffer([]byte{})
+		err := NewDelimitedProtoLoader().Write(ctx, buff, orders)
+		require.NoError(t, err)
+
+		output, err := NewAutoLoader("orders.pb").Read(ctx, buff)
+		require.NoError(t, err)
+		require.Len(t, output, len(orders))
+		for index, row := range output {
+			require.Equal(t, row.UnixTime, orders[index].UnixTime)
+			require.Equal(t, row.OrderItems, orders[index].OrderItems)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		err := NewAutoLoader("orders.xml").Write(ctx, bytes.NewBuffer([]byte{}), orders)
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+}
@@ -0,0 +1,178 @@
+package orders
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"io"
+)
+
+// Reader streams orders from an input one at a time, so the whole input never has to fit in memory.
+type Reader interface {
+	// Next returns the next order in the stream, or io.EOF once there are no more orders
+	Next() (*Order, error)
+}
+
+// Writer streams orders to an output one at a time.
+type Writer interface {
+	// WriteOrder appends a single order to the output
+	WriteOrder(order *Order) error
+
+	// Close flushes any buffered orders to the output.
+	// This does not close the underlying io.Writer, that is still owned by the caller.
+	Close() error
+}
+
+// StreamLoader opens streaming readers and writers for a single format.
+type StreamLoader interface {
+	NewReader(ctx context.Context, input io.Reader) (Reader, error)
+	NewWriter(ctx context.Context, output io.Writer) (Writer, error)
+}
+
+// Compile time type assertions
+var _ Loader = &streamLoader{}
+var _ Loader = &bufferedLoader{}
+
+// streamLoader builds the slice based Loader on top of a StreamLoader
+type streamLoader struct {
+	StreamLoader
+}
+
+// NewLoader creates a slice based Loader from any StreamLoader
+func NewLoader(loader StreamLoader) Loader {
+	return &streamLoader{StreamLoader: loader}
+}
+
+func (s streamLoader) Read(ctx context.Context, input io.Reader) ([]*Order, error) {
+	logger := ctxzap.Extract(ctx)
+
+	reader, err := s.NewReader(ctx, input)
+	if nil != err {
+		logger.Error("Failed to open reader", zap.Error(err))
+		return nil, err
+	}
+	return ReadAll(reader)
+}
+
+func (s streamLoader) Write(ctx context.Context, output io.Writer, input []*Order) error {
+	logger := ctxzap.Extract(ctx)
+
+	writer, err := s.NewWriter(ctx, output)
+	if nil != err {
+		logger.Error("Failed to open writer", zap.Error(err))
+		return err
+	}
+	return WriteAll(writer, input)
+}
+
+// ReadAll reads every remaining order from the stream into memory
+func ReadAll(reader Reader) ([]*Order, error) {
+	output := make([]*Order, 0)
+	for {
+		row, err := reader.Next()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return nil, err
+		}
+		output = append(output, row)
+	}
+	return output, nil
+}
+
+// WriteAll writes every order to the stream, and then closes it
+func WriteAll(writer Writer, orders []*Order) error {
+	for _, row := range orders {
+		err := writer.WriteOrder(row)
+		if nil != err {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Copy streams every order from the reader to the writer, and then closes the writer.
+// This is useful for converting between formats without loading the whole input into memory.
+func Copy(writer Writer, reader Reader) (int, error) {
+	count := 0
+	for {
+		row, err := reader.Next()
+		if nil != err && err == io.EOF {
+			break
+		}
+		if nil != err {
+			return count, err
+		}
+		err = writer.WriteOrder(row)
+		if nil != err {
+			return count, err
+		}
+		count++
+	}
+	return count, writer.Close()
+}
+
+// sliceLoader is a format that is read and written as a whole, ex: a single `Orders` proto message
+type sliceLoader interface {
+	Read(ctx context.Context, input io.Reader) ([]*Order, error)
+	Write(ctx context.Context, output io.Writer, input []*Order) error
+}
+
+// bufferedLoader builds the StreamLoader on top of a slice based format.
+// The reader reads the whole input when it's opened, and the writer holds the orders until it's closed.
+type bufferedLoader struct {
+	sliceLoader
+}
+
+// bufferedReader returns each order that was read, oldest first
+type bufferedReader struct {
+	rows []*Order
+}
+
+// bufferedWriter holds the orders, and writes all of them at once when it's closed
+type bufferedWriter struct {
+	ctx    context.Context
+	output io.Writer
+	loader sliceLoader
+	rows   []*Order
+}
+
+func newBufferedLoader(loader sliceLoader) Loader {
+	return &bufferedLoader{sliceLoader: loader}
+}
+
+func (b bufferedLoader) NewReader(ctx context.Context, input io.Reader) (Reader, error) {
+	rows, err := b.Read(ctx, input)
+	if nil != err {
+		return nil, err
+	}
+	return &bufferedReader{rows: rows}, nil
+}
+
+func (b bufferedLoader) NewWriter(ctx context.Context, output io.Writer) (Writer, error) {
+	return &bufferedWriter{
+		ctx:    ctx,
+		output: output,
+		loader: b.sliceLoader,
+		rows:   make([]*Order, 0),
+	}, nil
+}
+
+func (b *bufferedReader) Next() (*Order, error) {
+	if len(b.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := b.rows[0]
+	b.rows = b.rows[1:]
+	return row, nil
+}
+
+func (b *bufferedWriter) WriteOrder(order *Order) error {
+	b.rows = append(b.rows, order)
+	return nil
+}
+
+func (b *bufferedWriter) Close() error {
+	return b.loader.Write(b.ctx, b.output, b.rows)
+}
@@ -0,0 +1,66 @@
+package orders
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStreamLoader(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	orders := make([]*Order, 0)
+	for index := 0; index < 5; index++ {
+		orders = append(orders, NewOrder(
+			now.Add(time.Duration(index)*time_series.Day),
+			NewStockOrderItem(constants.Buy, "ABC", float64(100*(index+1)), 10.01),
+		))
+	}
+
+	ctx := context.Background()
+	loader := NewDelimitedProtoLoader()
+
+	buff := bytes.NewBuffer([]byte{})
+	writer, err := loader.NewWriter(ctx, buff)
+	require.NoError(t, err)
+	err = WriteAll(writer, orders)
+	require.NoError(t, err)
+
+	t.Run("ReadAll", func(t *testing.T) {
+		reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+
+		// Part of the stream has already been read
+		_, err = reader.Next()
+		require.NoError(t, err)
+		output, err := ReadAll(reader)
+		require.NoError(t, err)
+		require.Len(t, output, len(orders)-1)
+		for index, row := range output {
+			require.Equal(t, row.UnixTime, orders[index+1].UnixTime)
+			require.Equal(t, row.OrderItems, orders[index+1].OrderItems)
+		}
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		reader, err := loader.NewReader(ctx, bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+
+		copied := bytes.NewBuffer([]byte{})
+		writer, err := loader.NewWriter(ctx, copied)
+		require.NoError(t, err)
+		count, err := Copy(writer, reader)
+		require.NoError(t, err)
+		require.Equal(t, count, len(orders))
+		require.Equal(t, copied.Bytes(), buff.Bytes())
+
+		_, err = reader.Next()
+		require.Equal(t, err, io.EOF)
+	})
+}
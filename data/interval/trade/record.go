@@ -0,0 +1,111 @@
+package trade
+
+import (
+	"encoding/json"
+	"github.com/ta4g/ta4g/data/interval/trade/cost_model"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/json_float"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Record is a snapshot of a StandardTrade, which is what the Loaders read and write.
+// The cost models can't be written, so the fees and holding cost are kept as they were priced when the trade was made.
+type Record struct {
+	// Entry is the order that entered the trade
+	Entry *orders.Order `csv:"entry" avro:"entry" json:"entry"`
+	// Adjustments are the orders that adjusted the trade, oldest first
+	Adjustments []*orders.Order `csv:"adjustments" avro:"adjustments" json:"adjustments"`
+	// Exit is the order that exited the trade, nil while the trade is open
+	Exit *orders.Order `csv:"exit" avro:"exit" json:"exit,omitempty"`
+	// HoldingBars is the number of bars the trade has been held
+	HoldingBars int64 `csv:"holding_bars" avro:"holding_bars" json:"holding_bars"`
+	// Fees paid on the orders
+	Fees float64 `csv:"fees" avro:"fees" json:"fees"`
+	// HoldingCost is the cost of holding the trade
+	HoldingCost float64 `csv:"holding_cost" avro:"holding_cost" json:"holding_cost"`
+	// RealizedPnL is the profit (or loss) of the positions that were closed, after fees and holding cost
+	RealizedPnL float64 `csv:"realized_pnl" avro:"realized_pnl" json:"realized_pnl"`
+}
+
+// jsonRecord is the JSON form of a Record, see json_float.Float
+type jsonRecord struct {
+	Entry       **orders.Order    `json:"entry"`
+	Adjustments *[]*orders.Order  `json:"adjustments"`
+	Exit        **orders.Order    `json:"exit,omitempty"`
+	HoldingBars *int64            `json:"holding_bars"`
+	Fees        *json_float.Float `json:"fees"`
+	HoldingCost *json_float.Float `json:"holding_cost"`
+	RealizedPnL *json_float.Float `json:"realized_pnl"`
+}
+
+// Record is a snapshot of the trade, which shares it's orders
+func (t *StandardTrade) Record() *Record {
+	return &Record{
+		Entry:       t.Entry,
+		Adjustments: t.Adjustments,
+		Exit:        t.Exit,
+		HoldingBars: t.HoldingBars,
+		Fees:        t.Fees,
+		HoldingCost: t.HoldingCost,
+		RealizedPnL: t.RealizedPnL(),
+	}
+}
+
+func (r *Record) jsonForm() *jsonRecord {
+	return &jsonRecord{
+		Entry:       &r.Entry,
+		Adjustments: &r.Adjustments,
+		Exit:        &r.Exit,
+		HoldingBars: &r.HoldingBars,
+		Fees:        (*json_float.Float)(&r.Fees),
+		HoldingCost: (*json_float.Float)(&r.HoldingCost),
+		RealizedPnL: (*json_float.Float)(&r.RealizedPnL),
+	}
+}
+
+func (r Record) MarshalJSON() ([]byte, error) {
+	value := r.jsonForm()
+	// omitempty only leaves out pointers that are nil, so the empty fields are cleared here
+	if nil == r.Exit {
+		value.Exit = nil
+	}
+	return json.Marshal(value)
+}
+
+func (r *Record) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, r.jsonForm())
+}
+
+// Trade replays the orders of the record into a StandardTrade, which can be adjusted and closed with the cost models.
+// The fees, holding cost, and holding bars are restored from the record instead of being priced again.
+//
+// Errors:
+// - If the record doesn't have an entry, or any of it's orders can't be placed an error with GRPC status InvalidArgument will be returned
+//
+func (r *Record) Trade(orderCostModel, holdingCostModel cost_model.CostModel) (*StandardTrade, error) {
+	if nil == r.Entry {
+		return nil, status.Error(codes.InvalidArgument, "trade records need an entry")
+	}
+	output := newStandardTrade(orderCostModel, holdingCostModel)
+	err := output.place(r.Entry, false, false)
+	if nil != err {
+		return nil, err
+	}
+	for _, adjustment := range r.Adjustments {
+		err = output.place(adjustment, false, false)
+		if nil != err {
+			return nil, err
+		}
+	}
+	if nil != r.Exit {
+		err = output.place(r.Exit, true, false)
+		if nil != err {
+			return nil, err
+		}
+	}
+	output.HoldingBars = r.HoldingBars
+	output.Fees = r.Fees
+	output.HoldingCost = r.HoldingCost
+	return output, nil
+}
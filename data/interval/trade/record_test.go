@@ -0,0 +1,52 @@
+package trade
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/cost_model"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Trade", func(t *testing.T) {
+		trade := newCoveredCall(t, now, nil)
+		require.NoError(t, trade.Adjust(orders.NewOrder(now.Add(time_series.Day), orders.NewStockOrderItem(constants.Sell, "ABC", 50, 12))))
+		require.NoError(t, trade.AddBar())
+		record := trade.Record()
+		require.Equal(t, record.HoldingBars, int64(1))
+		require.InDelta(t, record.RealizedPnL, 100-2.9, 1e-9)
+
+		// The costs come from the record, and the positions from replaying the orders
+		output, err := record.Trade(cost_model.NewNoCostModel(), nil)
+		require.NoError(t, err)
+		require.Equal(t, output.Record(), record)
+		require.Equal(t, output.Positions(), trade.Positions())
+
+		// The restored trade can still be closed
+		require.NoError(t, output.Close(orders.NewOrder(
+			now.Add(2*time_series.Day),
+			orders.NewStockOrderItem(constants.Sell, "ABC", 50, 12),
+			orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 1, 1),
+		)))
+		require.InDelta(t, output.RealizedPnL(), 200-2.9, 1e-9)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := (&Record{}).Trade(nil, nil)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		// The exit must still close every position
+		record := newCoveredCall(t, now, nil).Record()
+		record.Exit = orders.NewOrder(now.Add(time_series.Day), orders.NewStockOrderItem(constants.Sell, "ABC", 100, 11))
+		_, err = record.Trade(nil, nil)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+}
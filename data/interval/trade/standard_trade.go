@@ -0,0 +1,326 @@
+package trade
+
+import (
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/cost_model"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sort"
+	"time"
+)
+
+// StandardTrade accumulates the orders of a trade, and keeps track of it's positions and profit as they are placed.
+//
+// 1. NewStandardTrade enters the trade with the first order
+// 2. Adjust adds any number of orders, ex: rolling an option or adding to a winning position
+// 3. AddBar holds the open positions for another bar, which adds the cost of the holding cost model
+// 4. Close exits the trade, the exit must close every position that is still open
+//
+// Each symbol is a single position at it's average price, so an order that buys (or sells) more of a symbol
+// changes the average price, and an order in the other direction realizes the profit of the part it closed.
+//
+type StandardTrade struct {
+	// Entry is the order that entered the trade
+	Entry *orders.Order `csv:"entry" avro:"entry" json:"entry"`
+	// Adjustments are the orders that adjusted the trade, oldest first
+	Adjustments []*orders.Order `csv:"adjustments" avro:"adjustments" json:"adjustments"`
+	// Exit is the order that exited the trade, nil while the trade is open
+	Exit *orders.Order `csv:"exit" avro:"exit" json:"exit,omitempty"`
+	// HoldingBars is the number of bars the trade has been held, see AddBar
+	HoldingBars int64 `csv:"holding_bars" avro:"holding_bars" json:"holding_bars"`
+	// Fees paid on the orders, priced by the order cost model
+	Fees float64 `csv:"fees" avro:"fees" json:"fees"`
+	// HoldingCost is the cost of holding the trade, priced by the holding cost model on each bar
+	HoldingCost float64 `csv:"holding_cost" avro:"holding_cost" json:"holding_cost"`
+
+	// OrderCostModel prices the fees of each order
+	OrderCostModel cost_model.CostModel `csv:"-" avro:"-" json:"-"`
+	// HoldingCostModel prices the cost of holding the open positions for a single bar
+	HoldingCostModel cost_model.CostModel `csv:"-" avro:"-" json:"-"`
+
+	// positions that are still open by their symbol
+	positions map[string]*position
+	// realized is the profit of the positions that were closed, before any costs
+	realized float64
+}
+
+// position is the net quantity of a single symbol held by the trade
+type position struct {
+	// item is the latest item of the symbol, which is used to price the position
+	item *orders.OrderItem
+	// quantity is the number of units held, negative for short positions
+	quantity float64
+	// price is the average price per unit the position was opened at
+	price float64
+}
+
+// NewStandardTrade enters a trade with the order, the cost models default to NoCostModel when they are nil
+//
+// Errors:
+// - If the entry doesn't have any items an error with GRPC status InvalidArgument will be returned
+// - If the entry can't be priced the error of the order cost model will be returned
+//
+func NewStandardTrade(entry *orders.Order, orderCostModel, holdingCostModel cost_model.CostModel) (*StandardTrade, error) {
+	output := newStandardTrade(orderCostModel, holdingCostModel)
+	err := output.place(entry, false, true)
+	if nil != err {
+		return nil, err
+	}
+	return output, nil
+}
+
+func newStandardTrade(orderCostModel, holdingCostModel cost_model.CostModel) *StandardTrade {
+	if nil == orderCostModel {
+		orderCostModel = cost_model.NewNoCostModel()
+	}
+	if nil == holdingCostModel {
+		holdingCostModel = cost_model.NewNoCostModel()
+	}
+	return &StandardTrade{
+		Adjustments:      make([]*orders.Order, 0),
+		OrderCostModel:   orderCostModel,
+		HoldingCostModel: holdingCostModel,
+		positions:        make(map[string]*position),
+	}
+}
+
+func (t *StandardTrade) GetEntry() *orders.Order {
+	return t.Entry
+}
+
+func (t *StandardTrade) GetAdjustments() []*orders.Order {
+	return t.Adjustments
+}
+
+func (t *StandardTrade) GetExit() *orders.Order {
+	return t.Exit
+}
+
+func (t *StandardTrade) GetOrderCostModel() cost_model.CostModel {
+	return t.OrderCostModel
+}
+
+func (t *StandardTrade) GetHoldingCostModel() cost_model.CostModel {
+	return t.HoldingCostModel
+}
+
+func (t *StandardTrade) GetHoldingBars() int64 {
+	return t.HoldingBars
+}
+
+func (t *StandardTrade) IsOpen() bool {
+	return nil == t.Exit
+}
+
+// Adjust adds the order to the open trade
+//
+// Errors:
+// - If the trade was already exited an error with GRPC status FailedPrecondition will be returned
+// - If the order doesn't have any items, or was placed before the last order an error with GRPC status InvalidArgument will be returned
+// - If the order can't be priced the error of the order cost model will be returned
+//
+func (t *StandardTrade) Adjust(order *orders.Order) error {
+	return t.place(order, false, true)
+}
+
+// Close exits the trade with the order
+//
+// Errors:
+// - If the order leaves any position open an error with GRPC status InvalidArgument will be returned
+// - See Adjust
+//
+func (t *StandardTrade) Close(exit *orders.Order) error {
+	return t.place(exit, true, true)
+}
+
+// AddBar holds the open positions for another bar, and adds their cost in the holding cost model
+//
+// Errors:
+// - If the trade was already exited an error with GRPC status FailedPrecondition will be returned
+// - If the positions can't be priced the error of the holding cost model will be returned
+//
+func (t *StandardTrade) AddBar() error {
+	if !t.IsOpen() {
+		return status.Error(codes.FailedPrecondition, "the trade was already exited")
+	}
+	holding := orders.NewOrder(time.Unix(t.lastOrder().UnixTime, 0))
+	for _, symbol := range t.symbols() {
+		held := t.positions[symbol]
+		item := held.item.Clone()
+		item.Direction = constants.Buy
+		if held.quantity < 0 {
+			item.Direction = constants.Sell
+		}
+		item.Amount = math.Abs(held.quantity) / item.QuantityPerAmount
+		item.Price = held.price
+		holding.OrderItems = append(holding.OrderItems, item)
+	}
+
+	cost, err := fees(t.HoldingCostModel, holding)
+	if nil != err {
+		return err
+	}
+	t.HoldingBars++
+	t.HoldingCost += cost
+	return nil
+}
+
+// Positions are the number of units of each symbol that are still open, negative for short positions
+func (t *StandardTrade) Positions() map[string]float64 {
+	output := make(map[string]float64, len(t.positions))
+	for symbol, held := range t.positions {
+		output[symbol] = held.quantity
+	}
+	return output
+}
+
+func (t *StandardTrade) RealizedPnL() float64 {
+	return t.realized - t.Fees - t.HoldingCost
+}
+
+// UnrealizedPnL is the profit (or loss) of the open positions at the price of each symbol, without any fees
+//
+// Errors:
+// - If there isn't a price for one of the open positions an error with GRPC status NotFound will be returned
+//
+func (t *StandardTrade) UnrealizedPnL(prices map[string]float64) (float64, error) {
+	output := 0.0
+	for _, symbol := range t.symbols() {
+		price, ok := prices[symbol]
+		if !ok {
+			return 0, status.Errorf(codes.NotFound, "no price for %q", symbol)
+		}
+		held := t.positions[symbol]
+		output += held.quantity * (price - held.price)
+	}
+	return output, nil
+}
+
+// place adds the order to the trade as the entry, an adjustment, or the exit, and prices it's fees when priced is set.
+// The trade is left as it was when the order can't be placed.
+func (t *StandardTrade) place(order *orders.Order, exit, priced bool) error {
+	if !t.IsOpen() {
+		return status.Error(codes.FailedPrecondition, "the trade was already exited")
+	}
+	if nil == order || len(order.OrderItems) == 0 {
+		return status.Error(codes.InvalidArgument, "orders need at least one item")
+	}
+	if last := t.lastOrder(); nil != last && order.UnixTime < last.UnixTime {
+		return status.Error(codes.InvalidArgument, "orders can't be placed before the last order of the trade")
+	}
+
+	// Apply the order to a copy of the positions, so nothing changes until the order is accepted
+	positions := make(map[string]*position, len(t.positions))
+	for symbol, held := range t.positions {
+		clone := *held
+		positions[symbol] = &clone
+	}
+	realized := 0.0
+	for _, item := range order.OrderItems {
+		quantity := item.Amount * item.QuantityPerAmount
+		if item.Direction == constants.Sell {
+			quantity *= -1
+		}
+		held, ok := positions[item.Symbol]
+		if !ok {
+			held = &position{}
+			positions[item.Symbol] = held
+		}
+		held.item = item
+		realized += held.add(quantity, item.Price)
+		if held.quantity == 0 {
+			delete(positions, item.Symbol)
+		}
+	}
+	if exit && len(positions) > 0 {
+		return status.Error(codes.InvalidArgument, "the exit must close every position of the trade")
+	}
+
+	cost := 0.0
+	if priced {
+		var err error
+		cost, err = fees(t.OrderCostModel, order)
+		if nil != err {
+			return err
+		}
+	}
+
+	order = order.Clone()
+	switch {
+	case nil == t.Entry:
+		t.Entry = order
+	case exit:
+		t.Exit = order
+	default:
+		t.Adjustments = append(t.Adjustments, order)
+	}
+	t.positions = positions
+	t.realized += realized
+	t.Fees += cost
+	return nil
+}
+
+// lastOrder is the latest order of the trade, nil before it's entered
+func (t *StandardTrade) lastOrder() *orders.Order {
+	if len(t.Adjustments) > 0 {
+		return t.Adjustments[len(t.Adjustments)-1]
+	}
+	return t.Entry
+}
+
+// symbols of the open positions, sorted so the positions are always visited in the same order
+func (t *StandardTrade) symbols() []string {
+	output := make([]string, 0, len(t.positions))
+	for symbol := range t.positions {
+		output = append(output, symbol)
+	}
+	sort.Strings(output)
+	return output
+}
+
+// add the quantity at the price to the position, and returns the profit of the part of the position it closed
+func (p *position) add(quantity, price float64) float64 {
+	if quantity == 0 {
+		return 0
+	}
+	// Opening or adding to the position changes it's average price
+	if p.quantity == 0 || (p.quantity > 0) == (quantity > 0) {
+		total := p.quantity + quantity
+		p.price = (p.price*math.Abs(p.quantity) + price*math.Abs(quantity)) / math.Abs(total)
+		p.quantity = total
+		return 0
+	}
+
+	// Closing the position realizes the profit of the part that was closed
+	closed := math.Min(math.Abs(quantity), math.Abs(p.quantity))
+	output := closed * (price - p.price)
+	if p.quantity < 0 {
+		output *= -1
+	}
+	remaining := p.quantity + quantity
+	if math.Abs(remaining) < 1e-9 {
+		remaining = 0
+	}
+	// The rest of the quantity opens a position in the other direction at the price
+	if remaining != 0 && (remaining > 0) != (p.quantity > 0) {
+		p.price = price
+	}
+	p.quantity = remaining
+	return output
+}
+
+// fees is the cost of the order in the cost model on top of the price of it's items.
+// Each item is priced on it's own, so the fees of the sells aren't netted against the fees of the buys.
+func fees(costModel cost_model.CostModel, order *orders.Order) (float64, error) {
+	output := 0.0
+	for _, item := range order.OrderItems {
+		cost, _, err := costModel.BalanceChangeOnOpen(orders.NewOrder(time.Unix(order.UnixTime, 0), item))
+		if nil != err {
+			return 0, err
+		}
+		output += math.Abs(cost - item.CalculatePrice(0, 0, 0))
+	}
+	return output, nil
+}
@@ -0,0 +1,143 @@
+package trade
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/cost_model"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+// newCoveredCall enters a covered call, buying 100 shares at $10 and selling a call for $1 per share
+func newCoveredCall(t *testing.T, now time.Time, holdingCostModel cost_model.CostModel) *StandardTrade {
+	entry := orders.NewOrder(
+		now,
+		orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10),
+		orders.NewOptionOrderItem(constants.Sell, "ABC CALL @ 12", 1, 1),
+	)
+	output, err := NewStandardTrade(entry, cost_model.DefaultStandardCostModel(), holdingCostModel)
+	require.NoError(t, err)
+	return output
+}
+
+func TestStandardTrade(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	// Holding the shares costs a penny per share per bar
+	holdingCostModel := cost_model.NewStandardCostModel(&cost_model.Fees{}, &cost_model.Fees{Amount: 0.01}, &cost_model.Fees{}, &cost_model.Fees{})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		trade := newCoveredCall(t, now, holdingCostModel)
+		require.True(t, trade.IsOpen())
+		require.Equal(t, trade.Positions(), map[string]float64{"ABC": 100, "ABC CALL @ 12": -100})
+		// $0.75 for the stock, and $0.75 + $0.65 for the option
+		require.InDelta(t, trade.Fees, 2.15, 1e-9)
+		require.InDelta(t, trade.RealizedPnL(), -2.15, 1e-9)
+
+		// Buying another 100 shares at $12 averages the price up to $11
+		err := trade.Adjust(orders.NewOrder(now.Add(2*time_series.Day), orders.NewStockOrderItem(constants.Buy, "ABC", 100, 12)))
+		require.NoError(t, err)
+		require.Len(t, trade.GetAdjustments(), 1)
+		require.Equal(t, trade.Positions()["ABC"], 200.0)
+
+		// $2 per bar for the 200 shares
+		for i := 0; i < 3; i++ {
+			require.NoError(t, trade.AddBar())
+		}
+		require.Equal(t, trade.GetHoldingBars(), int64(3))
+		require.InDelta(t, trade.HoldingCost, 6.0, 1e-9)
+
+		// The shares are up $2 each, and the call is down $0.50 per share
+		unrealized, err := trade.UnrealizedPnL(map[string]float64{"ABC": 13, "ABC CALL @ 12": 0.5})
+		require.NoError(t, err)
+		require.InDelta(t, unrealized, 450.0, 1e-9)
+
+		exit := orders.NewOrder(
+			now.Add(5*time_series.Day),
+			orders.NewStockOrderItem(constants.Sell, "ABC", 200, 13),
+			orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 1, 0.5),
+		)
+		require.NoError(t, trade.Close(exit))
+		require.False(t, trade.IsOpen())
+		require.Equal(t, trade.GetExit(), exit)
+		require.Empty(t, trade.Positions())
+
+		// $450 profit, less $5.05 in fees and $6 to hold the shares
+		require.InDelta(t, trade.RealizedPnL(), 438.95, 1e-9)
+		unrealized, err = trade.UnrealizedPnL(map[string]float64{})
+		require.NoError(t, err)
+		require.Equal(t, unrealized, 0.0)
+	})
+
+	t.Run("Partial close", func(t *testing.T) {
+		entry := orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10))
+		trade, err := NewStandardTrade(entry, nil, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, trade.Adjust(orders.NewOrder(now.Add(time_series.Day), orders.NewStockOrderItem(constants.Sell, "ABC", 50, 12))))
+		require.Equal(t, trade.RealizedPnL(), 100.0)
+		require.Equal(t, trade.Positions(), map[string]float64{"ABC": 50})
+
+		// Selling more than is held opens a short position at the price
+		require.NoError(t, trade.Adjust(orders.NewOrder(now.Add(2*time_series.Day), orders.NewStockOrderItem(constants.Sell, "ABC", 100, 8))))
+		require.Equal(t, trade.RealizedPnL(), 0.0)
+		require.Equal(t, trade.Positions(), map[string]float64{"ABC": -50})
+		unrealized, err := trade.UnrealizedPnL(map[string]float64{"ABC": 6})
+		require.NoError(t, err)
+		require.Equal(t, unrealized, 100.0)
+	})
+
+	t.Run("Futures", func(t *testing.T) {
+		// Only the fees are paid to open the contracts, the profit is realized when they're closed
+		contract := orders.NewFutureContract("ES", 0.25, 12.5, 12000, now.AddDate(0, 1, 0))
+		entry := orders.NewOrder(now, orders.NewFutureOrderItem(constants.Buy, "ESZ22", contract, 2, 4000))
+		trade, err := NewStandardTrade(entry, cost_model.DefaultStandardCostModel(), nil)
+		require.NoError(t, err)
+		require.InDelta(t, trade.Fees, 4.5, 1e-9)
+
+		// 10 points is $500 per contract
+		unrealized, err := trade.UnrealizedPnL(map[string]float64{"ESZ22": 4010})
+		require.NoError(t, err)
+		require.Equal(t, unrealized, 1000.0)
+
+		exit := orders.NewOrder(now.Add(time_series.Day), orders.NewFutureOrderItem(constants.Sell, "ESZ22", contract, 2, 4010))
+		require.NoError(t, trade.Close(exit))
+		require.InDelta(t, trade.RealizedPnL(), 991.0, 1e-9)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := NewStandardTrade(nil, nil, nil)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = NewStandardTrade(orders.NewOrder(now), nil, nil)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		trade := newCoveredCall(t, now, nil)
+		before := orders.NewOrder(now.Add(-time.Minute), orders.NewStockOrderItem(constants.Buy, "ABC", 1, 10))
+		require.Equal(t, status.Code(trade.Adjust(before)), codes.InvalidArgument)
+
+		_, err = trade.UnrealizedPnL(map[string]float64{"ABC": 10})
+		require.Equal(t, status.Code(err), codes.NotFound)
+
+		// The exit must close every position, and the trade is unchanged when it doesn't
+		partial := orders.NewOrder(now.Add(time_series.Day), orders.NewStockOrderItem(constants.Sell, "ABC", 100, 11))
+		require.Equal(t, status.Code(trade.Close(partial)), codes.InvalidArgument)
+		require.True(t, trade.IsOpen())
+		require.Len(t, trade.Positions(), 2)
+		require.Empty(t, trade.GetAdjustments())
+
+		exit := orders.NewOrder(
+			now.Add(time_series.Day),
+			orders.NewStockOrderItem(constants.Sell, "ABC", 100, 11),
+			orders.NewOptionOrderItem(constants.Buy, "ABC CALL @ 12", 1, 0),
+		)
+		require.NoError(t, trade.Close(exit))
+		require.Equal(t, status.Code(trade.Adjust(exit)), codes.FailedPrecondition)
+		require.Equal(t, status.Code(trade.Close(exit)), codes.FailedPrecondition)
+		require.Equal(t, status.Code(trade.AddBar()), codes.FailedPrecondition)
+	})
+}
@@ -5,10 +5,33 @@ import (
 	"github.com/ta4g/ta4g/data/interval/trade/orders"
 )
 
+// Trade is the lifecycle of a position, from the order that enters it, through any orders that adjust it,
+// to the order that exits it.
+//
+// The profit and loss is split in two:
+// 1. RealizedPnL - the profit of the positions that were closed, less the fees of every order and the cost of holding the trade
+// 2. UnrealizedPnL - the profit the positions that are still open would make if they were closed at the given prices
+//
 type Trade interface {
-	GetEntry() orders.Order
-	GetAdjustments() orders.Order
-	GetExit() orders.Order
+	// GetEntry is the order that entered the trade
+	GetEntry() *orders.Order
+	// GetAdjustments are the orders that adjusted the trade, oldest first
+	GetAdjustments() []*orders.Order
+	// GetExit is the order that exited the trade, nil while the trade is open
+	GetExit() *orders.Order
+	// GetOrderCostModel prices the fees of each order
 	GetOrderCostModel() cost_model.CostModel
+	// GetHoldingCostModel prices the cost of holding the open positions for a single bar
 	GetHoldingCostModel() cost_model.CostModel
+	// GetHoldingBars is the number of bars the trade has been held
+	GetHoldingBars() int64
+	// IsOpen checks if the trade hasn't been exited yet
+	IsOpen() bool
+	// RealizedPnL is the profit (or loss) of the positions that were closed, after fees and holding costs
+	RealizedPnL() float64
+	// UnrealizedPnL is the profit (or loss) of the open positions at the price of each symbol
+	UnrealizedPnL(prices map[string]float64) (float64, error)
 }
+
+// Compile time type assertions
+var _ Trade = &StandardTrade{}
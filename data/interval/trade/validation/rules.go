@@ -0,0 +1,227 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"strings"
+)
+
+// Names of the rules
+const (
+	StructureRule         = "structure"
+	MaxNotionalRule       = "max_notional"
+	MaxPositionRule       = "max_position"
+	RestrictedSymbolsRule = "restricted_symbols"
+	BuyingPowerRule       = "buying_power"
+	NoShortSalesRule      = "no_short_sales"
+)
+
+// Structure checks the order and each of it's items are well formed, the violations have GRPC status InvalidArgument:
+// 1. The execution is valid, see orders.Execution.Validate
+// 2. Each item has a known direction and item type, and a symbol
+// 3. Each item has a positive amount and quantity per amount, and a price that isn't negative
+// 4. The option, future, and forex contracts of each item are valid
+//
+func Structure() Rule {
+	return NewRule(StructureRule, func(order *orders.Order, account *Account) []*Violation {
+		output := make([]*Violation, 0)
+		add := func(index int, message string) {
+			output = append(output, &Violation{Code: codes.InvalidArgument, Item: index, Message: message})
+		}
+
+		err := order.Execution.Validate()
+		if nil != err {
+			add(-1, status.Convert(err).Message())
+		}
+		for index, item := range order.OrderItems {
+			if nil == item {
+				add(index, "items can't be empty")
+				continue
+			}
+			if item.Direction.String() == "" {
+				add(index, fmt.Sprintf("unknown direction %d", item.Direction))
+			}
+			if item.ItemType.String() == "" {
+				add(index, fmt.Sprintf("unknown item type %d", item.ItemType))
+			}
+			if strings.TrimSpace(item.Symbol) == "" {
+				add(index, "items need a symbol")
+			}
+			if !(item.Amount > 0) || math.IsInf(item.Amount, 0) {
+				add(index, "amount must be positive")
+			}
+			if !(item.QuantityPerAmount > 0) || math.IsInf(item.QuantityPerAmount, 0) {
+				add(index, "quantity per amount must be positive")
+			}
+			if !(item.Price >= 0) || math.IsInf(item.Price, 0) {
+				add(index, "price can't be negative")
+			}
+			for _, contract := range contracts(item) {
+				err := contract.Validate()
+				if nil != err {
+					add(index, status.Convert(err).Message())
+				}
+			}
+		}
+		return output
+	})
+}
+
+// MaxNotional checks the total value of the order's items is at most the limit, buys and sells both count towards the limit.
+// The violation has GRPC status FailedPrecondition.
+func MaxNotional(limit float64) Rule {
+	return NewRule(MaxNotionalRule, func(order *orders.Order, account *Account) []*Violation {
+		total := 0.0
+		for _, item := range order.OrderItems {
+			if nil != item {
+				total += math.Abs(item.Notional())
+			}
+		}
+		if total > limit {
+			return []*Violation{{
+				Code:    codes.FailedPrecondition,
+				Item:    -1,
+				Message: fmt.Sprintf("notional of %.2f is over the limit of %.2f", total, limit),
+			}}
+		}
+		return nil
+	})
+}
+
+// MaxPosition checks the number of units held of each symbol stays within the limit, long or short.
+// Items that make a position smaller are always allowed, so positions that are already over the limit can be closed.
+// The violations have GRPC status FailedPrecondition.
+func MaxPosition(limit float64) Rule {
+	return NewRule(MaxPositionRule, func(order *orders.Order, account *Account) []*Violation {
+		output := make([]*Violation, 0)
+		walk(order, account, func(index int, item *orders.OrderItem, before, after float64) {
+			if math.Abs(after) > limit && math.Abs(after) > math.Abs(before) {
+				output = append(output, &Violation{
+					Code:    codes.FailedPrecondition,
+					Item:    index,
+					Message: fmt.Sprintf("position of %v in %q is over the limit of %v", after, item.Symbol, limit),
+				})
+			}
+		})
+		return output
+	})
+}
+
+// RestrictedSymbols checks the order doesn't trade any of the symbols, ignoring case.
+// Options on a restricted underlying and futures with a restricted root are also restricted.
+// The violations have GRPC status PermissionDenied.
+func RestrictedSymbols(symbols ...string) Rule {
+	restricted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		restricted[strings.ToUpper(strings.TrimSpace(symbol))] = true
+	}
+	return NewRule(RestrictedSymbolsRule, func(order *orders.Order, account *Account) []*Violation {
+		output := make([]*Violation, 0)
+		for index, item := range order.OrderItems {
+			if nil == item {
+				continue
+			}
+			names := []string{item.Symbol}
+			if nil != item.Option {
+				names = append(names, item.Option.Underlying)
+			}
+			if nil != item.Future {
+				names = append(names, item.Future.Root)
+			}
+			for _, name := range names {
+				if restricted[strings.ToUpper(strings.TrimSpace(name))] {
+					output = append(output, &Violation{
+						Code:    codes.PermissionDenied,
+						Item:    index,
+						Message: fmt.Sprintf("symbol %q is restricted", name),
+					})
+					break
+				}
+			}
+		}
+		return output
+	})
+}
+
+// BuyingPower checks the account's buying power covers the margin requirement of the positions the order opens.
+//
+// Only the part of each item that opens or adds to a position needs margin, see orders.OrderItem.MarginRequirement.
+// Items that close positions don't free up any buying power for the rest of the order, and short positions need
+// the same margin as long positions, so the check errs on the side of caution.
+// The violation has GRPC status FailedPrecondition.
+//
+func BuyingPower() Rule {
+	return NewRule(BuyingPowerRule, func(order *orders.Order, account *Account) []*Violation {
+		requirement := 0.0
+		walk(order, account, func(index int, item *orders.OrderItem, before, after float64) {
+			change := math.Abs(after - before)
+			if change == 0 {
+				return
+			}
+			opened := change
+			if before*(after-before) < 0 {
+				// The item closes the position first, and only the rest of it opens a position in the other direction
+				opened = math.Max(0, change-math.Abs(before))
+			}
+			requirement += math.Abs(item.MarginRequirement()) * opened / change
+		})
+		if requirement > account.BuyingPower {
+			return []*Violation{{
+				Code:    codes.FailedPrecondition,
+				Item:    -1,
+				Message: fmt.Sprintf("margin requirement of %.2f is over the buying power of %.2f", requirement, account.BuyingPower),
+			}}
+		}
+		return nil
+	})
+}
+
+// NoShortSales checks the account doesn't sell more of a symbol than it holds.
+// The rule only applies to the item types, or to every item type when there aren't any, ex: NoShortSales(constants.Stock) still allows writing options.
+// The violations have GRPC status FailedPrecondition.
+func NoShortSales(itemTypes ...constants.ItemType) Rule {
+	applies := make(map[constants.ItemType]bool, len(itemTypes))
+	for _, itemType := range itemTypes {
+		applies[itemType] = true
+	}
+	return NewRule(NoShortSalesRule, func(order *orders.Order, account *Account) []*Violation {
+		output := make([]*Violation, 0)
+		walk(order, account, func(index int, item *orders.OrderItem, before, after float64) {
+			if len(applies) > 0 && !applies[item.ItemType] {
+				return
+			}
+			if item.Direction == constants.Sell && after < 0 {
+				output = append(output, &Violation{
+					Code:    codes.FailedPrecondition,
+					Item:    index,
+					Message: fmt.Sprintf("can't sell %v of %q with only %v held", before-after, item.Symbol, math.Max(0, before)),
+				})
+			}
+		})
+		return output
+	})
+}
+
+// validator is implemented by each of the contracts an item can have
+type validator interface {
+	Validate() error
+}
+
+// contracts of the item that it has
+func contracts(item *orders.OrderItem) []validator {
+	output := make([]validator, 0)
+	if nil != item.Option {
+		output = append(output, item.Option)
+	}
+	if nil != item.Future {
+		output = append(output, item.Future)
+	}
+	if nil != item.Forex {
+		output = append(output, item.Forex)
+	}
+	return output
+}
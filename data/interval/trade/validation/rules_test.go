@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	type args struct {
+		rule    Rule
+		order   *orders.Order
+		account *Account
+		// items with a violation, -1 is the whole order
+		items []int
+		code  codes.Code
+	}
+	stock := func(direction constants.Direction, symbol string, amount, price float64) *orders.OrderItem {
+		return orders.NewStockOrderItem(direction, symbol, amount, price)
+	}
+	call := orders.NewOptionContract("ABC", now.Add(15*time_series.Day), constants.Call, 12)
+	account := NewAccount(5000, map[string]float64{"ABC": 100, "XYZ": -50})
+
+	tests := map[string]args{
+		"Structure valid": {
+			rule:  Structure(),
+			order: orders.NewOrder(now, stock(constants.Buy, "ABC", 100, 10), orders.NewOptionContractOrderItem(constants.Sell, call, 1, 1)),
+		},
+		"Structure invalid items": {
+			rule: Structure(),
+			order: &orders.Order{OrderItems: []*orders.OrderItem{
+				nil,
+				{Direction: constants.Direction(9), ItemType: constants.Stock, Symbol: "ABC", Amount: 1, QuantityPerAmount: 1, Price: 1},
+				{Direction: constants.Buy, ItemType: constants.ItemType(9), Symbol: "ABC", Amount: 1, QuantityPerAmount: 1, Price: 1},
+				{Direction: constants.Buy, ItemType: constants.Stock, Symbol: " ", Amount: 1, QuantityPerAmount: 1, Price: 1},
+				{Direction: constants.Buy, ItemType: constants.Stock, Symbol: "ABC", Amount: 1, QuantityPerAmount: 0, Price: 1},
+			}},
+			items: []int{0, 1, 2, 3, 4},
+			code:  codes.InvalidArgument,
+		},
+		"Structure invalid execution and contract": {
+			rule: Structure(),
+			order: func() *orders.Order {
+				invalid := call.Clone()
+				invalid.Strike = 0
+				order := orders.NewOrder(now, orders.NewOptionContractOrderItem(constants.Buy, invalid, 1, 1))
+				order.Execution = orders.NewLimitExecution(0)
+				return order
+			}(),
+			items: []int{-1, 0},
+			code:  codes.InvalidArgument,
+		},
+		"Max notional within the limit": {
+			rule:  MaxNotional(2000),
+			order: orders.NewOrder(now, stock(constants.Buy, "ABC", 100, 10), stock(constants.Sell, "XYZ", 100, 10)),
+		},
+		"Max notional over the limit": {
+			rule:  MaxNotional(1999),
+			order: orders.NewOrder(now, stock(constants.Buy, "ABC", 100, 10), stock(constants.Sell, "XYZ", 100, 10)),
+			items: []int{-1},
+			code:  codes.FailedPrecondition,
+		},
+		"Max position within the limit": {
+			rule:    MaxPosition(200),
+			order:   orders.NewOrder(now, stock(constants.Buy, "ABC", 100, 10)),
+			account: account,
+		},
+		"Max position over the limit": {
+			rule:    MaxPosition(200),
+			order:   orders.NewOrder(now, stock(constants.Buy, "ABC", 50, 10), stock(constants.Buy, "ABC", 60, 10), stock(constants.Sell, "XYZ", 200, 10)),
+			account: account,
+			items:   []int{1, 2},
+			code:    codes.FailedPrecondition,
+		},
+		"Max position can shrink": {
+			rule:    MaxPosition(10),
+			order:   orders.NewOrder(now, stock(constants.Sell, "ABC", 50, 10)),
+			account: account,
+		},
+		"Restricted symbols": {
+			rule:  RestrictedSymbols("abc", "ES"),
+			order: orders.NewOrder(now, stock(constants.Buy, "XYZ", 1, 10), orders.NewOptionContractOrderItem(constants.Sell, call, 1, 1), stock(constants.Buy, "ABC", 1, 10)),
+			items: []int{1, 2},
+			code:  codes.PermissionDenied,
+		},
+		"Restricted future root": {
+			rule:  RestrictedSymbols("abc", "ES"),
+			order: orders.NewOrder(now, orders.NewFutureOrderItem(constants.Buy, "ESZ22", orders.NewFutureContract("ES", 0.25, 12.5, 12000, now.Add(15*time_series.Day)), 1, 4000)),
+			items: []int{0},
+			code:  codes.PermissionDenied,
+		},
+		"Buying power": {
+			rule:    BuyingPower(),
+			order:   orders.NewOrder(now, stock(constants.Buy, "ABC", 500, 10)),
+			account: account,
+		},
+		"Buying power exceeded": {
+			rule:    BuyingPower(),
+			order:   orders.NewOrder(now, stock(constants.Buy, "ABC", 501, 10)),
+			account: account,
+			items:   []int{-1},
+			code:    codes.FailedPrecondition,
+		},
+		"Buying power only counts opening": {
+			// Covering the 50 short shares doesn't need margin, and the other 500 shares open a long position
+			rule:    BuyingPower(),
+			order:   orders.NewOrder(now, stock(constants.Sell, "ABC", 100, 10), stock(constants.Buy, "XYZ", 550, 10)),
+			account: account,
+		},
+		"Buying power of short positions": {
+			rule:    BuyingPower(),
+			order:   orders.NewOrder(now, stock(constants.Sell, "ABC", 100, 10), stock(constants.Sell, "ABC", 501, 10)),
+			account: account,
+			items:   []int{-1},
+			code:    codes.FailedPrecondition,
+		},
+		"No short sales": {
+			rule:    NoShortSales(),
+			order:   orders.NewOrder(now, stock(constants.Sell, "ABC", 60, 10), stock(constants.Sell, "ABC", 40, 10)),
+			account: account,
+		},
+		"No short sales of more than is held": {
+			rule:    NoShortSales(),
+			order:   orders.NewOrder(now, stock(constants.Sell, "ABC", 60, 10), stock(constants.Sell, "ABC", 50, 10), stock(constants.Sell, "XYZ", 1, 10)),
+			account: account,
+			items:   []int{1, 2},
+			code:    codes.FailedPrecondition,
+		},
+		"No short sales of stock still allows writing options": {
+			rule:  NoShortSales(constants.Stock),
+			order: orders.NewOrder(now, orders.NewOptionContractOrderItem(constants.Sell, call, 1, 1)),
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			account := test.account
+			if nil == account {
+				account = NewAccount(0, nil)
+			}
+			items := make([]int, 0)
+			for _, violation := range test.rule.Check(test.order, account) {
+				require.Equal(t, violation.Code, test.code, violation.Message)
+				items = append(items, violation.Item)
+			}
+			if nil == test.items {
+				test.items = []int{}
+			}
+			require.Equal(t, items, test.items)
+		})
+	}
+}
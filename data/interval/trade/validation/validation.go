@@ -0,0 +1,171 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// Account is what the rules know about the account placing the order
+type Account struct {
+	// Positions are the number of units held of each symbol, negative for short positions, ex: 100 per option contract
+	Positions map[string]float64 `json:"positions"`
+	// BuyingPower is the cash and margin available to open new positions
+	BuyingPower float64 `json:"buying_power"`
+}
+
+func NewAccount(buyingPower float64, positions map[string]float64) *Account {
+	if nil == positions {
+		positions = make(map[string]float64)
+	}
+	return &Account{Positions: positions, BuyingPower: buyingPower}
+}
+
+// Violation is a single problem with an order that was found by a Rule
+type Violation struct {
+	// Rule is the name of the rule that found the problem
+	Rule string `json:"rule"`
+	// Code is the GRPC status of the problem, ex: InvalidArgument for an order that is malformed
+	Code codes.Code `json:"code"`
+	// Item is the index of the order item with the problem, or -1 when it's a problem with the whole order
+	Item int `json:"item"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	if v.Item < 0 {
+		return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s: item %d: %s", v.Rule, v.Item, v.Message)
+}
+
+// Error is every Violation of an order, the GRPC status is the code of the first violation
+type Error struct {
+	Violations []*Violation `json:"violations"`
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// GRPCStatus lets status.Code and status.Convert see the code of the first violation
+func (e *Error) GRPCStatus() *status.Status {
+	code := codes.Unknown
+	if len(e.Violations) > 0 {
+		code = e.Violations[0].Code
+	}
+	return status.New(code, e.Error())
+}
+
+// Rule checks a single order, and returns every Violation it finds.
+//
+// There are several rules to choose from, and NewRule makes a Rule from any function:
+// 1. Structure - the order and it's items are well formed
+// 2. MaxNotional - the value of the order is within a limit
+// 3. MaxPosition - the position in each symbol stays within a limit
+// 4. RestrictedSymbols - the order doesn't trade any of the symbols
+// 5. BuyingPower - the account can pay for the margin of the new positions
+// 6. NoShortSales - the account doesn't sell more than it holds
+//
+type Rule interface {
+	// Name of the rule, which is given to the violations that don't have one
+	Name() string
+	// Check the order placed by the account, the account is never nil and the order always has items
+	Check(order *orders.Order, account *Account) []*Violation
+}
+
+// Compile time type assertions
+var _ Rule = &rule{}
+var _ error = &Error{}
+
+type rule struct {
+	name  string
+	check func(order *orders.Order, account *Account) []*Violation
+}
+
+// NewRule creates a Rule from the function
+func NewRule(name string, check func(order *orders.Order, account *Account) []*Violation) Rule {
+	return &rule{name: name, check: check}
+}
+
+func (r *rule) Name() string {
+	return r.name
+}
+
+func (r *rule) Check(order *orders.Order, account *Account) []*Violation {
+	return r.check(order, account)
+}
+
+// Validator runs each of it's rules against an order, and collects all of their violations
+type Validator struct {
+	Rules []Rule
+}
+
+// NewValidator creates a Validator with the rules, which usually starts with Structure
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{Rules: rules}
+}
+
+// Validate checks the order against every rule, an account without any positions or buying power is used when it's nil.
+// The rules are only run for orders with items, since there is nothing else to check.
+//
+// Errors:
+// - If the order is nil or doesn't have any items an *Error with GRPC status InvalidArgument will be returned
+// - If any rule finds a violation an *Error with every violation will be returned, with the GRPC status of the first violation
+//
+func (v *Validator) Validate(order *orders.Order, account *Account) error {
+	if nil == order || len(order.OrderItems) == 0 {
+		return &Error{Violations: []*Violation{{
+			Rule:    StructureRule,
+			Code:    codes.InvalidArgument,
+			Item:    -1,
+			Message: "orders need at least one item",
+		}}}
+	}
+	if nil == account {
+		account = NewAccount(0, nil)
+	}
+
+	violations := make([]*Violation, 0)
+	for _, rule := range v.Rules {
+		for _, violation := range rule.Check(order, account) {
+			if violation.Rule == "" {
+				violation.Rule = rule.Name()
+			}
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+// walk calls the function with each item, and the position of it's symbol before and after the item.
+// The positions include the earlier items of the order, and items that are nil are skipped.
+func walk(order *orders.Order, account *Account, fn func(index int, item *orders.OrderItem, before, after float64)) {
+	positions := make(map[string]float64)
+	for index, item := range order.OrderItems {
+		if nil == item {
+			continue
+		}
+		before, ok := positions[item.Symbol]
+		if !ok {
+			before = account.Positions[item.Symbol]
+		}
+		units := item.Amount * item.QuantityPerAmount
+		if item.Direction == constants.Sell {
+			units *= -1
+		}
+		positions[item.Symbol] = before + units
+		fn(index, item, before, before+units)
+	}
+}
@@ -0,0 +1,68 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestValidator(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	validator := NewValidator(Structure(), RestrictedSymbols("XYZ"), MaxNotional(5000))
+
+	t.Run("Valid", func(t *testing.T) {
+		order := orders.NewOrder(now, orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10))
+		require.NoError(t, validator.Validate(order, nil))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		for name, order := range map[string]*orders.Order{"Nil": nil, "No items": orders.NewOrder(now)} {
+			err := validator.Validate(order, nil)
+			require.Equal(t, status.Code(err), codes.InvalidArgument, name)
+			require.Equal(t, err.(*Error).Violations[0].Rule, StructureRule, name)
+		}
+	})
+
+	t.Run("Every violation", func(t *testing.T) {
+		order := orders.NewOrder(
+			now,
+			orders.NewStockOrderItem(constants.Buy, "XYZ", 1000, 10),
+			orders.NewStockOrderItem(constants.Sell, "ABC", 0, -1),
+		)
+		err := validator.Validate(order, nil)
+		require.Error(t, err)
+		require.Equal(t, err.(*Error).Violations, []*Violation{
+			{Rule: StructureRule, Code: codes.InvalidArgument, Item: 1, Message: "amount must be positive"},
+			{Rule: StructureRule, Code: codes.InvalidArgument, Item: 1, Message: "price can't be negative"},
+			{Rule: RestrictedSymbolsRule, Code: codes.PermissionDenied, Item: 0, Message: `symbol "XYZ" is restricted`},
+			{Rule: MaxNotionalRule, Code: codes.FailedPrecondition, Item: -1, Message: "notional of 10000.00 is over the limit of 5000.00"},
+		})
+
+		// The status is the code of the first violation, with every message
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		require.Equal(t, status.Convert(err).Message(), "structure: item 1: amount must be positive; structure: item 1: price can't be negative; "+
+			`restricted_symbols: item 0: symbol "XYZ" is restricted; max_notional: notional of 10000.00 is over the limit of 5000.00`)
+	})
+
+	t.Run("Custom rule", func(t *testing.T) {
+		noCrypto := NewRule("no_crypto", func(order *orders.Order, account *Account) []*Violation {
+			output := make([]*Violation, 0)
+			for index, item := range order.OrderItems {
+				if item.ItemType == constants.Crypto {
+					output = append(output, &Violation{Code: codes.PermissionDenied, Item: index, Message: "crypto isn't allowed"})
+				}
+			}
+			return output
+		})
+		validator := NewValidator(Structure(), noCrypto)
+		err := validator.Validate(orders.NewOrder(now, orders.NewCryptoOrderItem(constants.Buy, "BTC", 1, 17000)), nil)
+		require.Equal(t, status.Code(err), codes.PermissionDenied)
+		require.Equal(t, err.Error(), "no_crypto: item 0: crypto isn't allowed")
+	})
+}
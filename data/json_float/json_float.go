@@ -0,0 +1,57 @@
+package json_float
+
+import (
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"strconv"
+)
+
+// Special values are written as strings, the same as the protobuf JSON mapping
+const (
+	nanStr         = "NaN"
+	infinityStr    = "Infinity"
+	negInfinityStr = "-Infinity"
+)
+
+// Float is a float64 that keeps NaN and ±Inf in JSON, json.Marshal fails on them otherwise.
+// Finite values are plain JSON numbers, and the special values are the strings "NaN", "Infinity", and "-Infinity".
+//
+// Reading also accepts numbers inside of strings, ex: "1.5", and the other spellings strconv.ParseFloat allows, ex: "+Inf".
+type Float float64
+
+func (f Float) MarshalJSON() ([]byte, error) {
+	value := float64(f)
+	switch {
+	case math.IsNaN(value):
+		return json.Marshal(nanStr)
+	case math.IsInf(value, 1):
+		return json.Marshal(infinityStr)
+	case math.IsInf(value, -1):
+		return json.Marshal(negInfinityStr)
+	}
+	return json.Marshal(value)
+}
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	// Leave the value alone, the same as the standard library does for null numbers
+	if string(data) == "null" {
+		return nil
+	}
+
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		err := json.Unmarshal(data, &text)
+		if nil != err {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if nil != err {
+		return status.Errorf(codes.InvalidArgument, "invalid float %s", data)
+	}
+	*f = Float(value)
+	return nil
+}
@@ -0,0 +1,74 @@
+package json_float
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+)
+
+func TestFloat(t *testing.T) {
+	type args struct {
+		value    float64
+		expected string
+	}
+	tests := map[string]args{
+		"zero":              {value: 0, expected: `0`},
+		"integer":           {value: 100, expected: `100`},
+		"fraction":          {value: 10.01, expected: `10.01`},
+		"negative":          {value: -0.5, expected: `-0.5`},
+		"large":             {value: 1e21, expected: `1e+21`},
+		"NaN":               {value: math.NaN(), expected: `"NaN"`},
+		"positive infinity": {value: math.Inf(1), expected: `"Infinity"`},
+		"negative infinity": {value: math.Inf(-1), expected: `"-Infinity"`},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(Float(test.value))
+			require.NoError(t, err)
+			require.Equal(t, string(data), test.expected)
+
+			var output Float
+			err = json.Unmarshal(data, &output)
+			require.NoError(t, err)
+			if math.IsNaN(test.value) {
+				require.True(t, math.IsNaN(float64(output)))
+			} else {
+				require.Equal(t, float64(output), test.value)
+			}
+		})
+	}
+
+	t.Run("Other spellings", func(t *testing.T) {
+		inputs := map[string]float64{
+			`"1.5"`:  1.5,
+			`"+Inf"`: math.Inf(1),
+			`"-inf"`: math.Inf(-1),
+			`2e3`:    2000,
+		}
+		for input, expected := range inputs {
+			var output Float
+			err := json.Unmarshal([]byte(input), &output)
+			require.NoError(t, err)
+			require.Equal(t, float64(output), expected)
+		}
+	})
+
+	t.Run("Null leaves the value alone", func(t *testing.T) {
+		output := Float(1.5)
+		err := json.Unmarshal([]byte(`null`), &output)
+		require.NoError(t, err)
+		require.Equal(t, output, Float(1.5))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, input := range []string{`"abc"`, `""`, `true`} {
+			var output Float
+			err := output.UnmarshalJSON([]byte(input))
+			require.Equal(t, status.Code(err), codes.InvalidArgument)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package parquet_file
+
+import (
+	"bytes"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"io/ioutil"
+)
+
+// Compression is the codec used to compress each page of a parquet file
+type Compression int
+
+const (
+	_            Compression = iota
+	Snappy                   // Snappy is fast with a reasonable compression ratio, this is the default
+	Zstd                     // Zstd is slower than Snappy with a much better compression ratio
+	Gzip                     // Gzip is the most widely supported codec
+	Uncompressed             // Uncompressed stores the raw pages
+)
+
+const (
+	snappyCompressionStr       = "snappy"
+	zstdCompressionStr         = "zstd"
+	gzipCompressionStr         = "gzip"
+	uncompressedCompressionStr = "uncompressed"
+)
+
+var compressions = map[Compression]string{
+	Snappy:       snappyCompressionStr,
+	Zstd:         zstdCompressionStr,
+	Gzip:         gzipCompressionStr,
+	Uncompressed: uncompressedCompressionStr,
+}
+
+var codecs = map[Compression]parquet.CompressionCodec{
+	Snappy:       parquet.CompressionCodec_SNAPPY,
+	Zstd:         parquet.CompressionCodec_ZSTD,
+	Gzip:         parquet.CompressionCodec_GZIP,
+	Uncompressed: parquet.CompressionCodec_UNCOMPRESSED,
+}
+
+func (c Compression) String() string {
+	return compressions[c]
+}
+
+// DefaultRowGroupSize is the number of bytes buffered in memory before a row group is written out
+const DefaultRowGroupSize = 64 * 1024 * 1024
+
+// DefaultBatchSize is the number of rows that are read into memory at a time
+const DefaultBatchSize = 4096
+
+// Options control how the parquet files are read and written
+type Options struct {
+	// Compression codec for the pages, defaults to Snappy
+	Compression Compression
+	// RowGroupSize is the number of bytes buffered before a row group is written, defaults to DefaultRowGroupSize
+	RowGroupSize int64
+	// BatchSize is the number of rows that are read at a time, defaults to DefaultBatchSize
+	BatchSize int
+}
+
+// DefaultOptions are snappy compressed files with the default row group and batch sizes
+func DefaultOptions() Options {
+	return Options{
+		Compression:  Snappy,
+		RowGroupSize: DefaultRowGroupSize,
+		BatchSize:    DefaultBatchSize,
+	}
+}
+
+// WithDefaults fills in any missing options with their default value
+func (o Options) WithDefaults() Options {
+	defaults := DefaultOptions()
+	if o.Compression == 0 {
+		o.Compression = defaults.Compression
+	}
+	if o.RowGroupSize <= 0 {
+		o.RowGroupSize = defaults.RowGroupSize
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaults.BatchSize
+	}
+	return o
+}
+
+// NewWriter creates a parquet writer for the schema of the given struct
+//
+// Errors:
+// - If the compression is unknown an error with GRPC status InvalidArgument will be returned
+//
+func NewWriter(output io.Writer, schema interface{}, options Options) (*writer.ParquetWriter, error) {
+	options = options.WithDefaults()
+	codec, ok := codecs[options.Compression]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown compression")
+	}
+
+	parquetWriter, err := writer.NewParquetWriterFromWriter(output, schema, 1)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	parquetWriter.CompressionType = codec
+	parquetWriter.RowGroupSize = options.RowGroupSize
+	return parquetWriter, nil
+}
+
+// Compile time type assertion
+var _ source.ParquetFile = &File{}
+
+// File is a read-only parquet file backed by any io.ReaderAt,
+// each call to Open creates a new independent cursor so columns can be read in parallel.
+type File struct {
+	input io.ReaderAt
+	size  int64
+	*io.SectionReader
+}
+
+// NewFile creates a read-only parquet file.
+//
+// Parquet needs random access to read the footer at the end of the file,
+// so files (or anything else that is an io.ReaderAt and io.Seeker) are read in place,
+// and any other io.Reader is read fully into memory first.
+//
+func NewFile(input io.Reader) (*File, error) {
+	if readerAt, ok := input.(io.ReaderAt); ok {
+		if seeker, ok := input.(io.Seeker); ok {
+			size, err := seeker.Seek(0, io.SeekEnd)
+			if nil != err {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			return newFile(readerAt, size), nil
+		}
+	}
+
+	data, err := ioutil.ReadAll(input)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return newFile(bytes.NewReader(data), int64(len(data))), nil
+}
+
+func newFile(input io.ReaderAt, size int64) *File {
+	return &File{
+		input:         input,
+		size:          size,
+		SectionReader: io.NewSectionReader(input, 0, size),
+	}
+}
+
+// Size is the total number of bytes in the file
+func (f *File) Size() int64 {
+	return f.size
+}
+
+func (f *File) Open(_ string) (source.ParquetFile, error) {
+	return newFile(f.input, f.size), nil
+}
+
+func (f *File) Create(_ string) (source.ParquetFile, error) {
+	return nil, status.Error(codes.Unimplemented, "parquet file is read-only")
+}
+
+func (f *File) Write(_ []byte) (int, error) {
+	return 0, status.Error(codes.Unimplemented, "parquet file is read-only")
+}
+
+func (f *File) Close() error {
+	return nil
+}
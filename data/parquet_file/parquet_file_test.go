@@ -0,0 +1,65 @@
+package parquet_file
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, Snappy.String(), snappyCompressionStr)
+		require.Equal(t, Zstd.String(), zstdCompressionStr)
+		require.Equal(t, Gzip.String(), gzipCompressionStr)
+		require.Equal(t, Uncompressed.String(), uncompressedCompressionStr)
+	})
+}
+
+func TestOptions(t *testing.T) {
+	output := Options{}.WithDefaults()
+	require.Equal(t, output, DefaultOptions())
+
+	output = Options{Compression: Zstd, RowGroupSize: 1, BatchSize: 2}.WithDefaults()
+	require.Equal(t, output, Options{Compression: Zstd, RowGroupSize: 1, BatchSize: 2})
+
+	_, err := NewWriter(ioutil.Discard, nil, Options{Compression: Compression(100)})
+	require.Error(t, err)
+}
+
+func TestFile(t *testing.T) {
+	data := []byte("0123456789")
+
+	for name, input := range map[string]io.Reader{
+		"ReaderAt": bytes.NewReader(data),
+		"Reader":   ioutil.NopCloser(bytes.NewReader(data)),
+	} {
+		input := input
+		t.Run(name, func(t *testing.T) {
+			file, err := NewFile(input)
+			require.NoError(t, err)
+			require.Equal(t, file.Size(), int64(len(data)))
+
+			// Each opened file has it's own cursor
+			other, err := file.Open("")
+			require.NoError(t, err)
+			_, err = file.Seek(5, io.SeekStart)
+			require.NoError(t, err)
+
+			buff := make([]byte, 2)
+			_, err = io.ReadFull(file, buff)
+			require.NoError(t, err)
+			require.Equal(t, string(buff), "56")
+			_, err = io.ReadFull(other, buff)
+			require.NoError(t, err)
+			require.Equal(t, string(buff), "01")
+
+			// Read-only
+			_, err = file.Write(data)
+			require.Error(t, err)
+			_, err = file.Create("")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package parquet_file
+
+import (
+	"encoding/json"
+	"github.com/xitongsys/parquet-go/reader"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"strings"
+)
+
+// schemaItem is a single field of parquet-go's JSON schema
+type schemaItem struct {
+	Tag    string        `json:"Tag"`
+	Fields []*schemaItem `json:"Fields,omitempty"`
+}
+
+// NewReader creates a parquet reader for the rows of the given struct.
+//
+// Columns of the struct that aren't in the file are skipped, and are left as their zero value when the rows are read,
+// so files written before new columns were added to the struct can still be read.
+//
+// Errors:
+// - If the footer can't be read an error with GRPC status Internal will be returned
+//
+func NewReader(file *File, schema interface{}, np int64) (*reader.ParquetReader, error) {
+	footer := &reader.ParquetReader{PFile: file}
+	err := footer.ReadFooter()
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// Find the path of every column and group within the file, skipping the root
+	columns := make(map[string]bool)
+	elements := footer.Footer.GetSchema()
+	if len(elements) > 0 {
+		index := 1
+		var walk func(prefix string, children int32)
+		walk = func(prefix string, children int32) {
+			for child := int32(0); child < children && index < len(elements); child++ {
+				element := elements[index]
+				index++
+				path := prefix + strings.ToLower(element.GetName())
+				columns[path] = true
+				walk(path+".", element.GetNumChildren())
+			}
+		}
+		walk("", elements[0].GetNumChildren())
+	}
+
+	root := &schemaItem{
+		Tag:    "name=parquet_go_root, repetitiontype=REQUIRED",
+		Fields: schemaFields(reflect.TypeOf(schema), "", columns),
+	}
+	data, err := json.Marshal(root)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	output, err := reader.NewParquetReader(file, string(data), np)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return output, nil
+}
+
+// schemaFields are the fields of the struct that are in the columns, named by their field so the rows can be read into the struct
+func schemaFields(structType reflect.Type, prefix string, columns map[string]bool) []*schemaItem {
+	for structType.Kind() == reflect.Ptr || structType.Kind() == reflect.Slice {
+		structType = structType.Elem()
+	}
+
+	output := make([]*schemaItem, 0, structType.NumField())
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		tag := field.Tag.Get("parquet")
+		name := strings.ToLower(tagValue(tag, "name"))
+		if name == "" || !columns[prefix+name] {
+			continue
+		}
+
+		item := &schemaItem{Tag: tag + ", inname=" + field.Name}
+		if tagValue(tag, "type") == "" {
+			item.Fields = schemaFields(field.Type, prefix+name+".", columns)
+			if len(item.Fields) == 0 {
+				continue
+			}
+		}
+		output = append(output, item)
+	}
+	return output
+}
+
+// tagValue is the value of the key within a parquet tag, ex: the name is "time" for `parquet:"name=time, type=INT64"`
+func tagValue(tag, key string) string {
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, key+"=") {
+			return strings.TrimPrefix(part, key+"=")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package parquet_file
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewReader(t *testing.T) {
+	type oldChild struct {
+		Name string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	}
+	type oldRow struct {
+		Time     int64      `parquet:"name=time, type=INT64"`
+		Children []oldChild `parquet:"name=children, repetitiontype=REPEATED"`
+	}
+	type newChild struct {
+		Name  string  `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Price float64 `parquet:"name=price, type=DOUBLE"`
+	}
+	type newGroup struct {
+		Value int32 `parquet:"name=value, type=INT32"`
+	}
+	type newRow struct {
+		Time     int64      `parquet:"name=time, type=INT64"`
+		Children []newChild `parquet:"name=children, repetitiontype=REPEATED"`
+		Group    *newGroup  `parquet:"name=group, repetitiontype=OPTIONAL"`
+		Tag      string     `parquet:"name=tag, type=BYTE_ARRAY, convertedtype=UTF8"`
+	}
+
+	write := func(schema interface{}, rows ...interface{}) *File {
+		buff := bytes.NewBuffer([]byte{})
+		writer, err := NewWriter(buff, schema, DefaultOptions())
+		require.NoError(t, err)
+		for _, row := range rows {
+			require.NoError(t, writer.Write(row))
+		}
+		require.NoError(t, writer.WriteStop())
+		file, err := NewFile(bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err)
+		return file
+	}
+
+	t.Run("Same schema", func(t *testing.T) {
+		input := newRow{Time: 1, Children: []newChild{{Name: "a", Price: 1.5}}, Group: &newGroup{Value: 2}, Tag: "b"}
+		reader, err := NewReader(write(new(newRow), &input), new(newRow), 1)
+		require.NoError(t, err)
+		defer reader.ReadStop()
+
+		rows := make([]newRow, 1)
+		require.NoError(t, reader.Read(&rows))
+		require.Equal(t, rows, []newRow{input})
+	})
+
+	t.Run("Missing columns are left empty", func(t *testing.T) {
+		input := oldRow{Time: 1, Children: []oldChild{{Name: "a"}, {Name: "b"}}}
+		reader, err := NewReader(write(new(oldRow), &input), new(newRow), 1)
+		require.NoError(t, err)
+		defer reader.ReadStop()
+		require.Equal(t, reader.GetNumRows(), int64(1))
+
+		rows := make([]newRow, 1)
+		require.NoError(t, reader.Read(&rows))
+		require.Equal(t, rows, []newRow{{Time: 1, Children: []newChild{{Name: "a"}, {Name: "b"}}}})
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		file, err := NewFile(bytes.NewReader([]byte("not a parquet file")))
+		require.NoError(t, err)
+		_, err = NewReader(file, new(newRow), 1)
+		require.Error(t, err)
+	})
+}
+
+func TestTagValue(t *testing.T) {
+	tag := "name=time, type=INT64, repetitiontype=OPTIONAL"
+	require.Equal(t, tagValue(tag, "name"), "time")
+	require.Equal(t, tagValue(tag, "type"), "INT64")
+	require.Equal(t, tagValue(tag, "repetitiontype"), "OPTIONAL")
+	require.Empty(t, tagValue(tag, "convertedtype"))
+}
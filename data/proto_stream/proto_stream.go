@@ -0,0 +1,86 @@
+package proto_stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+)
+
+// MaxMessageSize protects us from allocating huge buffers when the input is corrupt
+const MaxMessageSize = 64 << 20
+
+// ReadDelimited reads the next message prefixed with it's length as a varint.
+// This is the same framing as `writeDelimitedTo` / `parseDelimitedFrom` in the Java, C++, and C# protobuf libraries.
+//
+// io.EOF is only returned when the stream ends cleanly between two messages,
+// a stream that ends part way through a message returns io.ErrUnexpectedEOF.
+//
+// Errors:
+// - If the length is more than MaxMessageSize an error with GRPC status OutOfRange will be returned
+//
+func ReadDelimited(reader *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if nil != err {
+		return nil, err
+	}
+	if length > MaxMessageSize {
+		return nil, status.Error(codes.OutOfRange, "message is too large")
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(reader, data)
+	if nil != err && err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+// WriteDelimited writes the message prefixed with it's length as a varint, see ReadDelimited
+func WriteDelimited(writer io.Writer, data []byte) error {
+	_, err := writer.Write(protowire.AppendBytes(nil, data))
+	return err
+}
+
+// ReadField reads the next field from a stream of protobuf fields, ex: the items of a repeated message field.
+// Only length-delimited fields return their data, every other type of field is read and discarded.
+//
+// io.EOF is only returned when the stream ends cleanly between two fields,
+// a stream that ends part way through a field returns io.ErrUnexpectedEOF.
+//
+// Errors:
+// - If the length of a field is more than MaxMessageSize an error with GRPC status OutOfRange will be returned
+// - If the field is a group, which are deprecated, an error with GRPC status InvalidArgument will be returned
+//
+func ReadField(reader *bufio.Reader) (protowire.Number, protowire.Type, []byte, error) {
+	tag, err := binary.ReadUvarint(reader)
+	if nil != err {
+		return 0, 0, nil, err
+	}
+	number, wireType := protowire.DecodeTag(tag)
+
+	var data []byte
+	switch wireType {
+	case protowire.VarintType:
+		_, err = binary.ReadUvarint(reader)
+	case protowire.Fixed32Type:
+		_, err = reader.Discard(4)
+	case protowire.Fixed64Type:
+		_, err = reader.Discard(8)
+	case protowire.BytesType:
+		data, err = ReadDelimited(reader)
+		if nil != err && status.Code(err) == codes.OutOfRange {
+			return 0, 0, nil, err
+		}
+	default:
+		return 0, 0, nil, status.Error(codes.InvalidArgument, "unsupported wire type")
+	}
+
+	// We are part way through a field, so running out of data is unexpected
+	if nil != err && err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return number, wireType, data, err
+}
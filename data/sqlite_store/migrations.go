@@ -0,0 +1,253 @@
+package sqlite_store
+
+import (
+	"context"
+	"database/sql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// migrations are applied in order, and each one is only ever applied once.
+// Never edit a migration once it has been released, always add a new one to the end.
+var migrations = []string{
+	// 1. Bars, keyed by their symbol, interval, and time, so corrected data replaces the original rows
+	`
+	CREATE TABLE bars (
+		symbol        TEXT    NOT NULL,
+		interval      INTEGER NOT NULL,
+		time          INTEGER NOT NULL,
+		open          REAL    NOT NULL,
+		high          REAL    NOT NULL,
+		low           REAL    NOT NULL,
+		close         REAL    NOT NULL,
+		volume        REAL    NOT NULL,
+		open_interest INTEGER NOT NULL DEFAULT -1,
+		PRIMARY KEY (symbol, interval, time)
+	) WITHOUT ROWID;
+	`,
+	// 2. Orders, and the items within each order in their original order
+	`
+	CREATE TABLE orders (
+		id   INTEGER PRIMARY KEY AUTOINCREMENT,
+		time INTEGER NOT NULL
+	);
+	CREATE INDEX orders_time ON orders (time);
+
+	CREATE TABLE order_items (
+		order_id            INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+		position            INTEGER NOT NULL,
+		direction           INTEGER NOT NULL,
+		item_type           INTEGER NOT NULL,
+		symbol              TEXT    NOT NULL,
+		amount              REAL    NOT NULL,
+		quantity_per_amount REAL    NOT NULL,
+		price               REAL    NOT NULL,
+		PRIMARY KEY (order_id, position)
+	);
+	CREATE INDEX order_items_symbol ON order_items (symbol);
+	`,
+	// 3. How each order is executed, orders without an execution type are market orders
+	`
+	ALTER TABLE orders ADD COLUMN execution_type   INTEGER;
+	ALTER TABLE orders ADD COLUMN limit_price      REAL;
+	ALTER TABLE orders ADD COLUMN stop_price       REAL;
+	ALTER TABLE orders ADD COLUMN trailing_amount  REAL;
+	ALTER TABLE orders ADD COLUMN trailing_percent REAL;
+	ALTER TABLE orders ADD COLUMN triggered        INTEGER;
+	`,
+	// 4. The order lifecycle, `external_id` is the order's own id since `id` is the row id.
+	//    The fills and events of each order are kept in their original order.
+	`
+	ALTER TABLE orders ADD COLUMN external_id   TEXT    NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN client_tag    TEXT    NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN time_in_force INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN expire_time   INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN state         INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX orders_external_id ON orders (external_id);
+	CREATE INDEX orders_client_tag ON orders (client_tag);
+
+	CREATE TABLE order_fills (
+		order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		time     INTEGER NOT NULL,
+		item     INTEGER NOT NULL,
+		amount   REAL    NOT NULL,
+		price    REAL    NOT NULL,
+		PRIMARY KEY (order_id, position)
+	);
+
+	CREATE TABLE order_events (
+		order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		time     INTEGER NOT NULL,
+		state    INTEGER NOT NULL,
+		reason   TEXT    NOT NULL,
+		PRIMARY KEY (order_id, position)
+	);
+	`,
+	// 5. The contract of option items, items without an option underlying don't have a contract
+	`
+	ALTER TABLE order_items ADD COLUMN option_underlying TEXT;
+	ALTER TABLE order_items ADD COLUMN option_strike     REAL;
+	ALTER TABLE order_items ADD COLUMN option_expiration INTEGER;
+	ALTER TABLE order_items ADD COLUMN option_right      INTEGER;
+	ALTER TABLE order_items ADD COLUMN option_style      INTEGER;
+	ALTER TABLE order_items ADD COLUMN option_multiplier REAL;
+	`,
+	// 6. The contract of future items, and the currency pair of forex items, both are NULL when the item doesn't have one
+	`
+	ALTER TABLE order_items ADD COLUMN future_root               TEXT;
+	ALTER TABLE order_items ADD COLUMN future_tick_size          REAL;
+	ALTER TABLE order_items ADD COLUMN future_tick_value         REAL;
+	ALTER TABLE order_items ADD COLUMN future_multiplier         REAL;
+	ALTER TABLE order_items ADD COLUMN future_initial_margin     REAL;
+	ALTER TABLE order_items ADD COLUMN future_maintenance_margin REAL;
+	ALTER TABLE order_items ADD COLUMN future_expiration         INTEGER;
+	ALTER TABLE order_items ADD COLUMN future_roll_date          INTEGER;
+	ALTER TABLE order_items ADD COLUMN forex_base                TEXT;
+	ALTER TABLE order_items ADD COLUMN forex_quote               TEXT;
+	ALTER TABLE order_items ADD COLUMN forex_pip_size            REAL;
+	ALTER TABLE order_items ADD COLUMN forex_lot_size            REAL;
+	ALTER TABLE order_items ADD COLUMN forex_margin_rate         REAL;
+	`,
+	// 7. The prices and volume of bars are nullable, since SQLite stores NaN as NULL.
+	//    SQLite can't drop a NOT NULL constraint, so the table is rebuilt with the same rows.
+	`
+	CREATE TABLE bars_nullable (
+		symbol        TEXT    NOT NULL,
+		interval      INTEGER NOT NULL,
+		time          INTEGER NOT NULL,
+		open          REAL,
+		high          REAL,
+		low           REAL,
+		close         REAL,
+		volume        REAL,
+		open_interest INTEGER NOT NULL DEFAULT -1,
+		PRIMARY KEY (symbol, interval, time)
+	) WITHOUT ROWID;
+	INSERT INTO bars_nullable (symbol, interval, time, open, high, low, close, volume, open_interest)
+		SELECT symbol, interval, time, open, high, low, close, volume, open_interest FROM bars;
+	DROP TABLE bars;
+	ALTER TABLE bars_nullable RENAME TO bars;
+	`,
+	// 8. The amounts and prices of order items and fills are nullable too, the same as the bars in migration 7
+	`
+	CREATE TABLE order_items_nullable (
+		order_id                  INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+		position                  INTEGER NOT NULL,
+		direction                 INTEGER NOT NULL,
+		item_type                 INTEGER NOT NULL,
+		symbol                    TEXT    NOT NULL,
+		amount                    REAL,
+		quantity_per_amount       REAL,
+		price                     REAL,
+		option_underlying         TEXT,
+		option_strike             REAL,
+		option_expiration         INTEGER,
+		option_right              INTEGER,
+		option_style              INTEGER,
+		option_multiplier         REAL,
+		future_root               TEXT,
+		future_tick_size          REAL,
+		future_tick_value         REAL,
+		future_multiplier         REAL,
+		future_initial_margin     REAL,
+		future_maintenance_margin REAL,
+		future_expiration         INTEGER,
+		future_roll_date          INTEGER,
+		forex_base                TEXT,
+		forex_quote               TEXT,
+		forex_pip_size            REAL,
+		forex_lot_size            REAL,
+		forex_margin_rate         REAL,
+		PRIMARY KEY (order_id, position)
+	);
+	INSERT INTO order_items_nullable SELECT
+		order_id, position, direction, item_type, symbol, amount, quantity_per_amount, price,
+		option_underlying, option_strike, option_expiration, option_right, option_style, option_multiplier,
+		future_root, future_tick_size, future_tick_value, future_multiplier,
+		future_initial_margin, future_maintenance_margin, future_expiration, future_roll_date,
+		forex_base, forex_quote, forex_pip_size, forex_lot_size, forex_margin_rate
+		FROM order_items;
+	DROP TABLE order_items;
+	ALTER TABLE order_items_nullable RENAME TO order_items;
+	CREATE INDEX order_items_symbol ON order_items (symbol);
+
+	CREATE TABLE order_fills_nullable (
+		order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		time     INTEGER NOT NULL,
+		item     INTEGER NOT NULL,
+		amount   REAL,
+		price    REAL,
+		PRIMARY KEY (order_id, position)
+	);
+	INSERT INTO order_fills_nullable (order_id, position, time, item, amount, price)
+		SELECT order_id, position, time, item, amount, price FROM order_fills;
+	DROP TABLE order_fills;
+	ALTER TABLE order_fills_nullable RENAME TO order_fills;
+	`,
+}
+
+// migrate creates the migrations table, and applies every migration that hasn't been applied yet.
+// Each migration is applied in it's own transaction, so a failed migration leaves the database at the previous version.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at INTEGER NOT NULL
+		)
+	`)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	current, err := schemaVersion(ctx, db)
+	if nil != err {
+		return err
+	}
+	if current > len(migrations) {
+		return status.Errorf(codes.FailedPrecondition, "database version %d is newer than the latest migration %d", current, len(migrations))
+	}
+
+	for index := current; index < len(migrations); index++ {
+		err = applyMigration(ctx, db, index+1, migrations[index])
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, version int, migration string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, migration)
+	if nil != err {
+		return status.Errorf(codes.Internal, "migration %d failed: %s", version, err.Error())
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().Unix())
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	err = tx.Commit()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// schemaVersion is the latest migration applied to the database, or 0 for a new database
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if nil != err {
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+	return int(version.Int64), nil
+}
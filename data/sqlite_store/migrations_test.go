@@ -0,0 +1,43 @@
+package sqlite_store
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ta4g.db")
+
+	store, err := Open(ctx, path)
+	require.NoError(t, err)
+	version, err := store.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, version, len(migrations))
+
+	var tables int
+	err = store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('bars', 'orders', 'order_items')`).Scan(&tables)
+	require.NoError(t, err)
+	require.Equal(t, tables, 3)
+	require.NoError(t, store.Close())
+
+	// Re-opening doesn't apply the migrations again
+	store, err = Open(ctx, path)
+	require.NoError(t, err)
+	var count int
+	err = store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, count, len(migrations))
+
+	// A database written by a newer version of the code can't be used
+	_, err = store.DB().ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, 0)`, len(migrations)+1)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	_, err = Open(ctx, path)
+	require.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
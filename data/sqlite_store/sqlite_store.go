@@ -0,0 +1,803 @@
+package sqlite_store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"modernc.org/sqlite"
+	"strings"
+	"time"
+)
+
+// DefaultBatchSize is the number of rows written by each INSERT statement
+const DefaultBatchSize = 500
+
+// sqliteConstraint is the primary result code of any constraint violation, ex: a NOT NULL column without a value
+const sqliteConstraint = 19
+
+// sqliteConstraintPrimaryKey is the extended result code of a duplicate primary key
+const sqliteConstraintPrimaryKey = 1555
+
+// Store persists bars and orders in an embedded SQLite database, using a pure Go driver so cgo isn't needed.
+//
+// The tables can be queried directly with any SQLite client:
+// 1. `bars` has one row per bar, keyed by symbol, interval (in seconds), and time (unix seconds)
+// 2. `orders` has one row per order, and `order_items` has one row per item with the id of it's order
+// 3. `orders.execution_type` is NULL for orders without an execution
+//
+type Store struct {
+	db        *sql.DB
+	batchSize int
+}
+
+// Open opens or creates the database file, and applies any new migrations.
+// Use ":memory:" for a temporary in memory database.
+//
+// Errors:
+// - If the database is newer than this version of the code an error with GRPC status FailedPrecondition will be returned
+//
+func Open(ctx context.Context, path string) (*Store, error) {
+	logger := ctxzap.Extract(ctx)
+
+	db, err := sql.Open("sqlite", path)
+	if nil != err {
+		logger.Error("Failed to open database", zap.String("path", path), zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// SQLite only allows a single writer, so a single connection avoids any "database is locked" errors within the process,
+	// and is required for in memory databases, where every connection would be a separate database.
+	db.SetMaxOpenConns(1)
+	pragmas := []string{
+		`PRAGMA foreign_keys = ON`,
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA journal_mode = WAL`,
+	}
+	for _, pragma := range pragmas {
+		_, err = db.ExecContext(ctx, pragma)
+		if nil != err {
+			db.Close()
+			logger.Error("Failed to configure database", zap.String("pragma", pragma), zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	err = migrate(ctx, db)
+	if nil != err {
+		db.Close()
+		logger.Error("Failed to migrate database", zap.Error(err))
+		return nil, err
+	}
+	return &Store{db: db, batchSize: DefaultBatchSize}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	err := s.db.Close()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// DB is the underlying database, for any ad-hoc queries
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Version is the latest migration applied to the database
+func (s *Store) Version(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, s.db)
+}
+
+//
+// Bars
+//
+
+// barColumns are the columns of the bars table, in the order they are inserted
+var barColumns = append([]string{"symbol", "interval"}, bar.Columns...)
+
+// InsertBars adds new bars, in batches within a single transaction.
+//
+// Errors:
+// - If any of the bars already exist an error with GRPC status AlreadyExists will be returned, and none of the bars are added
+//
+func (s *Store) InsertBars(ctx context.Context, symbol string, interval time.Duration, bars []bar.Bar) error {
+	return s.writeBars(ctx, symbol, interval, bars, false)
+}
+
+// UpsertBars adds new bars and replaces any existing bars with the same symbol, interval, and time.
+// This is used to load corrected vendor data over the original data.
+func (s *Store) UpsertBars(ctx context.Context, symbol string, interval time.Duration, bars []bar.Bar) error {
+	return s.writeBars(ctx, symbol, interval, bars, true)
+}
+
+func (s *Store) writeBars(ctx context.Context, symbol string, interval time.Duration, bars []bar.Bar, upsert bool) error {
+	logger := ctxzap.Extract(ctx)
+
+	err := validateSeries(symbol, interval)
+	if nil != err {
+		return err
+	}
+	return s.transaction(ctx, func(tx *sql.Tx) error {
+		for start := 0; start < len(bars); start += s.batchSize {
+			end := start + s.batchSize
+			if end > len(bars) {
+				end = len(bars)
+			}
+			batch := bars[start:end]
+
+			args := make([]interface{}, 0, len(batch)*len(barColumns))
+			for _, row := range batch {
+				args = append(
+					args,
+					symbol,
+					int64(interval/time.Second),
+					row.GetTime().Unix(),
+					row.GetOpen(),
+					row.GetHigh(),
+					row.GetLow(),
+					row.GetClose(),
+					row.GetVolume(),
+					row.GetOpenInterest(),
+				)
+			}
+			_, err := tx.ExecContext(ctx, insertBarsStatement(len(batch), upsert), args...)
+			if nil != err {
+				logger.Error("Failed to insert bars", zap.String("symbol", symbol), zap.Error(err))
+				return toStatus(err)
+			}
+		}
+		return nil
+	})
+}
+
+// QueryBars reads the bars in the time range [start, end) sorted by time
+func (s *Store) QueryBars(ctx context.Context, symbol string, interval time.Duration, start, end time.Time) ([]bar.Bar, error) {
+	logger := ctxzap.Extract(ctx)
+
+	err := validateSeries(symbol, interval)
+	if nil != err {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+strings.Join(bar.Columns, ", ")+` FROM bars
+		WHERE symbol = ? AND interval = ? AND time >= ? AND time < ?
+		ORDER BY time`,
+		symbol,
+		int64(interval/time.Second),
+		start.Unix(),
+		end.Unix(),
+	)
+	if nil != err {
+		logger.Error("Failed to query bars", zap.String("symbol", symbol), zap.Error(err))
+		return nil, toStatus(err)
+	}
+	defer rows.Close()
+
+	output := make([]bar.Bar, 0)
+	for rows.Next() {
+		row := &bar.StandardBar{}
+		var openValue, highValue, lowValue, closeValue, volumeValue sql.NullFloat64
+		err = rows.Scan(&row.UnixTime, &openValue, &highValue, &lowValue, &closeValue, &volumeValue, &row.OpenInterest)
+		if nil != err {
+			logger.Error("Failed to read bar", zap.Error(err))
+			return nil, toStatus(err)
+		}
+		row.Open = nullToNaN(openValue)
+		row.High = nullToNaN(highValue)
+		row.Low = nullToNaN(lowValue)
+		row.Close = nullToNaN(closeValue)
+		row.Volume = nullToNaN(volumeValue)
+		output = append(output, row)
+	}
+	err = rows.Err()
+	if nil != err {
+		return nil, toStatus(err)
+	}
+	return output, nil
+}
+
+// DeleteBars removes the bars in the time range [start, end), returning the number of bars removed
+func (s *Store) DeleteBars(ctx context.Context, symbol string, interval time.Duration, start, end time.Time) (int64, error) {
+	err := validateSeries(symbol, interval)
+	if nil != err {
+		return 0, err
+	}
+	result, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM bars WHERE symbol = ? AND interval = ? AND time >= ? AND time < ?`,
+		symbol,
+		int64(interval/time.Second),
+		start.Unix(),
+		end.Unix(),
+	)
+	if nil != err {
+		return 0, toStatus(err)
+	}
+	count, err := result.RowsAffected()
+	if nil != err {
+		return 0, toStatus(err)
+	}
+	return count, nil
+}
+
+// Symbols lists every symbol with bars, sorted alphabetically
+func (s *Store) Symbols(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT symbol FROM bars ORDER BY symbol`)
+	if nil != err {
+		return nil, toStatus(err)
+	}
+	defer rows.Close()
+
+	output := make([]string, 0)
+	for rows.Next() {
+		var symbol string
+		err = rows.Scan(&symbol)
+		if nil != err {
+			return nil, toStatus(err)
+		}
+		output = append(output, symbol)
+	}
+	err = rows.Err()
+	if nil != err {
+		return nil, toStatus(err)
+	}
+	return output, nil
+}
+
+func insertBarsStatement(rows int, upsert bool) string {
+	values := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(barColumns)), ", ") + ")"
+	statement := `INSERT INTO bars (` + strings.Join(barColumns, ", ") + `) VALUES ` +
+		strings.TrimSuffix(strings.Repeat(values+", ", rows), ", ")
+	if !upsert {
+		return statement
+	}
+
+	updates := make([]string, 0, len(bar.Columns)-1)
+	for _, column := range bar.Columns[1:] {
+		updates = append(updates, column+" = excluded."+column)
+	}
+	return statement + ` ON CONFLICT (symbol, interval, time) DO UPDATE SET ` + strings.Join(updates, ", ")
+}
+
+func validateSeries(symbol string, interval time.Duration) error {
+	if symbol == "" {
+		return status.Error(codes.InvalidArgument, "symbol is required")
+	}
+	if interval <= 0 || interval%time.Second != 0 {
+		return status.Errorf(codes.InvalidArgument, "interval must be a whole number of seconds: %s", interval)
+	}
+	return nil
+}
+
+//
+// Orders
+//
+
+// orderItemColumns are the columns of the order_items table, in the order they are inserted
+var orderItemColumns = []string{
+	"order_id",
+	"position",
+	"direction",
+	"item_type",
+	"symbol",
+	"amount",
+	"quantity_per_amount",
+	"price",
+	"option_underlying",
+	"option_strike",
+	"option_expiration",
+	"option_right",
+	"option_style",
+	"option_multiplier",
+	"future_root",
+	"future_tick_size",
+	"future_tick_value",
+	"future_multiplier",
+	"future_initial_margin",
+	"future_maintenance_margin",
+	"future_expiration",
+	"future_roll_date",
+	"forex_base",
+	"forex_quote",
+	"forex_pip_size",
+	"forex_lot_size",
+	"forex_margin_rate",
+}
+
+// orderFillColumns are the columns of the order_fills table, in the order they are inserted
+var orderFillColumns = []string{"order_id", "position", "time", "item", "amount", "price"}
+
+// orderEventColumns are the columns of the order_events table, in the order they are inserted
+var orderEventColumns = []string{"order_id", "position", "time", "state", "reason"}
+
+// InsertOrders adds the orders and their items within a single transaction, returning the id of each order
+func (s *Store) InsertOrders(ctx context.Context, input []*orders.Order) ([]int64, error) {
+	output := make([]int64, 0, len(input))
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		ids, err := s.insertOrders(ctx, tx, input)
+		output = ids
+		return err
+	})
+	if nil != err {
+		return nil, err
+	}
+	return output, nil
+}
+
+// ReplaceOrders removes every order in the time range [start, end) and adds the new orders within a single transaction.
+// Orders don't have a natural key, so this is how a corrected range of orders is loaded.
+//
+// Errors:
+// - If any of the new orders are outside of the time range an error with GRPC status InvalidArgument will be returned
+//
+func (s *Store) ReplaceOrders(ctx context.Context, start, end time.Time, input []*orders.Order) ([]int64, error) {
+	for _, order := range input {
+		if order.UnixTime < start.Unix() || order.UnixTime >= end.Unix() {
+			return nil, status.Error(codes.InvalidArgument, "order is outside of the time range")
+		}
+	}
+
+	output := make([]int64, 0, len(input))
+	err := s.transaction(ctx, func(tx *sql.Tx) error {
+		// The items are removed by the foreign key's cascade
+		_, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE time >= ? AND time < ?`, start.Unix(), end.Unix())
+		if nil != err {
+			return toStatus(err)
+		}
+		ids, err := s.insertOrders(ctx, tx, input)
+		output = ids
+		return err
+	})
+	if nil != err {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (s *Store) insertOrders(ctx context.Context, tx *sql.Tx, input []*orders.Order) ([]int64, error) {
+	logger := ctxzap.Extract(ctx)
+
+	ids := make([]int64, 0, len(input))
+	items := newBatch("order_items", orderItemColumns, s.batchSize)
+	fills := newBatch("order_fills", orderFillColumns, s.batchSize)
+	events := newBatch("order_events", orderEventColumns, s.batchSize)
+	for _, order := range input {
+		args := []interface{}{order.UnixTime}
+		args = append(args, executionArgs(order.Execution)...)
+		args = append(args, order.ID, order.ClientTag, int64(order.TimeInForce), order.ExpireTime, int64(order.State))
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO orders (
+				time,
+				execution_type, limit_price, stop_price, trailing_amount, trailing_percent, triggered,
+				external_id, client_tag, time_in_force, expire_time, state
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			args...,
+		)
+		if nil != err {
+			logger.Error("Failed to insert order", zap.Error(err))
+			return nil, toStatus(err)
+		}
+		id, err := result.LastInsertId()
+		if nil != err {
+			return nil, toStatus(err)
+		}
+		ids = append(ids, id)
+
+		for position, item := range order.OrderItems {
+			values := []interface{}{
+				id,
+				position,
+				int64(item.Direction),
+				int64(item.ItemType),
+				item.Symbol,
+				item.Amount,
+				item.QuantityPerAmount,
+				item.Price,
+			}
+			values = append(values, optionArgs(item.Option)...)
+			values = append(values, futureArgs(item.Future)...)
+			err = items.add(ctx, tx, append(values, forexArgs(item.Forex)...)...)
+			if nil != err {
+				return nil, err
+			}
+		}
+		for position, fill := range order.Fills {
+			err = fills.add(ctx, tx, id, position, fill.UnixTime, fill.Item, fill.Amount, fill.Price)
+			if nil != err {
+				return nil, err
+			}
+		}
+		for position, event := range order.Events {
+			err = events.add(ctx, tx, id, position, event.UnixTime, int64(event.State), event.Reason)
+			if nil != err {
+				return nil, err
+			}
+		}
+	}
+	for _, remaining := range []*batch{items, fills, events} {
+		err := remaining.flush(ctx, tx)
+		if nil != err {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// executionArgs are the execution columns of an order, which are all NULL without an execution
+func executionArgs(execution *orders.Execution) []interface{} {
+	if nil == execution {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		int64(execution.Type),
+		execution.LimitPrice,
+		execution.StopPrice,
+		execution.TrailingAmount,
+		execution.TrailingPercent,
+		execution.Triggered,
+	}
+}
+
+// optionArgs are the option columns of an order item, which are all NULL without an option contract
+func optionArgs(option *orders.OptionContract) []interface{} {
+	if nil == option {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		option.Underlying,
+		option.Strike,
+		option.Expiration,
+		int64(option.Right),
+		int64(option.Style),
+		option.Multiplier,
+	}
+}
+
+// futureArgs are the future columns of an order item, which are all NULL without a future contract
+func futureArgs(future *orders.FutureContract) []interface{} {
+	if nil == future {
+		return []interface{}{nil, nil, nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{
+		future.Root,
+		future.TickSize,
+		future.TickValue,
+		future.Multiplier,
+		future.InitialMargin,
+		future.MaintenanceMargin,
+		future.Expiration,
+		future.RollDate,
+	}
+}
+
+// forexArgs are the forex columns of an order item, which are all NULL without a forex pair
+func forexArgs(forex *orders.ForexPair) []interface{} {
+	if nil == forex {
+		return []interface{}{nil, nil, nil, nil, nil}
+	}
+	return []interface{}{forex.Base, forex.Quote, forex.PipSize, forex.LotSize, forex.MarginRate}
+}
+
+// batch collects the rows of a table, and writes them with a single INSERT statement once there are enough of them
+type batch struct {
+	table   string
+	columns []string
+	size    int
+	count   int
+	args    []interface{}
+}
+
+func newBatch(table string, columns []string, size int) *batch {
+	return &batch{table: table, columns: columns, size: size, args: make([]interface{}, 0)}
+}
+
+// add appends a row with a value for each column, and writes the batch when it's full
+func (b *batch) add(ctx context.Context, tx *sql.Tx, values ...interface{}) error {
+	b.args = append(b.args, values...)
+	b.count++
+	if b.count == b.size {
+		return b.flush(ctx, tx)
+	}
+	return nil
+}
+
+// flush writes any rows that haven't been written yet
+func (b *batch) flush(ctx context.Context, tx *sql.Tx) error {
+	if b.count == 0 {
+		return nil
+	}
+	values := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ") + ")"
+	statement := `INSERT INTO ` + b.table + ` (` + strings.Join(b.columns, ", ") + `) VALUES ` +
+		strings.TrimSuffix(strings.Repeat(values+", ", b.count), ", ")
+	_, err := tx.ExecContext(ctx, statement, b.args...)
+	if nil != err {
+		ctxzap.Extract(ctx).Error("Failed to insert rows", zap.String("table", b.table), zap.Error(err))
+		return toStatus(err)
+	}
+	b.args = b.args[:0]
+	b.count = 0
+	return nil
+}
+
+// QueryOrders reads the orders in the time range [start, end) sorted by time, and then the order they were added.
+// When the symbol isn't empty, only orders with at least one item for the symbol are returned, along with all of their items.
+func (s *Store) QueryOrders(ctx context.Context, symbol string, start, end time.Time) ([]*orders.Order, error) {
+	logger := ctxzap.Extract(ctx)
+
+	query := `
+		SELECT o.id, o.time,
+			o.execution_type, o.limit_price, o.stop_price, o.trailing_amount, o.trailing_percent, o.triggered,
+			o.external_id, o.client_tag, o.time_in_force, o.expire_time, o.state,
+			i.direction, i.item_type, i.symbol, i.amount, i.quantity_per_amount, i.price,
+			i.option_underlying, i.option_strike, i.option_expiration, i.option_right, i.option_style, i.option_multiplier,
+			i.future_root, i.future_tick_size, i.future_tick_value, i.future_multiplier,
+			i.future_initial_margin, i.future_maintenance_margin, i.future_expiration, i.future_roll_date,
+			i.forex_base, i.forex_quote, i.forex_pip_size, i.forex_lot_size, i.forex_margin_rate
+		FROM orders o
+		LEFT JOIN order_items i ON i.order_id = o.id
+		WHERE o.time >= ? AND o.time < ?`
+	args := []interface{}{start.Unix(), end.Unix()}
+	if symbol != "" {
+		query += ` AND o.id IN (SELECT order_id FROM order_items WHERE symbol = ?)`
+		args = append(args, symbol)
+	}
+	query += ` ORDER BY o.time, o.id, i.position`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if nil != err {
+		logger.Error("Failed to query orders", zap.Error(err))
+		return nil, toStatus(err)
+	}
+	defer rows.Close()
+
+	output := make([]*orders.Order, 0)
+	byID := make(map[int64]*orders.Order)
+	lastID := int64(-1)
+	for rows.Next() {
+		var id, unixTime int64
+		var executionType sql.NullInt64
+		var limitPrice, stopPrice, trailingAmount, trailingPercent sql.NullFloat64
+		var triggered sql.NullBool
+		var externalID, clientTag string
+		var timeInForce, expireTime, state int64
+		var direction, itemType sql.NullInt64
+		var itemSymbol sql.NullString
+		var amount, quantityPerAmount, price sql.NullFloat64
+		var optionUnderlying sql.NullString
+		var optionStrike, optionMultiplier sql.NullFloat64
+		var optionExpiration, optionRight, optionStyle sql.NullInt64
+		var futureRoot, forexBase, forexQuote sql.NullString
+		var futureTickSize, futureTickValue, futureMultiplier, futureInitialMargin, futureMaintenanceMargin sql.NullFloat64
+		var futureExpiration, futureRollDate sql.NullInt64
+		var forexPipSize, forexLotSize, forexMarginRate sql.NullFloat64
+		err = rows.Scan(
+			&id, &unixTime,
+			&executionType, &limitPrice, &stopPrice, &trailingAmount, &trailingPercent, &triggered,
+			&externalID, &clientTag, &timeInForce, &expireTime, &state,
+			&direction, &itemType, &itemSymbol, &amount, &quantityPerAmount, &price,
+			&optionUnderlying, &optionStrike, &optionExpiration, &optionRight, &optionStyle, &optionMultiplier,
+			&futureRoot, &futureTickSize, &futureTickValue, &futureMultiplier,
+			&futureInitialMargin, &futureMaintenanceMargin, &futureExpiration, &futureRollDate,
+			&forexBase, &forexQuote, &forexPipSize, &forexLotSize, &forexMarginRate,
+		)
+		if nil != err {
+			logger.Error("Failed to read order", zap.Error(err))
+			return nil, toStatus(err)
+		}
+		if id != lastID {
+			lastID = id
+			output = append(output, &orders.Order{
+				UnixTime:    unixTime,
+				OrderItems:  make([]*orders.OrderItem, 0),
+				ID:          externalID,
+				ClientTag:   clientTag,
+				TimeInForce: constants.TimeInForce(timeInForce),
+				ExpireTime:  expireTime,
+				State:       constants.OrderState(state),
+			})
+			byID[id] = output[len(output)-1]
+			if executionType.Valid {
+				output[len(output)-1].Execution = &orders.Execution{
+					Type:            constants.ExecutionType(executionType.Int64),
+					LimitPrice:      nullToNaN(limitPrice),
+					StopPrice:       nullToNaN(stopPrice),
+					TrailingAmount:  nullToNaN(trailingAmount),
+					TrailingPercent: nullToNaN(trailingPercent),
+					Triggered:       triggered.Bool,
+				}
+			}
+		}
+
+		// Orders without any items have a single row of NULL items
+		if !itemSymbol.Valid {
+			continue
+		}
+		order := output[len(output)-1]
+		item := &orders.OrderItem{
+			Direction:         constants.Direction(direction.Int64),
+			ItemType:          constants.ItemType(itemType.Int64),
+			Symbol:            itemSymbol.String,
+			Amount:            nullToNaN(amount),
+			QuantityPerAmount: nullToNaN(quantityPerAmount),
+			Price:             nullToNaN(price),
+		}
+		if optionUnderlying.Valid {
+			item.Option = &orders.OptionContract{
+				Underlying: optionUnderlying.String,
+				Strike:     nullToNaN(optionStrike),
+				Expiration: optionExpiration.Int64,
+				Right:      constants.OptionRight(optionRight.Int64),
+				Style:      constants.OptionStyle(optionStyle.Int64),
+				Multiplier: nullToNaN(optionMultiplier),
+			}
+		}
+		if futureRoot.Valid {
+			item.Future = &orders.FutureContract{
+				Root:              futureRoot.String,
+				TickSize:          nullToNaN(futureTickSize),
+				TickValue:         nullToNaN(futureTickValue),
+				Multiplier:        nullToNaN(futureMultiplier),
+				InitialMargin:     nullToNaN(futureInitialMargin),
+				MaintenanceMargin: nullToNaN(futureMaintenanceMargin),
+				Expiration:        futureExpiration.Int64,
+				RollDate:          futureRollDate.Int64,
+			}
+		}
+		if forexBase.Valid {
+			item.Forex = &orders.ForexPair{
+				Base:       forexBase.String,
+				Quote:      forexQuote.String,
+				PipSize:    nullToNaN(forexPipSize),
+				LotSize:    nullToNaN(forexLotSize),
+				MarginRate: nullToNaN(forexMarginRate),
+			}
+		}
+		order.OrderItems = append(order.OrderItems, item)
+	}
+	err = rows.Err()
+	if nil != err {
+		return nil, toStatus(err)
+	}
+
+	err = s.queryOrderFills(ctx, byID, start, end)
+	if nil != err {
+		return nil, err
+	}
+	err = s.queryOrderEvents(ctx, byID, start, end)
+	if nil != err {
+		return nil, err
+	}
+	return output, nil
+}
+
+// queryOrderFills adds the fills of the orders in the time range [start, end) to the orders by their row id
+func (s *Store) queryOrderFills(ctx context.Context, byID map[int64]*orders.Order, start, end time.Time) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.order_id, f.time, f.item, f.amount, f.price
+		FROM order_fills f
+		JOIN orders o ON o.id = f.order_id
+		WHERE o.time >= ? AND o.time < ?
+		ORDER BY f.order_id, f.position`,
+		start.Unix(), end.Unix(),
+	)
+	if nil != err {
+		ctxzap.Extract(ctx).Error("Failed to query order fills", zap.Error(err))
+		return toStatus(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var amount, price sql.NullFloat64
+		fill := &orders.Fill{}
+		err = rows.Scan(&id, &fill.UnixTime, &fill.Item, &amount, &price)
+		if nil != err {
+			return toStatus(err)
+		}
+		fill.Amount = nullToNaN(amount)
+		fill.Price = nullToNaN(price)
+		// Orders that didn't match the symbol
+		if order, ok := byID[id]; ok {
+			order.Fills = append(order.Fills, fill)
+		}
+	}
+	err = rows.Err()
+	if nil != err {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// queryOrderEvents adds the events of the orders in the time range [start, end) to the orders by their row id
+func (s *Store) queryOrderEvents(ctx context.Context, byID map[int64]*orders.Order, start, end time.Time) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.order_id, e.time, e.state, e.reason
+		FROM order_events e
+		JOIN orders o ON o.id = e.order_id
+		WHERE o.time >= ? AND o.time < ?
+		ORDER BY e.order_id, e.position`,
+		start.Unix(), end.Unix(),
+	)
+	if nil != err {
+		ctxzap.Extract(ctx).Error("Failed to query order events", zap.Error(err))
+		return toStatus(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, state int64
+		event := &orders.Event{}
+		err = rows.Scan(&id, &event.UnixTime, &state, &event.Reason)
+		if nil != err {
+			return toStatus(err)
+		}
+		event.State = constants.OrderState(state)
+		if order, ok := byID[id]; ok {
+			order.Events = append(order.Events, event)
+		}
+	}
+	err = rows.Err()
+	if nil != err {
+		return toStatus(err)
+	}
+	return nil
+}
+
+//
+// Helpers
+//
+
+// transaction runs the function within a transaction, which is committed if the function succeeds and rolled back otherwise
+func (s *Store) transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if nil != err {
+		return toStatus(err)
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if nil != err {
+		return err
+	}
+	err = tx.Commit()
+	if nil != err {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// toStatus converts a database error to a GRPC status.
+// Duplicate primary keys are AlreadyExists, any other constraint violation is InvalidArgument, and everything else is Internal.
+func toStatus(err error) error {
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) && sqliteError.Code() == sqliteConstraintPrimaryKey {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.As(err, &sqliteError) && sqliteError.Code()&0xff == sqliteConstraint {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// nullToNaN is the value of the column, SQLite stores NaN as NULL so NULL is read back as NaN
+func nullToNaN(value sql.NullFloat64) float64 {
+	if !value.Valid {
+		return math.NaN()
+	}
+	return value.Float64
+}
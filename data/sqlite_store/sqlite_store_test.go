@@ -0,0 +1,341 @@
+package sqlite_store
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/interval/trade/constants"
+	"github.com/ta4g/ta4g/data/interval/trade/orders"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	store, err := Open(context.Background(), ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store
+}
+
+func TestBars(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	bars := make([]bar.Bar, 0)
+	for index := 0; index < 25; index++ {
+		bars = append(bars, bar.NewFakeBar(now.Add(time.Duration(index)*time_series.Day)))
+	}
+
+	t.Run("Insert and Query", func(t *testing.T) {
+		store := newTestStore(t)
+		store.batchSize = 7
+
+		err := store.InsertBars(ctx, "AAPL", time_series.Day, bars)
+		require.NoError(t, err)
+		err = store.InsertBars(ctx, "MSFT", time_series.Day, bars[:3])
+		require.NoError(t, err)
+		err = store.InsertBars(ctx, "AAPL", time.Hour, bars[:3])
+		require.NoError(t, err)
+
+		output, err := store.QueryBars(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, bars)
+
+		// The start is inclusive and the end is exclusive
+		output, err = store.QueryBars(ctx, "AAPL", time_series.Day, now.Add(time_series.Day), now.Add(3*time_series.Day))
+		require.NoError(t, err)
+		require.Equal(t, output, bars[1:3])
+
+		output, err = store.QueryBars(ctx, "GOOG", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Empty(t, output)
+
+		symbols, err := store.Symbols(ctx)
+		require.NoError(t, err)
+		require.Equal(t, symbols, []string{"AAPL", "MSFT"})
+	})
+
+	t.Run("Insert duplicates", func(t *testing.T) {
+		store := newTestStore(t)
+		store.batchSize = 7
+
+		err := store.InsertBars(ctx, "AAPL", time_series.Day, bars[:10])
+		require.NoError(t, err)
+
+		// The whole insert is rolled back
+		err = store.InsertBars(ctx, "AAPL", time_series.Day, bars[5:])
+		require.Equal(t, status.Code(err), codes.AlreadyExists)
+
+		output, err := store.QueryBars(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, 10)
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		store := newTestStore(t)
+
+		err := store.UpsertBars(ctx, "AAPL", time_series.Day, bars[:10])
+		require.NoError(t, err)
+
+		// A vendor correction, along with some new bars
+		corrected := []bar.Bar{bar.New(bars[3].GetTime(), 1, 2, 0.5, 1.5, 1000, 7)}
+		corrected = append(corrected, bars[10:]...)
+		err = store.UpsertBars(ctx, "AAPL", time_series.Day, corrected)
+		require.NoError(t, err)
+
+		output, err := store.QueryBars(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		require.Equal(t, output[3], corrected[0])
+		require.Equal(t, output[4], bars[4])
+	})
+
+	t.Run("NaN", func(t *testing.T) {
+		store := newTestStore(t)
+
+		// SQLite stores NaN as NULL, which is read back as NaN
+		missing := bar.New(now, 10, 12, math.NaN(), 11, math.NaN(), 3)
+		err := store.InsertBars(ctx, "AAPL", time_series.Day, []bar.Bar{missing})
+		require.NoError(t, err)
+
+		output, err := store.QueryBars(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, output[0].GetTime().Unix(), now.Unix())
+		require.Equal(t, output[0].GetOpen(), 10.0)
+		require.Equal(t, output[0].GetHigh(), 12.0)
+		require.True(t, math.IsNaN(output[0].GetLow()))
+		require.Equal(t, output[0].GetClose(), 11.0)
+		require.True(t, math.IsNaN(output[0].GetVolume()))
+		require.Equal(t, output[0].GetOpenInterest(), int64(3))
+
+		// The bar already exists, even though it has a NaN
+		err = store.InsertBars(ctx, "AAPL", time_series.Day, []bar.Bar{missing})
+		require.Equal(t, status.Code(err), codes.AlreadyExists)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newTestStore(t)
+
+		err := store.InsertBars(ctx, "AAPL", time_series.Day, bars)
+		require.NoError(t, err)
+		count, err := store.DeleteBars(ctx, "AAPL", time_series.Day, now, now.Add(5*time_series.Day))
+		require.NoError(t, err)
+		require.Equal(t, count, int64(5))
+
+		output, err := store.QueryBars(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, bars[5:])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		store := newTestStore(t)
+
+		err := store.InsertBars(ctx, "", time_series.Day, bars)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		err = store.UpsertBars(ctx, "AAPL", time.Millisecond, bars)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = store.QueryBars(ctx, "AAPL", 0, now, now)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		err = store.InsertBars(cancelled, "AAPL", time_series.Day, bars)
+		require.Equal(t, status.Code(err), codes.Canceled)
+	})
+}
+
+func TestOrders(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	newOrders := func(start time.Time, count int) []*orders.Order {
+		output := make([]*orders.Order, 0, count)
+		for index := 0; index < count; index++ {
+			output = append(output, orders.NewOrder(
+				start.Add(time.Duration(index)*time_series.Day),
+				orders.NewStockOrderItem(constants.Buy, "ABC", 100, 10.01),
+				orders.NewOptionOrderItem(constants.Sell, "ABC CALL @ 10.0", 1, 1.01*100),
+			))
+		}
+		return output
+	}
+
+	t.Run("Insert and Query", func(t *testing.T) {
+		store := newTestStore(t)
+		store.batchSize = 3
+
+		input := newOrders(now, 10)
+		input = append(input, orders.NewOrder(now, orders.NewStockOrderItem(constants.Sell, "XYZ", 5, 1.5)))
+		input = append(input, orders.NewOrder(now.Add(time.Hour)))
+		ids, err := store.InsertOrders(ctx, input)
+		require.NoError(t, err)
+		require.Len(t, ids, len(input))
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(input))
+
+		// Sorted by time, and then by the order they were added
+		require.Equal(t, output[0], input[0])
+		require.Equal(t, output[1], input[10])
+		require.Equal(t, output[2], input[11])
+		require.Empty(t, output[2].OrderItems)
+		require.Equal(t, output[3:], input[1:10])
+
+		// Only orders with an item for the symbol, with all of their items
+		output, err = store.QueryOrders(ctx, "ABC CALL @ 10.0", now, now.Add(2*time_series.Day))
+		require.NoError(t, err)
+		require.Equal(t, output, input[:2])
+		output, err = store.QueryOrders(ctx, "XYZ", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, input[10:11])
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		store := newTestStore(t)
+
+		_, err := store.InsertOrders(ctx, newOrders(now, 10))
+		require.NoError(t, err)
+
+		// Replace the first 5 days with a single corrected order
+		corrected := orders.NewOrder(now.Add(time.Hour), orders.NewStockOrderItem(constants.Buy, "ABC", 50, 10.02))
+		_, err = store.ReplaceOrders(ctx, now, now.Add(5*time_series.Day), []*orders.Order{corrected})
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, 6)
+		require.Equal(t, output[0], corrected)
+
+		// The items of the removed orders are removed too
+		var items int
+		err = store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM order_items`).Scan(&items)
+		require.NoError(t, err)
+		require.Equal(t, items, 1+5*2)
+
+		_, err = store.ReplaceOrders(ctx, now, now.Add(time.Hour), []*orders.Order{corrected})
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+	})
+
+	t.Run("Executions", func(t *testing.T) {
+		store := newTestStore(t)
+
+		input := newOrders(now, 3)
+		input[0].Execution = orders.NewLimitExecution(10)
+		input[1].Execution = &orders.Execution{Type: constants.StopLimitExecution, StopPrice: 11, LimitPrice: 11.5, Triggered: true}
+		_, err := store.InsertOrders(ctx, input)
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, input)
+		require.Nil(t, output[2].Execution)
+	})
+
+	t.Run("Option contracts", func(t *testing.T) {
+		store := newTestStore(t)
+
+		input := newOrders(now, 2)
+		contract := orders.NewOptionContract("ABC", now.AddDate(0, 0, 15), constants.Put, 9.5)
+		contract.Style = constants.European
+		input[0].OrderItems = append(input[0].OrderItems, orders.NewOptionContractOrderItem(constants.Buy, contract, 1, 0.55))
+		_, err := store.InsertOrders(ctx, input)
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, input)
+		require.Equal(t, output[0].OrderItems[len(output[0].OrderItems)-1].Option, contract)
+	})
+
+	t.Run("Futures and forex", func(t *testing.T) {
+		store := newTestStore(t)
+
+		input := newOrders(now, 2)
+		future := orders.NewFutureContract("ES", 0.25, 12.5, 12000, now.AddDate(0, 0, 15))
+		future.MaintenanceMargin = 11000
+		future.RollDate = now.AddDate(0, 0, 7).Unix()
+		pair := orders.NewForexPair("USD", "JPY")
+		pair.MarginRate = 0.02
+		input[0].OrderItems = append(input[0].OrderItems, orders.NewFutureOrderItem(constants.Sell, "ESZ22", future, 1, 4000))
+		input[1].OrderItems = append(input[1].OrderItems, orders.NewForexOrderItem(constants.Buy, pair, 0.5, 135.25))
+		_, err := store.InsertOrders(ctx, input)
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, input)
+	})
+
+	t.Run("NaN", func(t *testing.T) {
+		store := newTestStore(t)
+
+		// SQLite stores NaN as NULL, which is read back as NaN, the same as the order loaders
+		future := orders.NewFutureContract("ES", 0.25, 12.5, math.NaN(), now.AddDate(0, 0, 15))
+		pair := orders.NewForexPair("USD", "JPY")
+		pair.MarginRate = math.NaN()
+		order := orders.NewOrder(
+			now,
+			orders.NewStockOrderItem(constants.Buy, "ABC", 10, math.NaN()),
+			orders.NewOptionContractOrderItem(constants.Buy, orders.NewOptionContract("ABC", now.AddDate(0, 0, 15), constants.Put, math.NaN()), 1, 0.55),
+			orders.NewFutureOrderItem(constants.Sell, "ESZ22", future, 1, 4000),
+			orders.NewForexOrderItem(constants.Buy, pair, 0.5, 135.25),
+		)
+		order.Execution = orders.NewLimitExecution(math.NaN())
+		order.Fills = []*orders.Fill{{UnixTime: now.Unix(), Item: 0, Amount: math.NaN(), Price: math.NaN()}}
+		_, err := store.InsertOrders(ctx, []*orders.Order{order})
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.True(t, math.IsNaN(output[0].Execution.LimitPrice))
+		require.True(t, math.IsNaN(output[0].OrderItems[0].Price))
+		require.Equal(t, output[0].OrderItems[0].Amount, 10.0)
+		require.True(t, math.IsNaN(output[0].OrderItems[1].Option.Strike))
+		require.True(t, math.IsNaN(output[0].OrderItems[2].Future.InitialMargin))
+		require.Equal(t, output[0].OrderItems[2].Future.TickValue, 12.5)
+		require.True(t, math.IsNaN(output[0].OrderItems[3].Forex.MarginRate))
+		require.Len(t, output[0].Fills, 1)
+		require.True(t, math.IsNaN(output[0].Fills[0].Amount))
+		require.True(t, math.IsNaN(output[0].Fills[0].Price))
+	})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		store := newTestStore(t)
+		store.batchSize = 2
+
+		input := newOrders(now, 3)
+		input[0].ClientTag = "my-strategy"
+		input[0].TimeInForce = constants.GoodTillDateTimeInForce
+		input[0].ExpireTime = now.Add(7 * time_series.Day).Unix()
+		require.NoError(t, input[0].Accept(now))
+		require.NoError(t, input[0].AddFills(now, &orders.Fill{UnixTime: now.Unix(), Item: 0, Amount: 50, Price: 10}))
+		require.NoError(t, input[0].AddFills(now, &orders.Fill{UnixTime: now.Unix(), Item: 0, Amount: 50, Price: 10.1}))
+		require.NoError(t, input[0].Cancel(now.Add(time.Hour), "the option wasn't filled"))
+		require.NoError(t, input[1].Reject(now, "market closed"))
+		_, err := store.InsertOrders(ctx, input)
+		require.NoError(t, err)
+
+		output, err := store.QueryOrders(ctx, "", time.Time{}, now.AddDate(1, 0, 0))
+		require.NoError(t, err)
+		require.Equal(t, output, input)
+
+		// The fills and events are removed with their order
+		_, err = store.ReplaceOrders(ctx, now, now.Add(time_series.Day), nil)
+		require.NoError(t, err)
+		var rows int
+		err = store.DB().QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM order_fills) + (SELECT COUNT(*) FROM order_events)`).Scan(&rows)
+		require.NoError(t, err)
+		require.Equal(t, rows, 1)
+	})
+}
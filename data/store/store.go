@@ -0,0 +1,549 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/flock"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/ta4g/ta4g/data/compression"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store persists bars on the local disk, partitioned by symbol, interval, year, and month:
+//
+//   <root>/<symbol>/<interval>/<year>/<month>/<segment>
+//
+// Example: the daily bars of AAPL from December 2022 are in "<root>/AAPL/1d/2022/12/00000001.pb.zst"
+//
+// 1. Every append writes a new segment file to each month it touches, existing files are never modified
+// 2. Queries read every segment of the months in the range, when the same time is in several segments the latest append wins
+// 3. Compaction merges the segments of each month into a single segment
+// 4. Each symbol and interval has it's own lock file, appends and compaction hold an exclusive lock and queries hold a shared lock,
+//    so several processes can safely share the same store
+//
+type Store struct {
+	root      string
+	extension string
+}
+
+// Options control how the segment files are written
+type Options struct {
+	// Format is the name of the bar format for the segment files, see bar.Formats, defaults to bar.ProtoFormat
+	Format string
+	// Compression of the segment files, defaults to compression.Zstd
+	Compression compression.Compression
+}
+
+// DefaultOptions are zstd compressed proto files, which are small and quick to stream
+func DefaultOptions() Options {
+	return Options{
+		Format:      bar.ProtoFormat,
+		Compression: compression.Zstd,
+	}
+}
+
+// WithDefaults fills in any missing options with their default value
+func (o Options) WithDefaults() Options {
+	defaults := DefaultOptions()
+	if o.Format == "" {
+		o.Format = defaults.Format
+	}
+	if o.Compression == 0 {
+		o.Compression = defaults.Compression
+	}
+	return o
+}
+
+const (
+	lockFile       = ".lock"
+	tempPrefix     = ".tmp-"
+	lockRetryDelay = 10 * time.Millisecond
+)
+
+// New opens the store in the root directory, creating the directory if needed
+//
+// Errors:
+// - If the format is unknown an error with GRPC status NotFound will be returned
+// - If the format doesn't have a file extension an error with GRPC status InvalidArgument will be returned
+//
+func New(root string, options Options) (*Store, error) {
+	options = options.WithDefaults()
+	format, err := bar.LookupFormat(options.Format)
+	if nil != err {
+		return nil, err
+	}
+	if len(format.Extensions) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "format doesn't have a file extension: %s", format.Name)
+	}
+
+	err = os.MkdirAll(root, 0755)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &Store{
+		root:      root,
+		extension: format.Extensions[0] + options.Compression.Extension(),
+	}, nil
+}
+
+// Root is the directory the store is in
+func (s *Store) Root() string {
+	return s.root
+}
+
+// Append writes the bars to a new segment in each month they belong to, the bars don't need to be sorted.
+//
+// Errors:
+// - If the symbol or interval are invalid an error with GRPC status InvalidArgument will be returned
+//
+func (s *Store) Append(ctx context.Context, symbol string, interval time.Duration, bars []bar.Bar) error {
+	logger := ctxzap.Extract(ctx)
+
+	dir, err := s.seriesDir(symbol, interval)
+	if nil != err {
+		return err
+	}
+	if len(bars) == 0 {
+		return nil
+	}
+	err = os.MkdirAll(dir, 0755)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	lock, err := s.lock(ctx, dir, true)
+	if nil != err {
+		logger.Error("Failed to lock partition", zap.String("symbol", symbol), zap.Error(err))
+		return err
+	}
+	defer lock.Unlock()
+
+	// Group the bars by month
+	months := make(map[string][]bar.Bar)
+	for _, row := range bars {
+		key := monthDir(dir, row.GetTime())
+		months[key] = append(months[key], row)
+	}
+	for month, rows := range months {
+		sortBars(rows)
+		err = s.writeSegment(ctx, month, rows)
+		if nil != err {
+			logger.Error("Failed to write segment", zap.String("partition", month), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// Query reads the bars in the time range [start, end) sorted by time.
+// If the same time was appended more than once, only the latest bar is returned.
+//
+// Errors:
+// - If the symbol or interval are invalid, or the end is not after the start, an error with GRPC status InvalidArgument will be returned
+// - If the symbol and interval have never been appended an error with GRPC status NotFound will be returned
+//
+func (s *Store) Query(ctx context.Context, symbol string, interval time.Duration, start, end time.Time) ([]bar.Bar, error) {
+	logger := ctxzap.Extract(ctx)
+
+	dir, err := s.seriesDir(symbol, interval)
+	if nil != err {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, status.Error(codes.InvalidArgument, "end must be after start")
+	}
+	if _, err := os.Stat(dir); nil != err {
+		return nil, status.Errorf(codes.NotFound, "no bars for %s %s", symbol, intervalName(interval))
+	}
+
+	lock, err := s.lock(ctx, dir, false)
+	if nil != err {
+		logger.Error("Failed to lock partition", zap.String("symbol", symbol), zap.Error(err))
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	months, err := listMonths(dir)
+	if nil != err {
+		return nil, err
+	}
+	output := make([]bar.Bar, 0)
+	for _, month := range months {
+		// Skip any month that's entirely outside of the range
+		if !month.end.After(start) || !month.start.Before(end) {
+			continue
+		}
+		rows, err := s.readMonth(ctx, month.path)
+		if nil != err {
+			logger.Error("Failed to read partition", zap.String("partition", month.path), zap.Error(err))
+			return nil, err
+		}
+		for _, row := range rows {
+			if !row.GetTime().Before(start) && row.GetTime().Before(end) {
+				output = append(output, row)
+			}
+		}
+	}
+	return output, nil
+}
+
+// Compact merges the segments of each month into a single segment, removing any duplicate times.
+// Compacting a month with a single segment does nothing.
+//
+// Errors:
+// - If the symbol or interval are invalid an error with GRPC status InvalidArgument will be returned
+// - If the symbol and interval have never been appended an error with GRPC status NotFound will be returned
+//
+func (s *Store) Compact(ctx context.Context, symbol string, interval time.Duration) error {
+	logger := ctxzap.Extract(ctx)
+
+	dir, err := s.seriesDir(symbol, interval)
+	if nil != err {
+		return err
+	}
+	if _, err := os.Stat(dir); nil != err {
+		return status.Errorf(codes.NotFound, "no bars for %s %s", symbol, intervalName(interval))
+	}
+
+	lock, err := s.lock(ctx, dir, true)
+	if nil != err {
+		logger.Error("Failed to lock partition", zap.String("symbol", symbol), zap.Error(err))
+		return err
+	}
+	defer lock.Unlock()
+
+	months, err := listMonths(dir)
+	if nil != err {
+		return err
+	}
+	for _, month := range months {
+		segments, err := listSegments(month.path)
+		if nil != err {
+			return err
+		}
+		if len(segments) <= 1 {
+			continue
+		}
+		rows, err := s.readMonth(ctx, month.path)
+		if nil != err {
+			logger.Error("Failed to read partition", zap.String("partition", month.path), zap.Error(err))
+			return err
+		}
+
+		// The merged segment is written before the old segments are removed,
+		// if anything fails in between the duplicates are still resolved by the latest segment winning
+		err = s.writeSegment(ctx, month.path, rows)
+		if nil != err {
+			logger.Error("Failed to write segment", zap.String("partition", month.path), zap.Error(err))
+			return err
+		}
+		for _, segment := range segments {
+			err = os.Remove(segment.path)
+			if nil != err {
+				logger.Error("Failed to remove segment", zap.String("segment", segment.path), zap.Error(err))
+				return status.Error(codes.Internal, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Symbols lists every symbol in the store, sorted alphabetically
+func (s *Store) Symbols() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.root)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	output := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		symbol, err := url.PathUnescape(entry.Name())
+		if nil != err {
+			continue
+		}
+		output = append(output, symbol)
+	}
+	sort.Strings(output)
+	return output, nil
+}
+
+// Intervals lists every interval stored for a symbol, from the smallest to the largest
+//
+// Errors:
+// - If the symbol is invalid an error with GRPC status InvalidArgument will be returned
+// - If the symbol is not in the store an error with GRPC status NotFound will be returned
+//
+func (s *Store) Intervals(symbol string) ([]time.Duration, error) {
+	dir, err := s.symbolDir(symbol)
+	if nil != err {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if nil != err && os.IsNotExist(err) {
+		return nil, status.Errorf(codes.NotFound, "unknown symbol: %s", symbol)
+	}
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	output := make([]time.Duration, 0, len(entries))
+	for _, entry := range entries {
+		interval, err := parseInterval(entry.Name())
+		if !entry.IsDir() || nil != err {
+			continue
+		}
+		output = append(output, interval)
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i] < output[j]
+	})
+	return output, nil
+}
+
+func (s *Store) symbolDir(symbol string) (string, error) {
+	if symbol == "" || strings.HasPrefix(symbol, ".") {
+		return "", status.Errorf(codes.InvalidArgument, "invalid symbol: %q", symbol)
+	}
+	// Symbols may contain slashes, ex: "BTC/USD"
+	return filepath.Join(s.root, url.PathEscape(symbol)), nil
+}
+
+func (s *Store) seriesDir(symbol string, interval time.Duration) (string, error) {
+	dir, err := s.symbolDir(symbol)
+	if nil != err {
+		return "", err
+	}
+	if interval <= 0 || interval%time.Second != 0 {
+		return "", status.Errorf(codes.InvalidArgument, "interval must be a whole number of seconds: %s", interval)
+	}
+	return filepath.Join(dir, intervalName(interval)), nil
+}
+
+// lock takes the series' lock file, waiting until it's available or the context is done
+func (s *Store) lock(ctx context.Context, dir string, exclusive bool) (*flock.Flock, error) {
+	lock := flock.New(filepath.Join(dir, lockFile))
+	var locked bool
+	var err error
+	if exclusive {
+		locked, err = lock.TryLockContext(ctx, lockRetryDelay)
+	} else {
+		locked, err = lock.TryRLockContext(ctx, lockRetryDelay)
+	}
+	if nil != err && nil != ctx.Err() {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !locked {
+		return nil, status.Error(codes.Unavailable, "partition is locked")
+	}
+	return lock, nil
+}
+
+// writeSegment writes the bars to a temporary file, which is renamed into place once it's complete
+func (s *Store) writeSegment(ctx context.Context, dir string, bars []bar.Bar) error {
+	err := os.MkdirAll(dir, 0755)
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	segments, err := listSegments(dir)
+	if nil != err {
+		return err
+	}
+	sequence := 1
+	if len(segments) > 0 {
+		sequence = segments[len(segments)-1].sequence + 1
+	}
+	name := fmt.Sprintf("%08d%s", sequence, s.extension)
+
+	file, err := ioutil.TempFile(dir, tempPrefix+"*")
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer os.Remove(file.Name())
+
+	err = bar.NewAutoLoader(name).Write(ctx, file, bars)
+	if nil != err {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	err = os.Rename(file.Name(), filepath.Join(dir, name))
+	if nil != err {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// readMonth reads every segment of a month, sorted by time, with the latest segment winning any duplicate times
+func (s *Store) readMonth(ctx context.Context, dir string) ([]bar.Bar, error) {
+	segments, err := listSegments(dir)
+	if nil != err {
+		return nil, err
+	}
+	rows := make(map[int64]bar.Bar)
+	for _, segment := range segments {
+		file, err := os.Open(segment.path)
+		if nil != err {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		bars, err := bar.NewAutoLoader(segment.path).Read(ctx, file)
+		file.Close()
+		if nil != err {
+			return nil, err
+		}
+		for _, row := range bars {
+			rows[row.GetTime().UnixNano()] = row
+		}
+	}
+
+	output := make([]bar.Bar, 0, len(rows))
+	for _, row := range rows {
+		output = append(output, row)
+	}
+	sortBars(output)
+	return output, nil
+}
+
+//
+// Partitions
+//
+
+type month struct {
+	path  string
+	start time.Time
+	end   time.Time
+}
+
+type segment struct {
+	path     string
+	sequence int
+}
+
+func monthDir(dir string, t time.Time) string {
+	t = t.UTC()
+	return filepath.Join(dir, fmt.Sprintf("%04d", t.Year()), fmt.Sprintf("%02d", int(t.Month())))
+}
+
+// listMonths finds every year/month directory of a series, sorted by time
+func listMonths(dir string) ([]month, error) {
+	years, err := ioutil.ReadDir(dir)
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	output := make([]month, 0)
+	for _, yearEntry := range years {
+		year, err := strconv.Atoi(yearEntry.Name())
+		if !yearEntry.IsDir() || nil != err {
+			continue
+		}
+		months, err := ioutil.ReadDir(filepath.Join(dir, yearEntry.Name()))
+		if nil != err {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		for _, monthEntry := range months {
+			value, err := strconv.Atoi(monthEntry.Name())
+			if !monthEntry.IsDir() || nil != err || value < 1 || value > 12 {
+				continue
+			}
+			start := time.Date(year, time.Month(value), 1, 0, 0, 0, 0, time.UTC)
+			output = append(output, month{
+				path:  filepath.Join(dir, yearEntry.Name(), monthEntry.Name()),
+				start: start,
+				end:   start.AddDate(0, 1, 0),
+			})
+		}
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].start.Before(output[j].start)
+	})
+	return output, nil
+}
+
+// listSegments finds every segment of a month sorted by their sequence, temporary files are skipped
+func listSegments(dir string) ([]segment, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if nil != err && os.IsNotExist(err) {
+		return []segment{}, nil
+	}
+	if nil != err {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	output := make([]segment, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		prefix := entry.Name()
+		if index := strings.Index(prefix, "."); index >= 0 {
+			prefix = prefix[:index]
+		}
+		sequence, err := strconv.Atoi(prefix)
+		if nil != err {
+			continue
+		}
+		output = append(output, segment{path: filepath.Join(dir, entry.Name()), sequence: sequence})
+	}
+	sort.Slice(output, func(i, j int) bool {
+		return output[i].sequence < output[j].sequence
+	})
+	return output, nil
+}
+
+// intervalName is the directory name of an interval, using the largest whole unit, ex: "1d", "4h", "15m", or "30s"
+func intervalName(interval time.Duration) string {
+	switch {
+	case interval%time_series.Day == 0:
+		return fmt.Sprintf("%dd", interval/time_series.Day)
+	case interval%time.Hour == 0:
+		return fmt.Sprintf("%dh", interval/time.Hour)
+	case interval%time.Minute == 0:
+		return fmt.Sprintf("%dm", interval/time.Minute)
+	}
+	return fmt.Sprintf("%ds", interval/time.Second)
+}
+
+// parseInterval is the reverse of intervalName
+func parseInterval(name string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'd': time_series.Day,
+		'h': time.Hour,
+		'm': time.Minute,
+		's': time.Second,
+	}
+	if len(name) < 2 {
+		return 0, time_series.InvalidArgument
+	}
+	unit, ok := units[name[len(name)-1]]
+	if !ok {
+		return 0, time_series.InvalidArgument
+	}
+	value, err := strconv.Atoi(name[:len(name)-1])
+	if nil != err || value <= 0 {
+		return 0, time_series.InvalidArgument
+	}
+	return time.Duration(value) * unit, nil
+}
+
+func sortBars(bars []bar.Bar) {
+	sort.SliceStable(bars, func(i, j int) bool {
+		return bars[i].GetTime().Before(bars[j].GetTime())
+	})
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"github.com/gofrs/flock"
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/compression"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newDays(start time.Time, days int) []bar.Bar {
+	bars := make([]bar.Bar, 0, days)
+	for index := 0; index < days; index++ {
+		bars = append(bars, bar.NewFakeBar(start.Add(time.Duration(index)*time_series.Day)))
+	}
+	return bars
+}
+
+func requireSegments(t *testing.T, dir string, count int) {
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Len(t, segments, count)
+}
+
+func TestStore(t *testing.T) {
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("Append and Query", func(t *testing.T) {
+		root := t.TempDir()
+		store, err := New(root, DefaultOptions())
+		require.NoError(t, err)
+
+		// November 16th to January 14th, appended out of order
+		bars := newDays(now.Add(-15*time_series.Day), 60)
+		err = store.Append(ctx, "AAPL", time_series.Day, bars[30:])
+		require.NoError(t, err)
+		err = store.Append(ctx, "AAPL", time_series.Day, bars[:30])
+		require.NoError(t, err)
+
+		require.FileExists(t, filepath.Join(root, "AAPL", "1d", "2022", "11", "00000001.pb.zst"))
+		require.FileExists(t, filepath.Join(root, "AAPL", "1d", "2022", "12", "00000001.pb.zst"))
+		require.FileExists(t, filepath.Join(root, "AAPL", "1d", "2022", "12", "00000002.pb.zst"))
+		require.FileExists(t, filepath.Join(root, "AAPL", "1d", "2023", "01", "00000001.pb.zst"))
+
+		output, err := store.Query(ctx, "AAPL", time_series.Day, time.Time{}, now.AddDate(10, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		for index, row := range output {
+			require.Equal(t, row.GetTime().Unix(), bars[index].GetTime().Unix())
+			require.Equal(t, row.GetClose(), bars[index].GetClose())
+		}
+
+		// The start is inclusive and the end is exclusive
+		output, err = store.Query(ctx, "AAPL", time_series.Day, now, now.Add(7*time_series.Day))
+		require.NoError(t, err)
+		require.Len(t, output, 7)
+		require.Equal(t, output[0].GetTime().Unix(), now.Unix())
+
+		output, err = store.Query(ctx, "AAPL", time_series.Day, now.AddDate(1, 0, 0), now.AddDate(2, 0, 0))
+		require.NoError(t, err)
+		require.Empty(t, output)
+	})
+
+	t.Run("Latest append wins, and Compact", func(t *testing.T) {
+		store, err := New(t.TempDir(), DefaultOptions())
+		require.NoError(t, err)
+
+		bars := newDays(now, 10)
+		err = store.Append(ctx, "AAPL", time_series.Day, bars)
+		require.NoError(t, err)
+
+		// A vendor correction of a single day
+		corrected := bar.New(bars[3].GetTime(), 1, 2, 0.5, 1.5, 1000, -1)
+		err = store.Append(ctx, "AAPL", time_series.Day, []bar.Bar{corrected})
+		require.NoError(t, err)
+
+		output, err := store.Query(ctx, "AAPL", time_series.Day, now, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+		require.Equal(t, output[3].GetClose(), 1.5)
+
+		dir, err := store.seriesDir("AAPL", time_series.Day)
+		require.NoError(t, err)
+		month := monthDir(dir, now)
+		requireSegments(t, month, 2)
+
+		err = store.Compact(ctx, "AAPL", time_series.Day)
+		require.NoError(t, err)
+		requireSegments(t, month, 1)
+
+		compacted, err := store.Query(ctx, "AAPL", time_series.Day, now, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		require.Equal(t, len(compacted), len(output))
+		for index, row := range compacted {
+			require.Equal(t, row.GetTime().Unix(), output[index].GetTime().Unix())
+			require.Equal(t, row.GetClose(), output[index].GetClose())
+		}
+
+		// Compacting again does nothing
+		err = store.Compact(ctx, "AAPL", time_series.Day)
+		require.NoError(t, err)
+		requireSegments(t, month, 1)
+	})
+
+	t.Run("Symbols and Intervals", func(t *testing.T) {
+		store, err := New(t.TempDir(), DefaultOptions())
+		require.NoError(t, err)
+
+		bars := newDays(now, 2)
+		for _, symbol := range []string{"MSFT", "BTC/USD", "AAPL"} {
+			err = store.Append(ctx, symbol, time_series.Day, bars)
+			require.NoError(t, err)
+		}
+		err = store.Append(ctx, "AAPL", 5*time.Minute, bars)
+		require.NoError(t, err)
+		err = store.Append(ctx, "AAPL", 4*time.Hour, bars)
+		require.NoError(t, err)
+
+		symbols, err := store.Symbols()
+		require.NoError(t, err)
+		require.Equal(t, symbols, []string{"AAPL", "BTC/USD", "MSFT"})
+
+		intervals, err := store.Intervals("AAPL")
+		require.NoError(t, err)
+		require.Equal(t, intervals, []time.Duration{5 * time.Minute, 4 * time.Hour, time_series.Day})
+
+		_, err = store.Intervals("GOOG")
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+
+	t.Run("Other formats", func(t *testing.T) {
+		root := t.TempDir()
+		store, err := New(root, Options{Format: bar.CSVFormat, Compression: compression.Uncompressed})
+		require.NoError(t, err)
+
+		bars := newDays(now, 3)
+		err = store.Append(ctx, "AAPL", time_series.Day, bars)
+		require.NoError(t, err)
+		data, err := ioutil.ReadFile(filepath.Join(root, "AAPL", "1d", "2022", "12", "00000001.csv"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), "time,open,high,low,close,volume,open_interest")
+
+		output, err := store.Query(ctx, "AAPL", time_series.Day, now, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+
+		_, err = New(root, Options{Format: "xml"})
+		require.Equal(t, status.Code(err), codes.NotFound)
+	})
+
+	t.Run("Concurrent appends", func(t *testing.T) {
+		store, err := New(t.TempDir(), DefaultOptions())
+		require.NoError(t, err)
+
+		bars := newDays(now, 28)
+		wg := sync.WaitGroup{}
+		for index := 0; index < len(bars); index += 4 {
+			wg.Add(1)
+			go func(rows []bar.Bar) {
+				defer wg.Done()
+				require.NoError(t, store.Append(ctx, "AAPL", time_series.Day, rows))
+			}(bars[index : index+4])
+		}
+		wg.Wait()
+
+		output, err := store.Query(ctx, "AAPL", time_series.Day, now, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		require.Len(t, output, len(bars))
+
+		dir, err := store.seriesDir("AAPL", time_series.Day)
+		require.NoError(t, err)
+		requireSegments(t, monthDir(dir, now), 7)
+	})
+
+	t.Run("Locked by another process", func(t *testing.T) {
+		store, err := New(t.TempDir(), DefaultOptions())
+		require.NoError(t, err)
+		err = store.Append(ctx, "AAPL", time_series.Day, newDays(now, 1))
+		require.NoError(t, err)
+
+		dir, err := store.seriesDir("AAPL", time_series.Day)
+		require.NoError(t, err)
+		lock := flock.New(filepath.Join(dir, lockFile))
+		require.NoError(t, lock.Lock())
+		defer lock.Unlock()
+
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		err = store.Append(timeout, "AAPL", time_series.Day, newDays(now, 1))
+		require.Equal(t, status.Code(err), codes.DeadlineExceeded)
+
+		timeout, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = store.Query(timeout, "AAPL", time_series.Day, now, now.Add(time_series.Day))
+		require.Equal(t, status.Code(err), codes.DeadlineExceeded)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		root := t.TempDir()
+		store, err := New(root, DefaultOptions())
+		require.NoError(t, err)
+
+		bars := newDays(now, 1)
+		for _, symbol := range []string{"", ".", "..", ".hidden"} {
+			err = store.Append(ctx, symbol, time_series.Day, bars)
+			require.Equal(t, status.Code(err), codes.InvalidArgument)
+		}
+		for _, interval := range []time.Duration{0, -time.Minute, time.Millisecond} {
+			err = store.Append(ctx, "AAPL", interval, bars)
+			require.Equal(t, status.Code(err), codes.InvalidArgument)
+		}
+
+		_, err = store.Query(ctx, "AAPL", time_series.Day, now, now)
+		require.Equal(t, status.Code(err), codes.InvalidArgument)
+		_, err = store.Query(ctx, "AAPL", time_series.Day, now, now.Add(time_series.Day))
+		require.Equal(t, status.Code(err), codes.NotFound)
+		err = store.Compact(ctx, "AAPL", time_series.Day)
+		require.Equal(t, status.Code(err), codes.NotFound)
+
+		// Nothing was written
+		entries, err := os.ReadDir(root)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+}
+
+func TestIntervalName(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1d":  time_series.Day,
+		"7d":  7 * time_series.Day,
+		"4h":  4 * time.Hour,
+		"25h": 25 * time.Hour,
+		"15m": 15 * time.Minute,
+		"90m": 90 * time.Minute,
+		"30s": 30 * time.Second,
+	}
+	for name, interval := range tests {
+		require.Equal(t, intervalName(interval), name)
+		parsed, err := parseInterval(name)
+		require.NoError(t, err)
+		require.Equal(t, parsed, interval)
+	}
+
+	for _, name := range []string{"", "d", "1w", "0d", "-1d", "x1d"} {
+		_, err := parseInterval(name)
+		require.Error(t, err)
+	}
+}
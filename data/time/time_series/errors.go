@@ -7,3 +7,4 @@ import (
 
 var OutOfRange = status.Error(codes.OutOfRange, "out of range")
 var InvalidArgument = status.Error(codes.InvalidArgument, "invalid argument")
+var FailedPrecondition = status.Error(codes.FailedPrecondition, "failed precondition")
@@ -0,0 +1,284 @@
+package time_series
+
+import (
+	"sort"
+	"time"
+	_ "time/tzdata"
+)
+
+// Session is a block of trading hours within a single trading day
+type Session int
+
+const (
+	_          Session = iota
+	Overnight          // Overnight is the session that starts the evening before the trading day, and ends before the pre-market
+	PreMarket          // PreMarket is the extended hours session before the regular open
+	Regular            // Regular is the normal trading session of the exchange
+	AfterHours         // AfterHours is the extended hours session after the regular close
+)
+
+const (
+	overnightSessionStr  = "Overnight"
+	preMarketSessionStr  = "PreMarket"
+	regularSessionStr    = "Regular"
+	afterHoursSessionStr = "AfterHours"
+)
+
+var sessions = map[Session]string{
+	Overnight:  overnightSessionStr,
+	PreMarket:  preMarketSessionStr,
+	Regular:    regularSessionStr,
+	AfterHours: afterHoursSessionStr,
+}
+
+func (s Session) String() string {
+	return sessions[s]
+}
+
+// SessionHours is the open and close of a session, relative to midnight of the trading day in the calendar's location.
+//
+// Sessions that start the evening before the trading day use a negative Open, for example
+// an overnight session from 20:00 the night before until 04:00 is `{Overnight, -4 * time.Hour, 4 * time.Hour}`.
+//
+type SessionHours struct {
+	Session Session
+	Open    time.Duration
+	Close   time.Duration
+}
+
+// maxLookBack is how many calendar days we will search when looking for the previous session
+const maxLookBack = 14
+
+// SessionCalendar describes when a market is open, and which session is trading at any point in time.
+type SessionCalendar struct {
+	location *time.Location
+	weekdays map[time.Weekday]bool
+	sessions []SessionHours
+	holidays map[string]bool
+}
+
+// NewSessionCalendar creates a new SessionCalendar instance.
+// The input is validated to make sure all of these conditions are true:
+//
+// 1. The location is not nil.
+// 2. There is at least one trading weekday and one session.
+// 3. Each session closes after it opens, and the sessions do not overlap.
+//
+func NewSessionCalendar(location *time.Location, weekdays []time.Weekday, hours ...SessionHours) (*SessionCalendar, error) {
+	if nil == location || len(weekdays) == 0 || len(hours) == 0 {
+		return nil, InvalidArgument
+	}
+
+	values := make([]SessionHours, len(hours))
+	copy(values, hours)
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Open < values[j].Open
+	})
+	for index, value := range values {
+		if value.Close <= value.Open {
+			return nil, InvalidArgument
+		}
+		if index > 0 && values[index-1].Close > value.Open {
+			return nil, InvalidArgument
+		}
+	}
+
+	output := &SessionCalendar{
+		location: location,
+		weekdays: make(map[time.Weekday]bool, len(weekdays)),
+		sessions: values,
+		holidays: make(map[string]bool),
+	}
+	for _, weekday := range weekdays {
+		output.weekdays[weekday] = true
+	}
+	return output, nil
+}
+
+// USEquityCalendar is the pre-canned calendar for US stock exchanges (NYSE, NASDAQ) in New York time:
+//
+// 1. Overnight  20:00 - 04:00 (the night before)
+// 2. PreMarket  04:00 - 09:30
+// 3. Regular    09:30 - 16:00
+// 4. AfterHours 16:00 - 20:00
+//
+// NOTE: Exchange holidays change every year, add them with `AddHoliday`.
+//
+func USEquityCalendar() *SessionCalendar {
+	location, err := time.LoadLocation("America/New_York")
+	if nil != err {
+		panic(err)
+	}
+	output, err := NewSessionCalendar(
+		location,
+		[]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		SessionHours{Overnight, -4 * time.Hour, 4 * time.Hour},
+		SessionHours{PreMarket, 4 * time.Hour, 9*time.Hour + 30*time.Minute},
+		SessionHours{Regular, 9*time.Hour + 30*time.Minute, 16 * time.Hour},
+		SessionHours{AfterHours, 16 * time.Hour, 20 * time.Hour},
+	)
+	if nil != err {
+		panic(err)
+	}
+	return output
+}
+
+// Location is the time zone that the sessions are defined in
+func (c *SessionCalendar) Location() *time.Location {
+	return c.location
+}
+
+// AddHoliday marks the trading day of the given date as closed, for all sessions
+func (c *SessionCalendar) AddHoliday(date time.Time) {
+	c.holidays[c.dateKey(date)] = true
+}
+
+// IsTradingDay returns true when the market has sessions on the given date
+func (c *SessionCalendar) IsTradingDay(date time.Time) bool {
+	date = date.In(c.location)
+	return c.weekdays[date.Weekday()] && !c.holidays[c.dateKey(date)]
+}
+
+// SessionAt returns the session that is trading at the given time.
+// If the market is closed, then false is returned.
+func (c *SessionCalendar) SessionAt(t time.Time) (Session, bool) {
+	session, _, _, ok := c.sessionAt(t)
+	return session, ok
+}
+
+// Bounds returns the open and close of the session that is trading at the given time.
+// If the market is closed, then false is returned.
+func (c *SessionCalendar) Bounds(t time.Time) (Session, time.Time, time.Time, bool) {
+	return c.sessionAt(t)
+}
+
+// Open returns the time the session opens on the given trading day
+//
+// Errors:
+// - If the date is not a trading day, or the calendar has no such session, an error with GRPC status InvalidArgument will be returned
+//
+func (c *SessionCalendar) Open(date time.Time, session Session) (time.Time, error) {
+	hours, ok := c.hours(date, session)
+	if !ok {
+		return TimeZero, InvalidArgument
+	}
+	return c.at(date, hours.Open), nil
+}
+
+// Close returns the time the session closes on the given trading day
+//
+// Errors:
+// - If the date is not a trading day, or the calendar has no such session, an error with GRPC status InvalidArgument will be returned
+//
+func (c *SessionCalendar) Close(date time.Time, session Session) (time.Time, error) {
+	hours, ok := c.hours(date, session)
+	if !ok {
+		return TimeZero, InvalidArgument
+	}
+	return c.at(date, hours.Close), nil
+}
+
+// PreviousOpen returns the most recent time the session opened, at or before the given time.
+// This is useful for anchoring indicators to a session, eg resetting them at the regular open.
+//
+// Errors:
+// - If the session has not opened within the last two weeks an error with GRPC status OutOfRange will be returned
+//
+func (c *SessionCalendar) PreviousOpen(t time.Time, session Session) (time.Time, error) {
+	// The trading day can start the evening before, so start looking from tomorrow
+	day := t.In(c.location).AddDate(0, 0, 1)
+	for index := 0; index <= maxLookBack; index++ {
+		date := day.AddDate(0, 0, -index)
+		open, err := c.Open(date, session)
+		if nil != err {
+			continue
+		}
+		if !open.After(t) {
+			return open, nil
+		}
+	}
+	return TimeZero, OutOfRange
+}
+
+// NewTimeSeries creates a TimeSeries with one value per interval for every session between start and end (inclusive).
+// When no sessions are given, all of the calendar's sessions are included.
+//
+// Errors:
+// - If there are no trading times within the range an error with GRPC status InvalidArgument will be returned
+//
+func (c *SessionCalendar) NewTimeSeries(start, end time.Time, intervalSize time.Duration, include ...Session) (TimeSeries, error) {
+	if intervalSize <= time.Duration(0) || end.Before(start) {
+		return nil, InvalidArgument
+	}
+
+	wanted := make(map[Session]bool, len(include))
+	for _, session := range include {
+		wanted[session] = true
+	}
+
+	// Walk the calendar dates at midnight, the session after end can belong to the next trading day (eg overnight)
+	values := make([]time.Time, 0)
+	lastDay := c.at(end, 0).AddDate(0, 0, 1)
+	for day := c.at(start, 0); !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		if !c.IsTradingDay(day) {
+			continue
+		}
+		for _, hours := range c.sessions {
+			if len(wanted) > 0 && !wanted[hours.Session] {
+				continue
+			}
+			open, closeTime := c.at(day, hours.Open), c.at(day, hours.Close)
+			for value := open; value.Before(closeTime); value = value.Add(intervalSize) {
+				if value.Before(start) || value.After(end) {
+					continue
+				}
+				values = append(values, value)
+			}
+		}
+	}
+	return NewInMemoryTimeSeries(intervalSize, values)
+}
+
+func (c *SessionCalendar) sessionAt(t time.Time) (Session, time.Time, time.Time, bool) {
+	local := t.In(c.location)
+
+	// A session may belong to tomorrow's trading day (eg overnight), so check both days
+	for _, date := range []time.Time{local, local.AddDate(0, 0, 1), local.AddDate(0, 0, -1)} {
+		if !c.IsTradingDay(date) {
+			continue
+		}
+		for _, hours := range c.sessions {
+			open, closeTime := c.at(date, hours.Open), c.at(date, hours.Close)
+			if !t.Before(open) && t.Before(closeTime) {
+				return hours.Session, open, closeTime, true
+			}
+		}
+	}
+	return 0, TimeZero, TimeZero, false
+}
+
+func (c *SessionCalendar) hours(date time.Time, session Session) (SessionHours, bool) {
+	if !c.IsTradingDay(date) {
+		return SessionHours{}, false
+	}
+	for _, hours := range c.sessions {
+		if hours.Session == session {
+			return hours, true
+		}
+	}
+	return SessionHours{}, false
+}
+
+// at returns the wall clock time of the offset from midnight on the given date.
+// This is done on the wall clock, rather than adding a duration, so daylight savings is handled correctly.
+func (c *SessionCalendar) at(date time.Time, offset time.Duration) time.Time {
+	date = date.In(c.location)
+	hour := int(offset / time.Hour)
+	minute := int((offset % time.Hour) / time.Minute)
+	second := int((offset % time.Minute) / time.Second)
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, c.location)
+}
+
+func (c *SessionCalendar) dateKey(date time.Time) string {
+	return date.In(c.location).Format("2006-01-02")
+}
@@ -0,0 +1,186 @@
+package time_series
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		require.Equal(t, Overnight.String(), overnightSessionStr)
+		require.Equal(t, PreMarket.String(), preMarketSessionStr)
+		require.Equal(t, Regular.String(), regularSessionStr)
+		require.Equal(t, AfterHours.String(), afterHoursSessionStr)
+	})
+}
+
+func TestSessionCalendar(t *testing.T) {
+	t.Parallel()
+
+	calendar := USEquityCalendar()
+	location := calendar.Location()
+
+	// Thursday December 1st, 2022 in New York
+	thursday := time.Date(2022, 12, 1, 0, 0, 0, 0, location)
+
+	t.Run("New", func(t *testing.T) {
+		weekdays := []time.Weekday{time.Monday}
+		type args struct {
+			location *time.Location
+			weekdays []time.Weekday
+			hours    []SessionHours
+			ok       bool
+		}
+		tests := map[string]args{
+			"Nil location":     {nil, weekdays, []SessionHours{{Regular, time.Hour, 2 * time.Hour}}, false},
+			"No weekdays":      {time.UTC, nil, []SessionHours{{Regular, time.Hour, 2 * time.Hour}}, false},
+			"No sessions":      {time.UTC, weekdays, nil, false},
+			"Empty session":    {time.UTC, weekdays, []SessionHours{{Regular, time.Hour, time.Hour}}, false},
+			"Overlapping":      {time.UTC, weekdays, []SessionHours{{Regular, time.Hour, 3 * time.Hour}, {AfterHours, 2 * time.Hour, 4 * time.Hour}}, false},
+			"OK":               {time.UTC, weekdays, []SessionHours{{AfterHours, 2 * time.Hour, 4 * time.Hour}, {Regular, time.Hour, 2 * time.Hour}}, true},
+			"OK - 24h session": {time.UTC, weekdays, []SessionHours{{Regular, 0, 24 * time.Hour}}, true},
+		}
+		for key, arg := range tests {
+			t.Run(key, func(t *testing.T) {
+				output, err := NewSessionCalendar(arg.location, arg.weekdays, arg.hours...)
+				if !arg.ok {
+					require.Error(t, err)
+					require.Nil(t, output)
+				} else {
+					require.NoError(t, err)
+					require.NotNil(t, output)
+				}
+			})
+		}
+	})
+
+	t.Run("SessionAt", func(t *testing.T) {
+		type args struct {
+			value   time.Time
+			session Session
+			ok      bool
+		}
+		tests := map[string]args{
+			"Overnight - evening before": {thursday.Add(-3 * time.Hour), Overnight, true},
+			"Overnight - early morning":  {thursday.Add(3 * time.Hour), Overnight, true},
+			"PreMarket":                  {thursday.Add(4 * time.Hour), PreMarket, true},
+			"Regular - open":             {thursday.Add(9*time.Hour + 30*time.Minute), Regular, true},
+			"Regular - before close":     {thursday.Add(16*time.Hour - time.Second), Regular, true},
+			"AfterHours - close":         {thursday.Add(16 * time.Hour), AfterHours, true},
+			"Overnight - next day":       {thursday.Add(21 * time.Hour), Overnight, true},
+			"Friday night is closed":     {thursday.AddDate(0, 0, 1).Add(21 * time.Hour), 0, false},
+			"Saturday is closed":         {thursday.AddDate(0, 0, 2).Add(12 * time.Hour), 0, false},
+			"Sunday night is overnight":  {thursday.AddDate(0, 0, 3).Add(21 * time.Hour), Overnight, true},
+			"UTC input":                  {time.Date(2022, 12, 1, 15, 0, 0, 0, time.UTC), Regular, true},
+		}
+		for key, arg := range tests {
+			t.Run(key, func(t *testing.T) {
+				session, ok := calendar.SessionAt(arg.value)
+				require.Equal(t, ok, arg.ok)
+				require.Equal(t, session, arg.session)
+			})
+		}
+	})
+
+	t.Run("Holidays", func(t *testing.T) {
+		calendar := USEquityCalendar()
+		christmas := time.Date(2022, 12, 26, 12, 0, 0, 0, location)
+		require.True(t, calendar.IsTradingDay(christmas))
+		calendar.AddHoliday(christmas)
+		require.False(t, calendar.IsTradingDay(christmas))
+		_, ok := calendar.SessionAt(christmas)
+		require.False(t, ok)
+	})
+
+	t.Run("Open and Close", func(t *testing.T) {
+		open, err := calendar.Open(thursday, Regular)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), thursday.Add(9*time.Hour+30*time.Minute).String())
+
+		closeTime, err := calendar.Close(thursday, Regular)
+		require.NoError(t, err)
+		require.Equal(t, closeTime.String(), thursday.Add(16*time.Hour).String())
+
+		open, err = calendar.Open(thursday, Overnight)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), thursday.Add(-4*time.Hour).String())
+
+		// Weekends have no sessions
+		_, err = calendar.Open(thursday.AddDate(0, 0, 2), Regular)
+		require.Error(t, err)
+
+		// Daylight savings started March 13th 2022, the regular open is still 09:30 local time
+		dst := time.Date(2022, 3, 14, 0, 0, 0, 0, location)
+		open, err = calendar.Open(dst, Regular)
+		require.NoError(t, err)
+		require.Equal(t, open.Hour(), 9)
+		require.Equal(t, open.Minute(), 30)
+		require.Equal(t, open.UTC().Hour(), 13)
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		session, open, closeTime, ok := calendar.Bounds(thursday.Add(12 * time.Hour))
+		require.True(t, ok)
+		require.Equal(t, session, Regular)
+		require.Equal(t, open.String(), thursday.Add(9*time.Hour+30*time.Minute).String())
+		require.Equal(t, closeTime.String(), thursday.Add(16*time.Hour).String())
+	})
+
+	t.Run("PreviousOpen", func(t *testing.T) {
+		// Pre-market belongs to yesterday's regular session
+		open, err := calendar.PreviousOpen(thursday.Add(5*time.Hour), Regular)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), thursday.AddDate(0, 0, -1).Add(9*time.Hour+30*time.Minute).String())
+
+		// Exactly at the open
+		open, err = calendar.PreviousOpen(thursday.Add(9*time.Hour+30*time.Minute), Regular)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), thursday.Add(9*time.Hour+30*time.Minute).String())
+
+		// Monday morning looks back to Friday
+		monday := thursday.AddDate(0, 0, 4)
+		open, err = calendar.PreviousOpen(monday.Add(time.Hour), Regular)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), thursday.AddDate(0, 0, 1).Add(9*time.Hour+30*time.Minute).String())
+
+		// The overnight session opens the evening before
+		open, err = calendar.PreviousOpen(monday.Add(time.Hour), Overnight)
+		require.NoError(t, err)
+		require.Equal(t, open.String(), monday.Add(-4*time.Hour).String())
+	})
+
+	t.Run("NewTimeSeries", func(t *testing.T) {
+		// Thursday and Friday regular hours, then the weekend
+		series, err := calendar.NewTimeSeries(thursday, thursday.AddDate(0, 0, 3), time.Hour, Regular)
+		require.NoError(t, err)
+		require.Equal(t, series.IntervalSize(), time.Hour)
+		require.Equal(t, series.MinValue().String(), thursday.Add(9*time.Hour+30*time.Minute).String())
+		require.Equal(t, series.MaxValue().String(), thursday.AddDate(0, 0, 1).Add(15*time.Hour+30*time.Minute).String())
+
+		// 7x hours per day, for 2x days
+		value, err := series.Offset(13)
+		require.NoError(t, err)
+		require.Equal(t, value.String(), series.MaxValue().String())
+		_, err = series.Offset(14)
+		require.Error(t, err)
+
+		// Tuesday evening is part of Wednesday's overnight session, even though the range starts later in the day than it ends
+		monday := thursday.AddDate(0, 0, 4)
+		series, err = calendar.NewTimeSeries(monday.Add(23*time.Hour), monday.AddDate(0, 0, 1).Add(22*time.Hour), time.Hour, Overnight)
+		require.NoError(t, err)
+		require.Equal(t, series.MinValue().String(), monday.Add(23*time.Hour).String())
+		require.Equal(t, series.MaxValue().String(), monday.AddDate(0, 0, 1).Add(22*time.Hour).String())
+		value, err = series.Offset(4)
+		require.NoError(t, err)
+		require.Equal(t, value.String(), monday.AddDate(0, 0, 1).Add(3*time.Hour).String())
+		value, err = series.Offset(5)
+		require.NoError(t, err)
+		require.Equal(t, value.String(), monday.AddDate(0, 0, 1).Add(20*time.Hour).String())
+
+		// No sessions on the weekend
+		saturday := thursday.AddDate(0, 0, 2)
+		_, err = calendar.NewTimeSeries(saturday, saturday.Add(12*time.Hour), time.Hour)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,225 @@
+package time_series
+
+import (
+	"sync"
+	"time"
+)
+
+// Compile time type assertion
+var _ TimeSeries = &SharedTimeSeries{}
+
+// SharedTimeSeries is a thread-safe clock that many go-routines can observe at the same time.
+//
+// There is a single driver that moves the clock with `Add` or `MoveTo`,
+// every other go-routine is an observer that either reads the current value or subscribes to every tick.
+//
+// NOTE: Each tick is delivered to every subscriber before the driver returns,
+//       so a slow subscriber will slow down the driver rather than miss a tick.
+//       This keeps parallel simulations in lock-step with the clock.
+//
+type SharedTimeSeries struct {
+	// lock guards the series, the subscribers, and the closed flag
+	lock   sync.RWMutex
+	series TimeSeries
+	closed bool
+
+	// tickLock serializes the ticks so every subscriber sees them in order
+	tickLock    sync.Mutex
+	subscribers map[int]*subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	output chan time.Time
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *subscriber) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// NewSharedTimeSeries creates a new SharedTimeSeries from a copy of the input series,
+// so the caller can no longer move the shared clock through the original instance.
+//
+func NewSharedTimeSeries(series TimeSeries) (*SharedTimeSeries, error) {
+	if nil == series {
+		return nil, InvalidArgument
+	}
+	value, err := series.Copy()
+	if nil != err {
+		return nil, err
+	}
+	output := &SharedTimeSeries{
+		series:      value,
+		subscribers: make(map[int]*subscriber),
+	}
+	return output, nil
+}
+
+func (s *SharedTimeSeries) IntervalSize() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.IntervalSize()
+}
+
+func (s *SharedTimeSeries) MinValue() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.MinValue()
+}
+
+func (s *SharedTimeSeries) MaxValue() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.MaxValue()
+}
+
+func (s *SharedTimeSeries) CurrentValue() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.CurrentValue()
+}
+
+func (s *SharedTimeSeries) Offset(units int) (time.Time, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.Offset(units)
+}
+
+func (s *SharedTimeSeries) Range(start, end int) ([]time.Time, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.Range(start, end)
+}
+
+// Add moves the clock by N units and notifies every subscriber of the new value.
+func (s *SharedTimeSeries) Add(units int) error {
+	return s.tick(func(series TimeSeries) error {
+		return series.Add(units)
+	})
+}
+
+// MoveTo moves the clock to a specific time and notifies every subscriber of the new value.
+func (s *SharedTimeSeries) MoveTo(value time.Time) error {
+	return s.tick(func(series TimeSeries) error {
+		return series.MoveTo(value)
+	})
+}
+
+// Copy creates a private, non-shared, copy of the clock at it's current point in time.
+// Moving the copy has no effect on the shared clock or it's subscribers.
+func (s *SharedTimeSeries) Copy() (TimeSeries, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.series.Copy()
+}
+
+// Subscribe returns a channel that receives the new current value after every tick,
+// along with a function to cancel the subscription.
+//
+// The channel is closed once the subscription is cancelled or the clock is closed.
+// The bufferSize allows the driver to run ahead of the subscriber by N ticks.
+//
+// Errors:
+// - If the clock is already closed an error with GRPC status FailedPrecondition will be returned
+//
+func (s *SharedTimeSeries) Subscribe(bufferSize int) (<-chan time.Time, func(), error) {
+	if bufferSize < 0 {
+		return nil, nil, InvalidArgument
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil, nil, FailedPrecondition
+	}
+
+	id := s.nextID
+	s.nextID++
+	sub := &subscriber{
+		output: make(chan time.Time, bufferSize),
+		done:   make(chan struct{}),
+	}
+	s.subscribers[id] = sub
+
+	unsubscribe := func() {
+		// Release the driver first, it may be blocked waiting for us to read a tick
+		sub.stop()
+		s.tickLock.Lock()
+		defer s.tickLock.Unlock()
+		s.remove(id)
+	}
+	return sub.output, unsubscribe, nil
+}
+
+// Close stops the clock and closes every subscriber's channel.
+// Any further calls to `Add` or `MoveTo` will fail.
+func (s *SharedTimeSeries) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	for _, sub := range s.subscribers {
+		sub.stop()
+	}
+	s.lock.Unlock()
+
+	s.tickLock.Lock()
+	defer s.tickLock.Unlock()
+	s.lock.RLock()
+	ids := make([]int, 0, len(s.subscribers))
+	for id := range s.subscribers {
+		ids = append(ids, id)
+	}
+	s.lock.RUnlock()
+	for _, id := range ids {
+		s.remove(id)
+	}
+	return nil
+}
+
+// remove drops a subscriber and closes it's channel, the caller must hold the tickLock
+// so we never close a channel that the driver is sending on.
+func (s *SharedTimeSeries) remove(id int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sub, ok := s.subscribers[id]
+	if !ok {
+		return
+	}
+	delete(s.subscribers, id)
+	close(sub.output)
+}
+
+func (s *SharedTimeSeries) tick(move func(series TimeSeries) error) error {
+	s.tickLock.Lock()
+	defer s.tickLock.Unlock()
+
+	// Move the clock and take a snapshot of who needs to know about it
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return FailedPrecondition
+	}
+	err := move(s.series)
+	if nil != err {
+		s.lock.Unlock()
+		return err
+	}
+	value := s.series.CurrentValue()
+	subscribers := make([]*subscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	s.lock.Unlock()
+
+	// Deliver the tick without holding the lock, so subscribers are free to read the clock
+	for _, sub := range subscribers {
+		select {
+		case sub.output <- value:
+		case <-sub.done:
+		}
+	}
+	return nil
+}
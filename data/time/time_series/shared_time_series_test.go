@@ -0,0 +1,168 @@
+package time_series
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharedTimeSeries(t *testing.T) {
+	t.Parallel()
+
+	// December 1st, 2022
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	values := []time.Time{
+		now.Add(-5 * Day),
+		now.Add(-4 * Day),
+		now.Add(-2 * Day), // Weekend hop
+		now.Add(-1 * Day),
+		now,
+		now.Add(1 * Day),
+		now.Add(2 * Day),
+	}
+
+	newSeries := func(t *testing.T) *SharedTimeSeries {
+		series, err := NewInMemoryTimeSeries(Day, values)
+		require.NoError(t, err)
+		shared, err := NewSharedTimeSeries(series)
+		require.NoError(t, err)
+		require.NotNil(t, shared)
+		return shared
+	}
+
+	t.Run("New", func(t *testing.T) {
+		output, err := NewSharedTimeSeries(nil)
+		require.Error(t, err)
+		require.Nil(t, output)
+
+		// The original series must not be able to move the shared clock
+		series, err := NewInMemoryTimeSeries(Day, values)
+		require.NoError(t, err)
+		shared, err := NewSharedTimeSeries(series)
+		require.NoError(t, err)
+		err = series.Add(4)
+		require.NoError(t, err)
+		require.Equal(t, shared.CurrentValue().String(), values[0].String())
+		require.Equal(t, shared.IntervalSize(), Day)
+		require.Equal(t, shared.MinValue().String(), values[0].String())
+		require.Equal(t, shared.MaxValue().String(), values[len(values)-1].String())
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		shared := newSeries(t)
+
+		ticks, unsubscribe, err := shared.Subscribe(len(values))
+		require.NoError(t, err)
+		defer unsubscribe()
+
+		err = shared.Add(1)
+		require.NoError(t, err)
+		err = shared.MoveTo(now)
+		require.NoError(t, err)
+
+		// Out of range moves are not ticks
+		err = shared.Add(10)
+		require.Error(t, err)
+
+		require.Equal(t, (<-ticks).String(), values[1].String())
+		require.Equal(t, (<-ticks).String(), now.String())
+		require.Len(t, ticks, 0)
+
+		_, _, err = shared.Subscribe(-1)
+		require.Error(t, err)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		shared := newSeries(t)
+
+		// Un-buffered and never read, the driver must not block forever
+		ticks, unsubscribe, err := shared.Subscribe(0)
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			done <- shared.Add(1)
+		}()
+		unsubscribe()
+		require.NoError(t, <-done)
+
+		// The channel is closed after any pending tick is drained
+		for range ticks {
+		}
+
+		// Calling it twice is fine
+		unsubscribe()
+		require.NoError(t, shared.Add(1))
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		shared := newSeries(t)
+
+		ticks, unsubscribe, err := shared.Subscribe(1)
+		require.NoError(t, err)
+
+		err = shared.Close()
+		require.NoError(t, err)
+		_, ok := <-ticks
+		require.False(t, ok)
+		unsubscribe()
+
+		err = shared.Add(1)
+		require.Error(t, err)
+		_, _, err = shared.Subscribe(1)
+		require.Error(t, err)
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		shared := newSeries(t)
+
+		output, err := shared.Copy()
+		require.NoError(t, err)
+		err = output.Add(2)
+		require.NoError(t, err)
+		require.Equal(t, shared.CurrentValue().String(), values[0].String())
+		require.Equal(t, output.CurrentValue().String(), values[2].String())
+	})
+
+	t.Run("Concurrent Subscribers", func(t *testing.T) {
+		shared := newSeries(t)
+
+		const subscriberCount = 8
+		var wg sync.WaitGroup
+		results := make([][]time.Time, subscriberCount)
+		for index := 0; index < subscriberCount; index++ {
+			ticks, unsubscribe, err := shared.Subscribe(0)
+			require.NoError(t, err)
+
+			wg.Add(1)
+			go func(index int, ticks <-chan time.Time, unsubscribe func()) {
+				defer wg.Done()
+				defer unsubscribe()
+				for value := range ticks {
+					// Observers are free to read the clock while it is being driven
+					_ = shared.CurrentValue()
+					_, _ = shared.Range(-1, 0)
+					results[index] = append(results[index], value)
+				}
+			}(index, ticks, unsubscribe)
+		}
+
+		// Single driver walks the entire series
+		for index := 1; index < len(values); index++ {
+			err := shared.Add(1)
+			require.NoError(t, err)
+		}
+		err := shared.Close()
+		require.NoError(t, err)
+		wg.Wait()
+
+		for _, result := range results {
+			require.Len(t, result, len(values)-1)
+			for index, value := range result {
+				require.Equal(t, value.String(), values[index+1].String())
+			}
+		}
+	})
+}
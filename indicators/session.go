@@ -0,0 +1,117 @@
+package indicators
+
+import (
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"math"
+)
+
+// SessionVWAP is the volume weighted average price of the typical price ((high + low + close) / 3),
+// which resets at every open of the anchor session.
+//
+// Example: with an anchor of time_series.Regular the VWAP starts over at 09:30 each day,
+// and any pre-market bars are included in the previous day's VWAP.
+//
+// There is one output value per input bar. Each bar is anchored to the most recent open within the last two weeks,
+// even when that open is before the first bar, see time_series.SessionCalendar.PreviousOpen.
+// Bars without an open in the last two weeks are NaN, as are bars when there hasn't been any volume since the open.
+//
+func SessionVWAP(bars []bar.Bar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	return SessionVWAPColumnar(bar.NewColumnar(bars), calendar, anchor)
+}
+
+// SessionVWAPColumnar is the SessionVWAP of bars in columnar form
+func SessionVWAPColumnar(bars *bar.Columnar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	var totalPrice, totalVolume float64
+	return anchored(
+		bars,
+		calendar,
+		anchor,
+		func() {
+			totalPrice, totalVolume = 0, 0
+		},
+		func(index int) float64 {
+			typicalPrice := (bars.High[index] + bars.Low[index] + bars.Close[index]) / 3.0
+			totalPrice += typicalPrice * bars.Volume[index]
+			totalVolume += bars.Volume[index]
+			if totalVolume == 0 {
+				return math.NaN()
+			}
+			return totalPrice / totalVolume
+		},
+	)
+}
+
+// SessionHigh is the highest high since the most recent open of the anchor session.
+// There is one output value per input bar, bars without an open in the last two weeks are NaN, see SessionVWAP.
+func SessionHigh(bars []bar.Bar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	return SessionHighColumnar(bar.NewColumnar(bars), calendar, anchor)
+}
+
+// SessionHighColumnar is the SessionHigh of bars in columnar form
+func SessionHighColumnar(bars *bar.Columnar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	high := math.Inf(-1)
+	return anchored(
+		bars,
+		calendar,
+		anchor,
+		func() {
+			high = math.Inf(-1)
+		},
+		func(index int) float64 {
+			high = math.Max(high, bars.High[index])
+			return high
+		},
+	)
+}
+
+// SessionLow is the lowest low since the most recent open of the anchor session.
+// There is one output value per input bar, bars without an open in the last two weeks are NaN, see SessionVWAP.
+func SessionLow(bars []bar.Bar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	return SessionLowColumnar(bar.NewColumnar(bars), calendar, anchor)
+}
+
+// SessionLowColumnar is the SessionLow of bars in columnar form
+func SessionLowColumnar(bars *bar.Columnar, calendar *time_series.SessionCalendar, anchor time_series.Session) []float64 {
+	low := math.Inf(1)
+	return anchored(
+		bars,
+		calendar,
+		anchor,
+		func() {
+			low = math.Inf(1)
+		},
+		func(index int) float64 {
+			low = math.Min(low, bars.Low[index])
+			return low
+		},
+	)
+}
+
+// anchored walks the bars in order, calling reset each time a new anchor session has opened
+// and step for every bar to compute it's output value.
+func anchored(
+	bars *bar.Columnar,
+	calendar *time_series.SessionCalendar,
+	anchor time_series.Session,
+	reset func(),
+	step func(index int) float64,
+) []float64 {
+	output := make([]float64, 0, bars.Len())
+	var currentOpen int64
+	started := false
+	for index := 0; index < bars.Len(); index++ {
+		open, err := calendar.PreviousOpen(bars.TimeAt(index), anchor)
+		if nil != err {
+			output = append(output, math.NaN())
+			continue
+		}
+		if !started || open.Unix() != currentOpen {
+			reset()
+			started = true
+			currentOpen = open.Unix()
+		}
+		output = append(output, step(index))
+	}
+	return output
+}
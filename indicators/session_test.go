@@ -0,0 +1,79 @@
+package indicators
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/ta4g/ta4g/data/interval/bar"
+	"github.com/ta4g/ta4g/data/time/time_series"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSessionIndicators(t *testing.T) {
+	calendar := time_series.USEquityCalendar()
+
+	// Thursday December 1st, 2022 in New York
+	thursday := time.Date(2022, 12, 1, 0, 0, 0, 0, calendar.Location())
+	friday := thursday.AddDate(0, 0, 1)
+
+	bars := []bar.Bar{
+		// Pre-market is anchored to Wednesday's regular open
+		bar.New(thursday.Add(9*time.Hour), 10, 10, 10, 10, 100, -1),
+		// Thursday regular session
+		bar.New(thursday.Add(10*time.Hour), 10, 12, 9, 12, 100, -1),
+		bar.New(thursday.Add(11*time.Hour), 12, 15, 12, 15, 300, -1),
+		// After hours and Friday pre-market are still part of Thursday's session
+		bar.New(thursday.Add(17*time.Hour), 15, 16, 15, 16, 0, -1),
+		bar.New(friday.Add(5*time.Hour), 16, 18, 16, 18, 100, -1),
+		// Friday regular open resets everything
+		bar.New(friday.Add(9*time.Hour+30*time.Minute), 20, 21, 19, 20, 100, -1),
+	}
+
+	t.Run("SessionVWAP", func(t *testing.T) {
+		output := SessionVWAP(bars, calendar, time_series.Regular)
+		require.Len(t, output, len(bars))
+		require.InDelta(t, output[0], 10.0, 1e-9)
+
+		// Typical prices: 11, 14, 15.666, 17.333, 20
+		require.InDelta(t, output[1], 11.0, 1e-9)
+		require.InDelta(t, output[2], (11.0*100+14.0*300)/400, 1e-9)
+		require.InDelta(t, output[3], output[2], 1e-9) // No volume
+		require.InDelta(t, output[4], (11.0*100+14.0*300+(52.0/3)*100)/500, 1e-9)
+		require.InDelta(t, output[5], 20.0, 1e-9)
+	})
+
+	t.Run("Nothing to anchor to", func(t *testing.T) {
+		// A market that has never had a regular session
+		empty, err := time_series.NewSessionCalendar(
+			calendar.Location(),
+			[]time.Weekday{time.Sunday},
+			time_series.SessionHours{Session: time_series.PreMarket, Open: time.Hour, Close: 2 * time.Hour},
+		)
+		require.NoError(t, err)
+
+		output := SessionVWAP(bars, empty, time_series.Regular)
+		require.Len(t, output, len(bars))
+		for _, value := range output {
+			require.True(t, math.IsNaN(value))
+		}
+	})
+
+	t.Run("SessionHigh", func(t *testing.T) {
+		output := SessionHigh(bars, calendar, time_series.Regular)
+		require.Len(t, output, len(bars))
+		require.Equal(t, output, []float64{10, 12, 15, 16, 18, 21})
+	})
+
+	t.Run("SessionLow", func(t *testing.T) {
+		output := SessionLow(bars, calendar, time_series.Regular)
+		require.Len(t, output, len(bars))
+		require.Equal(t, output, []float64{10, 9, 9, 9, 9, 19})
+	})
+
+	t.Run("Columnar", func(t *testing.T) {
+		columns := bar.NewColumnar(bars)
+		require.Equal(t, SessionVWAPColumnar(columns, calendar, time_series.Regular), SessionVWAP(bars, calendar, time_series.Regular))
+		require.Equal(t, SessionHighColumnar(columns, calendar, time_series.Regular), SessionHigh(bars, calendar, time_series.Regular))
+		require.Equal(t, SessionLowColumnar(columns, calendar, time_series.Regular), SessionLow(bars, calendar, time_series.Regular))
+	})
+}